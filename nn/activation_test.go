@@ -0,0 +1,88 @@
+package nn
+
+import (
+	"testing"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+func TestActivationModulesMatchTensorOps(t *testing.T) {
+	input := tensor.MustNew([]float64{-3.5, -1, -0.2, 0.3, 2, 7}, 2, 3)
+	exactGELU := tensor.GELU(input)
+	tanhGELU := tensor.GELUTanh(input)
+	softmax, _ := tensor.Softmax(input, 1)
+	logSoftmax, _ := tensor.LogSoftmax(input, 1)
+	glu, _ := tensor.GLU(tensor.MustNew(input.Data(), 3, 2), 1)
+	cases := []struct {
+		name string
+		mod  Module
+		want *tensor.Tensor
+	}{
+		{"leaky relu", LeakyRelu(0.1), tensor.LeakyRelu(input, 0.1)},
+		{"elu", ELU(1), tensor.ELU(input, 1)},
+		{"celu", CELU(2), tensor.CELU(input, 2)},
+		{"softplus", Softplus(2), tensor.Softplus(input, 2)},
+		{"gelu", GELU(""), exactGELU},
+		{"gelu tanh", GELU("tanh"), tanhGELU},
+		{"silu", SiLU(), tensor.SiLU(input)},
+		{"mish", Mish(), tensor.Mish(input)},
+		{"hardswish", Hardswish(), tensor.Hardswish(input)},
+		{"hardsigmoid", Hardsigmoid(), tensor.Hardsigmoid(input)},
+		{"selu", SELU(), tensor.SELU(input)},
+		{"softsign", Softsign(), tensor.Softsign(input)},
+		{"relu6", Relu6(), tensor.Relu6(input)},
+		{"softmax", Softmax(1), softmax},
+		{"log softmax", LogSoftmax(-1), logSoftmax},
+	}
+	for _, tc := range cases {
+		out, err := tc.mod.Forward(input)
+		if err != nil {
+			t.Fatalf("%s forward failed: %v", tc.name, err)
+		}
+		if !floatsAlmostEqual(out.Data(), tc.want.Data(), 1e-12) {
+			t.Fatalf("%s mismatch: got %v want %v", tc.name, out.Data(), tc.want.Data())
+		}
+	}
+	out, err := GLU(1).Forward(tensor.MustNew(input.Data(), 3, 2))
+	if err != nil || !floatsAlmostEqual(out.Data(), glu.Data(), 1e-12) {
+		t.Fatalf("glu mismatch: %v (err %v)", out, err)
+	}
+	if _, err := GELU("sigmoid").Forward(input); err == nil {
+		t.Fatalf("expected unknown approximation error")
+	}
+}
+
+func TestPReLULearnsSlope(t *testing.T) {
+	p, err := NewPReLU(2)
+	if err != nil {
+		t.Fatalf("NewPReLU failed: %v", err)
+	}
+	if !floatsAlmostEqual(p.Weight().Data(), []float64{0.25, 0.25}, 0) {
+		t.Fatalf("unexpected default slope %v", p.Weight().Data())
+	}
+	if zero, err := NewPReLUWithInit(1, 0); err != nil || zero.Weight().Data()[0] != 0 {
+		t.Fatalf("a zero initial slope must be kept: %v", err)
+	}
+	if _, err := NewPReLUWithInit(0, 0.1); err == nil {
+		t.Fatalf("expected parameter count error")
+	}
+	input := tensor.MustNew([]float64{-2, 1, 3, -4}, 1, 2, 2)
+	out, err := p.Forward(input)
+	if err != nil {
+		t.Fatalf("prelu forward failed: %v", err)
+	}
+	if !floatsAlmostEqual(out.Data(), []float64{-0.5, 1, 3, -1}, 1e-12) {
+		t.Fatalf("unexpected prelu output %v", out.Data())
+	}
+	if err := tensor.Sum(out).Backward(); err != nil {
+		t.Fatalf("prelu backward failed: %v", err)
+	}
+	if !floatsAlmostEqual(p.Weight().Grad().Data(), []float64{-2, -4}, 1e-12) {
+		t.Fatalf("unexpected slope gradient %v", p.Weight().Grad().Data())
+	}
+	state := map[string]*tensor.Tensor{}
+	p.StateDict("act", state)
+	if got := stateKeys(state); got != "act.weight" {
+		t.Fatalf("unexpected prelu state keys %s", got)
+	}
+}
@@ -0,0 +1,109 @@
+package nn
+
+import "github.com/fumitoshi0524/ixeoriNet/tensor"
+
+// AdaptiveAvgPool averages its input down to a fixed spatial size regardless
+// of the input resolution.
+type AdaptiveAvgPool struct {
+	outputSize []int
+}
+
+func NewAdaptiveAvgPool1d(outW int) *AdaptiveAvgPool {
+	return &AdaptiveAvgPool{outputSize: []int{outW}}
+}
+
+func NewAdaptiveAvgPool2d(outH, outW int) *AdaptiveAvgPool {
+	return &AdaptiveAvgPool{outputSize: []int{outH, outW}}
+}
+
+func NewAdaptiveAvgPool3d(outD, outH, outW int) *AdaptiveAvgPool {
+	return &AdaptiveAvgPool{outputSize: []int{outD, outH, outW}}
+}
+
+func (a *AdaptiveAvgPool) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	return tensor.AdaptiveAvgPool(input, a.outputSize)
+}
+
+func (a *AdaptiveAvgPool) Parameters() []*tensor.Tensor {
+	return nil
+}
+
+func (a *AdaptiveAvgPool) ZeroGrad() {}
+
+// AdaptiveMaxPool takes window maxima so the spatial output has a fixed size.
+type AdaptiveMaxPool struct {
+	outputSize []int
+}
+
+func NewAdaptiveMaxPool1d(outW int) *AdaptiveMaxPool {
+	return &AdaptiveMaxPool{outputSize: []int{outW}}
+}
+
+func NewAdaptiveMaxPool2d(outH, outW int) *AdaptiveMaxPool {
+	return &AdaptiveMaxPool{outputSize: []int{outH, outW}}
+}
+
+func NewAdaptiveMaxPool3d(outD, outH, outW int) *AdaptiveMaxPool {
+	return &AdaptiveMaxPool{outputSize: []int{outD, outH, outW}}
+}
+
+func (a *AdaptiveMaxPool) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	return tensor.AdaptiveMaxPool(input, a.outputSize)
+}
+
+// ForwardWithIndices also returns the argmax positions for MaxUnpool.
+func (a *AdaptiveMaxPool) ForwardWithIndices(input *tensor.Tensor) (*tensor.Tensor, []int, error) {
+	return tensor.AdaptiveMaxPoolWithIndices(input, a.outputSize)
+}
+
+func (a *AdaptiveMaxPool) Parameters() []*tensor.Tensor {
+	return nil
+}
+
+func (a *AdaptiveMaxPool) ZeroGrad() {}
+
+// GlobalAvgPool averages every spatial position of a [batch, channels, ...]
+// input and returns [batch, channels], so it can feed a Linear head directly.
+type GlobalAvgPool struct{}
+
+func NewGlobalAvgPool() *GlobalAvgPool {
+	return &GlobalAvgPool{}
+}
+
+func (g *GlobalAvgPool) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	out, err := tensor.AdaptiveAvgPool(input, []int{1})
+	if err != nil {
+		return nil, err
+	}
+	shape := input.Shape()
+	return out.Reshape(shape[0], shape[1])
+}
+
+func (g *GlobalAvgPool) Parameters() []*tensor.Tensor {
+	return nil
+}
+
+func (g *GlobalAvgPool) ZeroGrad() {}
+
+// GlobalMaxPool takes the maximum over every spatial position of a
+// [batch, channels, ...] input and returns [batch, channels].
+type GlobalMaxPool struct{}
+
+func NewGlobalMaxPool() *GlobalMaxPool {
+	return &GlobalMaxPool{}
+}
+
+func (g *GlobalMaxPool) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	out, err := tensor.AdaptiveMaxPool(input, []int{1})
+	if err != nil {
+		return nil, err
+	}
+	shape := input.Shape()
+	return out.Reshape(shape[0], shape[1])
+}
+
+func (g *GlobalMaxPool) Parameters() []*tensor.Tensor {
+	return nil
+}
+
+func (g *GlobalMaxPool) ZeroGrad() {}
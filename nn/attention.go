@@ -26,7 +26,7 @@ type Attention struct {
 
 // NewAttention creates a Transformer-style attention block for inputs with
 // sequence length seq and embedding dimension dim.
-func NewAttention(seq, dim int) *Attention {
+func NewAttention(seq, dim int, opts ...InitOption) *Attention {
     // init weights [dim, dim]
     wq := tensor.Randn(dim, dim)
     wk := tensor.Randn(dim, dim)
@@ -42,6 +42,7 @@ func NewAttention(seq, dim int) *Attention {
     wk.SetRequiresGrad(true)
     wv.SetRequiresGrad(true)
     wo.SetRequiresGrad(true)
+    applyInit(opts, []*tensor.Tensor{wq, wk, wv, wo}, nil)
     ln := NewLayerNorm([]int{dim}, 1e-5, true)
     return &Attention{seq: seq, dim: dim, wq: wq, wk: wk, wv: wv, wo: wo, ln: ln}
 }
@@ -60,6 +61,56 @@ func (a *Attention) ZeroGrad() {
     }
 }
 
+func (a *Attention) Children() []Module {
+    return []Module{a.ln}
+}
+
+func (a *Attention) NamedChildren() []NamedModule {
+    return []NamedModule{{Name: "ln", Module: a.ln}}
+}
+
+func (a *Attention) NamedParameters() []NamedParameter {
+    named := []NamedParameter{
+        {Name: "wq", Param: a.wq},
+        {Name: "wk", Param: a.wk},
+        {Name: "wv", Param: a.wv},
+        {Name: "wo", Param: a.wo},
+    }
+    return append(named, prefixedParameters("ln", a.ln)...)
+}
+
+func (a *Attention) StateDict(prefix string, state map[string]*tensor.Tensor) {
+    if state == nil {
+        return
+    }
+    for _, p := range a.NamedParameters() {
+        state[joinPrefix(prefix, p.Name)] = p.Param.Clone()
+    }
+}
+
+// LoadState restores projections and the LayerNorm. Checkpoints written
+// before Attention had named keys used positional "param_<i>" keys and are
+// still accepted.
+func (a *Attention) LoadState(prefix string, state map[string]*tensor.Tensor) error {
+    if state == nil {
+        return fmt.Errorf("state dict is nil")
+    }
+    if _, ok := state[joinPrefix(prefix, "wq")]; !ok {
+        return loadParameters(prefix, a, state)
+    }
+    for _, p := range a.NamedParameters() {
+        key := joinPrefix(prefix, p.Name)
+        t, ok := state[key]
+        if !ok {
+            return fmt.Errorf("Attention missing %s", key)
+        }
+        if err := tensor.CopyInto(p.Param, t); err != nil {
+            return fmt.Errorf("load %s: %w", key, err)
+        }
+    }
+    return nil
+}
+
 func (a *Attention) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
     if input == nil {
         return nil, errors.New("attention: nil input")
@@ -173,3 +224,80 @@ func (a *Attention) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
     }
     return out, nil
 }
+
+// ForwardCached is the incremental form of Forward for autoregressive
+// decoding. input [batch, newLen, dim] holds only the new positions; their
+// keys and values are appended to cache, and each new position attends to
+// every cached position and to the new ones up to itself. Instead of pooling,
+// it returns the per-position outputs [batch, newLen, dim] after the residual
+// and LayerNorm, and the cached length is not bound to seq.
+func (a *Attention) ForwardCached(input *tensor.Tensor, cache *AttentionCache) (*tensor.Tensor, error) {
+    if cache == nil {
+        return nil, errors.New("ForwardCached requires a cache")
+    }
+    if input == nil {
+        return nil, errors.New("attention: nil input")
+    }
+    shape := input.Shape()
+    if len(shape) != 3 || shape[2] != a.dim {
+        return nil, fmt.Errorf("attention expects [batch, seq, %d] input, got %v", a.dim, shape)
+    }
+    batch, newLen := shape[0], shape[1]
+    x2d, err := input.Reshape(batch*newLen, a.dim)
+    if err != nil {
+        return nil, err
+    }
+    // project to a single head [batch, 1, newLen, dim]
+    project := func(w *tensor.Tensor) (*tensor.Tensor, error) {
+        p, err := tensor.MatMul(x2d, w.MustTranspose())
+        if err != nil {
+            return nil, err
+        }
+        return p.Reshape(batch, 1, newLen, a.dim)
+    }
+    q, err := project(a.wq)
+    if err != nil {
+        return nil, err
+    }
+    k, err := project(a.wk)
+    if err != nil {
+        return nil, err
+    }
+    v, err := project(a.wv)
+    if err != nil {
+        return nil, err
+    }
+    past := cache.Len()
+    keys, values, err := cache.append(k, v)
+    if err != nil {
+        return nil, err
+    }
+    kLen := past + newLen
+    mask := make([]float64, newLen*kLen)
+    for i := 0; i < newLen; i++ {
+        for j := past + i + 1; j < kLen; j++ {
+            mask[i*kLen+j] = math.Inf(-1)
+        }
+    }
+    ctx, _, err := tensor.ScaledDotProductAttention(q, keys, values, tensor.MustNew(mask, newLen, kLen), 0, false)
+    if err != nil {
+        return nil, err
+    }
+    ctx2d, err := ctx.Reshape(batch*newLen, a.dim)
+    if err != nil {
+        return nil, err
+    }
+    outProj2d, err := tensor.MatMul(ctx2d, a.wo.MustTranspose())
+    if err != nil {
+        return nil, err
+    }
+    outTok, err := tensor.Add(x2d, outProj2d)
+    if err != nil {
+        return nil, err
+    }
+    outNorm, err := a.ln.Forward(outTok)
+    if err != nil {
+        return nil, err
+    }
+    return outNorm.Reshape(batch, newLen, a.dim)
+}
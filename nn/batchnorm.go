@@ -16,6 +16,7 @@ type BatchNorm struct {
 	bias        *tensor.Tensor
 	runningMean *tensor.Tensor
 	runningVar  *tensor.Tensor
+	ranks       []int
 }
 
 func NewBatchNorm(numFeatures int, momentum, eps float64, affine bool) *BatchNorm {
@@ -49,7 +50,42 @@ func NewBatchNorm(numFeatures int, momentum, eps float64, affine bool) *BatchNor
 	}
 }
 
+// NewBatchNorm1d normalizes [batch, features] or [batch, channels, length]
+// inputs, as produced by Linear and Conv1d.
+func NewBatchNorm1d(numFeatures int, momentum, eps float64, affine bool) *BatchNorm {
+	bn := NewBatchNorm(numFeatures, momentum, eps, affine)
+	bn.ranks = []int{2, 3}
+	return bn
+}
+
+// NewBatchNorm2d normalizes [batch, channels, height, width] inputs.
+func NewBatchNorm2d(numFeatures int, momentum, eps float64, affine bool) *BatchNorm {
+	bn := NewBatchNorm(numFeatures, momentum, eps, affine)
+	bn.ranks = []int{4}
+	return bn
+}
+
+// NewBatchNorm3d normalizes [batch, channels, depth, height, width] inputs.
+func NewBatchNorm3d(numFeatures int, momentum, eps float64, affine bool) *BatchNorm {
+	bn := NewBatchNorm(numFeatures, momentum, eps, affine)
+	bn.ranks = []int{5}
+	return bn
+}
+
 func (bn *BatchNorm) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	if input != nil && len(bn.ranks) > 0 {
+		rank := len(input.Shape())
+		allowed := false
+		for _, r := range bn.ranks {
+			allowed = allowed || r == rank
+		}
+		if !allowed {
+			return nil, fmt.Errorf("BatchNorm expects input rank %v, got %d", bn.ranks, rank)
+		}
+	}
+	if input != nil && len(input.Shape()) >= 2 && input.Shape()[1] != bn.numFeatures {
+		return nil, fmt.Errorf("BatchNorm expects %d channels, got %d", bn.numFeatures, input.Shape()[1])
+	}
 	return tensor.BatchNorm(input, bn.runningMean, bn.runningVar, bn.weight, bn.bias, bn.momentum, bn.eps, bn.training)
 }
 
@@ -76,6 +112,17 @@ func (bn *BatchNorm) Eval() {
 	bn.training = false
 }
 
+func (bn *BatchNorm) IsTraining() bool {
+	return bn.training
+}
+
+func (bn *BatchNorm) NamedParameters() []NamedParameter {
+	return namedOrNil(
+		NamedParameter{Name: "weight", Param: bn.weight},
+		NamedParameter{Name: "bias", Param: bn.bias},
+	)
+}
+
 func (bn *BatchNorm) StateDict(prefix string, state map[string]*tensor.Tensor) {
 	if state == nil {
 		return
@@ -0,0 +1,128 @@
+package nn
+
+import (
+	"errors"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// CausalLM is a decoder-only language model: token embeddings, a stack of
+// causally masked Transformer layers and a linear head producing logits. It
+// implements IncrementalDecoder, so Generate can drive it directly.
+type CausalLM struct {
+	embed    *Embedding
+	blocks   *TransformerEncoder
+	head     *Linear
+	training bool
+}
+
+// NewCausalLM builds a model over vocab tokens with numLayers layers
+// configured by cfg and a final LayerNorm. Token embeddings carry no position
+// information, so cfg.Position should be "rope" or "alibi".
+func NewCausalLM(vocab int, cfg TransformerConfig, numLayers int) (*CausalLM, error) {
+	if vocab <= 0 {
+		return nil, errors.New("CausalLM requires a positive vocabulary size")
+	}
+	blocks, err := NewTransformerEncoder(cfg, numLayers, true)
+	if err != nil {
+		return nil, err
+	}
+	cfg, _ = cfg.withDefaults()
+	return &CausalLM{
+		embed:    NewEmbedding(vocab, cfg.DModel),
+		blocks:   blocks,
+		head:     NewLinear(cfg.DModel, vocab, true),
+		training: true,
+	}, nil
+}
+
+func (m *CausalLM) Embedding() *Embedding {
+	return m.embed
+}
+
+func (m *CausalLM) Blocks() *TransformerEncoder {
+	return m.blocks
+}
+
+func (m *CausalLM) Head() *Linear {
+	return m.head
+}
+
+// Forward maps tokens [batch, len] to logits [batch, len, vocab], each
+// position seeing only itself and earlier positions.
+func (m *CausalLM) Forward(tokens *tensor.Tensor) (*tensor.Tensor, error) {
+	x, err := m.embed.Forward(tokens)
+	if err != nil {
+		return nil, err
+	}
+	h, err := m.blocks.ForwardMasked(x, AttentionOptions{Causal: true})
+	if err != nil {
+		return nil, err
+	}
+	return linearOverSequence(m.head, h)
+}
+
+// ForwardStep is Forward for the new tokens only, with earlier positions read
+// from cache, which it extends.
+func (m *CausalLM) ForwardStep(tokens *tensor.Tensor, cache *KVCache) (*tensor.Tensor, error) {
+	if cache == nil {
+		return nil, errors.New("ForwardStep requires a cache")
+	}
+	x, err := m.embed.Forward(tokens)
+	if err != nil {
+		return nil, err
+	}
+	h, err := m.blocks.ForwardCached(x, cache, AttentionOptions{Causal: true})
+	if err != nil {
+		return nil, err
+	}
+	return linearOverSequence(m.head, h)
+}
+
+func (m *CausalLM) Parameters() []*tensor.Tensor {
+	return childrenParameters(m.Children())
+}
+
+func (m *CausalLM) ZeroGrad() {
+	for _, child := range m.Children() {
+		child.ZeroGrad()
+	}
+}
+
+func (m *CausalLM) Children() []Module {
+	return []Module{m.embed, m.blocks, m.head}
+}
+
+func (m *CausalLM) NamedChildren() []NamedModule {
+	return []NamedModule{
+		{Name: "embed", Module: m.embed},
+		{Name: "blocks", Module: m.blocks},
+		{Name: "head", Module: m.head},
+	}
+}
+
+func (m *CausalLM) NamedParameters() []NamedParameter {
+	return childrenNamedParameters(m.NamedChildren())
+}
+
+func (m *CausalLM) Train() {
+	m.training = true
+	m.blocks.Train()
+}
+
+func (m *CausalLM) Eval() {
+	m.training = false
+	m.blocks.Eval()
+}
+
+func (m *CausalLM) IsTraining() bool {
+	return m.training
+}
+
+func (m *CausalLM) StateDict(prefix string, state map[string]*tensor.Tensor) {
+	childrenStateDict(prefix, m.NamedChildren(), state)
+}
+
+func (m *CausalLM) LoadState(prefix string, state map[string]*tensor.Tensor) error {
+	return childrenLoadState(prefix, m.NamedChildren(), state)
+}
@@ -0,0 +1,281 @@
+package nn
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// ModuleList holds submodules in order so they are registered for
+// Parameters, StateDict and train/eval, while the owner decides how to call
+// them. StateDict keys are prefixed with the child index.
+type ModuleList struct {
+	modules  []Module
+	training bool
+}
+
+// NewModuleList builds a list from mods, none of which may be nil.
+func NewModuleList(mods ...Module) (*ModuleList, error) {
+	l := &ModuleList{modules: make([]Module, 0, len(mods)), training: true}
+	for _, mod := range mods {
+		if err := l.Append(mod); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// Append adds mod to the end of the list.
+func (l *ModuleList) Append(mod Module) error {
+	if mod == nil {
+		return fmt.Errorf("ModuleList entry %d: nil module", len(l.modules))
+	}
+	l.modules = append(l.modules, mod)
+	return nil
+}
+
+// Get returns the module at index idx, or false when idx is out of range.
+func (l *ModuleList) Get(idx int) (Module, bool) {
+	if idx < 0 || idx >= len(l.modules) {
+		return nil, false
+	}
+	return l.modules[idx], true
+}
+
+func (l *ModuleList) Len() int {
+	return len(l.modules)
+}
+
+// Forward always fails: a ModuleList has no defined call order. Iterate over
+// its modules instead.
+func (l *ModuleList) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	return nil, errors.New("ModuleList does not implement Forward; call its modules directly")
+}
+
+func (l *ModuleList) Parameters() []*tensor.Tensor {
+	var params []*tensor.Tensor
+	for _, m := range l.modules {
+		params = append(params, m.Parameters()...)
+	}
+	return params
+}
+
+func (l *ModuleList) ZeroGrad() {
+	for _, m := range l.modules {
+		m.ZeroGrad()
+	}
+}
+
+func (l *ModuleList) Children() []Module {
+	children := make([]Module, len(l.modules))
+	copy(children, l.modules)
+	return children
+}
+
+func (l *ModuleList) NamedChildren() []NamedModule {
+	named := make([]NamedModule, len(l.modules))
+	for idx, mod := range l.modules {
+		named[idx] = NamedModule{Name: fmt.Sprintf("%d", idx), Module: mod}
+	}
+	return named
+}
+
+func (l *ModuleList) NamedParameters() []NamedParameter {
+	return childrenNamedParameters(l.NamedChildren())
+}
+
+func (l *ModuleList) Train() {
+	l.training = true
+	for _, m := range l.modules {
+		SetTraining(m, true)
+	}
+}
+
+func (l *ModuleList) Eval() {
+	l.training = false
+	for _, m := range l.modules {
+		SetTraining(m, false)
+	}
+}
+
+func (l *ModuleList) IsTraining() bool {
+	return l.training
+}
+
+func (l *ModuleList) StateDict(prefix string, state map[string]*tensor.Tensor) {
+	childrenStateDict(prefix, l.NamedChildren(), state)
+}
+
+func (l *ModuleList) LoadState(prefix string, state map[string]*tensor.Tensor) error {
+	return childrenLoadState(prefix, l.NamedChildren(), state)
+}
+
+// ModuleDict holds submodules by name, keeping insertion order. StateDict
+// keys are prefixed with the child name.
+type ModuleDict struct {
+	names    []string
+	modules  map[string]Module
+	training bool
+}
+
+// NewModuleDict builds a dictionary from (name, module) pairs. Names must be
+// unique, non-empty and free of dots.
+func NewModuleDict(mods ...NamedModule) (*ModuleDict, error) {
+	d := &ModuleDict{modules: make(map[string]Module, len(mods)), training: true}
+	for _, nm := range mods {
+		if err := d.Set(nm.Name, nm.Module); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// Set adds mod under name, replacing any existing module with that name in
+// place. mod must not be nil.
+func (d *ModuleDict) Set(name string, mod Module) error {
+	if mod == nil {
+		return fmt.Errorf("ModuleDict entry %q: nil module", name)
+	}
+	if _, ok := d.modules[name]; !ok {
+		if err := validChildName(name, nil); err != nil {
+			return err
+		}
+		d.names = append(d.names, name)
+	}
+	d.modules[name] = mod
+	return nil
+}
+
+// Get returns the module stored under name.
+func (d *ModuleDict) Get(name string) (Module, bool) {
+	mod, ok := d.modules[name]
+	return mod, ok
+}
+
+// Keys returns the module names in insertion order.
+func (d *ModuleDict) Keys() []string {
+	return append([]string(nil), d.names...)
+}
+
+func (d *ModuleDict) Len() int {
+	return len(d.names)
+}
+
+// Forward always fails: a ModuleDict has no defined call order. Look up its
+// modules by name instead.
+func (d *ModuleDict) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	return nil, errors.New("ModuleDict does not implement Forward; call its modules directly")
+}
+
+func (d *ModuleDict) Parameters() []*tensor.Tensor {
+	var params []*tensor.Tensor
+	for _, name := range d.names {
+		params = append(params, d.modules[name].Parameters()...)
+	}
+	return params
+}
+
+func (d *ModuleDict) ZeroGrad() {
+	for _, name := range d.names {
+		d.modules[name].ZeroGrad()
+	}
+}
+
+func (d *ModuleDict) Children() []Module {
+	children := make([]Module, len(d.names))
+	for idx, name := range d.names {
+		children[idx] = d.modules[name]
+	}
+	return children
+}
+
+func (d *ModuleDict) NamedChildren() []NamedModule {
+	named := make([]NamedModule, len(d.names))
+	for idx, name := range d.names {
+		named[idx] = NamedModule{Name: name, Module: d.modules[name]}
+	}
+	return named
+}
+
+func (d *ModuleDict) NamedParameters() []NamedParameter {
+	return childrenNamedParameters(d.NamedChildren())
+}
+
+func (d *ModuleDict) Train() {
+	d.training = true
+	for _, name := range d.names {
+		SetTraining(d.modules[name], true)
+	}
+}
+
+func (d *ModuleDict) Eval() {
+	d.training = false
+	for _, name := range d.names {
+		SetTraining(d.modules[name], false)
+	}
+}
+
+func (d *ModuleDict) IsTraining() bool {
+	return d.training
+}
+
+func (d *ModuleDict) StateDict(prefix string, state map[string]*tensor.Tensor) {
+	childrenStateDict(prefix, d.NamedChildren(), state)
+}
+
+func (d *ModuleDict) LoadState(prefix string, state map[string]*tensor.Tensor) error {
+	return childrenLoadState(prefix, d.NamedChildren(), state)
+}
+
+func validChildName(name string, seen map[string]bool) error {
+	if name == "" {
+		return errors.New("module name must not be empty")
+	}
+	if strings.Contains(name, ".") {
+		return fmt.Errorf("module name %q must not contain '.'", name)
+	}
+	if seen != nil {
+		if seen[name] {
+			return fmt.Errorf("duplicate module name %q", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+func childrenNamedParameters(children []NamedModule) []NamedParameter {
+	var named []NamedParameter
+	for _, child := range children {
+		named = append(named, prefixedParameters(child.Name, child.Module)...)
+	}
+	return named
+}
+
+func childrenStateDict(prefix string, children []NamedModule, state map[string]*tensor.Tensor) {
+	for _, child := range children {
+		childPrefix := joinPrefix(prefix, child.Name)
+		if sm, ok := child.Module.(StatefulModule); ok {
+			sm.StateDict(childPrefix, state)
+		} else if len(child.Module.Parameters()) > 0 {
+			captureParameters(childPrefix, child.Module, state)
+		}
+	}
+}
+
+func childrenLoadState(prefix string, children []NamedModule, state map[string]*tensor.Tensor) error {
+	for _, child := range children {
+		childPrefix := joinPrefix(prefix, child.Name)
+		if sm, ok := child.Module.(StatefulModule); ok {
+			if err := sm.LoadState(childPrefix, state); err != nil {
+				return err
+			}
+		} else if len(child.Module.Parameters()) > 0 {
+			if err := loadParameters(childPrefix, child.Module, state); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package nn
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+func stateKeys(state map[string]*tensor.Tensor) string {
+	keys := make([]string, 0, len(state))
+	for k := range state {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+func TestNamedSequentialKeys(t *testing.T) {
+	model, err := NewNamedSequential(
+		NamedModule{Name: "encoder", Module: NewLinear(3, 2, true)},
+		NamedModule{Name: "act", Module: Relu()},
+		NamedModule{Name: "head", Module: NewLinear(2, 1, false)},
+	)
+	if err != nil {
+		t.Fatalf("NewNamedSequential failed: %v", err)
+	}
+	out, err := model.Forward(tensor.Ones(4, 3))
+	if err != nil {
+		t.Fatalf("forward failed: %v", err)
+	}
+	if shape := out.Shape(); shape[0] != 4 || shape[1] != 1 {
+		t.Fatalf("unexpected output shape %v", shape)
+	}
+	state := map[string]*tensor.Tensor{}
+	model.StateDict("model", state)
+	if got := stateKeys(state); got != "model.encoder.bias,model.encoder.weight,model.head.weight" {
+		t.Fatalf("unexpected state keys %s", got)
+	}
+	clone, _ := NewNamedSequential(
+		NamedModule{Name: "encoder", Module: NewLinear(3, 2, true)},
+		NamedModule{Name: "act", Module: Relu()},
+		NamedModule{Name: "head", Module: NewLinear(2, 1, false)},
+	)
+	if err := clone.LoadState("model", state); err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	for i, p := range clone.Parameters() {
+		if !floatsAlmostEqual(p.Data(), model.Parameters()[i].Data(), 0) {
+			t.Fatalf("parameter %d mismatch after load", i)
+		}
+	}
+
+	if _, err := NewNamedSequential(NamedModule{Name: "a", Module: Relu()}, NamedModule{Name: "a", Module: Relu()}); err == nil {
+		t.Fatalf("expected duplicate name error")
+	}
+	if _, err := NewNamedSequential(NamedModule{Name: "a.b", Module: Relu()}); err == nil {
+		t.Fatalf("expected dotted name error")
+	}
+	if _, err := NewNamedSequential(NamedModule{Name: "a"}); err == nil {
+		t.Fatalf("expected nil module error")
+	}
+}
+
+func TestModuleListAndDict(t *testing.T) {
+	layers, err := NewModuleList(NewLinear(2, 2, true), NewDropout(0.5))
+	if err != nil {
+		t.Fatalf("NewModuleList failed: %v", err)
+	}
+	if err := layers.Append(NewLinear(2, 2, false)); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if err := layers.Append(nil); err == nil {
+		t.Fatalf("expected nil module error")
+	}
+	if _, err := NewModuleList(Relu(), nil); err == nil {
+		t.Fatalf("expected nil module error")
+	}
+	if _, ok := layers.Get(3); ok {
+		t.Fatalf("expected out-of-range Get to fail")
+	}
+	if layers.Len() != 3 {
+		t.Fatalf("unexpected list length %d", layers.Len())
+	}
+	if _, err := layers.Forward(tensor.Ones(1, 2)); err == nil {
+		t.Fatalf("expected ModuleList.Forward to fail")
+	}
+
+	heads, err := NewModuleDict(
+		NamedModule{Name: "cls", Module: NewLinear(2, 3, true)},
+		NamedModule{Name: "norm", Module: NewBatchNorm(2, 0.1, 1e-5, true)},
+	)
+	if err != nil {
+		t.Fatalf("NewModuleDict failed: %v", err)
+	}
+	if err := heads.Set("reg", NewLinear(2, 1, true)); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := heads.Set("none", nil); err == nil {
+		t.Fatalf("expected nil module error")
+	}
+	if strings.Join(heads.Keys(), ",") != "cls,norm,reg" {
+		t.Fatalf("unexpected key order %v", heads.Keys())
+	}
+	if _, ok := heads.Get("cls"); !ok {
+		t.Fatalf("expected cls head")
+	}
+
+	model, _ := NewNamedSequential(
+		NamedModule{Name: "layers", Module: layers},
+		NamedModule{Name: "heads", Module: heads},
+	)
+	state := map[string]*tensor.Tensor{}
+	model.StateDict("", state)
+	want := "heads.cls.bias,heads.cls.weight,heads.norm.bias,heads.norm.running_mean,heads.norm.running_var,heads.norm.weight," +
+		"heads.reg.bias,heads.reg.weight,layers.0.bias,layers.0.weight,layers.2.weight"
+	if got := stateKeys(state); got != want {
+		t.Fatalf("unexpected state keys:\n got %s\nwant %s", got, want)
+	}
+	if len(model.Parameters()) != 9 {
+		t.Fatalf("expected 9 parameters, got %d", len(model.Parameters()))
+	}
+	for _, p := range NamedParameters(model) {
+		if _, ok := state[p.Name]; !ok {
+			t.Fatalf("named parameter %s missing from state", p.Name)
+		}
+	}
+	if err := model.LoadState("", state); err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+
+	SetTraining(model, false)
+	second, _ := layers.Get(1)
+	drop := second.(*Dropout)
+	norm, _ := heads.Get("norm")
+	if drop.IsTraining() || norm.(*BatchNorm).IsTraining() || layers.IsTraining() || heads.IsTraining() {
+		t.Fatalf("eval mode did not reach container children")
+	}
+}
@@ -20,7 +20,7 @@ type Conv2d struct {
 	bias        *tensor.Tensor
 }
 
-func NewConv2d(inChannels, outChannels, kernelH, kernelW int, strideH, strideW, padH, padW int, withBias bool) *Conv2d {
+func NewConv2d(inChannels, outChannels, kernelH, kernelW int, strideH, strideW, padH, padW int, withBias bool, opts ...InitOption) *Conv2d {
 	if strideH <= 0 {
 		strideH = 1
 	}
@@ -37,6 +37,7 @@ func NewConv2d(inChannels, outChannels, kernelH, kernelW int, strideH, strideW,
 		b = tensor.Zeros(outChannels)
 		b.SetRequiresGrad(true)
 	}
+	applyInit(opts, []*tensor.Tensor{w}, []*tensor.Tensor{b})
 	return &Conv2d{
 		inChannels:  inChannels,
 		outChannels: outChannels,
@@ -77,6 +78,13 @@ func (c *Conv2d) Bias() *tensor.Tensor {
 	return c.bias
 }
 
+func (c *Conv2d) NamedParameters() []NamedParameter {
+	return namedOrNil(
+		NamedParameter{Name: "weight", Param: c.weight},
+		NamedParameter{Name: "bias", Param: c.bias},
+	)
+}
+
 func (c *Conv2d) StateDict(prefix string, state map[string]*tensor.Tensor) {
 	if state == nil {
 		return
@@ -111,3 +119,13 @@ func (c *Conv2d) LoadState(prefix string, state map[string]*tensor.Tensor) error
 	}
 	return nil
 }
+
+func (c *Conv2d) parameterSlot(name string) **tensor.Tensor {
+	switch name {
+	case "weight":
+		return &c.weight
+	case "bias":
+		return &c.bias
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"math"
 
 	"github.com/fumitoshi0524/ixeoriNet/tensor"
+	"github.com/fumitoshi0524/ixeoriNet/tensor/fft"
 )
 
 type Conv1d struct {
@@ -15,9 +16,10 @@ type Conv1d struct {
 	pad         int
 	weight      *tensor.Tensor
 	bias        *tensor.Tensor
+	useFFT      bool
 }
 
-func NewConv1d(inChannels, outChannels, kernelW, stride, pad int, withBias bool) *Conv1d {
+func NewConv1d(inChannels, outChannels, kernelW, stride, pad int, withBias bool, opts ...InitOption) *Conv1d {
 	if stride <= 0 {
 		stride = 1
 	}
@@ -31,6 +33,7 @@ func NewConv1d(inChannels, outChannels, kernelW, stride, pad int, withBias bool)
 		b = tensor.Zeros(outChannels)
 		b.SetRequiresGrad(true)
 	}
+	applyInit(opts, []*tensor.Tensor{w}, []*tensor.Tensor{b})
 	return &Conv1d{
 		inChannels:  inChannels,
 		outChannels: outChannels,
@@ -43,9 +46,18 @@ func NewConv1d(inChannels, outChannels, kernelW, stride, pad int, withBias bool)
 }
 
 func (c *Conv1d) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	if c.useFFT {
+		return fft.Conv1D(input, c.weight, c.bias, c.stride, c.pad)
+	}
 	return tensor.Conv1D(input, c.weight, c.bias, c.stride, c.pad)
 }
 
+// UseFFT switches the forward pass to FFT-based convolution, which is
+// faster for long kernels. Results match the direct path up to rounding.
+func (c *Conv1d) UseFFT(enabled bool) {
+	c.useFFT = enabled
+}
+
 func (c *Conv1d) Parameters() []*tensor.Tensor {
 	params := []*tensor.Tensor{c.weight}
 	if c.bias != nil {
@@ -60,6 +72,13 @@ func (c *Conv1d) ZeroGrad() {
 	}
 }
 
+func (c *Conv1d) NamedParameters() []NamedParameter {
+	return namedOrNil(
+		NamedParameter{Name: "weight", Param: c.weight},
+		NamedParameter{Name: "bias", Param: c.bias},
+	)
+}
+
 func (c *Conv1d) StateDict(prefix string, state map[string]*tensor.Tensor) {
 	if state == nil {
 		return
@@ -94,3 +113,13 @@ func (c *Conv1d) LoadState(prefix string, state map[string]*tensor.Tensor) error
 	}
 	return nil
 }
+
+func (c *Conv1d) parameterSlot(name string) **tensor.Tensor {
+	switch name {
+	case "weight":
+		return &c.weight
+	case "bias":
+		return &c.bias
+	}
+	return nil
+}
@@ -24,7 +24,7 @@ type Conv3d struct {
 }
 
 // NewConv3d constructs a Conv3d module.
-func NewConv3d(inChannels, outChannels, kernelD, kernelH, kernelW int, strideD, strideH, strideW, padD, padH, padW int, withBias bool) *Conv3d {
+func NewConv3d(inChannels, outChannels, kernelD, kernelH, kernelW int, strideD, strideH, strideW, padD, padH, padW int, withBias bool, opts ...InitOption) *Conv3d {
 	if strideD <= 0 {
 		strideD = 1
 	}
@@ -48,6 +48,7 @@ func NewConv3d(inChannels, outChannels, kernelD, kernelH, kernelW int, strideD,
 		bias.SetRequiresGrad(true)
 	}
 
+	applyInit(opts, []*tensor.Tensor{weight}, []*tensor.Tensor{bias})
 	return &Conv3d{
 		inChannels:  inChannels,
 		outChannels: outChannels,
@@ -87,6 +88,13 @@ func (c *Conv3d) Train() {}
 
 func (c *Conv3d) Eval() {}
 
+func (c *Conv3d) NamedParameters() []NamedParameter {
+	return namedOrNil(
+		NamedParameter{Name: "weight", Param: c.weight},
+		NamedParameter{Name: "bias", Param: c.bias},
+	)
+}
+
 func (c *Conv3d) StateDict(prefix string, state map[string]*tensor.Tensor) {
 	if state == nil {
 		return
@@ -121,3 +129,13 @@ func (c *Conv3d) LoadState(prefix string, state map[string]*tensor.Tensor) error
 	}
 	return nil
 }
+
+func (c *Conv3d) parameterSlot(name string) **tensor.Tensor {
+	switch name {
+	case "weight":
+		return &c.weight
+	case "bias":
+		return &c.bias
+	}
+	return nil
+}
@@ -247,3 +247,30 @@ func TestConv1dStateDictRoundTrip(t *testing.T) {
 		t.Fatalf("bias mismatch after LoadState")
 	}
 }
+
+func TestConv1dFFTPathMatchesDirect(t *testing.T) {
+	conv := NewConv1d(2, 3, 7, 2, 3, true)
+	data := make([]float64, 2*2*16)
+	for i := range data {
+		data[i] = float64(i%5) - 1.5
+	}
+	input := tensor.MustNew(data, 2, 2, 16)
+	direct, err := conv.Forward(input)
+	if err != nil {
+		t.Fatalf("direct conv1d failed: %v", err)
+	}
+	conv.UseFFT(true)
+	viaFFT, err := conv.Forward(input)
+	if err != nil {
+		t.Fatalf("fft conv1d failed: %v", err)
+	}
+	if !floatsAlmostEqual(viaFFT.Data(), direct.Data(), 1e-9) {
+		t.Fatalf("fft conv1d mismatch: got %v want %v", viaFFT.Data(), direct.Data())
+	}
+	if err := tensor.Sum(viaFFT).Backward(); err != nil {
+		t.Fatalf("fft conv1d backward failed: %v", err)
+	}
+	if conv.weight.Grad() == nil || conv.bias.Grad() == nil {
+		t.Fatalf("expected gradients on fft conv1d parameters")
+	}
+}
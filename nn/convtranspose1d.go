@@ -18,7 +18,7 @@ type ConvTranspose1d struct {
 
 // NewConvTranspose1d creates a ConvTranspose1d module.
 // weight shape: [in_channels, out_channels, kernel]
-func NewConvTranspose1d(inChannels, outChannels, kernel, stride, padding int, withBias bool) *ConvTranspose1d {
+func NewConvTranspose1d(inChannels, outChannels, kernel, stride, padding int, withBias bool, opts ...InitOption) *ConvTranspose1d {
 	if stride <= 0 {
 		stride = 1
 	}
@@ -36,6 +36,7 @@ func NewConvTranspose1d(inChannels, outChannels, kernel, stride, padding int, wi
 		bias.SetRequiresGrad(true)
 	}
 
+	applyInit(opts, []*tensor.Tensor{weight}, []*tensor.Tensor{bias})
 	return &ConvTranspose1d{
 		weight:  weight,
 		bias:    bias,
@@ -66,6 +67,13 @@ func (c *ConvTranspose1d) Train() {}
 
 func (c *ConvTranspose1d) Eval() {}
 
+func (c *ConvTranspose1d) NamedParameters() []NamedParameter {
+	return namedOrNil(
+		NamedParameter{Name: "weight", Param: c.weight},
+		NamedParameter{Name: "bias", Param: c.bias},
+	)
+}
+
 func (c *ConvTranspose1d) StateDict(prefix string, state map[string]*tensor.Tensor) {
 	if state == nil {
 		return
@@ -100,3 +108,13 @@ func (c *ConvTranspose1d) LoadState(prefix string, state map[string]*tensor.Tens
 	}
 	return nil
 }
+
+func (c *ConvTranspose1d) parameterSlot(name string) **tensor.Tensor {
+	switch name {
+	case "weight":
+		return &c.weight
+	case "bias":
+		return &c.bias
+	}
+	return nil
+}
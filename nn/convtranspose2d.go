@@ -20,7 +20,7 @@ type ConvTranspose2d struct {
 
 // NewConvTranspose2d creates a ConvTranspose2d module.
 // weight shape: [in_channels, out_channels, kernelH, kernelW]
-func NewConvTranspose2d(inChannels, outChannels, kernelH, kernelW, strideH, strideW, padH, padW int, withBias bool) *ConvTranspose2d {
+func NewConvTranspose2d(inChannels, outChannels, kernelH, kernelW, strideH, strideW, padH, padW int, withBias bool, opts ...InitOption) *ConvTranspose2d {
 	if strideH <= 0 {
 		strideH = 1
 	}
@@ -41,6 +41,7 @@ func NewConvTranspose2d(inChannels, outChannels, kernelH, kernelW, strideH, stri
 		bias.SetRequiresGrad(true)
 	}
 
+	applyInit(opts, []*tensor.Tensor{weight}, []*tensor.Tensor{bias})
 	return &ConvTranspose2d{
 		weight:  weight,
 		bias:    bias,
@@ -73,6 +74,13 @@ func (c *ConvTranspose2d) Train() {}
 
 func (c *ConvTranspose2d) Eval() {}
 
+func (c *ConvTranspose2d) NamedParameters() []NamedParameter {
+	return namedOrNil(
+		NamedParameter{Name: "weight", Param: c.weight},
+		NamedParameter{Name: "bias", Param: c.bias},
+	)
+}
+
 func (c *ConvTranspose2d) StateDict(prefix string, state map[string]*tensor.Tensor) {
 	if state == nil {
 		return
@@ -107,3 +115,13 @@ func (c *ConvTranspose2d) LoadState(prefix string, state map[string]*tensor.Tens
 	}
 	return nil
 }
+
+func (c *ConvTranspose2d) parameterSlot(name string) **tensor.Tensor {
+	switch name {
+	case "weight":
+		return &c.weight
+	case "bias":
+		return &c.bias
+	}
+	return nil
+}
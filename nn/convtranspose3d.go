@@ -22,7 +22,7 @@ type ConvTranspose3d struct {
 
 // NewConvTranspose3d creates a ConvTranspose3d module.
 // weight shape: [in_channels, out_channels, kernelD, kernelH, kernelW]
-func NewConvTranspose3d(inChannels, outChannels, kernelD, kernelH, kernelW, strideD, strideH, strideW, padD, padH, padW int, withBias bool) *ConvTranspose3d {
+func NewConvTranspose3d(inChannels, outChannels, kernelD, kernelH, kernelW, strideD, strideH, strideW, padD, padH, padW int, withBias bool, opts ...InitOption) *ConvTranspose3d {
 	if strideD <= 0 {
 		strideD = 1
 	}
@@ -46,6 +46,7 @@ func NewConvTranspose3d(inChannels, outChannels, kernelD, kernelH, kernelW, stri
 		bias.SetRequiresGrad(true)
 	}
 
+	applyInit(opts, []*tensor.Tensor{weight}, []*tensor.Tensor{bias})
 	return &ConvTranspose3d{
 		weight:  weight,
 		bias:    bias,
@@ -80,6 +81,13 @@ func (c *ConvTranspose3d) Train() {}
 
 func (c *ConvTranspose3d) Eval() {}
 
+func (c *ConvTranspose3d) NamedParameters() []NamedParameter {
+	return namedOrNil(
+		NamedParameter{Name: "weight", Param: c.weight},
+		NamedParameter{Name: "bias", Param: c.bias},
+	)
+}
+
 func (c *ConvTranspose3d) StateDict(prefix string, state map[string]*tensor.Tensor) {
 	if state == nil {
 		return
@@ -114,3 +122,13 @@ func (c *ConvTranspose3d) LoadState(prefix string, state map[string]*tensor.Tens
 	}
 	return nil
 }
+
+func (c *ConvTranspose3d) parameterSlot(name string) **tensor.Tensor {
+	switch name {
+	case "weight":
+		return &c.weight
+	case "bias":
+		return &c.bias
+	}
+	return nil
+}
@@ -34,3 +34,7 @@ func (d *Dropout) Train() {
 func (d *Dropout) Eval() {
 	d.training = false
 }
+
+func (d *Dropout) IsTraining() bool {
+	return d.training
+}
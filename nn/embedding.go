@@ -1,32 +1,143 @@
 package nn
 
 import (
+	"errors"
 	"fmt"
 	"math"
 
 	"github.com/fumitoshi0524/ixeoriNet/tensor"
 )
 
+// EmbeddingConfig holds the optional behaviour shared by Embedding and
+// EmbeddingBag.
+type EmbeddingConfig struct {
+	// HasPaddingIdx enables PaddingIdx, a row that is zero-initialised and
+	// never receives a gradient. Negative values count from the end.
+	HasPaddingIdx bool
+	PaddingIdx    int
+	// MaxNorm, when positive, renormalises every looked-up row whose norm
+	// exceeds it in place before the lookup.
+	MaxNorm float64
+	// NormType is the p of the p-norm compared with MaxNorm (default 2).
+	NormType float64
+	// Sparse makes the weight gradient row-sparse, covering only the rows
+	// looked up since the last ZeroGrad (see tensor.Tensor.SparseGrad).
+	Sparse bool
+}
+
 type Embedding struct {
 	numEmbeddings int
 	embeddingDim  int
 	weight        *tensor.Tensor
+	paddingIdx    int
+	maxNorm       float64
+	normType      float64
+	sparse        bool
 }
 
-func NewEmbedding(numEmbeddings, embeddingDim int) *Embedding {
+func NewEmbedding(numEmbeddings, embeddingDim int, opts ...InitOption) *Embedding {
 	weight := tensor.Randn(numEmbeddings, embeddingDim)
 	scale := 1.0 / math.Sqrt(float64(embeddingDim))
 	weight.Scale(scale)
 	weight.SetRequiresGrad(true)
+	applyInit(opts, []*tensor.Tensor{weight}, nil)
 	return &Embedding{
 		numEmbeddings: numEmbeddings,
 		embeddingDim:  embeddingDim,
 		weight:        weight,
+		paddingIdx:    -1,
+	}
+}
+
+// NewEmbeddingWithConfig is NewEmbedding with padding, max-norm and sparse
+// gradient options. The padding row is zeroed after initialisation.
+func NewEmbeddingWithConfig(numEmbeddings, embeddingDim int, cfg EmbeddingConfig, opts ...InitOption) (*Embedding, error) {
+	if numEmbeddings <= 0 || embeddingDim <= 0 {
+		return nil, errors.New("numEmbeddings and embeddingDim must be positive")
+	}
+	e := NewEmbedding(numEmbeddings, embeddingDim, opts...)
+	if err := e.configure(cfg); err != nil {
+		return nil, err
+	}
+	if e.paddingIdx >= 0 {
+		if err := e.weight.SetRowValues([]int{e.paddingIdx}, make([]float64, embeddingDim)); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// NewEmbeddingFromPretrained builds an Embedding around a copy of weights,
+// shaped [numEmbeddings, embeddingDim]. With freeze the table is not
+// trained. The padding row keeps its pretrained value.
+func NewEmbeddingFromPretrained(weights *tensor.Tensor, freeze bool, cfg EmbeddingConfig) (*Embedding, error) {
+	if weights == nil {
+		return nil, errors.New("pretrained weights are nil")
+	}
+	shape := weights.Shape()
+	if len(shape) != 2 {
+		return nil, fmt.Errorf("pretrained embeddings must be [numEmbeddings, embeddingDim], got %v", shape)
+	}
+	weight := weights.Detach()
+	weight.SetRequiresGrad(!freeze)
+	e := &Embedding{
+		numEmbeddings: shape[0],
+		embeddingDim:  shape[1],
+		weight:        weight,
+	}
+	if err := e.configure(cfg); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *Embedding) configure(cfg EmbeddingConfig) error {
+	e.paddingIdx = -1
+	if cfg.HasPaddingIdx {
+		idx := cfg.PaddingIdx
+		if idx < 0 {
+			idx += e.numEmbeddings
+		}
+		if idx < 0 || idx >= e.numEmbeddings {
+			return fmt.Errorf("padding index %d out of range for %d embeddings", cfg.PaddingIdx, e.numEmbeddings)
+		}
+		e.paddingIdx = idx
+	}
+	if cfg.MaxNorm < 0 {
+		return fmt.Errorf("max norm must be non-negative, got %v", cfg.MaxNorm)
+	}
+	e.maxNorm = cfg.MaxNorm
+	e.normType = cfg.NormType
+	if e.normType <= 0 {
+		e.normType = 2
+	}
+	e.sparse = cfg.Sparse
+	return nil
+}
+
+// PaddingIdx returns the padding row and whether one is set.
+func (e *Embedding) PaddingIdx() (int, bool) {
+	return e.paddingIdx, e.paddingIdx >= 0
+}
+
+// Weight returns the embedding table.
+func (e *Embedding) Weight() *tensor.Tensor {
+	return e.weight
+}
+
+// renorm applies MaxNorm to the rows about to be looked up.
+func (e *Embedding) renorm(input *tensor.Tensor) error {
+	if e.maxNorm <= 0 || input == nil {
+		return nil
 	}
+	return tensor.EmbeddingRenorm(e.weight, input, e.maxNorm, e.normType)
 }
 
 func (e *Embedding) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
-	return tensor.Embedding(e.weight, input)
+	if err := e.renorm(input); err != nil {
+		return nil, err
+	}
+	return tensor.EmbeddingWithOptions(e.weight, input, e.paddingIdx, e.sparse)
 }
 
 func (e *Embedding) Parameters() []*tensor.Tensor {
@@ -37,6 +148,10 @@ func (e *Embedding) ZeroGrad() {
 	e.weight.ZeroGrad()
 }
 
+func (e *Embedding) NamedParameters() []NamedParameter {
+	return []NamedParameter{{Name: "weight", Param: e.weight}}
+}
+
 func (e *Embedding) StateDict(prefix string, state map[string]*tensor.Tensor) {
 	if state == nil {
 		return
@@ -58,3 +173,77 @@ func (e *Embedding) LoadState(prefix string, state map[string]*tensor.Tensor) er
 	}
 	return nil
 }
+
+func (e *Embedding) parameterSlot(name string) **tensor.Tensor {
+	switch name {
+	case "weight":
+		return &e.weight
+	}
+	return nil
+}
+
+// EmbeddingBag sums, averages or takes the element-wise maximum of bags of
+// embeddings without materialising every lookup. It shares the table,
+// state keys and options of Embedding.
+type EmbeddingBag struct {
+	*Embedding
+	mode string
+}
+
+// NewEmbeddingBag builds an EmbeddingBag reducing with mode "sum", "mean"
+// (the default when empty) or "max".
+func NewEmbeddingBag(numEmbeddings, embeddingDim int, mode string, cfg EmbeddingConfig, opts ...InitOption) (*EmbeddingBag, error) {
+	mode, err := embeddingBagMode(mode)
+	if err != nil {
+		return nil, err
+	}
+	e, err := NewEmbeddingWithConfig(numEmbeddings, embeddingDim, cfg, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &EmbeddingBag{Embedding: e, mode: mode}, nil
+}
+
+// NewEmbeddingBagFromPretrained is NewEmbeddingFromPretrained for bags.
+func NewEmbeddingBagFromPretrained(weights *tensor.Tensor, mode string, freeze bool, cfg EmbeddingConfig) (*EmbeddingBag, error) {
+	mode, err := embeddingBagMode(mode)
+	if err != nil {
+		return nil, err
+	}
+	e, err := NewEmbeddingFromPretrained(weights, freeze, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &EmbeddingBag{Embedding: e, mode: mode}, nil
+}
+
+func embeddingBagMode(mode string) (string, error) {
+	switch mode {
+	case "":
+		return "mean", nil
+	case "sum", "mean", "max":
+		return mode, nil
+	}
+	return "", fmt.Errorf("unsupported EmbeddingBag mode %q", mode)
+}
+
+// Mode returns the bag reduction.
+func (b *EmbeddingBag) Mode() string {
+	return b.mode
+}
+
+// Forward reduces a [bags, length] index tensor to [bags, embeddingDim].
+func (b *EmbeddingBag) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	return b.ForwardBags(input, nil, nil)
+}
+
+// ForwardBags reduces bags given either as a 2-D index with offsets nil or as
+// a flat 1-D index split at offsets, the first of which must be 0.
+// perSampleWeights (nil for none), shaped like index, scales each lookup and
+// requires "sum" mode.
+func (b *EmbeddingBag) ForwardBags(index *tensor.Tensor, offsets []int, perSampleWeights *tensor.Tensor) (*tensor.Tensor, error) {
+	if err := b.renorm(index); err != nil {
+		return nil, err
+	}
+	return tensor.EmbeddingBag(b.weight, index, offsets, perSampleWeights, b.mode, b.paddingIdx, b.sparse)
+}
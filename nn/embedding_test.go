@@ -0,0 +1,117 @@
+package nn
+
+import (
+	"math"
+	"testing"
+
+	"github.com/fumitoshi0524/ixeoriNet/optim"
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+func TestEmbeddingPaddingAndMaxNorm(t *testing.T) {
+	emb, err := NewEmbeddingWithConfig(4, 3, EmbeddingConfig{HasPaddingIdx: true, PaddingIdx: -1, MaxNorm: 0.5, Sparse: true})
+	if err != nil {
+		t.Fatalf("NewEmbeddingWithConfig failed: %v", err)
+	}
+	if idx, ok := emb.PaddingIdx(); !ok || idx != 3 {
+		t.Fatalf("unexpected padding index %d, %v", idx, ok)
+	}
+	mustSetData(t, emb.Weight(), []float64{3, 0, 4, 0.1, 0.2, 0.1, 1, 1, 1, 0, 0, 0})
+	input := tensor.MustNew([]float64{0, 3, 1, 3}, 2, 2)
+	out, err := emb.Forward(input)
+	if err != nil {
+		t.Fatalf("embedding forward failed: %v", err)
+	}
+	w := emb.Weight().Data()
+	if math.Abs(math.Sqrt(w[0]*w[0]+w[2]*w[2])-0.5) > 1e-6 {
+		t.Fatalf("looked-up row 0 was not renormalised: %v", w[:3])
+	}
+	if w[6] != 1 || w[3] != 0.1 {
+		t.Fatalf("rows within the norm or not looked up must stay put: %v", w)
+	}
+	if err := tensor.Sum(out).Backward(); err != nil {
+		t.Fatalf("backward failed: %v", err)
+	}
+	rows, _ := emb.Weight().SparseGrad()
+	if len(rows) != 2 || rows[0] != 0 || rows[1] != 1 {
+		t.Fatalf("expected sparse rows [0 1] without padding, got %v", rows)
+	}
+	if err := optim.NewSGD(emb.Parameters(), 0.1, 0).Step(); err != nil {
+		t.Fatalf("sgd step failed: %v", err)
+	}
+	if got := emb.Weight().Data(); got[9] != 0 || got[10] != 0 || got[11] != 0 || got[6] != 1 {
+		t.Fatalf("padding and untouched rows must not be updated: %v", got)
+	}
+
+	fresh, _ := NewEmbeddingWithConfig(5, 2, EmbeddingConfig{HasPaddingIdx: true, PaddingIdx: 1})
+	if got := fresh.Weight().Data(); got[2] != 0 || got[3] != 0 {
+		t.Fatalf("padding row must start at zero: %v", got)
+	}
+	if _, err := NewEmbeddingWithConfig(4, 3, EmbeddingConfig{HasPaddingIdx: true, PaddingIdx: 4}); err == nil {
+		t.Fatalf("expected padding range error")
+	}
+}
+
+func TestEmbeddingFromPretrained(t *testing.T) {
+	vectors := tensor.MustNew([]float64{1, 2, 3, 4, 5, 6}, 3, 2)
+	emb, err := NewEmbeddingFromPretrained(vectors, true, EmbeddingConfig{})
+	if err != nil {
+		t.Fatalf("NewEmbeddingFromPretrained failed: %v", err)
+	}
+	mustSetData(t, vectors, make([]float64, 6))
+	out, err := emb.Forward(tensor.MustNew([]float64{2, 0}, 2))
+	if err != nil {
+		t.Fatalf("pretrained forward failed: %v", err)
+	}
+	if !floatsAlmostEqual(out.Data(), []float64{5, 6, 1, 2}, 1e-12) {
+		t.Fatalf("unexpected pretrained lookup %v", out.Data())
+	}
+	if out.RequiresGrad() || len(TrainableParameters(emb)) != 0 {
+		t.Fatalf("frozen pretrained table must not train")
+	}
+	if _, err := NewEmbeddingFromPretrained(tensor.Zeros(3), false, EmbeddingConfig{}); err == nil {
+		t.Fatalf("expected rank error")
+	}
+}
+
+func TestEmbeddingBagModule(t *testing.T) {
+	if _, err := NewEmbeddingBag(4, 2, "median", EmbeddingConfig{}); err == nil {
+		t.Fatalf("expected unsupported mode error")
+	}
+	bag, err := NewEmbeddingBag(4, 2, "", EmbeddingConfig{})
+	if err != nil {
+		t.Fatalf("NewEmbeddingBag failed: %v", err)
+	}
+	if bag.Mode() != "mean" {
+		t.Fatalf("default mode should be mean, got %q", bag.Mode())
+	}
+	input := tensor.MustNew([]float64{0, 1, 3, 3}, 2, 2)
+	out, err := bag.Forward(input)
+	if err != nil {
+		t.Fatalf("bag forward failed: %v", err)
+	}
+	lookups, _ := bag.Embedding.Forward(input)
+	want, _ := tensor.MeanAxis(lookups, 1)
+	if !floatsAlmostEqual(out.Data(), want.Data(), 1e-12) {
+		t.Fatalf("mean bag %v, want %v", out.Data(), want.Data())
+	}
+
+	vectors := tensor.MustNew([]float64{1, 0, 0, 1, 2, 2}, 3, 2)
+	sum, err := NewEmbeddingBagFromPretrained(vectors, "sum", false, EmbeddingConfig{})
+	if err != nil {
+		t.Fatalf("NewEmbeddingBagFromPretrained failed: %v", err)
+	}
+	weights := tensor.MustNew([]float64{2, 3, 0.5}, 3)
+	out, err = sum.ForwardBags(tensor.MustNew([]float64{0, 1, 2}, 3), []int{0, 2}, weights)
+	if err != nil {
+		t.Fatalf("weighted bag failed: %v", err)
+	}
+	if !floatsAlmostEqual(out.Data(), []float64{2, 3, 1, 1}, 1e-12) {
+		t.Fatalf("unexpected weighted bags %v", out.Data())
+	}
+	state := map[string]*tensor.Tensor{}
+	sum.StateDict("bag", state)
+	if keys := stateKeys(state); keys != "bag.weight" {
+		t.Fatalf("unexpected bag state keys %s", keys)
+	}
+}
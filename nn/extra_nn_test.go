@@ -93,3 +93,140 @@ func TestPoolingModules(t *testing.T) {
 		t.Fatalf("unexpected avgpool result: %v", avgOut.Data())
 	}
 }
+
+func TestUpsampleModule(t *testing.T) {
+	input := tensor.MustNew([]float64{1, 2, 3, 4}, 1, 1, 2, 2)
+	up := NewUpsample(nil, []float64{2}, "nearest", false)
+	out, err := up.Forward(input)
+	if err != nil {
+		t.Fatalf("upsample forward failed: %v", err)
+	}
+	expected := []float64{
+		1, 1, 2, 2,
+		1, 1, 2, 2,
+		3, 3, 4, 4,
+		3, 3, 4, 4,
+	}
+	if !floatsAlmostEqual(out.Data(), expected, 1e-9) {
+		t.Fatalf("unexpected upsample result: %v", out.Data())
+	}
+
+	bilinear := NewUpsample([]int{3, 3}, nil, "bilinear", true)
+	out, err = bilinear.Forward(input)
+	if err != nil {
+		t.Fatalf("bilinear upsample forward failed: %v", err)
+	}
+	if math.Abs(out.Data()[4]-2.5) > 1e-9 {
+		t.Fatalf("unexpected bilinear centre value: %v", out.Data())
+	}
+}
+
+func TestPoolingModulesND(t *testing.T) {
+	seq := tensor.MustNew([]float64{1, 5, 2, 4, 3}, 1, 1, 5)
+	mp := NewMaxPool1d(2, 0, 0)
+	out, err := mp.Forward(seq)
+	if err != nil {
+		t.Fatalf("maxpool1d forward failed: %v", err)
+	}
+	if !floatsAlmostEqual(out.Data(), []float64{5, 4}, 1e-9) {
+		t.Fatalf("unexpected maxpool1d result: %v", out.Data())
+	}
+	mp.SetCeilMode(true)
+	out, indices, err := mp.ForwardWithIndices(seq)
+	if err != nil {
+		t.Fatalf("maxpool1d ceil forward failed: %v", err)
+	}
+	if !floatsAlmostEqual(out.Data(), []float64{5, 4, 3}, 1e-9) {
+		t.Fatalf("unexpected ceil-mode maxpool1d result: %v", out.Data())
+	}
+	unpool := NewMaxUnpool1d(2, 0, 0)
+	if _, err := unpool.Forward(out); err == nil {
+		t.Fatalf("expected MaxUnpool.Forward to require indices")
+	}
+	restored, err := unpool.ForwardWithIndices(out, indices, []int{5})
+	if err != nil {
+		t.Fatalf("unpool failed: %v", err)
+	}
+	if !floatsAlmostEqual(restored.Data(), []float64{0, 5, 0, 4, 3}, 1e-9) {
+		t.Fatalf("unexpected unpool result: %v", restored.Data())
+	}
+
+	vol := tensor.Ones(2, 3, 4, 4, 4)
+	avg, err := NewAvgPool3d(2, 2, 2, 0, 0, 0, 0, 0, 0).Forward(vol)
+	if err != nil {
+		t.Fatalf("avgpool3d forward failed: %v", err)
+	}
+	if shape := avg.Shape(); len(shape) != 5 || shape[2] != 2 || shape[3] != 2 || shape[4] != 2 {
+		t.Fatalf("unexpected avgpool3d shape %v", shape)
+	}
+
+	lp, err := NewLPPool1d(2, 2, 0).Forward(tensor.MustNew([]float64{3, 4, 6, 8}, 1, 1, 4))
+	if err != nil {
+		t.Fatalf("lppool forward failed: %v", err)
+	}
+	if !floatsAlmostEqual(lp.Data(), []float64{5, 10}, 1e-9) {
+		t.Fatalf("unexpected lppool result: %v", lp.Data())
+	}
+}
+
+func TestGlobalPoolingHeadIsSizeAgnostic(t *testing.T) {
+	head := NewSequential(NewGlobalAvgPool(), NewLinear(3, 2, true))
+	for _, size := range []int{4, 7} {
+		input := tensor.Randn(2, 3, size, size)
+		out, err := head.Forward(input)
+		if err != nil {
+			t.Fatalf("head forward failed for size %d: %v", size, err)
+		}
+		if shape := out.Shape(); shape[0] != 2 || shape[1] != 2 {
+			t.Fatalf("unexpected head output shape %v", shape)
+		}
+	}
+
+	input := tensor.MustNew([]float64{1, 9, 3, 4, 2, 8, 7, 6}, 1, 2, 2, 2)
+	mx, err := NewGlobalMaxPool().Forward(input)
+	if err != nil {
+		t.Fatalf("global max pool failed: %v", err)
+	}
+	if !floatsAlmostEqual(mx.Data(), []float64{9, 8}, 1e-9) {
+		t.Fatalf("unexpected global max result: %v", mx.Data())
+	}
+	adaptive, err := NewAdaptiveAvgPool2d(1, 2).Forward(input)
+	if err != nil {
+		t.Fatalf("adaptive avg pool failed: %v", err)
+	}
+	if !floatsAlmostEqual(adaptive.Data(), []float64{2, 6.5, 4.5, 7}, 1e-9) {
+		t.Fatalf("unexpected adaptive avg result: %v", adaptive.Data())
+	}
+}
+
+func TestSetTrainingRecursesThroughContainers(t *testing.T) {
+	drop := NewDropout(0.5)
+	bn := NewBatchNorm(3, 0.1, 1e-5, true)
+	inner := NewSequential(NewLinear(3, 3, true), drop)
+	model := NewSequential(bn, NewAttention(1, 3), inner)
+
+	if !IsTraining(model) || !drop.IsTraining() || !bn.IsTraining() {
+		t.Fatalf("modules should start in training mode")
+	}
+	SetTraining(model, false)
+	if IsTraining(model) || IsTraining(inner) || drop.IsTraining() || bn.IsTraining() {
+		t.Fatalf("SetTraining(false) did not reach every module")
+	}
+	input := tensor.Ones(2, 3)
+	out, err := NewSequential(drop).Forward(input)
+	if err != nil {
+		t.Fatalf("forward failed: %v", err)
+	}
+	if !floatsAlmostEqual(out.Data(), input.Data(), 0) {
+		t.Fatalf("dropout should be the identity in eval mode")
+	}
+
+	SetTraining(model, true)
+	if !drop.IsTraining() || !bn.IsTraining() || !inner.IsTraining() {
+		t.Fatalf("SetTraining(true) did not reach every module")
+	}
+	if IsTraining(NewLinear(2, 2, true)) {
+		t.Fatalf("modules without mode-dependent behaviour should not report training")
+	}
+	var _ Trainable = model
+}
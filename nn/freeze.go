@@ -0,0 +1,65 @@
+package nn
+
+import "github.com/fumitoshi0524/ixeoriNet/tensor"
+
+// Freeze stops gradient tracking for every parameter of mod. Frozen
+// parameters receive no gradients and are skipped by the optimizers, so a
+// pretrained backbone stays fixed while the rest of a model trains.
+func Freeze(mod Module) {
+	setRequiresGrad(mod, false)
+}
+
+// Unfreeze re-enables gradient tracking for every parameter of mod.
+func Unfreeze(mod Module) {
+	setRequiresGrad(mod, true)
+}
+
+func setRequiresGrad(mod Module, requiresGrad bool) {
+	if mod == nil {
+		return
+	}
+	for _, p := range mod.Parameters() {
+		if p != nil {
+			p.SetRequiresGrad(requiresGrad)
+		}
+	}
+}
+
+// TrainableParameters returns the parameters of mod that still require
+// gradients, ready to hand to an optimizer.
+func TrainableParameters(mod Module) []*tensor.Tensor {
+	if mod == nil {
+		return nil
+	}
+	var out []*tensor.Tensor
+	for _, p := range mod.Parameters() {
+		if p != nil && p.RequiresGrad() {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// NamedTrainableParameters is NamedParameters restricted to parameters that
+// require gradients.
+func NamedTrainableParameters(mod Module) []NamedParameter {
+	var out []NamedParameter
+	for _, np := range NamedParameters(mod) {
+		if np.Param.RequiresGrad() {
+			out = append(out, np)
+		}
+	}
+	return out
+}
+
+// FrozenParameters returns the names of the parameters of mod that do not
+// require gradients.
+func FrozenParameters(mod Module) []string {
+	var out []string
+	for _, np := range NamedParameters(mod) {
+		if !np.Param.RequiresGrad() {
+			out = append(out, np.Name)
+		}
+	}
+	return out
+}
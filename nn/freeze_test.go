@@ -0,0 +1,72 @@
+package nn
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fumitoshi0524/ixeoriNet/loss"
+	"github.com/fumitoshi0524/ixeoriNet/optim"
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+func TestFreezeBackboneTrainsOnlyHead(t *testing.T) {
+	backbone := NewSequential(NewLinear(3, 4, true), Relu())
+	head := NewLinear(4, 2, true)
+	model, err := NewNamedSequential(
+		NamedModule{Name: "backbone", Module: backbone},
+		NamedModule{Name: "head", Module: head},
+	)
+	if err != nil {
+		t.Fatalf("NewNamedSequential failed: %v", err)
+	}
+	Freeze(backbone)
+	if got := len(TrainableParameters(model)); got != 2 {
+		t.Fatalf("expected only the head to be trainable, got %d parameters", got)
+	}
+	if got := strings.Join(FrozenParameters(model), ","); got != "backbone.0.weight,backbone.0.bias" {
+		t.Fatalf("unexpected frozen parameters %s", got)
+	}
+	for _, np := range NamedTrainableParameters(model) {
+		if !strings.HasPrefix(np.Name, "head.") {
+			t.Fatalf("unexpected trainable parameter %s", np.Name)
+		}
+	}
+
+	before := tensor.MustNew(backbone.Parameters()[0].Data(), 4, 3)
+	headBefore := head.Weight().Data()
+	// handing every parameter to the optimizer is safe: frozen ones are skipped
+	opt := optim.NewAdam(model.Parameters(), 0.05, 0.9, 0.999, 1e-8)
+	input := tensor.Randn(5, 3)
+	target := tensor.Randn(5, 2)
+	for step := 0; step < 3; step++ {
+		opt.ZeroGrad()
+		out, err := model.Forward(input)
+		if err != nil {
+			t.Fatalf("forward failed: %v", err)
+		}
+		l, err := loss.MSE(out, target)
+		if err != nil {
+			t.Fatalf("loss failed: %v", err)
+		}
+		if err := l.Backward(); err != nil {
+			t.Fatalf("backward failed: %v", err)
+		}
+		if backbone.Parameters()[0].Grad() != nil {
+			t.Fatalf("frozen parameter received a gradient")
+		}
+		if err := opt.Step(); err != nil {
+			t.Fatalf("step failed: %v", err)
+		}
+	}
+	if !floatsAlmostEqual(backbone.Parameters()[0].Data(), before.Data(), 0) {
+		t.Fatalf("frozen backbone changed")
+	}
+	if floatsAlmostEqual(head.Weight().Data(), headBefore, 1e-12) {
+		t.Fatalf("head did not train")
+	}
+
+	Unfreeze(model)
+	if got := len(TrainableParameters(model)); got != 4 {
+		t.Fatalf("expected all parameters trainable after Unfreeze, got %d", got)
+	}
+}
@@ -1,6 +1,10 @@
 package nn
 
-import "github.com/fumitoshi0524/ixeoriNet/tensor"
+import (
+	"fmt"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
 
 type Functional struct {
 	fn func(*tensor.Tensor) (*tensor.Tensor, error)
@@ -37,3 +41,87 @@ func Tanh() Module {
 		return tensor.Tanh(x), nil
 	})
 }
+
+// pointwise wraps an element-wise tensor activation as a Module.
+func pointwise(fn func(*tensor.Tensor) *tensor.Tensor) Module {
+	return NewFunctional(func(x *tensor.Tensor) (*tensor.Tensor, error) {
+		return fn(x), nil
+	})
+}
+
+func LeakyRelu(negativeSlope float64) Module {
+	return pointwise(func(x *tensor.Tensor) *tensor.Tensor { return tensor.LeakyRelu(x, negativeSlope) })
+}
+
+func ELU(alpha float64) Module {
+	return pointwise(func(x *tensor.Tensor) *tensor.Tensor { return tensor.ELU(x, alpha) })
+}
+
+func CELU(alpha float64) Module {
+	return pointwise(func(x *tensor.Tensor) *tensor.Tensor { return tensor.CELU(x, alpha) })
+}
+
+func Softplus(beta float64) Module {
+	return pointwise(func(x *tensor.Tensor) *tensor.Tensor { return tensor.Softplus(x, beta) })
+}
+
+// GELU uses the exact erf form when approximate is "" or "none" and the
+// tanh approximation when it is "tanh".
+func GELU(approximate string) Module {
+	return NewFunctional(func(x *tensor.Tensor) (*tensor.Tensor, error) {
+		switch approximate {
+		case "", "none":
+			return tensor.GELU(x), nil
+		case "tanh":
+			return tensor.GELUTanh(x), nil
+		}
+		return nil, fmt.Errorf("unknown GELU approximation %q", approximate)
+	})
+}
+
+func SiLU() Module {
+	return pointwise(tensor.SiLU)
+}
+
+func Mish() Module {
+	return pointwise(tensor.Mish)
+}
+
+func Hardswish() Module {
+	return pointwise(tensor.Hardswish)
+}
+
+func Hardsigmoid() Module {
+	return pointwise(tensor.Hardsigmoid)
+}
+
+func SELU() Module {
+	return pointwise(tensor.SELU)
+}
+
+func Softsign() Module {
+	return pointwise(tensor.Softsign)
+}
+
+func Relu6() Module {
+	return pointwise(tensor.Relu6)
+}
+
+// GLU halves the input along axis into a and b and returns a·σ(b).
+func GLU(axis int) Module {
+	return NewFunctional(func(x *tensor.Tensor) (*tensor.Tensor, error) {
+		return tensor.GLU(x, axis)
+	})
+}
+
+func Softmax(axis int) Module {
+	return NewFunctional(func(x *tensor.Tensor) (*tensor.Tensor, error) {
+		return tensor.Softmax(x, axis)
+	})
+}
+
+func LogSoftmax(axis int) Module {
+	return NewFunctional(func(x *tensor.Tensor) (*tensor.Tensor, error) {
+		return tensor.LogSoftmax(x, axis)
+	})
+}
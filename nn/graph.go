@@ -0,0 +1,399 @@
+package nn
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// MultiModule generalises Module to any number of inputs and outputs.
+// Recurrent modules implement it to expose their states, and Graph uses it
+// for every node.
+type MultiModule interface {
+	ForwardMulti(inputs ...*tensor.Tensor) ([]*tensor.Tensor, error)
+	Parameters() []*tensor.Tensor
+	ZeroGrad()
+}
+
+// AsMulti adapts a single-input, single-output Module to MultiModule. Modules
+// that already implement MultiModule are returned unchanged.
+func AsMulti(mod Module) MultiModule {
+	if mm, ok := mod.(MultiModule); ok {
+		return mm
+	}
+	return singleModule{mod}
+}
+
+type singleModule struct {
+	Module
+}
+
+func (s singleModule) ForwardMulti(inputs ...*tensor.Tensor) ([]*tensor.Tensor, error) {
+	if len(inputs) != 1 {
+		return nil, fmt.Errorf("module expects 1 input, got %d", len(inputs))
+	}
+	out, err := s.Forward(inputs[0])
+	if err != nil {
+		return nil, err
+	}
+	return []*tensor.Tensor{out}, nil
+}
+
+// MultiFunctional wraps a parameter-free function of several tensors.
+type MultiFunctional struct {
+	fn func(inputs []*tensor.Tensor) ([]*tensor.Tensor, error)
+}
+
+func NewMultiFunctional(fn func(inputs []*tensor.Tensor) ([]*tensor.Tensor, error)) *MultiFunctional {
+	return &MultiFunctional{fn: fn}
+}
+
+func (f *MultiFunctional) ForwardMulti(inputs ...*tensor.Tensor) ([]*tensor.Tensor, error) {
+	return f.fn(inputs)
+}
+
+// Forward applies the function to a single input and expects one output.
+func (f *MultiFunctional) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	outs, err := f.fn([]*tensor.Tensor{input})
+	if err != nil {
+		return nil, err
+	}
+	if len(outs) != 1 {
+		return nil, fmt.Errorf("function returned %d outputs, want 1", len(outs))
+	}
+	return outs[0], nil
+}
+
+func (f *MultiFunctional) Parameters() []*tensor.Tensor {
+	return nil
+}
+
+func (f *MultiFunctional) ZeroGrad() {}
+
+// AddInputs returns a node that sums all of its inputs, e.g. for residual
+// connections.
+func AddInputs() MultiModule {
+	return NewMultiFunctional(func(inputs []*tensor.Tensor) ([]*tensor.Tensor, error) {
+		if len(inputs) == 0 {
+			return nil, errors.New("AddInputs requires at least one input")
+		}
+		out := inputs[0]
+		for _, in := range inputs[1:] {
+			var err error
+			out, err = tensor.Add(out, in)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return []*tensor.Tensor{out}, nil
+	})
+}
+
+// ConcatInputs returns a node that concatenates its inputs along axis, e.g.
+// for U-Net skip connections.
+func ConcatInputs(axis int) MultiModule {
+	return NewMultiFunctional(func(inputs []*tensor.Tensor) ([]*tensor.Tensor, error) {
+		out, err := tensor.Concat(axis, inputs...)
+		if err != nil {
+			return nil, err
+		}
+		return []*tensor.Tensor{out}, nil
+	})
+}
+
+type graphNode struct {
+	name   string
+	module Module
+	multi  MultiModule
+	inputs []graphRef
+}
+
+// graphRef points at output index of a node, or at a graph input when
+// node is empty.
+type graphRef struct {
+	node  string
+	index int
+}
+
+// Graph is a container whose nodes form a directed acyclic graph. Each node
+// names the graph inputs or earlier node outputs it consumes, so residual
+// and skip connections can be declared directly. References are either a
+// graph input name, a node name (its first output) or "node:i" for the i-th
+// output of a multi-output node. Nodes must be added after everything they
+// reference, which keeps the graph acyclic.
+type Graph struct {
+	inputNames []string
+	nodes      []*graphNode
+	byName     map[string]*graphNode
+	outputs    []graphRef
+	training   bool
+}
+
+// NewGraph creates an empty graph with the given input names.
+func NewGraph(inputs ...string) (*Graph, error) {
+	g := &Graph{byName: map[string]*graphNode{}, training: true}
+	seen := map[string]bool{}
+	for _, name := range inputs {
+		if err := validNodeName(name, seen); err != nil {
+			return nil, err
+		}
+		g.inputNames = append(g.inputNames, name)
+	}
+	if len(g.inputNames) == 0 {
+		return nil, errors.New("graph requires at least one input")
+	}
+	return g, nil
+}
+
+// Add appends a single-input module fed by the referenced value.
+func (g *Graph) Add(name string, mod Module, inputs ...string) error {
+	if mod == nil {
+		return fmt.Errorf("graph node %s: nil module", name)
+	}
+	if len(inputs) != 1 {
+		if _, ok := mod.(MultiModule); !ok {
+			return fmt.Errorf("graph node %s: Module takes exactly one input", name)
+		}
+	}
+	return g.addNode(name, mod, AsMulti(mod), inputs)
+}
+
+// AddMulti appends a multi-input module fed by the referenced values in
+// order. Modules that also implement Module, as every built-in does, take
+// part in Children, StateDict and train/eval.
+func (g *Graph) AddMulti(name string, mod MultiModule, inputs ...string) error {
+	if mod == nil {
+		return fmt.Errorf("graph node %s: nil module", name)
+	}
+	plain, _ := mod.(Module)
+	return g.addNode(name, plain, mod, inputs)
+}
+
+func (g *Graph) addNode(name string, mod Module, multi MultiModule, inputs []string) error {
+	if multi == nil {
+		return fmt.Errorf("graph node %s: nil module", name)
+	}
+	if err := validNodeName(name, nil); err != nil {
+		return err
+	}
+	if g.byName[name] != nil || g.isInput(name) {
+		return fmt.Errorf("duplicate graph node %q", name)
+	}
+	if len(inputs) == 0 {
+		return fmt.Errorf("graph node %s has no inputs", name)
+	}
+	refs := make([]graphRef, len(inputs))
+	for i, in := range inputs {
+		ref, err := g.resolve(in)
+		if err != nil {
+			return fmt.Errorf("graph node %s: %w", name, err)
+		}
+		refs[i] = ref
+	}
+	node := &graphNode{name: name, module: mod, multi: multi, inputs: refs}
+	g.nodes = append(g.nodes, node)
+	g.byName[name] = node
+	return nil
+}
+
+// SetOutputs selects the values returned by ForwardMulti. When never called,
+// the graph returns the first output of its last node.
+func (g *Graph) SetOutputs(refs ...string) error {
+	outputs := make([]graphRef, len(refs))
+	for i, r := range refs {
+		ref, err := g.resolve(r)
+		if err != nil {
+			return err
+		}
+		outputs[i] = ref
+	}
+	g.outputs = outputs
+	return nil
+}
+
+func (g *Graph) resolve(ref string) (graphRef, error) {
+	if g.isInput(ref) {
+		return graphRef{index: g.inputIndex(ref)}, nil
+	}
+	name, index := ref, 0
+	if pos := strings.LastIndex(ref, ":"); pos >= 0 {
+		idx, err := strconv.Atoi(ref[pos+1:])
+		if err != nil || idx < 0 {
+			return graphRef{}, fmt.Errorf("invalid graph reference %q", ref)
+		}
+		name, index = ref[:pos], idx
+	}
+	if g.byName[name] == nil {
+		return graphRef{}, fmt.Errorf("unknown graph reference %q", ref)
+	}
+	return graphRef{node: name, index: index}, nil
+}
+
+func (g *Graph) isInput(name string) bool {
+	return g.inputIndex(name) >= 0
+}
+
+func (g *Graph) inputIndex(name string) int {
+	for i, in := range g.inputNames {
+		if in == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// ForwardMulti evaluates every node in insertion order and returns the
+// selected outputs.
+func (g *Graph) ForwardMulti(inputs ...*tensor.Tensor) ([]*tensor.Tensor, error) {
+	if len(inputs) != len(g.inputNames) {
+		return nil, fmt.Errorf("graph expects %d inputs, got %d", len(g.inputNames), len(inputs))
+	}
+	if len(g.nodes) == 0 {
+		return nil, errors.New("graph has no nodes")
+	}
+	values := make(map[string][]*tensor.Tensor, len(g.nodes))
+	lookup := func(ref graphRef) (*tensor.Tensor, error) {
+		if ref.node == "" {
+			return inputs[ref.index], nil
+		}
+		outs := values[ref.node]
+		if ref.index >= len(outs) {
+			return nil, fmt.Errorf("graph node %s has %d outputs, requested %d", ref.node, len(outs), ref.index)
+		}
+		return outs[ref.index], nil
+	}
+	for _, node := range g.nodes {
+		args := make([]*tensor.Tensor, len(node.inputs))
+		for i, ref := range node.inputs {
+			v, err := lookup(ref)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = v
+		}
+		outs, err := node.multi.ForwardMulti(args...)
+		if err != nil {
+			return nil, fmt.Errorf("graph node %s: %w", node.name, err)
+		}
+		values[node.name] = outs
+	}
+	outputs := g.outputs
+	if outputs == nil {
+		outputs = []graphRef{{node: g.nodes[len(g.nodes)-1].name}}
+	}
+	result := make([]*tensor.Tensor, len(outputs))
+	for i, ref := range outputs {
+		v, err := lookup(ref)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = v
+	}
+	return result, nil
+}
+
+// Forward runs a graph with one input and one output.
+func (g *Graph) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	outs, err := g.ForwardMulti(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(outs) != 1 {
+		return nil, fmt.Errorf("graph Forward expects a single output, got %d; use ForwardMulti", len(outs))
+	}
+	return outs[0], nil
+}
+
+// Parameters returns the parameters of every node; modules reused by several
+// nodes contribute their parameters once.
+func (g *Graph) Parameters() []*tensor.Tensor {
+	seen := map[*tensor.Tensor]bool{}
+	var params []*tensor.Tensor
+	for _, node := range g.nodes {
+		for _, p := range node.multi.Parameters() {
+			if p == nil || seen[p] {
+				continue
+			}
+			seen[p] = true
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+func (g *Graph) ZeroGrad() {
+	for _, node := range g.nodes {
+		node.multi.ZeroGrad()
+	}
+}
+
+// Children returns the nodes that are also plain Modules.
+func (g *Graph) Children() []Module {
+	var children []Module
+	for _, child := range g.NamedChildren() {
+		children = append(children, child.Module)
+	}
+	return children
+}
+
+// NamedChildren returns the nodes that are also plain Modules. A module
+// reused by several nodes is listed once, under its first node's name, so
+// NamedParameters and the state dict hold each tensor once.
+func (g *Graph) NamedChildren() []NamedModule {
+	var named []NamedModule
+	seen := map[Module]bool{}
+	for _, node := range g.nodes {
+		if node.module == nil {
+			continue
+		}
+		if reflect.TypeOf(node.module).Comparable() {
+			if seen[node.module] {
+				continue
+			}
+			seen[node.module] = true
+		}
+		named = append(named, NamedModule{Name: node.name, Module: node.module})
+	}
+	return named
+}
+
+func (g *Graph) NamedParameters() []NamedParameter {
+	return childrenNamedParameters(g.NamedChildren())
+}
+
+func (g *Graph) Train() {
+	g.training = true
+	for _, child := range g.Children() {
+		SetTraining(child, true)
+	}
+}
+
+func (g *Graph) Eval() {
+	g.training = false
+	for _, child := range g.Children() {
+		SetTraining(child, false)
+	}
+}
+
+func (g *Graph) IsTraining() bool {
+	return g.training
+}
+
+func (g *Graph) StateDict(prefix string, state map[string]*tensor.Tensor) {
+	childrenStateDict(prefix, g.NamedChildren(), state)
+}
+
+func (g *Graph) LoadState(prefix string, state map[string]*tensor.Tensor) error {
+	return childrenLoadState(prefix, g.NamedChildren(), state)
+}
+
+func validNodeName(name string, seen map[string]bool) error {
+	if strings.Contains(name, ":") {
+		return fmt.Errorf("graph name %q must not contain ':'", name)
+	}
+	return validChildName(name, seen)
+}
@@ -0,0 +1,139 @@
+package nn
+
+import (
+	"testing"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+func TestGraphResidualBlock(t *testing.T) {
+	fc := NewLinear(3, 3, true)
+	if err := fc.Weight().SetData([]float64{1, 0, 0, 0, 1, 0, 0, 0, 1}); err != nil {
+		t.Fatalf("set weight: %v", err)
+	}
+	if err := fc.Bias().SetData([]float64{0, 0, 0}); err != nil {
+		t.Fatalf("set bias: %v", err)
+	}
+	g, err := NewGraph("x")
+	if err != nil {
+		t.Fatalf("NewGraph failed: %v", err)
+	}
+	if err := g.Add("fc", fc, "x"); err != nil {
+		t.Fatalf("add fc: %v", err)
+	}
+	if err := g.Add("act", Relu(), "fc"); err != nil {
+		t.Fatalf("add act: %v", err)
+	}
+	if err := g.AddMulti("residual", AddInputs(), "act", "x"); err != nil {
+		t.Fatalf("add residual: %v", err)
+	}
+
+	input := tensor.MustNew([]float64{1, -2, 3}, 1, 3)
+	input.SetRequiresGrad(true)
+	out, err := g.Forward(input)
+	if err != nil {
+		t.Fatalf("forward failed: %v", err)
+	}
+	if !floatsAlmostEqual(out.Data(), []float64{2, -2, 6}, 1e-9) {
+		t.Fatalf("unexpected residual output %v", out.Data())
+	}
+	if err := tensor.Sum(out).Backward(); err != nil {
+		t.Fatalf("backward failed: %v", err)
+	}
+	if !floatsAlmostEqual(input.Grad().Data(), []float64{2, 1, 2}, 1e-9) {
+		t.Fatalf("unexpected input grad %v", input.Grad().Data())
+	}
+	if fc.Weight().Grad() == nil {
+		t.Fatalf("expected gradient on graph parameters")
+	}
+
+	state := map[string]*tensor.Tensor{}
+	g.StateDict("block", state)
+	if got := stateKeys(state); got != "block.fc.bias,block.fc.weight" {
+		t.Fatalf("unexpected graph state keys %s", got)
+	}
+	if len(g.Parameters()) != 2 {
+		t.Fatalf("expected 2 parameters, got %d", len(g.Parameters()))
+	}
+}
+
+func TestGraphSkipConnectionsAndMultiOutput(t *testing.T) {
+	g, _ := NewGraph("image", "tokens")
+	shared := NewConv2d(1, 1, 3, 3, 1, 1, 1, 1, true)
+	steps := []struct {
+		name   string
+		mod    Module
+		inputs []string
+	}{
+		{"down", NewMaxPool2d(2, 2, 0, 0, 0, 0), []string{"image"}},
+		{"conv", shared, []string{"down"}},
+		{"up", NewUpsample(nil, []float64{2}, "nearest", false), []string{"conv"}},
+		{"again", shared, []string{"up"}},
+	}
+	for _, s := range steps {
+		if err := g.Add(s.name, s.mod, s.inputs...); err != nil {
+			t.Fatalf("add %s: %v", s.name, err)
+		}
+	}
+	if err := g.AddMulti("skip", ConcatInputs(1), "image", "again"); err != nil {
+		t.Fatalf("add skip: %v", err)
+	}
+	if err := g.Add("rnn", NewGRU(2, 3, true), "tokens"); err != nil {
+		t.Fatalf("add rnn: %v", err)
+	}
+	if err := g.SetOutputs("skip", "rnn:1"); err != nil {
+		t.Fatalf("SetOutputs failed: %v", err)
+	}
+
+	outs, err := g.ForwardMulti(tensor.Randn(2, 1, 4, 4), tensor.Randn(5, 2, 2))
+	if err != nil {
+		t.Fatalf("forward failed: %v", err)
+	}
+	if shape := outs[0].Shape(); shape[0] != 2 || shape[1] != 2 || shape[2] != 4 || shape[3] != 4 {
+		t.Fatalf("unexpected skip output shape %v", shape)
+	}
+	if shape := outs[1].Shape(); shape[0] != 2 || shape[1] != 3 {
+		t.Fatalf("unexpected hidden state shape %v", shape)
+	}
+	if _, err := g.Forward(tensor.Randn(2, 1, 4, 4)); err == nil {
+		t.Fatalf("expected Forward to reject a two-input graph")
+	}
+	// the shared conv is registered once
+	if got := len(g.Parameters()); got != 2+len(NewGRU(2, 3, true).Parameters()) {
+		t.Fatalf("unexpected parameter count %d", got)
+	}
+	if got := len(g.NamedParameters()); got != len(g.Parameters()) {
+		t.Fatalf("shared module named %d times for %d parameters", got, len(g.Parameters()))
+	}
+	state := map[string]*tensor.Tensor{}
+	g.StateDict("", state)
+	if _, ok := state["again.weight"]; ok || state["conv.weight"] == nil {
+		t.Fatalf("shared module must be stored once under its first name: %s", stateKeys(state))
+	}
+	if err := g.LoadState("", state); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	numel := 0
+	for _, p := range g.Parameters() {
+		numel += p.Numel()
+	}
+	if s, err := SummaryMulti(g, []int{2, 1, 4, 4}, []int{5, 2, 2}); err != nil || s.TotalParams != numel {
+		t.Fatalf("summary must count the shared module once: %v", err)
+	}
+
+	if err := g.Add("none", nil, "image"); err == nil {
+		t.Fatalf("expected nil module error")
+	}
+	if err := g.AddMulti("none", nil, "image"); err == nil {
+		t.Fatalf("expected nil multi-module error")
+	}
+	if err := g.Add("bad", Relu(), "missing"); err == nil {
+		t.Fatalf("expected unknown reference error")
+	}
+	if err := g.Add("conv", Relu(), "image"); err == nil {
+		t.Fatalf("expected duplicate node error")
+	}
+	if err := g.Add("pair", Relu(), "image", "tokens"); err == nil {
+		t.Fatalf("expected error for single-input module with two inputs")
+	}
+}
@@ -1,7 +1,6 @@
 package nn
 
 import (
-	"errors"
 	"fmt"
 	"math"
 
@@ -15,23 +14,20 @@ const (
 	gruGateTotal
 )
 
-type GRU struct {
+// GRUCell computes a single GRU time step on [batch, features] inputs; see
+// LSTMCell.
+type GRUCell struct {
 	inputSize  int
 	hiddenSize int
 	withBias   bool
-
-	weightIH [gruGateTotal]*tensor.Tensor
-	weightHH [gruGateTotal]*tensor.Tensor
-	biasIH   [gruGateTotal]*tensor.Tensor
-	biasHH   [gruGateTotal]*tensor.Tensor
+	weightIH   [gruGateTotal]*tensor.Tensor
+	weightHH   [gruGateTotal]*tensor.Tensor
+	biasIH     [gruGateTotal]*tensor.Tensor
+	biasHH     [gruGateTotal]*tensor.Tensor
 }
 
-func NewGRU(inputSize, hiddenSize int, withBias bool) *GRU {
-	g := &GRU{
-		inputSize:  inputSize,
-		hiddenSize: hiddenSize,
-		withBias:   withBias,
-	}
+func NewGRUCell(inputSize, hiddenSize int, withBias bool, opts ...InitOption) *GRUCell {
+	g := &GRUCell{inputSize: inputSize, hiddenSize: hiddenSize, withBias: withBias}
 	for gate := 0; gate < gruGateTotal; gate++ {
 		wIn := tensor.Randn(hiddenSize, inputSize)
 		wHidden := tensor.Randn(hiddenSize, hiddenSize)
@@ -52,156 +48,177 @@ func NewGRU(inputSize, hiddenSize int, withBias bool) *GRU {
 			g.biasHH[gate] = bHidden
 		}
 	}
+	applyInit(opts, append(g.weightIH[:], g.weightHH[:]...), append(g.biasIH[:], g.biasHH[:]...))
 	return g
 }
 
-func (g *GRU) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
-	out, _, err := g.ForwardWithState(input, nil)
-	return out, err
+func (g *GRUCell) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	return g.ForwardWithState(input, nil)
 }
 
-func (g *GRU) ForwardWithState(input *tensor.Tensor, hx *tensor.Tensor) (*tensor.Tensor, *tensor.Tensor, error) {
-	shape := input.Shape()
-	if len(shape) != 3 {
-		return nil, nil, errors.New("GRU expects input shape [seq, batch, features]")
+// ForwardMulti runs one step on (input[, h]) and returns [h].
+func (g *GRUCell) ForwardMulti(inputs ...*tensor.Tensor) ([]*tensor.Tensor, error) {
+	if len(inputs) < 1 || len(inputs) > 2 {
+		return nil, fmt.Errorf("GRUCell expects 1 or 2 inputs, got %d", len(inputs))
 	}
-	seqLen := shape[0]
-	batch := shape[1]
-	features := shape[2]
-	if features != g.inputSize {
-		return nil, nil, errors.New("input feature mismatch")
+	var hx *tensor.Tensor
+	if len(inputs) > 1 {
+		hx = inputs[1]
 	}
-	if hx == nil {
-		hx = tensor.Zeros(batch, g.hiddenSize)
-	}
-	hShape := hx.Shape()
-	if len(hShape) != 2 || hShape[0] != batch || hShape[1] != g.hiddenSize {
-		return nil, nil, errors.New("hidden state shape mismatch")
+	h, err := g.ForwardWithState(inputs[0], hx)
+	if err != nil {
+		return nil, err
 	}
+	return []*tensor.Tensor{h}, nil
+}
 
-	sizes := make([]int, seqLen)
-	for i := 0; i < seqLen; i++ {
-		sizes[i] = 1
+// ForwardWithState advances the hidden state [batch, hidden] (nil for zeros)
+// by one step of input [batch, features].
+func (g *GRUCell) ForwardWithState(input, hx *tensor.Tensor) (*tensor.Tensor, error) {
+	batch, err := checkCellInput("GRUCell", input, g.inputSize)
+	if err != nil {
+		return nil, err
 	}
-	steps, err := tensor.Split(0, sizes, input)
+	if hx, err = cellState(hx, batch, g.hiddenSize, "hidden"); err != nil {
+		return nil, err
+	}
+	x, err := input.Reshape(1, batch, g.inputSize)
+	if err != nil {
+		return nil, err
+	}
+	_, h, err := g.sequence(x, hx, nil, false)
+	return h, err
+}
+
+// sequence runs the cell over x [seq, batch, features] with the fused
+// kernel; see tensor.GRUSequence.
+func (g *GRUCell) sequence(x, h *tensor.Tensor, lengths []int, reverse bool) (*tensor.Tensor, *tensor.Tensor, error) {
+	weightIH, weightHH, bias, err := fuseGates(g.weightIH[:], g.weightHH[:], g.biasIH[:], g.biasHH[:], g.withBias)
 	if err != nil {
 		return nil, nil, err
 	}
-	current := hx
-	frames := make([]*tensor.Tensor, 0, seqLen)
-	for _, step := range steps {
-		var x *tensor.Tensor
-		x, err = step.Reshape(batch, features)
-		if err != nil {
-			return nil, nil, err
-		}
+	return tensor.GRUSequence(x, h, weightIH, weightHH, bias, lengths, reverse)
+}
 
-		zPre, err := g.affine(x, current, gruGateUpdate)
-		if err != nil {
-			return nil, nil, err
+func (g *GRUCell) Parameters() []*tensor.Tensor {
+	params := make([]*tensor.Tensor, 0, gruGateTotal*4)
+	for gate := 0; gate < gruGateTotal; gate++ {
+		params = append(params, g.weightIH[gate], g.weightHH[gate])
+		if g.withBias {
+			params = append(params, g.biasIH[gate], g.biasHH[gate])
 		}
-		z := tensor.Sigmoid(zPre)
+	}
+	return params
+}
 
-		rPre, err := g.affine(x, current, gruGateReset)
-		if err != nil {
-			return nil, nil, err
-		}
-		r := tensor.Sigmoid(rPre)
+func (g *GRUCell) ZeroGrad() {
+	for _, p := range g.Parameters() {
+		p.ZeroGrad()
+	}
+}
 
-		rHidden, err := tensor.Mul(r, current)
-		if err != nil {
-			return nil, nil, err
-		}
-		nPreInput, err := tensor.MatMul(x, g.weightIH[gruGateNew].MustTranspose())
-		if err != nil {
-			return nil, nil, err
-		}
-		nPreHidden, err := tensor.MatMul(rHidden, g.weightHH[gruGateNew].MustTranspose())
-		if err != nil {
-			return nil, nil, err
-		}
-		nPre, err := tensor.Add(nPreInput, nPreHidden)
-		if err != nil {
-			return nil, nil, err
-		}
-		if g.withBias {
-			nPre, err = tensor.AddBias2D(nPre, g.biasIH[gruGateNew])
-			if err != nil {
-				return nil, nil, err
-			}
-			nPre, err = tensor.AddBias2D(nPre, g.biasHH[gruGateNew])
-			if err != nil {
-				return nil, nil, err
-			}
-		}
-		nCandidate := tensor.Tanh(nPre)
+func (g *GRUCell) NamedParameters() []NamedParameter {
+	return g.namedParameters("")
+}
 
-		shapeHidden := z.Shape()
-		ones := tensor.Ones(shapeHidden...)
-		oneMinusZ, err := tensor.Sub(ones, z)
-		if err != nil {
-			return nil, nil, err
-		}
-		part1, err := tensor.Mul(oneMinusZ, nCandidate)
-		if err != nil {
-			return nil, nil, err
-		}
-		part2, err := tensor.Mul(z, current)
-		if err != nil {
-			return nil, nil, err
-		}
-		current, err = tensor.Add(part1, part2)
-		if err != nil {
-			return nil, nil, err
-		}
+func (g *GRUCell) StateDict(prefix string, state map[string]*tensor.Tensor) {
+	namedStateDict(prefix, g.NamedParameters(), state)
+}
+
+func (g *GRUCell) LoadState(prefix string, state map[string]*tensor.Tensor) error {
+	return loadNamedState("GRUCell", prefix, g.NamedParameters(), state)
+}
 
-		frame, err := tensor.Unsqueeze(current, 0)
-		if err != nil {
-			return nil, nil, err
+func (g *GRUCell) namedParameters(suffix string) []NamedParameter {
+	var named []NamedParameter
+	gateNames := []string{"update", "reset", "new"}
+	for gate, name := range gateNames {
+		named = append(named,
+			NamedParameter{Name: "weight_ih_" + name + suffix, Param: g.weightIH[gate]},
+			NamedParameter{Name: "weight_hh_" + name + suffix, Param: g.weightHH[gate]},
+		)
+		if g.withBias {
+			named = append(named,
+				NamedParameter{Name: "bias_ih_" + name + suffix, Param: g.biasIH[gate]},
+				NamedParameter{Name: "bias_hh_" + name + suffix, Param: g.biasHH[gate]},
+			)
 		}
-		frames = append(frames, frame)
 	}
+	return named
+}
 
-	output, err := tensor.Concat(0, frames...)
-	if err != nil {
-		return nil, nil, err
+// GRU is a gated recurrent unit network with optional stacking,
+// bidirectionality, inter-layer dropout and batch-first layout.
+type GRU struct {
+	recurrentLayout
+	cells []*GRUCell
+}
+
+func NewGRU(inputSize, hiddenSize int, withBias bool, opts ...InitOption) *GRU {
+	return NewGRUWithConfig(inputSize, hiddenSize, withBias, RecurrentConfig{}, opts...)
+}
+
+func NewGRUWithConfig(inputSize, hiddenSize int, withBias bool, cfg RecurrentConfig, opts ...InitOption) *GRU {
+	g := &GRU{recurrentLayout: newRecurrentLayout("GRU", inputSize, hiddenSize, cfg)}
+	for cell := 0; cell < g.numCells(); cell++ {
+		g.cells = append(g.cells, NewGRUCell(g.cellInputSize(cell/g.directions()), hiddenSize, withBias, opts...))
 	}
-	return output, current, nil
+	return g
+}
+
+func (g *GRU) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	out, _, err := g.ForwardWithState(input, nil)
+	return out, err
 }
 
-func (g *GRU) affine(x, h *tensor.Tensor, gate int) (*tensor.Tensor, error) {
-	inputPart, err := tensor.MatMul(x, g.weightIH[gate].MustTranspose())
+// ForwardMulti runs the GRU on (input[, h0]) and returns [output, hN].
+func (g *GRU) ForwardMulti(inputs ...*tensor.Tensor) ([]*tensor.Tensor, error) {
+	if len(inputs) < 1 || len(inputs) > 2 {
+		return nil, fmt.Errorf("GRU expects 1 or 2 inputs, got %d", len(inputs))
+	}
+	var hx *tensor.Tensor
+	if len(inputs) > 1 {
+		hx = inputs[1]
+	}
+	out, h, err := g.ForwardWithState(inputs[0], hx)
 	if err != nil {
 		return nil, err
 	}
-	hiddenPart, err := tensor.MatMul(h, g.weightHH[gate].MustTranspose())
+	return []*tensor.Tensor{out, h}, nil
+}
+
+// ForwardWithState runs the GRU from the initial hidden state (nil for
+// zeros). States follow the layout described on LSTM.ForwardWithState.
+func (g *GRU) ForwardWithState(input *tensor.Tensor, hx *tensor.Tensor) (*tensor.Tensor, *tensor.Tensor, error) {
+	out, states, err := g.run(input, nil, []*tensor.Tensor{hx}, g.sequence)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	sum, err := tensor.Add(inputPart, hiddenPart)
+	return out, states[0], nil
+}
+
+// ForwardPacked runs the GRU over variable-length sequences; see
+// LSTM.ForwardPacked.
+func (g *GRU) ForwardPacked(input *PackedSequence, hx *tensor.Tensor) (*PackedSequence, *tensor.Tensor, error) {
+	out, states, err := g.runPacked(input, []*tensor.Tensor{hx}, g.sequence)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	if g.withBias {
-		sum, err = tensor.AddBias2D(sum, g.biasIH[gate])
-		if err != nil {
-			return nil, err
-		}
-		sum, err = tensor.AddBias2D(sum, g.biasHH[gate])
-		if err != nil {
-			return nil, err
-		}
+	return out, states[0], nil
+}
+
+func (g *GRU) sequence(cell int, x *tensor.Tensor, state []*tensor.Tensor, lengths []int, reverse bool) (*tensor.Tensor, []*tensor.Tensor, error) {
+	out, h, err := g.cells[cell].sequence(x, state[0], lengths, reverse)
+	if err != nil {
+		return nil, nil, err
 	}
-	return sum, nil
+	return out, []*tensor.Tensor{h}, nil
 }
 
 func (g *GRU) Parameters() []*tensor.Tensor {
-	params := make([]*tensor.Tensor, 0, gruGateTotal*4)
-	for gate := 0; gate < gruGateTotal; gate++ {
-		params = append(params, g.weightIH[gate], g.weightHH[gate])
-		if g.withBias {
-			params = append(params, g.biasIH[gate], g.biasHH[gate])
-		}
+	var params []*tensor.Tensor
+	for _, cell := range g.cells {
+		params = append(params, cell.Parameters()...)
 	}
 	return params
 }
@@ -214,61 +231,18 @@ func (g *GRU) ZeroGrad() {
 	}
 }
 
-func (g *GRU) StateDict(prefix string, state map[string]*tensor.Tensor) {
-	if state == nil {
-		return
-	}
-	gateNames := []string{"update", "reset", "new"}
-	for gate, name := range gateNames {
-		state[joinPrefix(prefix, "weight_ih_"+name)] = g.weightIH[gate].Clone()
-		state[joinPrefix(prefix, "weight_hh_"+name)] = g.weightHH[gate].Clone()
-		if g.withBias {
-			state[joinPrefix(prefix, "bias_ih_"+name)] = g.biasIH[gate].Clone()
-			state[joinPrefix(prefix, "bias_hh_"+name)] = g.biasHH[gate].Clone()
-		}
+func (g *GRU) NamedParameters() []NamedParameter {
+	var named []NamedParameter
+	for idx, cell := range g.cells {
+		named = append(named, cell.namedParameters(g.cellSuffix(idx))...)
 	}
+	return named
+}
+
+func (g *GRU) StateDict(prefix string, state map[string]*tensor.Tensor) {
+	namedStateDict(prefix, g.NamedParameters(), state)
 }
 
 func (g *GRU) LoadState(prefix string, state map[string]*tensor.Tensor) error {
-	if state == nil {
-		return fmt.Errorf("state dict is nil")
-	}
-	gateNames := []string{"update", "reset", "new"}
-	for gate, name := range gateNames {
-		wIHKey := joinPrefix(prefix, "weight_ih_"+name)
-		wIH, ok := state[wIHKey]
-		if !ok {
-			return fmt.Errorf("GRU missing %s", wIHKey)
-		}
-		if err := tensor.CopyInto(g.weightIH[gate], wIH); err != nil {
-			return fmt.Errorf("load %s: %w", wIHKey, err)
-		}
-		wHHKey := joinPrefix(prefix, "weight_hh_"+name)
-		wHH, ok := state[wHHKey]
-		if !ok {
-			return fmt.Errorf("GRU missing %s", wHHKey)
-		}
-		if err := tensor.CopyInto(g.weightHH[gate], wHH); err != nil {
-			return fmt.Errorf("load %s: %w", wHHKey, err)
-		}
-		if g.withBias {
-			bIHKey := joinPrefix(prefix, "bias_ih_"+name)
-			bIH, ok := state[bIHKey]
-			if !ok {
-				return fmt.Errorf("GRU missing %s", bIHKey)
-			}
-			if err := tensor.CopyInto(g.biasIH[gate], bIH); err != nil {
-				return fmt.Errorf("load %s: %w", bIHKey, err)
-			}
-			bHHKey := joinPrefix(prefix, "bias_hh_"+name)
-			bHH, ok := state[bHHKey]
-			if !ok {
-				return fmt.Errorf("GRU missing %s", bHHKey)
-			}
-			if err := tensor.CopyInto(g.biasHH[gate], bHH); err != nil {
-				return fmt.Errorf("load %s: %w", bHHKey, err)
-			}
-		}
-	}
-	return nil
+	return loadNamedState("GRU", prefix, g.NamedParameters(), state)
 }
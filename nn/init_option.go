@@ -0,0 +1,64 @@
+package nn
+
+import (
+	"fmt"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// Initializer fills a freshly created parameter in place, typically by
+// calling one of the functions of the nn/initializer package.
+type Initializer func(param *tensor.Tensor) error
+
+// InitOption replaces the default initialisation of the weights or biases a
+// constructor creates. Constructors apply the options after their default
+// scheme, so an option only needs to cover the tensors it changes.
+type InitOption func(*initConfig)
+
+type initConfig struct {
+	weight Initializer
+	bias   Initializer
+}
+
+// WithWeightInit initialises every weight matrix or kernel with fn.
+func WithWeightInit(fn Initializer) InitOption {
+	return func(cfg *initConfig) {
+		cfg.weight = fn
+	}
+}
+
+// WithBiasInit initialises every bias vector with fn.
+func WithBiasInit(fn Initializer) InitOption {
+	return func(cfg *initConfig) {
+		cfg.bias = fn
+	}
+}
+
+// applyInit runs the configured initializers. Constructors have no error
+// result, so a failing initializer panics like tensor.MustNew.
+func applyInit(opts []InitOption, weights, biases []*tensor.Tensor) {
+	if len(opts) == 0 {
+		return
+	}
+	var cfg initConfig
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+	run := func(fn Initializer, params []*tensor.Tensor, kind string) {
+		if fn == nil {
+			return
+		}
+		for _, p := range params {
+			if p == nil {
+				continue
+			}
+			if err := fn(p); err != nil {
+				panic(fmt.Sprintf("initialise %s %v: %v", kind, p.Shape(), err))
+			}
+		}
+	}
+	run(cfg.weight, weights, "weight")
+	run(cfg.bias, biases, "bias")
+}
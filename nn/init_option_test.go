@@ -0,0 +1,64 @@
+package nn
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fumitoshi0524/ixeoriNet/nn/initializer"
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+func TestInitOptionsOverrideDefaults(t *testing.T) {
+	eye := WithWeightInit(initializer.Eye)
+	half := WithBiasInit(func(b *tensor.Tensor) error { return initializer.Constant(b, 0.5) })
+
+	lin := NewLinear(3, 3, true, eye, half)
+	if !floatsAlmostEqual(lin.weight.Data(), []float64{1, 0, 0, 0, 1, 0, 0, 0, 1}, 0) {
+		t.Fatalf("linear weight not initialised: %v", lin.weight.Data())
+	}
+	if !floatsAlmostEqual(lin.bias.Data(), []float64{0.5, 0.5, 0.5}, 0) || !lin.bias.RequiresGrad() {
+		t.Fatalf("linear bias not initialised: %v", lin.bias.Data())
+	}
+
+	conv := NewConv2d(2, 2, 3, 3, 1, 1, 1, 1, true, WithWeightInit(func(w *tensor.Tensor) error {
+		return initializer.Dirac(w, 1)
+	}))
+	input := tensor.Randn(1, 2, 4, 4)
+	out, err := conv.Forward(input)
+	if err != nil {
+		t.Fatalf("conv forward failed: %v", err)
+	}
+	if !floatsAlmostEqual(out.Data(), input.Data(), 1e-12) {
+		t.Fatalf("dirac-initialised conv should be the identity")
+	}
+
+	lstm := NewLSTMWithConfig(2, 3, true, RecurrentConfig{NumLayers: 2}, half)
+	for _, np := range lstm.NamedParameters() {
+		if !strings.HasPrefix(np.Name, "bias") {
+			continue
+		}
+		for _, v := range np.Param.Data() {
+			if v != 0.5 {
+				t.Fatalf("%s not initialised: %v", np.Name, np.Param.Data())
+			}
+		}
+	}
+
+	mha, err := NewMultiheadAttention(4, 2, 0, false, WithWeightInit(func(w *tensor.Tensor) error {
+		return initializer.Orthogonal(w, 1)
+	}))
+	if err != nil {
+		t.Fatalf("NewMultiheadAttention failed: %v", err)
+	}
+	gram, _ := tensor.MatMul(mha.qProj.weight, mha.qProj.weight.MustTranspose())
+	if !floatsAlmostEqual(gram.Data(), []float64{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}, 1e-9) {
+		t.Fatalf("orthogonal projection expected, got gram %v", gram.Data())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected a failing initializer to panic")
+		}
+	}()
+	NewEmbedding(4, 2, WithWeightInit(func(w *tensor.Tensor) error { return initializer.Dirac(w, 1) }))
+}
@@ -0,0 +1,76 @@
+// Package initializer fills parameter tensors in place with the usual
+// weight-initialisation schemes:
+//
+//	import "github.com/fumitoshi0524/ixeoriNet/nn/initializer"
+//
+//	err := initializer.KaimingUniform(w, 0, initializer.FanIn, "relu")
+//
+// Every function overwrites the tensor data without touching its
+// requires-grad flag or recording an autograd operation.
+package initializer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// FanMode selects which fan the Kaiming schemes preserve the variance of.
+type FanMode int
+
+const (
+	// FanIn preserves the magnitude of activations in the forward pass.
+	FanIn FanMode = iota
+	// FanOut preserves the magnitude of gradients in the backward pass.
+	FanOut
+)
+
+// Fans returns the fan-in and fan-out of a weight shaped [out, in, kernel...],
+// where every trailing kernel dimension multiplies both fans. Linear weights
+// are [out, in] and convolution weights [out, in, k...]; transposed
+// convolutions store [in, out, k...] and therefore report the two swapped.
+func Fans(shape []int) (fanIn, fanOut int, err error) {
+	if len(shape) < 2 {
+		return 0, 0, errors.New("fan computation needs a tensor with at least 2 dimensions")
+	}
+	receptive := 1
+	for _, dim := range shape[2:] {
+		receptive *= dim
+	}
+	return shape[1] * receptive, shape[0] * receptive, nil
+}
+
+func fan(shape []int, mode FanMode) (int, error) {
+	fanIn, fanOut, err := Fans(shape)
+	if err != nil {
+		return 0, err
+	}
+	switch mode {
+	case FanIn:
+		return fanIn, nil
+	case FanOut:
+		return fanOut, nil
+	}
+	return 0, fmt.Errorf("unknown fan mode %d", mode)
+}
+
+// Gain returns the recommended scaling for the given nonlinearity:
+// 1 for "linear", "identity", "sigmoid" and the convolution names, 5/3 for
+// "tanh", √2 for "relu", √(2/(1+param²)) for "leaky_relu" with param the
+// negative slope, and 3/4 for "selu".
+func Gain(nonlinearity string, param float64) (float64, error) {
+	switch nonlinearity {
+	case "", "linear", "identity", "sigmoid", "conv1d", "conv2d", "conv3d",
+		"conv_transpose1d", "conv_transpose2d", "conv_transpose3d":
+		return 1, nil
+	case "tanh":
+		return 5.0 / 3, nil
+	case "relu":
+		return math.Sqrt2, nil
+	case "leaky_relu":
+		return math.Sqrt(2 / (1 + param*param)), nil
+	case "selu":
+		return 0.75, nil
+	}
+	return 0, fmt.Errorf("unsupported nonlinearity %q", nonlinearity)
+}
@@ -0,0 +1,234 @@
+package initializer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+	"github.com/fumitoshi0524/ixeoriNet/tensor/linalg"
+)
+
+// Constant sets every element of t to value.
+func Constant(t *tensor.Tensor, value float64) error {
+	if t == nil {
+		return errors.New("initializer requires a tensor")
+	}
+	values := make([]float64, t.Numel())
+	for i := range values {
+		values[i] = value
+	}
+	return t.SetData(values)
+}
+
+// Zeros sets every element of t to zero.
+func Zeros(t *tensor.Tensor) error {
+	return Constant(t, 0)
+}
+
+// Ones sets every element of t to one.
+func Ones(t *tensor.Tensor) error {
+	return Constant(t, 1)
+}
+
+// Uniform fills t with samples from U(low, high).
+func Uniform(t *tensor.Tensor, low, high float64) error {
+	if t == nil {
+		return errors.New("initializer requires a tensor")
+	}
+	values := tensor.Rand(t.Numel()).Data()
+	for i, u := range values {
+		values[i] = low + (high-low)*u
+	}
+	return t.SetData(values)
+}
+
+// Normal fills t with samples from N(mean, std²).
+func Normal(t *tensor.Tensor, mean, std float64) error {
+	if t == nil {
+		return errors.New("initializer requires a tensor")
+	}
+	values := tensor.Randn(t.Numel()).Data()
+	for i, z := range values {
+		values[i] = mean + std*z
+	}
+	return t.SetData(values)
+}
+
+// TruncatedNormal fills t with samples from N(mean, std²) restricted to
+// [low, high], drawn by inverting the normal CDF over that interval.
+func TruncatedNormal(t *tensor.Tensor, mean, std, low, high float64) error {
+	if std <= 0 || low >= high {
+		return errors.New("TruncatedNormal needs std > 0 and low < high")
+	}
+	if t == nil {
+		return errors.New("initializer requires a tensor")
+	}
+	values := tensor.Rand(t.Numel()).Data()
+	cdf := func(x float64) float64 {
+		return 0.5 * (1 + math.Erf((x-mean)/(std*math.Sqrt2)))
+	}
+	lo, hi := cdf(low), cdf(high)
+	for i, u := range values {
+		p := lo + (hi-lo)*u
+		v := mean + std*math.Sqrt2*math.Erfinv(2*p-1)
+		values[i] = math.Min(math.Max(v, low), high)
+	}
+	return t.SetData(values)
+}
+
+// XavierUniform fills t from U(-a, a) with a = gain·√(6/(fanIn+fanOut)),
+// keeping activation variance roughly constant across layers.
+func XavierUniform(t *tensor.Tensor, gain float64) error {
+	if t == nil {
+		return errors.New("initializer requires a tensor")
+	}
+	fanIn, fanOut, err := Fans(t.Shape())
+	if err != nil {
+		return err
+	}
+	bound := gain * math.Sqrt(6/float64(fanIn+fanOut))
+	return Uniform(t, -bound, bound)
+}
+
+// XavierNormal fills t from N(0, std²) with std = gain·√(2/(fanIn+fanOut)).
+func XavierNormal(t *tensor.Tensor, gain float64) error {
+	if t == nil {
+		return errors.New("initializer requires a tensor")
+	}
+	fanIn, fanOut, err := Fans(t.Shape())
+	if err != nil {
+		return err
+	}
+	return Normal(t, 0, gain*math.Sqrt(2/float64(fanIn+fanOut)))
+}
+
+func kaimingStd(t *tensor.Tensor, slope float64, mode FanMode, nonlinearity string) (float64, error) {
+	if t == nil {
+		return 0, errors.New("initializer requires a tensor")
+	}
+	n, err := fan(t.Shape(), mode)
+	if err != nil {
+		return 0, err
+	}
+	gain, err := Gain(nonlinearity, slope)
+	if err != nil {
+		return 0, err
+	}
+	return gain / math.Sqrt(float64(n)), nil
+}
+
+// KaimingUniform fills t from U(-b, b) with b = gain·√(3/fan), where the gain
+// comes from Gain(nonlinearity, slope) and fan is picked by mode. It suits
+// layers followed by ReLU-like activations.
+func KaimingUniform(t *tensor.Tensor, slope float64, mode FanMode, nonlinearity string) error {
+	std, err := kaimingStd(t, slope, mode, nonlinearity)
+	if err != nil {
+		return err
+	}
+	bound := math.Sqrt(3) * std
+	return Uniform(t, -bound, bound)
+}
+
+// KaimingNormal fills t from N(0, std²) with std = gain/√fan.
+func KaimingNormal(t *tensor.Tensor, slope float64, mode FanMode, nonlinearity string) error {
+	std, err := kaimingStd(t, slope, mode, nonlinearity)
+	if err != nil {
+		return err
+	}
+	return Normal(t, 0, std)
+}
+
+// Orthogonal fills t, viewed as a [shape[0], rest] matrix, with a random
+// (semi-)orthogonal matrix scaled by gain: the rows are orthonormal when
+// there are fewer rows than columns and the columns otherwise.
+func Orthogonal(t *tensor.Tensor, gain float64) error {
+	if t == nil {
+		return errors.New("initializer requires a tensor")
+	}
+	shape := t.Shape()
+	if len(shape) < 2 {
+		return errors.New("Orthogonal needs a tensor with at least 2 dimensions")
+	}
+	rows := shape[0]
+	cols := t.Numel() / rows
+	m, n := rows, cols
+	if rows < cols {
+		m, n = cols, rows
+	}
+	q, r, err := linalg.QR(tensor.Randn(m, n))
+	if err != nil {
+		return err
+	}
+	// flip columns so the diagonal of R is positive, making Q uniformly
+	// distributed rather than biased by the decomposition's sign choices
+	qData, rData := q.Data(), r.Data()
+	values := make([]float64, rows*cols)
+	for i := 0; i < m; i++ {
+		for j := 0; j < n; j++ {
+			v := qData[i*n+j] * gain
+			if rData[j*n+j] < 0 {
+				v = -v
+			}
+			if rows < cols {
+				values[j*cols+i] = v
+			} else {
+				values[i*cols+j] = v
+			}
+		}
+	}
+	return t.SetData(values)
+}
+
+// Eye sets a 2-D tensor to the identity matrix, with ones on the main
+// diagonal of non-square shapes.
+func Eye(t *tensor.Tensor) error {
+	if t == nil {
+		return errors.New("initializer requires a tensor")
+	}
+	shape := t.Shape()
+	if len(shape) != 2 {
+		return errors.New("Eye needs a 2-D tensor")
+	}
+	values := make([]float64, t.Numel())
+	for i := 0; i < shape[0] && i < shape[1]; i++ {
+		values[i*shape[1]+i] = 1
+	}
+	return t.SetData(values)
+}
+
+// Dirac sets a [out, in, kernel...] convolution weight (rank 3 to 5) to the
+// identity: each output channel copies one input channel at the kernel
+// centre. With groups > 1 the output channels are split into groups that
+// each copy the input channels of their group.
+func Dirac(t *tensor.Tensor, groups int) error {
+	if t == nil {
+		return errors.New("initializer requires a tensor")
+	}
+	shape := t.Shape()
+	if len(shape) < 3 || len(shape) > 5 {
+		return errors.New("Dirac needs a tensor with 3 to 5 dimensions")
+	}
+	if groups <= 0 {
+		groups = 1
+	}
+	if shape[0]%groups != 0 {
+		return fmt.Errorf("Dirac output channels %d not divisible by groups %d", shape[0], groups)
+	}
+	kernel := shape[2:]
+	kernelSize := 1
+	centre := 0
+	for _, dim := range kernel {
+		kernelSize *= dim
+		centre = centre*dim + dim/2
+	}
+	perGroup := shape[0] / groups
+	values := make([]float64, t.Numel())
+	for g := 0; g < groups; g++ {
+		for d := 0; d < perGroup && d < shape[1]; d++ {
+			out := g*perGroup + d
+			values[(out*shape[1]+d)*kernelSize+centre] = 1
+		}
+	}
+	return t.SetData(values)
+}
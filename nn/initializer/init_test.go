@@ -0,0 +1,159 @@
+package initializer
+
+import (
+	"math"
+	"testing"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+func stats(values []float64) (mean, std float64) {
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	for _, v := range values {
+		std += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(std / float64(len(values)))
+}
+
+func TestFansAndGain(t *testing.T) {
+	fanIn, fanOut, err := Fans([]int{8, 4, 3, 3})
+	if err != nil || fanIn != 36 || fanOut != 72 {
+		t.Fatalf("unexpected fans %d, %d (err %v)", fanIn, fanOut, err)
+	}
+	if _, _, err := Fans([]int{5}); err == nil {
+		t.Fatalf("expected error for rank-1 fans")
+	}
+	if g, _ := Gain("relu", 0); math.Abs(g-math.Sqrt2) > 1e-12 {
+		t.Fatalf("unexpected relu gain %v", g)
+	}
+	if g, _ := Gain("leaky_relu", 1); math.Abs(g-1) > 1e-12 {
+		t.Fatalf("unexpected leaky_relu gain %v", g)
+	}
+	if _, err := Gain("swish", 0); err == nil {
+		t.Fatalf("expected unsupported nonlinearity error")
+	}
+}
+
+func TestRandomInitializersMatchTargetSpread(t *testing.T) {
+	w := tensor.Zeros(200, 100)
+	w.SetRequiresGrad(true)
+	if err := KaimingNormal(w, 0, FanIn, "relu"); err != nil {
+		t.Fatalf("KaimingNormal failed: %v", err)
+	}
+	if !w.RequiresGrad() {
+		t.Fatalf("initializer must keep requires-grad")
+	}
+	if _, std := stats(w.Data()); math.Abs(std-math.Sqrt(2.0/100)) > 0.01 {
+		t.Fatalf("kaiming normal std %v", std)
+	}
+	if err := KaimingUniform(w, 0, FanOut, "linear"); err != nil {
+		t.Fatalf("KaimingUniform failed: %v", err)
+	}
+	bound := math.Sqrt(3.0 / 200)
+	for _, v := range w.Data() {
+		if math.Abs(v) > bound {
+			t.Fatalf("kaiming uniform sample %v beyond %v", v, bound)
+		}
+	}
+	if err := XavierNormal(w, 1); err != nil {
+		t.Fatalf("XavierNormal failed: %v", err)
+	}
+	if _, std := stats(w.Data()); math.Abs(std-math.Sqrt(2.0/300)) > 0.01 {
+		t.Fatalf("xavier normal std %v", std)
+	}
+	if err := XavierUniform(w, 2); err != nil {
+		t.Fatalf("XavierUniform failed: %v", err)
+	}
+	if _, std := stats(w.Data()); math.Abs(std-2*math.Sqrt(2.0/300)) > 0.01 {
+		t.Fatalf("xavier uniform std %v", std)
+	}
+	if err := TruncatedNormal(w, 1, 2, 0, 1.5); err != nil {
+		t.Fatalf("TruncatedNormal failed: %v", err)
+	}
+	for _, v := range w.Data() {
+		if v < 0 || v > 1.5 {
+			t.Fatalf("truncated sample %v outside [0, 1.5]", v)
+		}
+	}
+	if err := KaimingNormal(tensor.Zeros(4), 0, FanIn, "relu"); err == nil {
+		t.Fatalf("expected fan error for a bias-shaped tensor")
+	}
+}
+
+func TestOrthogonal(t *testing.T) {
+	for _, shape := range [][]int{{5, 3}, {2, 3, 2}} {
+		w := tensor.Zeros(shape...)
+		if err := Orthogonal(w, 2); err != nil {
+			t.Fatalf("Orthogonal failed: %v", err)
+		}
+		rows := shape[0]
+		cols := w.Numel() / rows
+		flat, _ := w.Reshape(rows, cols)
+		var gram *tensor.Tensor
+		var err error
+		if rows < cols {
+			gram, err = tensor.MatMul(flat, flat.MustTranspose())
+		} else {
+			gram, err = tensor.MatMul(flat.MustTranspose(), flat)
+		}
+		if err != nil {
+			t.Fatalf("gram failed: %v", err)
+		}
+		n := gram.Shape()[0]
+		data := gram.Data()
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				want := 0.0
+				if i == j {
+					want = 4
+				}
+				if math.Abs(data[i*n+j]-want) > 1e-9 {
+					t.Fatalf("shape %v: gram[%d][%d] = %v, want %v", shape, i, j, data[i*n+j], want)
+				}
+			}
+		}
+	}
+}
+
+func TestDeterministicInitializers(t *testing.T) {
+	w := tensor.Zeros(2, 3)
+	if err := Eye(w); err != nil {
+		t.Fatalf("Eye failed: %v", err)
+	}
+	if got := w.Data(); got[0] != 1 || got[4] != 1 || got[1] != 0 || got[5] != 0 {
+		t.Fatalf("unexpected eye %v", got)
+	}
+	if err := Constant(w, 0.5); err != nil || w.Data()[5] != 0.5 {
+		t.Fatalf("Constant failed: %v", err)
+	}
+
+	// a Dirac weight makes a padded convolution return its input
+	kernel := tensor.Zeros(2, 2, 3, 3)
+	if err := Dirac(kernel, 1); err != nil {
+		t.Fatalf("Dirac failed: %v", err)
+	}
+	input := tensor.Randn(1, 2, 4, 4)
+	out, err := tensor.Conv2D(input, kernel, nil, 1, 1, 1, 1)
+	if err != nil {
+		t.Fatalf("conv failed: %v", err)
+	}
+	got, want := out.Data(), input.Data()
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-12 {
+			t.Fatalf("dirac conv changed the input at %d: %v vs %v", i, got[i], want[i])
+		}
+	}
+	grouped := tensor.Zeros(4, 1, 3)
+	if err := Dirac(grouped, 2); err != nil {
+		t.Fatalf("grouped Dirac failed: %v", err)
+	}
+	if got := grouped.Data(); got[1] != 1 || got[4] != 0 || got[7] != 1 || got[10] != 0 {
+		t.Fatalf("unexpected grouped dirac %v", got)
+	}
+	if err := Dirac(tensor.Zeros(3, 3), 1); err == nil {
+		t.Fatalf("expected Dirac rank error")
+	}
+}
@@ -0,0 +1,272 @@
+package nn
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// AttentionCache holds the projected keys and values [batch, heads, len,
+// headDim] that one attention layer has already seen, so incremental
+// decoding only projects the new tokens. Cached tensors are detached: the
+// cache is meant for inference.
+type AttentionCache struct {
+	keys   *tensor.Tensor
+	values *tensor.Tensor
+	// static marks caches of a fixed memory (cross-attention), which
+	// KVCache.Truncate leaves alone.
+	static bool
+}
+
+// Len returns the number of cached positions.
+func (c *AttentionCache) Len() int {
+	if c == nil || c.keys == nil {
+		return 0
+	}
+	return c.keys.Shape()[2]
+}
+
+// Reset drops every cached position.
+func (c *AttentionCache) Reset() {
+	if c == nil {
+		return
+	}
+	c.keys, c.values = nil, nil
+}
+
+// Truncate keeps the first n cached positions.
+func (c *AttentionCache) Truncate(n int) error {
+	if n < 0 || n > c.Len() {
+		return fmt.Errorf("cannot truncate cache of length %d to %d", c.Len(), n)
+	}
+	if n == c.Len() {
+		return nil
+	}
+	if n == 0 {
+		c.Reset()
+		return nil
+	}
+	keys, err := tensor.Split(2, []int{n, c.Len() - n}, c.keys)
+	if err != nil {
+		return err
+	}
+	values, err := tensor.Split(2, []int{n, c.Len() - n}, c.values)
+	if err != nil {
+		return err
+	}
+	c.keys, c.values = keys[0].Detach(), values[0].Detach()
+	return nil
+}
+
+// Reorder replaces the batch with the rows selected by indices, e.g. the
+// surviving hypotheses of a beam search step. Indices may repeat, so the
+// batch can also grow.
+func (c *AttentionCache) Reorder(indices []int) error {
+	if c.Len() == 0 {
+		return nil
+	}
+	keys, err := selectBatchRows(c.keys, indices)
+	if err != nil {
+		return err
+	}
+	values, err := selectBatchRows(c.values, indices)
+	if err != nil {
+		return err
+	}
+	c.keys, c.values = keys, values
+	return nil
+}
+
+// append adds keys and values for new positions and returns the full cached
+// sequences, keeping gradients for the new part.
+func (c *AttentionCache) append(keys, values *tensor.Tensor) (*tensor.Tensor, *tensor.Tensor, error) {
+	if c.Len() > 0 {
+		var err error
+		if keys, err = tensor.Concat(2, c.keys, keys); err != nil {
+			return nil, nil, fmt.Errorf("cached keys: %w", err)
+		}
+		if values, err = tensor.Concat(2, c.values, values); err != nil {
+			return nil, nil, fmt.Errorf("cached values: %w", err)
+		}
+	}
+	c.keys, c.values = keys.Detach(), values.Detach()
+	return keys, values, nil
+}
+
+func selectBatchRows(t *tensor.Tensor, indices []int) (*tensor.Tensor, error) {
+	shape := t.Shape()
+	rowSize := t.Numel() / shape[0]
+	data := t.Data()
+	out := make([]float64, 0, len(indices)*rowSize)
+	for _, idx := range indices {
+		if idx < 0 || idx >= shape[0] {
+			return nil, fmt.Errorf("reorder index %d out of range [0, %d)", idx, shape[0])
+		}
+		out = append(out, data[idx*rowSize:(idx+1)*rowSize]...)
+	}
+	newShape := append([]int{len(indices)}, shape[1:]...)
+	return tensor.New(out, newShape...)
+}
+
+// KVCache groups one AttentionCache per attention layer of a model. Layers
+// are created on first use, so a model only needs to agree on the index it
+// uses for each of its attention blocks.
+type KVCache struct {
+	layers []*AttentionCache
+}
+
+func NewKVCache() *KVCache {
+	return &KVCache{}
+}
+
+// Layer returns the cache of attention block i.
+func (c *KVCache) Layer(i int) *AttentionCache {
+	for len(c.layers) <= i {
+		c.layers = append(c.layers, &AttentionCache{})
+	}
+	return c.layers[i]
+}
+
+// Static returns the cache of attention block i and marks it as holding a
+// fixed memory, such as the encoder output seen by cross-attention.
+func (c *KVCache) Static(i int) *AttentionCache {
+	l := c.Layer(i)
+	l.static = true
+	return l
+}
+
+// Len returns the number of decoded positions, i.e. the length of the first
+// non-static layer.
+func (c *KVCache) Len() int {
+	for _, l := range c.layers {
+		if !l.static {
+			return l.Len()
+		}
+	}
+	return 0
+}
+
+func (c *KVCache) Reset() {
+	for _, l := range c.layers {
+		l.Reset()
+	}
+}
+
+// Truncate keeps the first n positions of every non-static layer.
+func (c *KVCache) Truncate(n int) error {
+	for i, l := range c.layers {
+		if l.static {
+			continue
+		}
+		if err := l.Truncate(n); err != nil {
+			return fmt.Errorf("cache layer %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Reorder selects batch rows in every layer; see AttentionCache.Reorder.
+func (c *KVCache) Reorder(indices []int) error {
+	for i, l := range c.layers {
+		if err := l.Reorder(indices); err != nil {
+			return fmt.Errorf("cache layer %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// IncrementalDecoder is an autoregressive model that processes only the new
+// tokens [batch, newLen] of a sequence, reading earlier positions from the
+// cache, and returns logits [batch, newLen, vocab].
+type IncrementalDecoder interface {
+	ForwardStep(tokens *tensor.Tensor, cache *KVCache) (*tensor.Tensor, error)
+}
+
+// GenerateOptions controls Generate.
+type GenerateOptions struct {
+	MaxNewTokens int
+	// StopTokens end a sequence once generated; finished sequences keep
+	// emitting their stop token until every sequence has stopped.
+	StopTokens []int
+	// Sample picks the next token from a row of logits; nil picks the
+	// argmax (greedy decoding).
+	Sample func(logits []float64) int
+}
+
+// Generate extends each prompt (all of the same length) by up to
+// MaxNewTokens tokens. The prompt is processed in one step, then each
+// further step feeds only the previous token thanks to the cache.
+func Generate(model IncrementalDecoder, prompts [][]int, opts GenerateOptions) ([][]int, error) {
+	if len(prompts) == 0 {
+		return nil, errors.New("Generate requires at least one prompt")
+	}
+	promptLen := len(prompts[0])
+	if promptLen == 0 {
+		return nil, errors.New("Generate requires non-empty prompts")
+	}
+	data := make([]float64, 0, len(prompts)*promptLen)
+	seqs := make([][]int, len(prompts))
+	for i, p := range prompts {
+		if len(p) != promptLen {
+			return nil, errors.New("Generate requires prompts of equal length")
+		}
+		for _, tok := range p {
+			data = append(data, float64(tok))
+		}
+		seqs[i] = append([]int(nil), p...)
+	}
+	sample := opts.Sample
+	if sample == nil {
+		sample = argmax
+	}
+	stop := make(map[int]bool, len(opts.StopTokens))
+	for _, tok := range opts.StopTokens {
+		stop[tok] = true
+	}
+
+	cache := NewKVCache()
+	tokens := tensor.MustNew(data, len(prompts), promptLen)
+	finished := make([]bool, len(prompts))
+	for step := 0; step < opts.MaxNewTokens; step++ {
+		logits, err := model.ForwardStep(tokens, cache)
+		if err != nil {
+			return nil, fmt.Errorf("generation step %d: %w", step, err)
+		}
+		shape := logits.Shape()
+		if len(shape) != 3 || shape[0] != len(prompts) {
+			return nil, fmt.Errorf("ForwardStep returned logits of shape %v", shape)
+		}
+		vocab := shape[2]
+		values := logits.Data()
+		next := make([]float64, len(prompts))
+		done := true
+		for b := range prompts {
+			tok := seqs[b][len(seqs[b])-1]
+			if !finished[b] {
+				last := (b*shape[1] + shape[1] - 1) * vocab
+				tok = sample(values[last : last+vocab])
+				finished[b] = stop[tok]
+			}
+			seqs[b] = append(seqs[b], tok)
+			next[b] = float64(tok)
+			done = done && finished[b]
+		}
+		if done {
+			break
+		}
+		tokens = tensor.MustNew(next, len(prompts), 1)
+	}
+	return seqs, nil
+}
+
+func argmax(values []float64) int {
+	best, bestVal := 0, math.Inf(-1)
+	for i, v := range values {
+		if v > bestVal {
+			best, bestVal = i, v
+		}
+	}
+	return best
+}
@@ -0,0 +1,216 @@
+package nn
+
+import (
+	"testing"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// sliceSeq returns positions [from, to) of x [batch, seq, dim].
+func sliceSeq(t *testing.T, x *tensor.Tensor, from, to int) *tensor.Tensor {
+	t.Helper()
+	shape := x.Shape()
+	var sizes []int
+	idx := 0
+	if from > 0 {
+		sizes = append(sizes, from)
+		idx = 1
+	}
+	sizes = append(sizes, to-from)
+	if to < shape[1] {
+		sizes = append(sizes, shape[1]-to)
+	}
+	parts, err := tensor.Split(1, sizes, x)
+	if err != nil {
+		t.Fatalf("split failed: %v", err)
+	}
+	return parts[idx]
+}
+
+func TestAttendCachedMatchesFullCausal(t *testing.T) {
+	mha, _ := NewMultiheadAttention(8, 2, 0, true)
+	rope, _ := NewRotaryEmbedding(4, 0)
+	if err := mha.SetRotary(rope); err != nil {
+		t.Fatalf("SetRotary failed: %v", err)
+	}
+	x := tensor.Randn(2, 5, 8)
+	causal := AttentionOptions{Causal: true}
+	full, _, err := mha.Attend(x, x, x, causal)
+	if err != nil {
+		t.Fatalf("Attend failed: %v", err)
+	}
+
+	cache := &AttentionCache{}
+	prompt := sliceSeq(t, x, 0, 3)
+	first, _, err := mha.AttendCached(prompt, prompt, prompt, cache, causal)
+	if err != nil {
+		t.Fatalf("AttendCached failed: %v", err)
+	}
+	steps := []*tensor.Tensor{first}
+	for pos := 3; pos < 5; pos++ {
+		tok := sliceSeq(t, x, pos, pos+1)
+		out, _, err := mha.AttendCached(tok, tok, tok, cache, causal)
+		if err != nil {
+			t.Fatalf("step %d failed: %v", pos, err)
+		}
+		steps = append(steps, out)
+	}
+	incremental, _ := tensor.Concat(1, steps...)
+	if !floatsAlmostEqual(full.Data(), incremental.Data(), 1e-10) {
+		t.Fatalf("incremental attention differs from full recomputation")
+	}
+	if cache.Len() != 5 {
+		t.Fatalf("expected 5 cached positions, got %d", cache.Len())
+	}
+
+	// rewinding to 4 positions and replaying the last token gives the same output
+	if err := cache.Truncate(4); err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+	last := sliceSeq(t, x, 4, 5)
+	again, _, _ := mha.AttendCached(last, last, last, cache, causal)
+	if !floatsAlmostEqual(again.Data(), steps[2].Data(), 1e-10) {
+		t.Fatalf("truncated cache replay mismatch")
+	}
+
+	// swapping the batch rows of the cache swaps the results
+	if err := cache.Truncate(4); err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+	if err := cache.Reorder([]int{1, 0}); err != nil {
+		t.Fatalf("Reorder failed: %v", err)
+	}
+	lastData := last.Data()
+	swapped := tensor.MustNew(append(append([]float64(nil), lastData[8:]...), lastData[:8]...), 2, 1, 8)
+	out, _, _ := mha.AttendCached(swapped, swapped, swapped, cache, causal)
+	want := steps[2].Data()
+	if !floatsAlmostEqual(out.Data()[:8], want[8:], 1e-10) || !floatsAlmostEqual(out.Data()[8:], want[:8], 1e-10) {
+		t.Fatalf("reordered cache mismatch")
+	}
+	if err := cache.Reorder([]int{2}); err == nil {
+		t.Fatalf("expected out-of-range reorder error")
+	}
+}
+
+func TestTransformerDecoderCachedMatchesFull(t *testing.T) {
+	dec, _ := NewTransformerDecoder(TransformerConfig{DModel: 4, NumHeads: 2, DimFeedforward: 8}, 2, true)
+	memory, tgt := tensor.Randn(1, 3, 4), tensor.Randn(1, 4, 4)
+	full, err := dec.ForwardDecoder(tgt, memory, AttentionOptions{Causal: true}, AttentionOptions{})
+	if err != nil {
+		t.Fatalf("ForwardDecoder failed: %v", err)
+	}
+	cache := NewKVCache()
+	var steps []*tensor.Tensor
+	for pos := 0; pos < 4; pos++ {
+		out, err := dec.ForwardDecoderCached(sliceSeq(t, tgt, pos, pos+1), memory, cache, AttentionOptions{Causal: true}, AttentionOptions{})
+		if err != nil {
+			t.Fatalf("step %d failed: %v", pos, err)
+		}
+		steps = append(steps, out)
+	}
+	incremental, _ := tensor.Concat(1, steps...)
+	if !floatsAlmostEqual(full.Data(), incremental.Data(), 1e-10) {
+		t.Fatalf("cached decoder differs from full decoding")
+	}
+	if cache.Len() != 4 || cache.Layer(1).Len() != 3 {
+		t.Fatalf("unexpected cache lengths %d and %d", cache.Len(), cache.Layer(1).Len())
+	}
+	if err := cache.Truncate(2); err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+	if cache.Len() != 2 || cache.Layer(1).Len() != 3 {
+		t.Fatalf("truncate must keep the cross-attention memory")
+	}
+	if _, err := dec.ForwardDecoderCached(tgt, memory, nil, AttentionOptions{}, AttentionOptions{}); err == nil {
+		t.Fatalf("expected missing cache error")
+	}
+	enc, _ := NewTransformerEncoder(TransformerConfig{DModel: 4, NumHeads: 2, DimFeedforward: 8}, 1, false)
+	if _, err := enc.ForwardCached(tgt, nil, AttentionOptions{}); err == nil {
+		t.Fatalf("expected missing cache error")
+	}
+	var empty *AttentionCache
+	empty.Reset()
+}
+
+func TestGenerateWithCache(t *testing.T) {
+	lm, err := NewCausalLM(6, TransformerConfig{DModel: 8, NumHeads: 2, DimFeedforward: 16, Position: "rope"}, 2)
+	if err != nil {
+		t.Fatalf("NewCausalLM failed: %v", err)
+	}
+	prompts := [][]int{{1, 2, 3}, {4, 0, 5}}
+	got, err := Generate(lm, prompts, GenerateOptions{MaxNewTokens: 5})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// greedy reference that feeds the full sequence at every step
+	for b, prompt := range prompts {
+		seq := append([]int(nil), prompt...)
+		for step := 0; step < 5; step++ {
+			data := make([]float64, len(seq))
+			for i, tok := range seq {
+				data[i] = float64(tok)
+			}
+			logits, err := lm.Forward(tensor.MustNew(data, 1, len(seq)))
+			if err != nil {
+				t.Fatalf("reference step failed: %v", err)
+			}
+			vals := logits.Data()
+			seq = append(seq, argmax(vals[len(vals)-6:]))
+		}
+		if len(got[b]) != len(seq) {
+			t.Fatalf("sequence %d has length %d, want %d", b, len(got[b]), len(seq))
+		}
+		for i := range seq {
+			if got[b][i] != seq[i] {
+				t.Fatalf("sequence %d = %v, want %v", b, got[b], seq)
+			}
+		}
+	}
+
+	first := got[0][3]
+	stopped, err := Generate(lm, prompts[:1], GenerateOptions{MaxNewTokens: 5, StopTokens: []int{first}})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if len(stopped[0]) != 4 || stopped[0][3] != first {
+		t.Fatalf("expected generation to stop after token %d, got %v", first, stopped[0])
+	}
+}
+
+func TestAttentionForwardCached(t *testing.T) {
+	attn := NewAttention(4, 6)
+	x := tensor.Randn(2, 4, 6)
+	whole, err := attn.ForwardCached(x, &AttentionCache{})
+	if err != nil {
+		t.Fatalf("ForwardCached failed: %v", err)
+	}
+	cache := &AttentionCache{}
+	var steps []*tensor.Tensor
+	for pos := 0; pos < 4; pos++ {
+		out, err := attn.ForwardCached(sliceSeq(t, x, pos, pos+1), cache)
+		if err != nil {
+			t.Fatalf("step %d failed: %v", pos, err)
+		}
+		steps = append(steps, out)
+	}
+	incremental, _ := tensor.Concat(1, steps...)
+	if !floatsAlmostEqual(whole.Data(), incremental.Data(), 1e-10) || cache.Len() != 4 {
+		t.Fatalf("token-by-token attention differs from one cached pass")
+	}
+
+	// a single position attends only to itself, so pooling changes nothing
+	single := NewAttention(1, 6)
+	tok := sliceSeq(t, x, 0, 1)
+	pooled, _ := single.Forward(tok)
+	cached, err := single.ForwardCached(tok, &AttentionCache{})
+	if err != nil {
+		t.Fatalf("ForwardCached failed: %v", err)
+	}
+	if !floatsAlmostEqual(pooled.Data(), cached.Data(), 1e-10) {
+		t.Fatalf("cached output %v differs from Forward %v", cached.Data(), pooled.Data())
+	}
+	if _, err := attn.ForwardCached(x, nil); err == nil {
+		t.Fatalf("expected missing cache error")
+	}
+}
@@ -55,6 +55,13 @@ func (ln *LayerNorm) ZeroGrad() {
 	ln.bias.ZeroGrad()
 }
 
+func (ln *LayerNorm) NamedParameters() []NamedParameter {
+	return namedOrNil(
+		NamedParameter{Name: "weight", Param: ln.weight},
+		NamedParameter{Name: "bias", Param: ln.bias},
+	)
+}
+
 func (ln *LayerNorm) StateDict(prefix string, state map[string]*tensor.Tensor) {
 	if state == nil {
 		return
@@ -14,7 +14,7 @@ type Linear struct {
 	bias        *tensor.Tensor
 }
 
-func NewLinear(inFeatures, outFeatures int, withBias bool) *Linear {
+func NewLinear(inFeatures, outFeatures int, withBias bool, opts ...InitOption) *Linear {
 	w := tensor.Randn(outFeatures, inFeatures)
 	scale := math.Sqrt(2.0 / float64(inFeatures+outFeatures))
 	w.Scale(scale)
@@ -25,6 +25,7 @@ func NewLinear(inFeatures, outFeatures int, withBias bool) *Linear {
 		b.Scale(scale)
 		b.SetRequiresGrad(true)
 	}
+	applyInit(opts, []*tensor.Tensor{w}, []*tensor.Tensor{b})
 	return &Linear{inFeatures: inFeatures, outFeatures: outFeatures, weight: w, bias: b}
 }
 
@@ -79,6 +80,13 @@ func (l *Linear) Bias() *tensor.Tensor {
 	return l.bias
 }
 
+func (l *Linear) NamedParameters() []NamedParameter {
+	return namedOrNil(
+		NamedParameter{Name: "weight", Param: l.weight},
+		NamedParameter{Name: "bias", Param: l.bias},
+	)
+}
+
 func (l *Linear) StateDict(prefix string, state map[string]*tensor.Tensor) {
 	if state == nil {
 		return
@@ -113,3 +121,13 @@ func (l *Linear) LoadState(prefix string, state map[string]*tensor.Tensor) error
 	}
 	return nil
 }
+
+func (l *Linear) parameterSlot(name string) **tensor.Tensor {
+	switch name {
+	case "weight":
+		return &l.weight
+	case "bias":
+		return &l.bias
+	}
+	return nil
+}
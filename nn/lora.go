@@ -0,0 +1,318 @@
+package nn
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// loraAdapter adds the trainable update scale · B·A to the weight of one
+// module. A is [rank, rest] and B is [out, rank], where the weight is viewed
+// as an [out, rest] matrix.
+type loraAdapter struct {
+	reparameterization
+	prefix string
+	base   *tensor.Tensor
+	a      *tensor.Tensor
+	b      *tensor.Tensor
+}
+
+// delta returns scale · B·A in the shape of the base weight.
+func (ad *loraAdapter) delta(scale float64) (*tensor.Tensor, error) {
+	prod, err := tensor.MatMul(ad.b, ad.a)
+	if err != nil {
+		return nil, err
+	}
+	prod, err = prod.Reshape(ad.base.Shape()...)
+	if err != nil {
+		return nil, err
+	}
+	return tensor.MulScalar(prod, scale), nil
+}
+
+func (ad *loraAdapter) named() []NamedParameter {
+	return []NamedParameter{
+		{Name: joinPrefix(ad.prefix, "lora_A"), Param: ad.a},
+		{Name: joinPrefix(ad.prefix, "lora_B"), Param: ad.b},
+	}
+}
+
+// LoRAWrapper fine-tunes a module through low-rank adapters: every adapted
+// weight W0 is frozen and used as W0 + (alpha/rank) · B·A, where only the
+// small A and B matrices train. B starts at zero, so the wrapped module
+// initially behaves exactly like the original.
+type LoRAWrapper struct {
+	module   Module
+	adapters []*loraAdapter
+	rank     int
+	alpha    float64
+	merged   bool
+}
+
+// LoRA wraps a Linear, a convolution or the four projections of a
+// MultiheadAttention with rank-rank adapters scaled by alpha/rank (alpha <= 0
+// means alpha = rank). Every parameter of mod is frozen. The state dict
+// holds only the adapters (lora_A and lora_B, under the projection name for
+// attention); the base weights stay with the wrapped module.
+func LoRA(mod Module, rank int, alpha float64) (*LoRAWrapper, error) {
+	if rank <= 0 {
+		return nil, errors.New("LoRA rank must be positive")
+	}
+	if alpha <= 0 {
+		alpha = float64(rank)
+	}
+	var targets []NamedModule
+	switch m := mod.(type) {
+	case *Linear, *Conv1d, *Conv2d, *Conv3d:
+		targets = []NamedModule{{Module: m}}
+	case *MultiheadAttention:
+		targets = m.NamedChildren()
+	default:
+		return nil, fmt.Errorf("LoRA does not support %T", mod)
+	}
+	l := &LoRAWrapper{module: mod, rank: rank, alpha: alpha}
+	for _, target := range targets {
+		r, err := newReparameterization("LoRA", target.Module, "weight")
+		if err != nil {
+			return nil, err
+		}
+		base := *r.slot
+		shape := base.Shape()
+		rows := shape[0]
+		cols := base.Numel() / rows
+		if rank > rows || rank > cols {
+			return nil, fmt.Errorf("LoRA rank %d exceeds the %dx%d weight of %T", rank, rows, cols, target.Module)
+		}
+		a := tensor.Randn(rank, cols)
+		a.Scale(1 / math.Sqrt(float64(cols)))
+		a.SetRequiresGrad(true)
+		b := tensor.Zeros(rows, rank)
+		b.SetRequiresGrad(true)
+		l.adapters = append(l.adapters, &loraAdapter{reparameterization: r, prefix: target.Name, base: base, a: a, b: b})
+	}
+	Freeze(mod)
+	return l, nil
+}
+
+// Module returns the wrapped module, which always holds the base weights
+// (merged with the adapters after Merge).
+func (l *LoRAWrapper) Module() Module {
+	return l.module
+}
+
+// Rank returns the rank of the adapters.
+func (l *LoRAWrapper) Rank() int {
+	return l.rank
+}
+
+// Alpha returns the scaling numerator; updates are scaled by Alpha/Rank.
+func (l *LoRAWrapper) Alpha() float64 {
+	return l.alpha
+}
+
+func (l *LoRAWrapper) scale() float64 {
+	return l.alpha / float64(l.rank)
+}
+
+// run calls fn with every adapted weight replaced by W0 + scale · B·A and
+// puts the base weights back afterwards. Merged adapters are already part of
+// the base weights.
+func (l *LoRAWrapper) run(fn func() error) error {
+	if l.merged {
+		return fn()
+	}
+	defer func() {
+		for _, ad := range l.adapters {
+			*ad.slot = ad.base
+		}
+	}()
+	for _, ad := range l.adapters {
+		delta, err := ad.delta(l.scale())
+		if err != nil {
+			return err
+		}
+		adapted, err := tensor.Add(ad.base, delta)
+		if err != nil {
+			return err
+		}
+		*ad.slot = adapted
+	}
+	return fn()
+}
+
+func (l *LoRAWrapper) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	var out *tensor.Tensor
+	err := l.run(func() error {
+		var err error
+		out, err = l.module.Forward(input)
+		return err
+	})
+	return out, err
+}
+
+func (l *LoRAWrapper) ForwardMulti(inputs ...*tensor.Tensor) ([]*tensor.Tensor, error) {
+	var outs []*tensor.Tensor
+	err := l.run(func() error {
+		var err error
+		outs, err = AsMulti(l.module).ForwardMulti(inputs...)
+		return err
+	})
+	return outs, err
+}
+
+func (l *LoRAWrapper) attention() (*MultiheadAttention, error) {
+	m, ok := l.module.(*MultiheadAttention)
+	if !ok {
+		return nil, fmt.Errorf("LoRA wraps %T, not MultiheadAttention", l.module)
+	}
+	return m, nil
+}
+
+// Attend runs MultiheadAttention.Attend through the adapted projections.
+func (l *LoRAWrapper) Attend(query, key, value *tensor.Tensor, opts AttentionOptions) (*tensor.Tensor, *tensor.Tensor, error) {
+	m, err := l.attention()
+	if err != nil {
+		return nil, nil, err
+	}
+	var out, weights *tensor.Tensor
+	err = l.run(func() error {
+		var err error
+		out, weights, err = m.Attend(query, key, value, opts)
+		return err
+	})
+	return out, weights, err
+}
+
+// AttendCached runs MultiheadAttention.AttendCached through the adapted
+// projections.
+func (l *LoRAWrapper) AttendCached(query, key, value *tensor.Tensor, cache *AttentionCache, opts AttentionOptions) (*tensor.Tensor, *tensor.Tensor, error) {
+	m, err := l.attention()
+	if err != nil {
+		return nil, nil, err
+	}
+	var out, weights *tensor.Tensor
+	err = l.run(func() error {
+		var err error
+		out, weights, err = m.AttendCached(query, key, value, cache, opts)
+		return err
+	})
+	return out, weights, err
+}
+
+// Merge folds every adapter into its base weight in place, so the wrapped
+// module alone computes the adapted output at no extra cost. Forward then
+// skips the adapters until Unmerge.
+func (l *LoRAWrapper) Merge() error {
+	return l.fold(true)
+}
+
+// Unmerge subtracts the adapters from the base weights again.
+func (l *LoRAWrapper) Unmerge() error {
+	return l.fold(false)
+}
+
+// Merged reports whether the adapters are folded into the base weights.
+func (l *LoRAWrapper) Merged() bool {
+	return l.merged
+}
+
+func (l *LoRAWrapper) fold(merge bool) error {
+	if l.merged == merge {
+		return nil
+	}
+	sign := 1.0
+	if !merge {
+		sign = -1
+	}
+	for _, ad := range l.adapters {
+		delta, err := ad.delta(sign * l.scale())
+		if err != nil {
+			return err
+		}
+		if err := ad.base.AddScaled(delta, 1); err != nil {
+			return err
+		}
+	}
+	l.merged = merge
+	return nil
+}
+
+// AdapterParameters returns the A and B matrices of every adapter.
+func (l *LoRAWrapper) AdapterParameters() []*tensor.Tensor {
+	var params []*tensor.Tensor
+	for _, ad := range l.adapters {
+		params = append(params, ad.a, ad.b)
+	}
+	return params
+}
+
+func (l *LoRAWrapper) namedAdapters() []NamedParameter {
+	var named []NamedParameter
+	for _, ad := range l.adapters {
+		named = append(named, ad.named()...)
+	}
+	return named
+}
+
+// Parameters returns the frozen parameters of the wrapped module followed
+// by the adapters.
+func (l *LoRAWrapper) Parameters() []*tensor.Tensor {
+	return append(l.module.Parameters(), l.AdapterParameters()...)
+}
+
+func (l *LoRAWrapper) ZeroGrad() {
+	l.module.ZeroGrad()
+	for _, p := range l.AdapterParameters() {
+		p.ZeroGrad()
+	}
+}
+
+func (l *LoRAWrapper) NamedParameters() []NamedParameter {
+	return append(NamedParameters(l.module), l.namedAdapters()...)
+}
+
+// StateDict stores only the adapter matrices.
+func (l *LoRAWrapper) StateDict(prefix string, state map[string]*tensor.Tensor) {
+	namedStateDict(prefix, l.namedAdapters(), state)
+}
+
+// LoadState restores the adapter matrices. The state is read into copies
+// first, so a failed load leaves the adapters and the merged state as they
+// were; merged adapters are unmerged and merged again with the new values.
+func (l *LoRAWrapper) LoadState(prefix string, state map[string]*tensor.Tensor) error {
+	named := l.namedAdapters()
+	staged := make([]NamedParameter, len(named))
+	for i, np := range named {
+		staged[i] = NamedParameter{Name: np.Name, Param: np.Param.Clone()}
+	}
+	if err := loadNamedState("LoRA", prefix, staged, state); err != nil {
+		return err
+	}
+	merged := l.merged
+	if err := l.Unmerge(); err != nil {
+		return err
+	}
+	for i, np := range named {
+		if err := tensor.CopyInto(np.Param, staged[i].Param); err != nil {
+			return err
+		}
+	}
+	if merged {
+		return l.Merge()
+	}
+	return nil
+}
+
+func (l *LoRAWrapper) Train() {
+	SetTraining(l.module, true)
+}
+
+func (l *LoRAWrapper) Eval() {
+	SetTraining(l.module, false)
+}
+
+func (l *LoRAWrapper) IsTraining() bool {
+	return IsTraining(l.module)
+}
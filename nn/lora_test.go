@@ -0,0 +1,175 @@
+package nn
+
+import (
+	"testing"
+
+	"github.com/fumitoshi0524/ixeoriNet/optim"
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+func TestLoRALinear(t *testing.T) {
+	lin := NewLinear(4, 3, true)
+	baseWeight := lin.Weight().Data()
+	input := tensor.Randn(5, 4)
+	want, _ := lin.Forward(input)
+	lora, err := LoRA(lin, 2, 4)
+	if err != nil {
+		t.Fatalf("LoRA failed: %v", err)
+	}
+	out, err := lora.Forward(input)
+	if err != nil {
+		t.Fatalf("LoRA forward failed: %v", err)
+	}
+	if !floatsAlmostEqual(out.Data(), want.Data(), 1e-12) {
+		t.Fatalf("fresh adapters must not change the output")
+	}
+	trainable := TrainableParameters(lora)
+	if len(trainable) != 2 || len(lora.Parameters()) != 4 {
+		t.Fatalf("expected only the two adapters to train, got %d of %d", len(trainable), len(lora.Parameters()))
+	}
+
+	target := tensor.Randn(5, 3)
+	opt := optim.NewSGD(trainable, 0.05, 0)
+	for step := 0; step < 20; step++ {
+		opt.ZeroGrad()
+		out, err := lora.Forward(input)
+		if err != nil {
+			t.Fatalf("LoRA forward failed: %v", err)
+		}
+		diff, _ := tensor.Sub(out, target)
+		if err := tensor.Mean(tensor.Pow(diff, 2)).Backward(); err != nil {
+			t.Fatalf("backward failed: %v", err)
+		}
+		if err := opt.Step(); err != nil {
+			t.Fatalf("step failed: %v", err)
+		}
+	}
+	if !floatsAlmostEqual(lin.Weight().Data(), baseWeight, 0) || lin.Weight().Grad() != nil {
+		t.Fatalf("base weight must stay frozen")
+	}
+	adapted, _ := lora.Forward(input)
+	if floatsAlmostEqual(adapted.Data(), want.Data(), 1e-6) {
+		t.Fatalf("training the adapters should change the output")
+	}
+
+	state := map[string]*tensor.Tensor{}
+	lora.StateDict("fc", state)
+	if got := stateKeys(state); got != "fc.lora_A,fc.lora_B" {
+		t.Fatalf("unexpected adapter state keys %s", got)
+	}
+
+	if err := lora.Merge(); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	merged, _ := lin.Forward(input)
+	if !floatsAlmostEqual(merged.Data(), adapted.Data(), 1e-9) {
+		t.Fatalf("merged module %v differs from adapted %v", merged.Data(), adapted.Data())
+	}
+	if again, _ := lora.Forward(input); !floatsAlmostEqual(again.Data(), adapted.Data(), 1e-9) {
+		t.Fatalf("merged wrapper must not add the adapters twice")
+	}
+	broken := map[string]*tensor.Tensor{"fc.lora_A": tensor.Zeros(2, 4)}
+	if err := lora.LoadState("fc", broken); err == nil {
+		t.Fatalf("expected missing adapter error")
+	}
+	if !lora.Merged() || !floatsAlmostEqual(lora.AdapterParameters()[0].Data(), state["fc.lora_A"].Data(), 0) {
+		t.Fatalf("a failed load must leave the merged adapters untouched")
+	}
+	if again, _ := lin.Forward(input); !floatsAlmostEqual(again.Data(), adapted.Data(), 1e-9) {
+		t.Fatalf("a failed load must keep the merged weights")
+	}
+	if err := lora.Unmerge(); err != nil {
+		t.Fatalf("Unmerge failed: %v", err)
+	}
+	if !floatsAlmostEqual(lin.Weight().Data(), baseWeight, 1e-12) {
+		t.Fatalf("Unmerge must restore the base weight")
+	}
+
+	// adapters saved from one wrapper reproduce it on a copy of the base
+	copyLin := NewLinear(4, 3, true)
+	base := map[string]*tensor.Tensor{}
+	lin.StateDict("", base)
+	if err := copyLin.LoadState("", base); err != nil {
+		t.Fatalf("base load failed: %v", err)
+	}
+	other, _ := LoRA(copyLin, 2, 4)
+	if err := other.LoadState("fc", state); err != nil {
+		t.Fatalf("adapter load failed: %v", err)
+	}
+	if got, _ := other.Forward(input); !floatsAlmostEqual(got.Data(), adapted.Data(), 1e-9) {
+		t.Fatalf("loaded adapters give %v, want %v", got.Data(), adapted.Data())
+	}
+}
+
+func TestLoRAConvAndAttention(t *testing.T) {
+	conv := NewConv2d(2, 3, 3, 3, 1, 1, 1, 1, false)
+	lora, err := LoRA(conv, 2, 0)
+	if err != nil {
+		t.Fatalf("conv LoRA failed: %v", err)
+	}
+	if lora.Alpha() != 2 || lora.Rank() != 2 {
+		t.Fatalf("alpha should default to the rank")
+	}
+	mustSetData(t, lora.adapters[0].b, []float64{1, 0, 0, 1, 0.5, -0.5})
+	input := tensor.Randn(1, 2, 4, 4)
+	out, err := lora.Forward(input)
+	if err != nil {
+		t.Fatalf("conv LoRA forward failed: %v", err)
+	}
+	if err := lora.Merge(); err != nil {
+		t.Fatalf("conv Merge failed: %v", err)
+	}
+	if merged, _ := conv.Forward(input); !floatsAlmostEqual(merged.Data(), out.Data(), 1e-9) {
+		t.Fatalf("merged conv differs from adapted conv")
+	}
+
+	mha, err := NewMultiheadAttention(4, 2, 0, true)
+	if err != nil {
+		t.Fatalf("NewMultiheadAttention failed: %v", err)
+	}
+	attn, err := LoRA(mha, 1, 1)
+	if err != nil {
+		t.Fatalf("attention LoRA failed: %v", err)
+	}
+	state := map[string]*tensor.Tensor{}
+	attn.StateDict("", state)
+	if got := stateKeys(state); got != "k_proj.lora_A,k_proj.lora_B,out_proj.lora_A,out_proj.lora_B,q_proj.lora_A,q_proj.lora_B,v_proj.lora_A,v_proj.lora_B" {
+		t.Fatalf("unexpected attention adapter keys %s", got)
+	}
+	for _, ad := range attn.adapters {
+		mustSetData(t, ad.b, []float64{0.3, -0.2, 0.1, 0.4})
+	}
+	x := tensor.Randn(2, 3, 4)
+	adapted, _, err := attn.Attend(x, x, x, AttentionOptions{Causal: true})
+	if err != nil {
+		t.Fatalf("adapted Attend failed: %v", err)
+	}
+	if err := tensor.Sum(adapted).Backward(); err != nil {
+		t.Fatalf("attention backward failed: %v", err)
+	}
+	for _, p := range attn.AdapterParameters() {
+		if p.Grad() == nil {
+			t.Fatalf("every adapter should receive a gradient")
+		}
+	}
+	if err := attn.Merge(); err != nil {
+		t.Fatalf("attention Merge failed: %v", err)
+	}
+	merged, _, _ := mha.Attend(x, x, x, AttentionOptions{Causal: true})
+	if !floatsAlmostEqual(merged.Data(), adapted.Data(), 1e-9) {
+		t.Fatalf("merged attention differs from adapted attention")
+	}
+
+	if _, err := LoRA(NewLinear(2, 2, false), 0, 1); err == nil {
+		t.Fatalf("expected rank error")
+	}
+	if _, err := LoRA(NewLinear(2, 2, false), 3, 1); err == nil {
+		t.Fatalf("expected rank-too-large error")
+	}
+	if _, err := LoRA(NewEmbedding(4, 2), 1, 1); err == nil {
+		t.Fatalf("expected unsupported module error")
+	}
+	if _, _, err := lora.Attend(x, x, x, AttentionOptions{}); err == nil {
+		t.Fatalf("Attend needs a wrapped MultiheadAttention")
+	}
+}
@@ -1,7 +1,6 @@
 package nn
 
 import (
-	"errors"
 	"fmt"
 	"math"
 
@@ -16,23 +15,21 @@ const (
 	lstmGateTotal
 )
 
-type LSTM struct {
+// LSTMCell computes a single LSTM time step on [batch, features] inputs,
+// for decoders that drive the recurrence themselves. It uses the gates and
+// state-dict keys of LSTM, whose layers and directions are made of cells.
+type LSTMCell struct {
 	inputSize  int
 	hiddenSize int
 	withBias   bool
-
-	weightIH [lstmGateTotal]*tensor.Tensor
-	weightHH [lstmGateTotal]*tensor.Tensor
-	biasIH   [lstmGateTotal]*tensor.Tensor
-	biasHH   [lstmGateTotal]*tensor.Tensor
+	weightIH   [lstmGateTotal]*tensor.Tensor
+	weightHH   [lstmGateTotal]*tensor.Tensor
+	biasIH     [lstmGateTotal]*tensor.Tensor
+	biasHH     [lstmGateTotal]*tensor.Tensor
 }
 
-func NewLSTM(inputSize, hiddenSize int, withBias bool) *LSTM {
-	l := &LSTM{
-		inputSize:  inputSize,
-		hiddenSize: hiddenSize,
-		withBias:   withBias,
-	}
+func NewLSTMCell(inputSize, hiddenSize int, withBias bool, opts ...InitOption) *LSTMCell {
+	l := &LSTMCell{inputSize: inputSize, hiddenSize: hiddenSize, withBias: withBias}
 	for gate := 0; gate < lstmGateTotal; gate++ {
 		wIn := tensor.Randn(hiddenSize, inputSize)
 		wHidden := tensor.Randn(hiddenSize, hiddenSize)
@@ -53,148 +50,193 @@ func NewLSTM(inputSize, hiddenSize int, withBias bool) *LSTM {
 			l.biasHH[gate] = bHidden
 		}
 	}
+	applyInit(opts, append(l.weightIH[:], l.weightHH[:]...), append(l.biasIH[:], l.biasHH[:]...))
 	return l
 }
 
-func (l *LSTM) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
-	out, _, _, err := l.ForwardWithState(input, nil, nil)
-	return out, err
+func (l *LSTMCell) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	h, _, err := l.ForwardWithState(input, nil, nil)
+	return h, err
 }
 
-func (l *LSTM) ForwardWithState(input *tensor.Tensor, hx, cx *tensor.Tensor) (*tensor.Tensor, *tensor.Tensor, *tensor.Tensor, error) {
-	shape := input.Shape()
-	if len(shape) != 3 {
-		return nil, nil, nil, errors.New("LSTM expects input shape [seq, batch, features]")
+// ForwardMulti runs one step on (input[, h[, c]]) and returns [h, c].
+func (l *LSTMCell) ForwardMulti(inputs ...*tensor.Tensor) ([]*tensor.Tensor, error) {
+	if len(inputs) < 1 || len(inputs) > 3 {
+		return nil, fmt.Errorf("LSTMCell expects 1 to 3 inputs, got %d", len(inputs))
 	}
-	seqLen := shape[0]
-	batch := shape[1]
-	features := shape[2]
-	if features != l.inputSize {
-		return nil, nil, nil, errors.New("input feature mismatch")
+	var hx, cx *tensor.Tensor
+	if len(inputs) > 1 {
+		hx = inputs[1]
 	}
-	if hx == nil {
-		hx = tensor.Zeros(batch, l.hiddenSize)
+	if len(inputs) > 2 {
+		cx = inputs[2]
 	}
-	if cx == nil {
-		cx = tensor.Zeros(batch, l.hiddenSize)
+	h, c, err := l.ForwardWithState(inputs[0], hx, cx)
+	if err != nil {
+		return nil, err
 	}
-	hShape := hx.Shape()
-	cShape := cx.Shape()
-	if len(hShape) != 2 || hShape[0] != batch || hShape[1] != l.hiddenSize {
-		return nil, nil, nil, errors.New("hidden state shape mismatch")
+	return []*tensor.Tensor{h, c}, nil
+}
+
+// ForwardWithState advances the hidden and cell states [batch, hidden] (nil
+// for zeros) by one step of input [batch, features].
+func (l *LSTMCell) ForwardWithState(input, hx, cx *tensor.Tensor) (*tensor.Tensor, *tensor.Tensor, error) {
+	batch, err := checkCellInput("LSTMCell", input, l.inputSize)
+	if err != nil {
+		return nil, nil, err
 	}
-	if len(cShape) != 2 || cShape[0] != batch || cShape[1] != l.hiddenSize {
-		return nil, nil, nil, errors.New("cell state shape mismatch")
+	if hx, err = cellState(hx, batch, l.hiddenSize, "hidden"); err != nil {
+		return nil, nil, err
 	}
-
-	sizes := make([]int, seqLen)
-	for i := 0; i < seqLen; i++ {
-		sizes[i] = 1
+	if cx, err = cellState(cx, batch, l.hiddenSize, "cell"); err != nil {
+		return nil, nil, err
 	}
-	steps, err := tensor.Split(0, sizes, input)
+	x, err := input.Reshape(1, batch, l.inputSize)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, err
 	}
-	currentH := hx
-	currentC := cx
-	frames := make([]*tensor.Tensor, 0, seqLen)
-	for _, step := range steps {
-		var x *tensor.Tensor
-		x, err = step.Reshape(batch, features)
-		if err != nil {
-			return nil, nil, nil, err
-		}
+	_, h, c, err := l.sequence(x, hx, cx, nil, false)
+	return h, c, err
+}
 
-		iPre, err := l.affine(x, currentH, lstmGateInput)
-		if err != nil {
-			return nil, nil, nil, err
-		}
-		inputGate := tensor.Sigmoid(iPre)
+// sequence runs the cell over x [seq, batch, features] with the fused
+// kernel; see tensor.LSTMSequence.
+func (l *LSTMCell) sequence(x, h, c *tensor.Tensor, lengths []int, reverse bool) (*tensor.Tensor, *tensor.Tensor, *tensor.Tensor, error) {
+	weightIH, weightHH, bias, err := fuseGates(l.weightIH[:], l.weightHH[:], l.biasIH[:], l.biasHH[:], l.withBias)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return tensor.LSTMSequence(x, h, c, weightIH, weightHH, bias, lengths, reverse)
+}
 
-		fPre, err := l.affine(x, currentH, lstmGateForget)
-		if err != nil {
-			return nil, nil, nil, err
+func (l *LSTMCell) Parameters() []*tensor.Tensor {
+	params := make([]*tensor.Tensor, 0, lstmGateTotal*4)
+	for gate := 0; gate < lstmGateTotal; gate++ {
+		params = append(params, l.weightIH[gate], l.weightHH[gate])
+		if l.withBias {
+			params = append(params, l.biasIH[gate], l.biasHH[gate])
 		}
-		forgetGate := tensor.Sigmoid(fPre)
+	}
+	return params
+}
 
-		gPre, err := l.affine(x, currentH, lstmGateCell)
-		if err != nil {
-			return nil, nil, nil, err
-		}
-		cellCandidate := tensor.Tanh(gPre)
+func (l *LSTMCell) ZeroGrad() {
+	for _, p := range l.Parameters() {
+		p.ZeroGrad()
+	}
+}
 
-		oPre, err := l.affine(x, currentH, lstmGateOutput)
-		if err != nil {
-			return nil, nil, nil, err
-		}
-		outputGate := tensor.Sigmoid(oPre)
+func (l *LSTMCell) NamedParameters() []NamedParameter {
+	return l.namedParameters("")
+}
 
-		t1, err := tensor.Mul(forgetGate, currentC)
-		if err != nil {
-			return nil, nil, nil, err
-		}
-		t2, err := tensor.Mul(inputGate, cellCandidate)
-		if err != nil {
-			return nil, nil, nil, err
-		}
-		currentC, err = tensor.Add(t1, t2)
-		if err != nil {
-			return nil, nil, nil, err
-		}
+func (l *LSTMCell) StateDict(prefix string, state map[string]*tensor.Tensor) {
+	namedStateDict(prefix, l.NamedParameters(), state)
+}
 
-		tanhC := tensor.Tanh(currentC)
-		currentH, err = tensor.Mul(outputGate, tanhC)
-		if err != nil {
-			return nil, nil, nil, err
-		}
+func (l *LSTMCell) LoadState(prefix string, state map[string]*tensor.Tensor) error {
+	return loadNamedState("LSTMCell", prefix, l.NamedParameters(), state)
+}
 
-		frame, err := tensor.Unsqueeze(currentH, 0)
-		if err != nil {
-			return nil, nil, nil, err
+func (l *LSTMCell) namedParameters(suffix string) []NamedParameter {
+	var named []NamedParameter
+	gateNames := []string{"input", "forget", "cell", "output"}
+	for gate, name := range gateNames {
+		named = append(named,
+			NamedParameter{Name: "weight_ih_" + name + suffix, Param: l.weightIH[gate]},
+			NamedParameter{Name: "weight_hh_" + name + suffix, Param: l.weightHH[gate]},
+		)
+		if l.withBias {
+			named = append(named,
+				NamedParameter{Name: "bias_ih_" + name + suffix, Param: l.biasIH[gate]},
+				NamedParameter{Name: "bias_hh_" + name + suffix, Param: l.biasHH[gate]},
+			)
 		}
-		frames = append(frames, frame)
 	}
+	return named
+}
 
-	output, err := tensor.Concat(0, frames...)
-	if err != nil {
-		return nil, nil, nil, err
+// LSTM is a long short-term memory network with optional stacking,
+// bidirectionality, inter-layer dropout and batch-first layout.
+type LSTM struct {
+	recurrentLayout
+	cells []*LSTMCell
+}
+
+func NewLSTM(inputSize, hiddenSize int, withBias bool, opts ...InitOption) *LSTM {
+	return NewLSTMWithConfig(inputSize, hiddenSize, withBias, RecurrentConfig{}, opts...)
+}
+
+func NewLSTMWithConfig(inputSize, hiddenSize int, withBias bool, cfg RecurrentConfig, opts ...InitOption) *LSTM {
+	l := &LSTM{recurrentLayout: newRecurrentLayout("LSTM", inputSize, hiddenSize, cfg)}
+	for cell := 0; cell < l.numCells(); cell++ {
+		l.cells = append(l.cells, NewLSTMCell(l.cellInputSize(cell/l.directions()), hiddenSize, withBias, opts...))
 	}
-	return output, currentH, currentC, nil
+	return l
 }
 
-func (l *LSTM) affine(x, h *tensor.Tensor, gate int) (*tensor.Tensor, error) {
-	inputPart, err := tensor.MatMul(x, l.weightIH[gate].MustTranspose())
+func (l *LSTM) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	out, _, _, err := l.ForwardWithState(input, nil, nil)
+	return out, err
+}
+
+// ForwardMulti runs the LSTM on (input[, h0[, c0]]) and returns
+// [output, hN, cN].
+func (l *LSTM) ForwardMulti(inputs ...*tensor.Tensor) ([]*tensor.Tensor, error) {
+	if len(inputs) < 1 || len(inputs) > 3 {
+		return nil, fmt.Errorf("LSTM expects 1 to 3 inputs, got %d", len(inputs))
+	}
+	var hx, cx *tensor.Tensor
+	if len(inputs) > 1 {
+		hx = inputs[1]
+	}
+	if len(inputs) > 2 {
+		cx = inputs[2]
+	}
+	out, h, c, err := l.ForwardWithState(inputs[0], hx, cx)
 	if err != nil {
 		return nil, err
 	}
-	hiddenPart, err := tensor.MatMul(h, l.weightHH[gate].MustTranspose())
+	return []*tensor.Tensor{out, h, c}, nil
+}
+
+// ForwardWithState runs the LSTM from the initial hidden and cell states
+// (nil for zeros) and returns the output [seq, batch, dirs*hidden] together
+// with the final states. States are [numLayers*dirs, batch, hidden], ordered
+// layer by layer with the forward direction first; a single-layer
+// unidirectional LSTM uses [batch, hidden].
+func (l *LSTM) ForwardWithState(input *tensor.Tensor, hx, cx *tensor.Tensor) (*tensor.Tensor, *tensor.Tensor, *tensor.Tensor, error) {
+	out, states, err := l.run(input, nil, []*tensor.Tensor{hx, cx}, l.sequence)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
-	sum, err := tensor.Add(inputPart, hiddenPart)
+	return out, states[0], states[1], nil
+}
+
+// ForwardPacked runs the LSTM over variable-length sequences. Each sequence
+// stops at its own length, so the final states are those of its last valid
+// step (the first step for the reverse direction). States are in the
+// original batch order.
+func (l *LSTM) ForwardPacked(input *PackedSequence, hx, cx *tensor.Tensor) (*PackedSequence, *tensor.Tensor, *tensor.Tensor, error) {
+	out, states, err := l.runPacked(input, []*tensor.Tensor{hx, cx}, l.sequence)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
-	if l.withBias {
-		sum, err = tensor.AddBias2D(sum, l.biasIH[gate])
-		if err != nil {
-			return nil, err
-		}
-		sum, err = tensor.AddBias2D(sum, l.biasHH[gate])
-		if err != nil {
-			return nil, err
-		}
+	return out, states[0], states[1], nil
+}
+
+func (l *LSTM) sequence(cell int, x *tensor.Tensor, state []*tensor.Tensor, lengths []int, reverse bool) (*tensor.Tensor, []*tensor.Tensor, error) {
+	out, h, c, err := l.cells[cell].sequence(x, state[0], state[1], lengths, reverse)
+	if err != nil {
+		return nil, nil, err
 	}
-	return sum, nil
+	return out, []*tensor.Tensor{h, c}, nil
 }
 
 func (l *LSTM) Parameters() []*tensor.Tensor {
-	params := make([]*tensor.Tensor, 0, lstmGateTotal*4)
-	for gate := 0; gate < lstmGateTotal; gate++ {
-		params = append(params, l.weightIH[gate], l.weightHH[gate])
-		if l.withBias {
-			params = append(params, l.biasIH[gate], l.biasHH[gate])
-		}
+	var params []*tensor.Tensor
+	for _, cell := range l.cells {
+		params = append(params, cell.Parameters()...)
 	}
 	return params
 }
@@ -207,61 +249,18 @@ func (l *LSTM) ZeroGrad() {
 	}
 }
 
-func (l *LSTM) StateDict(prefix string, state map[string]*tensor.Tensor) {
-	if state == nil {
-		return
-	}
-	gateNames := []string{"input", "forget", "cell", "output"}
-	for gate, name := range gateNames {
-		state[joinPrefix(prefix, "weight_ih_"+name)] = l.weightIH[gate].Clone()
-		state[joinPrefix(prefix, "weight_hh_"+name)] = l.weightHH[gate].Clone()
-		if l.withBias {
-			state[joinPrefix(prefix, "bias_ih_"+name)] = l.biasIH[gate].Clone()
-			state[joinPrefix(prefix, "bias_hh_"+name)] = l.biasHH[gate].Clone()
-		}
+func (l *LSTM) NamedParameters() []NamedParameter {
+	var named []NamedParameter
+	for idx, cell := range l.cells {
+		named = append(named, cell.namedParameters(l.cellSuffix(idx))...)
 	}
+	return named
+}
+
+func (l *LSTM) StateDict(prefix string, state map[string]*tensor.Tensor) {
+	namedStateDict(prefix, l.NamedParameters(), state)
 }
 
 func (l *LSTM) LoadState(prefix string, state map[string]*tensor.Tensor) error {
-	if state == nil {
-		return fmt.Errorf("state dict is nil")
-	}
-	gateNames := []string{"input", "forget", "cell", "output"}
-	for gate, name := range gateNames {
-		wIHKey := joinPrefix(prefix, "weight_ih_"+name)
-		wIH, ok := state[wIHKey]
-		if !ok {
-			return fmt.Errorf("LSTM missing %s", wIHKey)
-		}
-		if err := tensor.CopyInto(l.weightIH[gate], wIH); err != nil {
-			return fmt.Errorf("load %s: %w", wIHKey, err)
-		}
-		wHHKey := joinPrefix(prefix, "weight_hh_"+name)
-		wHH, ok := state[wHHKey]
-		if !ok {
-			return fmt.Errorf("LSTM missing %s", wHHKey)
-		}
-		if err := tensor.CopyInto(l.weightHH[gate], wHH); err != nil {
-			return fmt.Errorf("load %s: %w", wHHKey, err)
-		}
-		if l.withBias {
-			bIHKey := joinPrefix(prefix, "bias_ih_"+name)
-			bIH, ok := state[bIHKey]
-			if !ok {
-				return fmt.Errorf("LSTM missing %s", bIHKey)
-			}
-			if err := tensor.CopyInto(l.biasIH[gate], bIH); err != nil {
-				return fmt.Errorf("load %s: %w", bIHKey, err)
-			}
-			bHHKey := joinPrefix(prefix, "bias_hh_"+name)
-			bHH, ok := state[bHHKey]
-			if !ok {
-				return fmt.Errorf("LSTM missing %s", bHHKey)
-			}
-			if err := tensor.CopyInto(l.biasHH[gate], bHH); err != nil {
-				return fmt.Errorf("load %s: %w", bHHKey, err)
-			}
-		}
-	}
-	return nil
+	return loadNamedState("LSTM", prefix, l.NamedParameters(), state)
 }
@@ -19,6 +19,52 @@ type StatefulModule interface {
 	LoadState(prefix string, state map[string]*tensor.Tensor) error
 }
 
+// Trainable is implemented by modules that behave differently during training
+// and evaluation, such as Dropout and BatchNorm. Containers implementing it
+// forward the mode to their children.
+type Trainable interface {
+	Train()
+	Eval()
+	IsTraining() bool
+}
+
+// SetTraining switches mod and every module below it into training
+// (training == true) or evaluation mode.
+func SetTraining(mod Module, training bool) {
+	if mod == nil {
+		return
+	}
+	if t, ok := mod.(Trainable); ok {
+		if training {
+			t.Train()
+		} else {
+			t.Eval()
+		}
+		return
+	}
+	for _, child := range Children(mod) {
+		SetTraining(child, training)
+	}
+}
+
+// IsTraining reports whether mod is in training mode. Modules that do not
+// implement Trainable report true if any descendant is training, and false
+// when nothing below them depends on the mode.
+func IsTraining(mod Module) bool {
+	if mod == nil {
+		return false
+	}
+	if t, ok := mod.(Trainable); ok {
+		return t.IsTraining()
+	}
+	for _, child := range Children(mod) {
+		if IsTraining(child) {
+			return true
+		}
+	}
+	return false
+}
+
 func ZeroGradAll(mods ...Module) {
 	for _, m := range mods {
 		if m == nil {
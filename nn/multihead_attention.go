@@ -0,0 +1,367 @@
+package nn
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// MultiheadAttention projects query, key and value into numHeads heads,
+// applies scaled dot-product attention in each head and projects the
+// concatenated result back to embedDim. Inputs are batch-first
+// [batch, seq, embedDim]; query and key/value lengths may differ and may
+// change from call to call.
+type MultiheadAttention struct {
+	embedDim int
+	numHeads int
+	headDim  int
+	dropout  float64
+	training bool
+	qProj    *Linear
+	kProj    *Linear
+	vProj    *Linear
+	outProj  *Linear
+	rotary   *RotaryEmbedding
+	alibi    *ALiBi
+}
+
+// AttentionOptions carries the optional masks of a MultiheadAttention call.
+type AttentionOptions struct {
+	// KeyPaddingMask marks, per batch element, the key positions to ignore
+	// (true = padding). Its shape is [batch][keyLen].
+	KeyPaddingMask [][]bool
+	// AttnMask is an additive mask of shape [queryLen, keyLen] or
+	// [batch, heads, queryLen, keyLen]; use -Inf to block a position.
+	AttnMask *tensor.Tensor
+	// Causal blocks every key position after the query position.
+	Causal bool
+}
+
+// NewMultiheadAttention builds the four projections; embedDim must be
+// divisible by numHeads. dropout is applied to the attention weights during
+// training and opts override the initialisation of all four projections.
+func NewMultiheadAttention(embedDim, numHeads int, dropout float64, withBias bool, opts ...InitOption) (*MultiheadAttention, error) {
+	if embedDim <= 0 || numHeads <= 0 {
+		return nil, errors.New("embedDim and numHeads must be positive")
+	}
+	if embedDim%numHeads != 0 {
+		return nil, fmt.Errorf("embedDim %d is not divisible by numHeads %d", embedDim, numHeads)
+	}
+	if dropout < 0 {
+		dropout = 0
+	}
+	if dropout >= 1 {
+		dropout = 0.999 // clamp to avoid invalid probability
+	}
+	return &MultiheadAttention{
+		embedDim: embedDim,
+		numHeads: numHeads,
+		headDim:  embedDim / numHeads,
+		dropout:  dropout,
+		training: true,
+		qProj:    NewLinear(embedDim, embedDim, withBias, opts...),
+		kProj:    NewLinear(embedDim, embedDim, withBias, opts...),
+		vProj:    NewLinear(embedDim, embedDim, withBias, opts...),
+		outProj:  NewLinear(embedDim, embedDim, withBias, opts...),
+	}, nil
+}
+
+func (m *MultiheadAttention) EmbedDim() int {
+	return m.embedDim
+}
+
+func (m *MultiheadAttention) NumHeads() int {
+	return m.numHeads
+}
+
+// SetRotary makes the layer rotate projected queries and keys by their
+// position before computing scores; nil disables it.
+func (m *MultiheadAttention) SetRotary(r *RotaryEmbedding) error {
+	if r != nil && r.headDim != m.headDim {
+		return fmt.Errorf("rotary head dimension %d does not match %d", r.headDim, m.headDim)
+	}
+	m.rotary = r
+	return nil
+}
+
+// SetALiBi adds per-head linear distance biases to the attention scores; nil
+// disables it.
+func (m *MultiheadAttention) SetALiBi(a *ALiBi) error {
+	if a != nil && len(a.slopes) != m.numHeads {
+		return fmt.Errorf("ALiBi has %d slopes for %d heads", len(a.slopes), m.numHeads)
+	}
+	m.alibi = a
+	return nil
+}
+
+// Forward applies self-attention to input [batch, seq, embedDim].
+func (m *MultiheadAttention) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	out, _, err := m.Attend(input, input, input, AttentionOptions{})
+	return out, err
+}
+
+// ForwardMulti takes (query[, key[, value]]) and returns [output, weights].
+// A missing key defaults to the query and a missing value to the key.
+func (m *MultiheadAttention) ForwardMulti(inputs ...*tensor.Tensor) ([]*tensor.Tensor, error) {
+	if len(inputs) == 0 || len(inputs) > 3 {
+		return nil, fmt.Errorf("MultiheadAttention expects 1 to 3 inputs, got %d", len(inputs))
+	}
+	query, key := inputs[0], inputs[0]
+	if len(inputs) > 1 {
+		key = inputs[1]
+	}
+	value := key
+	if len(inputs) > 2 {
+		value = inputs[2]
+	}
+	out, weights, err := m.Attend(query, key, value, AttentionOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return []*tensor.Tensor{out, weights}, nil
+}
+
+// Attend runs attention with separate query [batch, qLen, embedDim], key and
+// value [batch, kLen, embedDim]. It returns the output [batch, qLen, embedDim]
+// and the per-head attention weights [batch, heads, qLen, kLen].
+func (m *MultiheadAttention) Attend(query, key, value *tensor.Tensor, opts AttentionOptions) (*tensor.Tensor, *tensor.Tensor, error) {
+	if key == nil || value == nil {
+		return nil, nil, errors.New("MultiheadAttention requires query, key and value")
+	}
+	return m.attend(query, key, value, nil, opts)
+}
+
+// AttendCached is Attend for incremental decoding: key and value hold only
+// the new positions, which are projected, appended to cache and attended
+// together with everything cached before. Masks and the causal flag refer to
+// the full key length, with queries aligned to its last positions. Passing
+// nil key and value reuses the cache as is, which suits cross-attention over
+// a fixed memory after the first step.
+func (m *MultiheadAttention) AttendCached(query, key, value *tensor.Tensor, cache *AttentionCache, opts AttentionOptions) (*tensor.Tensor, *tensor.Tensor, error) {
+	if cache == nil {
+		return nil, nil, errors.New("AttendCached requires a cache")
+	}
+	if (key == nil) != (value == nil) {
+		return nil, nil, errors.New("AttendCached requires both key and value, or neither")
+	}
+	if key == nil && cache.Len() == 0 {
+		return nil, nil, errors.New("AttendCached without key and value needs a filled cache")
+	}
+	return m.attend(query, key, value, cache, opts)
+}
+
+func (m *MultiheadAttention) attend(query, key, value *tensor.Tensor, cache *AttentionCache, opts AttentionOptions) (*tensor.Tensor, *tensor.Tensor, error) {
+	if query == nil {
+		return nil, nil, errors.New("MultiheadAttention requires query, key and value")
+	}
+	qs := query.Shape()
+	if len(qs) != 3 || qs[2] != m.embedDim {
+		return nil, nil, fmt.Errorf("MultiheadAttention expects [batch, seq, %d] query, got %v", m.embedDim, qs)
+	}
+	batch, qLen := qs[0], qs[1]
+	q, err := m.splitHeads(m.qProj, query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var k, v *tensor.Tensor
+	past := cache.Len()
+	if key != nil {
+		ks, vs := key.Shape(), value.Shape()
+		if len(ks) != 3 || len(vs) != 3 || ks[0] != batch || vs[0] != batch || vs[1] != ks[1] {
+			return nil, nil, fmt.Errorf("MultiheadAttention shape mismatch: query %v key %v value %v", qs, ks, vs)
+		}
+		if ks[2] != m.embedDim || vs[2] != m.embedDim {
+			return nil, nil, fmt.Errorf("MultiheadAttention expects embedDim %d", m.embedDim)
+		}
+		if k, err = m.splitHeads(m.kProj, key); err != nil {
+			return nil, nil, err
+		}
+		if v, err = m.splitHeads(m.vProj, value); err != nil {
+			return nil, nil, err
+		}
+		if m.rotary != nil {
+			if k, err = m.rotary.Rotate(k, past); err != nil {
+				return nil, nil, err
+			}
+		}
+		if cache != nil {
+			if k, v, err = cache.append(k, v); err != nil {
+				return nil, nil, err
+			}
+		}
+	} else {
+		k, v = cache.keys, cache.values
+		if k.Shape()[0] != batch {
+			return nil, nil, fmt.Errorf("cache batch %d does not match query batch %d", k.Shape()[0], batch)
+		}
+	}
+	kLen := k.Shape()[2]
+	if m.rotary != nil {
+		// queries are aligned with the last qLen key positions
+		if q, err = m.rotary.Rotate(q, kLen-qLen); err != nil {
+			return nil, nil, err
+		}
+	}
+	mask, err := m.buildMask(batch, qLen, kLen, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	attn, weights, err := tensor.ScaledDotProductAttention(q, k, v, mask, m.dropout, m.training)
+	if err != nil {
+		return nil, nil, err
+	}
+	merged, err := tensor.Permute(attn, 0, 2, 1, 3)
+	if err != nil {
+		return nil, nil, err
+	}
+	merged, err = merged.Reshape(batch*qLen, m.embedDim)
+	if err != nil {
+		return nil, nil, err
+	}
+	out, err := m.outProj.Forward(merged)
+	if err != nil {
+		return nil, nil, err
+	}
+	out, err = out.Reshape(batch, qLen, m.embedDim)
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, weights, nil
+}
+
+// splitHeads projects x [batch, seq, embedDim] and returns
+// [batch, heads, seq, headDim].
+func (m *MultiheadAttention) splitHeads(proj *Linear, x *tensor.Tensor) (*tensor.Tensor, error) {
+	shape := x.Shape()
+	flat, err := x.Reshape(shape[0]*shape[1], m.embedDim)
+	if err != nil {
+		return nil, err
+	}
+	projected, err := proj.Forward(flat)
+	if err != nil {
+		return nil, err
+	}
+	projected, err = projected.Reshape(shape[0], shape[1], m.numHeads, m.headDim)
+	if err != nil {
+		return nil, err
+	}
+	return tensor.Permute(projected, 0, 2, 1, 3)
+}
+
+// buildMask folds the explicit, padding, causal and ALiBi terms into one
+// additive mask, or returns nil when none is requested. Queries are aligned
+// with the last qLen key positions.
+func (m *MultiheadAttention) buildMask(batch, qLen, kLen int, opts AttentionOptions) (*tensor.Tensor, error) {
+	if opts.KeyPaddingMask == nil && !opts.Causal && m.alibi == nil {
+		return opts.AttnMask, nil
+	}
+	heads, maskHeads := 1, 0
+	if opts.AttnMask != nil {
+		ms := opts.AttnMask.Shape()
+		switch {
+		case len(ms) == 2 && ms[0] == qLen && ms[1] == kLen:
+		case len(ms) == 4 && ms[0] == batch && (ms[1] == 1 || ms[1] == m.numHeads) && ms[2] == qLen && ms[3] == kLen:
+			maskHeads = ms[1]
+		default:
+			return nil, fmt.Errorf("attention mask shape %v does not match [%d, %d]", ms, qLen, kLen)
+		}
+	}
+	if m.alibi != nil || maskHeads > 1 {
+		heads = m.numHeads
+	}
+	if opts.KeyPaddingMask != nil && len(opts.KeyPaddingMask) != batch {
+		return nil, fmt.Errorf("key padding mask has %d rows, want %d", len(opts.KeyPaddingMask), batch)
+	}
+	var src []float64
+	if opts.AttnMask != nil {
+		src = opts.AttnMask.Data()
+	}
+	plane := qLen * kLen
+	base := make([]float64, batch*heads*plane)
+	negInf := math.Inf(-1)
+	for b := 0; b < batch; b++ {
+		var pad []bool
+		if opts.KeyPaddingMask != nil {
+			pad = opts.KeyPaddingMask[b]
+			if len(pad) != kLen {
+				return nil, fmt.Errorf("key padding mask row %d has length %d, want %d", b, len(pad), kLen)
+			}
+		}
+		for h := 0; h < heads; h++ {
+			dst := base[(b*heads+h)*plane : (b*heads+h+1)*plane]
+			switch {
+			case src == nil:
+			case maskHeads == 0:
+				copy(dst, src)
+			default:
+				copy(dst, src[(b*maskHeads+h%maskHeads)*plane:])
+			}
+			for i := 0; i < qLen; i++ {
+				qPos := i + kLen - qLen
+				row := dst[i*kLen : (i+1)*kLen]
+				for j := range row {
+					if (pad != nil && pad[j]) || (opts.Causal && j > qPos) {
+						row[j] = negInf
+					} else if m.alibi != nil {
+						row[j] += m.alibi.Bias(h, qPos, j)
+					}
+				}
+			}
+		}
+	}
+	return tensor.New(base, batch, heads, qLen, kLen)
+}
+
+func (m *MultiheadAttention) Parameters() []*tensor.Tensor {
+	var params []*tensor.Tensor
+	for _, child := range m.Children() {
+		params = append(params, child.Parameters()...)
+	}
+	return params
+}
+
+func (m *MultiheadAttention) ZeroGrad() {
+	for _, child := range m.Children() {
+		child.ZeroGrad()
+	}
+}
+
+func (m *MultiheadAttention) Children() []Module {
+	return []Module{m.qProj, m.kProj, m.vProj, m.outProj}
+}
+
+func (m *MultiheadAttention) NamedChildren() []NamedModule {
+	return []NamedModule{
+		{Name: "q_proj", Module: m.qProj},
+		{Name: "k_proj", Module: m.kProj},
+		{Name: "v_proj", Module: m.vProj},
+		{Name: "out_proj", Module: m.outProj},
+	}
+}
+
+func (m *MultiheadAttention) NamedParameters() []NamedParameter {
+	return childrenNamedParameters(m.NamedChildren())
+}
+
+func (m *MultiheadAttention) Train() {
+	m.training = true
+}
+
+func (m *MultiheadAttention) Eval() {
+	m.training = false
+}
+
+func (m *MultiheadAttention) IsTraining() bool {
+	return m.training
+}
+
+func (m *MultiheadAttention) StateDict(prefix string, state map[string]*tensor.Tensor) {
+	childrenStateDict(prefix, m.NamedChildren(), state)
+}
+
+func (m *MultiheadAttention) LoadState(prefix string, state map[string]*tensor.Tensor) error {
+	return childrenLoadState(prefix, m.NamedChildren(), state)
+}
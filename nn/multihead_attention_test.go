@@ -0,0 +1,118 @@
+package nn
+
+import (
+	"math"
+	"testing"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+func TestMultiheadAttentionShapesAndMasks(t *testing.T) {
+	mha, err := NewMultiheadAttention(8, 2, 0, true)
+	if err != nil {
+		t.Fatalf("NewMultiheadAttention failed: %v", err)
+	}
+	if _, err := NewMultiheadAttention(6, 4, 0, true); err == nil {
+		t.Fatalf("expected divisibility error")
+	}
+
+	query := tensor.Randn(2, 3, 8)
+	memory := tensor.Randn(2, 5, 8)
+	out, weights, err := mha.Attend(query, memory, memory, AttentionOptions{
+		KeyPaddingMask: [][]bool{
+			{false, false, false, false, false},
+			{false, false, true, true, true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Attend failed: %v", err)
+	}
+	if shape := out.Shape(); shape[0] != 2 || shape[1] != 3 || shape[2] != 8 {
+		t.Fatalf("unexpected output shape %v", shape)
+	}
+	if shape := weights.Shape(); shape[0] != 2 || shape[1] != 2 || shape[2] != 3 || shape[3] != 5 {
+		t.Fatalf("unexpected weights shape %v", shape)
+	}
+	w := weights.Data()
+	for h := 0; h < 2; h++ {
+		for i := 0; i < 3; i++ {
+			row := w[((1*2+h)*3+i)*5:]
+			if row[2] != 0 || row[3] != 0 || row[4] != 0 {
+				t.Fatalf("padded keys received attention: %v", row[:5])
+			}
+		}
+	}
+
+	// with a causal mask, changing a later token must not affect earlier outputs
+	x := tensor.Randn(1, 4, 8)
+	causal := AttentionOptions{Causal: true}
+	before, _, err := mha.Attend(x, x, x, causal)
+	if err != nil {
+		t.Fatalf("causal Attend failed: %v", err)
+	}
+	data := x.Data()
+	for i := 3 * 8; i < 4*8; i++ {
+		data[i] += 1
+	}
+	shifted := tensor.MustNew(data, 1, 4, 8)
+	after, _, _ := mha.Attend(shifted, shifted, shifted, causal)
+	if !floatsAlmostEqual(before.Data()[:3*8], after.Data()[:3*8], 1e-12) {
+		t.Fatalf("causal attention leaked future tokens")
+	}
+	if floatsAlmostEqual(before.Data()[3*8:], after.Data()[3*8:], 1e-12) {
+		t.Fatalf("expected the last position to change")
+	}
+
+	// an additive mask matching the causal pattern gives the same result
+	mask := make([]float64, 16)
+	for i := 0; i < 4; i++ {
+		for j := i + 1; j < 4; j++ {
+			mask[i*4+j] = math.Inf(-1)
+		}
+	}
+	explicit, _, _ := mha.Attend(x, x, x, AttentionOptions{AttnMask: tensor.MustNew(mask, 4, 4)})
+	if !floatsAlmostEqual(before.Data(), explicit.Data(), 1e-12) {
+		t.Fatalf("explicit mask disagrees with causal flag")
+	}
+}
+
+func TestMultiheadAttentionBackwardAndState(t *testing.T) {
+	mha, _ := NewMultiheadAttention(4, 2, 0.1, false)
+	x := tensor.Randn(3, 2, 4)
+	x.SetRequiresGrad(true)
+	outs, err := mha.ForwardMulti(x)
+	if err != nil {
+		t.Fatalf("ForwardMulti failed: %v", err)
+	}
+	if len(outs) != 2 {
+		t.Fatalf("expected output and weights, got %d tensors", len(outs))
+	}
+	if err := tensor.Sum(outs[0]).Backward(); err != nil {
+		t.Fatalf("backward failed: %v", err)
+	}
+	if x.Grad() == nil {
+		t.Fatalf("expected input gradient")
+	}
+	for _, p := range NamedParameters(mha) {
+		if p.Param.Grad() == nil {
+			t.Fatalf("missing gradient for %s", p.Name)
+		}
+	}
+
+	state := map[string]*tensor.Tensor{}
+	mha.StateDict("attn", state)
+	if got := stateKeys(state); got != "attn.k_proj.weight,attn.out_proj.weight,attn.q_proj.weight,attn.v_proj.weight" {
+		t.Fatalf("unexpected state keys %s", got)
+	}
+	clone, _ := NewMultiheadAttention(4, 2, 0.1, false)
+	if err := clone.LoadState("attn", state); err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	SetTraining(mha, false)
+	SetTraining(clone, false)
+	a, _ := mha.Forward(x)
+	b, _ := clone.Forward(x)
+	if !floatsAlmostEqual(a.Data(), b.Data(), 1e-12) {
+		t.Fatalf("loaded module disagrees with original")
+	}
+}
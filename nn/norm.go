@@ -0,0 +1,316 @@
+package nn
+
+import (
+	"fmt"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// GroupNorm normalizes [batch, channels, ...] inputs over groups of channels
+// per sample, so its output does not depend on the batch size.
+type GroupNorm struct {
+	numGroups   int
+	numChannels int
+	eps         float64
+	affine      bool
+	weight      *tensor.Tensor
+	bias        *tensor.Tensor
+}
+
+func NewGroupNorm(numGroups, numChannels int, eps float64, affine bool) (*GroupNorm, error) {
+	if numGroups <= 0 || numChannels <= 0 || numChannels%numGroups != 0 {
+		return nil, fmt.Errorf("GroupNorm channels %d must be divisible by groups %d", numChannels, numGroups)
+	}
+	if eps <= 0 {
+		eps = 1e-5
+	}
+	var weight *tensor.Tensor
+	var bias *tensor.Tensor
+	if affine {
+		weight = tensor.Ones(numChannels)
+		bias = tensor.Zeros(numChannels)
+		weight.SetRequiresGrad(true)
+		bias.SetRequiresGrad(true)
+	}
+	return &GroupNorm{
+		numGroups:   numGroups,
+		numChannels: numChannels,
+		eps:         eps,
+		affine:      affine,
+		weight:      weight,
+		bias:        bias,
+	}, nil
+}
+
+func (gn *GroupNorm) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	if input != nil && len(input.Shape()) >= 2 && input.Shape()[1] != gn.numChannels {
+		return nil, fmt.Errorf("GroupNorm expects %d channels, got %d", gn.numChannels, input.Shape()[1])
+	}
+	return tensor.GroupNorm(input, gn.numGroups, gn.weight, gn.bias, gn.eps)
+}
+
+func (gn *GroupNorm) Parameters() []*tensor.Tensor {
+	if !gn.affine {
+		return nil
+	}
+	return []*tensor.Tensor{gn.weight, gn.bias}
+}
+
+func (gn *GroupNorm) ZeroGrad() {
+	if !gn.affine {
+		return
+	}
+	gn.weight.ZeroGrad()
+	gn.bias.ZeroGrad()
+}
+
+func (gn *GroupNorm) NamedParameters() []NamedParameter {
+	return namedOrNil(
+		NamedParameter{Name: "weight", Param: gn.weight},
+		NamedParameter{Name: "bias", Param: gn.bias},
+	)
+}
+
+func (gn *GroupNorm) StateDict(prefix string, state map[string]*tensor.Tensor) {
+	namedStateDict(prefix, gn.NamedParameters(), state)
+}
+
+func (gn *GroupNorm) LoadState(prefix string, state map[string]*tensor.Tensor) error {
+	return loadNamedState("GroupNorm", prefix, gn.NamedParameters(), state)
+}
+
+// InstanceNorm normalizes every channel of every sample over its spatial
+// positions. With trackRunningStats it also keeps running averages of the
+// instance statistics and uses them in evaluation mode, like BatchNorm.
+type InstanceNorm struct {
+	numFeatures       int
+	spatialDims       int
+	momentum          float64
+	eps               float64
+	affine            bool
+	trackRunningStats bool
+	training          bool
+	weight            *tensor.Tensor
+	bias              *tensor.Tensor
+	runningMean       *tensor.Tensor
+	runningVar        *tensor.Tensor
+}
+
+// NewInstanceNorm1d normalizes [batch, channels, length] inputs.
+func NewInstanceNorm1d(numFeatures int, momentum, eps float64, affine, trackRunningStats bool) *InstanceNorm {
+	return newInstanceNorm(numFeatures, 1, momentum, eps, affine, trackRunningStats)
+}
+
+// NewInstanceNorm2d normalizes [batch, channels, height, width] inputs.
+func NewInstanceNorm2d(numFeatures int, momentum, eps float64, affine, trackRunningStats bool) *InstanceNorm {
+	return newInstanceNorm(numFeatures, 2, momentum, eps, affine, trackRunningStats)
+}
+
+// NewInstanceNorm3d normalizes [batch, channels, depth, height, width] inputs.
+func NewInstanceNorm3d(numFeatures int, momentum, eps float64, affine, trackRunningStats bool) *InstanceNorm {
+	return newInstanceNorm(numFeatures, 3, momentum, eps, affine, trackRunningStats)
+}
+
+func newInstanceNorm(numFeatures, spatialDims int, momentum, eps float64, affine, trackRunningStats bool) *InstanceNorm {
+	if momentum <= 0 || momentum >= 1 {
+		momentum = 0.1
+	}
+	if eps <= 0 {
+		eps = 1e-5
+	}
+	in := &InstanceNorm{
+		numFeatures:       numFeatures,
+		spatialDims:       spatialDims,
+		momentum:          momentum,
+		eps:               eps,
+		affine:            affine,
+		trackRunningStats: trackRunningStats,
+		training:          true,
+	}
+	if affine {
+		in.weight = tensor.Ones(numFeatures)
+		in.bias = tensor.Zeros(numFeatures)
+		in.weight.SetRequiresGrad(true)
+		in.bias.SetRequiresGrad(true)
+	}
+	if trackRunningStats {
+		in.runningMean = tensor.Zeros(numFeatures)
+		in.runningVar = tensor.Ones(numFeatures)
+	}
+	return in
+}
+
+func (in *InstanceNorm) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	if input == nil {
+		return nil, fmt.Errorf("InstanceNorm requires input tensor")
+	}
+	shape := input.Shape()
+	if len(shape) != in.spatialDims+2 {
+		return nil, fmt.Errorf("InstanceNorm%dd expects rank %d input, got %d", in.spatialDims, in.spatialDims+2, len(shape))
+	}
+	if shape[1] != in.numFeatures {
+		return nil, fmt.Errorf("InstanceNorm expects %d channels, got %d", in.numFeatures, shape[1])
+	}
+	if in.trackRunningStats && !in.training {
+		return tensor.BatchNorm(input, in.runningMean, in.runningVar, in.weight, in.bias, in.momentum, in.eps, false)
+	}
+	if in.trackRunningStats {
+		in.updateRunningStats(input)
+	}
+	return tensor.GroupNorm(input, in.numFeatures, in.weight, in.bias, in.eps)
+}
+
+// updateRunningStats folds the per-instance means and variances, averaged
+// over the batch, into the running estimates.
+func (in *InstanceNorm) updateRunningStats(input *tensor.Tensor) {
+	shape := input.Shape()
+	batch, channels := shape[0], shape[1]
+	inner := input.Numel() / (batch * channels)
+	if inner == 0 {
+		return
+	}
+	data := input.Data()
+	mean := in.runningMean.Data()
+	variance := in.runningVar.Data()
+	for c := 0; c < channels; c++ {
+		batchMean, batchVar := 0.0, 0.0
+		for n := 0; n < batch; n++ {
+			vals := data[(n*channels+c)*inner : (n*channels+c+1)*inner]
+			m := 0.0
+			for _, v := range vals {
+				m += v
+			}
+			m /= float64(inner)
+			s := 0.0
+			for _, v := range vals {
+				s += (v - m) * (v - m)
+			}
+			batchMean += m / float64(batch)
+			batchVar += s / float64(inner) / float64(batch)
+		}
+		mean[c] = (1-in.momentum)*mean[c] + in.momentum*batchMean
+		variance[c] = (1-in.momentum)*variance[c] + in.momentum*batchVar
+	}
+	_ = in.runningMean.SetData(mean)
+	_ = in.runningVar.SetData(variance)
+}
+
+func (in *InstanceNorm) Parameters() []*tensor.Tensor {
+	if !in.affine {
+		return nil
+	}
+	return []*tensor.Tensor{in.weight, in.bias}
+}
+
+func (in *InstanceNorm) ZeroGrad() {
+	if !in.affine {
+		return
+	}
+	in.weight.ZeroGrad()
+	in.bias.ZeroGrad()
+}
+
+func (in *InstanceNorm) Train() {
+	in.training = true
+}
+
+func (in *InstanceNorm) Eval() {
+	in.training = false
+}
+
+func (in *InstanceNorm) IsTraining() bool {
+	return in.training
+}
+
+func (in *InstanceNorm) NamedParameters() []NamedParameter {
+	return namedOrNil(
+		NamedParameter{Name: "weight", Param: in.weight},
+		NamedParameter{Name: "bias", Param: in.bias},
+	)
+}
+
+func (in *InstanceNorm) stateTensors() []NamedParameter {
+	return namedOrNil(
+		NamedParameter{Name: "weight", Param: in.weight},
+		NamedParameter{Name: "bias", Param: in.bias},
+		NamedParameter{Name: "running_mean", Param: in.runningMean},
+		NamedParameter{Name: "running_var", Param: in.runningVar},
+	)
+}
+
+func (in *InstanceNorm) StateDict(prefix string, state map[string]*tensor.Tensor) {
+	namedStateDict(prefix, in.stateTensors(), state)
+}
+
+func (in *InstanceNorm) LoadState(prefix string, state map[string]*tensor.Tensor) error {
+	return loadNamedState("InstanceNorm", prefix, in.stateTensors(), state)
+}
+
+// RunningMean returns nil unless the module tracks running statistics.
+func (in *InstanceNorm) RunningMean() *tensor.Tensor {
+	return in.runningMean
+}
+
+func (in *InstanceNorm) RunningVar() *tensor.Tensor {
+	return in.runningVar
+}
+
+// RMSNorm rescales the trailing normalizedShape dimensions by their root
+// mean square with an optional learned gain and no bias.
+type RMSNorm struct {
+	normalizedShape []int
+	eps             float64
+	affine          bool
+	weight          *tensor.Tensor
+}
+
+func NewRMSNorm(normalizedShape []int, eps float64, affine bool) *RMSNorm {
+	shapeCopy := append([]int(nil), normalizedShape...)
+	if eps <= 0 {
+		eps = 1e-6
+	}
+	var weight *tensor.Tensor
+	if affine {
+		weight = tensor.Ones(shapeCopy...)
+		weight.SetRequiresGrad(true)
+	}
+	return &RMSNorm{
+		normalizedShape: shapeCopy,
+		eps:             eps,
+		affine:          affine,
+		weight:          weight,
+	}
+}
+
+func (rn *RMSNorm) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	if input == nil {
+		return nil, fmt.Errorf("RMSNorm requires input tensor")
+	}
+	return tensor.RMSNorm(input, rn.normalizedShape, rn.weight, rn.eps)
+}
+
+func (rn *RMSNorm) Parameters() []*tensor.Tensor {
+	if !rn.affine {
+		return nil
+	}
+	return []*tensor.Tensor{rn.weight}
+}
+
+func (rn *RMSNorm) ZeroGrad() {
+	if !rn.affine {
+		return
+	}
+	rn.weight.ZeroGrad()
+}
+
+func (rn *RMSNorm) NamedParameters() []NamedParameter {
+	return namedOrNil(NamedParameter{Name: "weight", Param: rn.weight})
+}
+
+func (rn *RMSNorm) StateDict(prefix string, state map[string]*tensor.Tensor) {
+	namedStateDict(prefix, rn.NamedParameters(), state)
+}
+
+func (rn *RMSNorm) LoadState(prefix string, state map[string]*tensor.Tensor) error {
+	return loadNamedState("RMSNorm", prefix, rn.NamedParameters(), state)
+}
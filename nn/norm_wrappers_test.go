@@ -102,3 +102,163 @@ func TestBatchNormWrapperMatchesTensorAndUpdatesState(t *testing.T) {
 		t.Fatalf("batchnorm eval mismatch: got %v want %v", eval.Data(), refEval.Data())
 	}
 }
+
+func TestBatchNormRankConstructors(t *testing.T) {
+	seq := tensor.MustNew([]float64{
+		1, 2, 3,
+		-1, 0, 4,
+		2, 2, 5,
+		0, -3, 1,
+	}, 2, 2, 3)
+	bn1 := NewBatchNorm1d(2, 0.1, 1e-5, true)
+	if _, err := bn1.Forward(seq); err != nil {
+		t.Fatalf("BatchNorm1d rank-3 forward failed: %v", err)
+	}
+	if _, err := bn1.Forward(tensor.Zeros(2, 2, 1, 1)); err == nil {
+		t.Fatalf("expected BatchNorm1d to reject rank-4 input")
+	}
+	if _, err := NewBatchNorm2d(2, 0.1, 1e-5, true).Forward(seq); err == nil {
+		t.Fatalf("expected BatchNorm2d to reject rank-3 input")
+	}
+	bn3 := NewBatchNorm3d(2, 0.1, 1e-5, false)
+	out, err := bn3.Forward(tensor.MustNew(seq.Data(), 2, 2, 3, 1, 1))
+	if err != nil {
+		t.Fatalf("BatchNorm3d forward failed: %v", err)
+	}
+	ref, _ := tensor.BatchNorm(seq, tensor.Zeros(2), tensor.Ones(2), nil, nil, 0.1, 1e-5, true)
+	if !floatsAlmostEqual(out.Data(), ref.Data(), 1e-9) {
+		t.Fatalf("BatchNorm3d mismatch: got %v want %v", out.Data(), ref.Data())
+	}
+	if _, err := bn3.Forward(tensor.Zeros(2, 3, 1, 1, 1)); err == nil {
+		t.Fatalf("expected channel mismatch error")
+	}
+}
+
+func TestGroupNormWrapperMatchesTensor(t *testing.T) {
+	if _, err := NewGroupNorm(3, 4, 1e-5, true); err == nil {
+		t.Fatalf("expected divisibility error")
+	}
+	gn, err := NewGroupNorm(2, 4, 1e-5, true)
+	if err != nil {
+		t.Fatalf("NewGroupNorm failed: %v", err)
+	}
+	mustSetData(t, gn.weight, []float64{1, 0.5, -0.5, 2})
+	input := tensor.MustNew([]float64{
+		1, 2, 3, 4, 5, 6, 7, 8,
+		-1, 0, 2, 1, 3, -2, 0, 4,
+	}, 2, 4, 2)
+	out, err := gn.Forward(input)
+	if err != nil {
+		t.Fatalf("groupnorm forward failed: %v", err)
+	}
+	ref, _ := tensor.GroupNorm(input, 2, gn.weight.Detach(), gn.bias.Detach(), gn.eps)
+	if !floatsAlmostEqual(out.Data(), ref.Data(), 1e-9) {
+		t.Fatalf("groupnorm wrapper mismatch: got %v want %v", out.Data(), ref.Data())
+	}
+	if err := tensor.Sum(out).Backward(); err != nil {
+		t.Fatalf("groupnorm backward failed: %v", err)
+	}
+	if gn.weight.Grad() == nil || gn.bias.Grad() == nil {
+		t.Fatalf("expected gradients on groupnorm affine params")
+	}
+	state := map[string]*tensor.Tensor{}
+	gn.StateDict("gn", state)
+	if got := stateKeys(state); got != "gn.bias,gn.weight" {
+		t.Fatalf("unexpected groupnorm state keys %v", got)
+	}
+	fresh, _ := NewGroupNorm(2, 4, 1e-5, true)
+	if err := fresh.LoadState("gn", state); err != nil {
+		t.Fatalf("groupnorm load failed: %v", err)
+	}
+	if !floatsAlmostEqual(fresh.weight.Data(), gn.weight.Data(), 0) {
+		t.Fatalf("groupnorm weight not restored")
+	}
+}
+
+func TestInstanceNormRunningStats(t *testing.T) {
+	input := tensor.MustNew([]float64{
+		1, 3, 0, 4,
+		2, 2, 6, 6,
+	}, 2, 1, 2, 2)
+	plain := NewInstanceNorm2d(1, 0.1, 1e-5, false, false)
+	out, err := plain.Forward(input)
+	if err != nil {
+		t.Fatalf("instancenorm forward failed: %v", err)
+	}
+	ref, _ := tensor.GroupNorm(input, 1, nil, nil, 1e-5)
+	if !floatsAlmostEqual(out.Data(), ref.Data(), 1e-9) {
+		t.Fatalf("instancenorm mismatch: got %v want %v", out.Data(), ref.Data())
+	}
+	plain.Eval()
+	if _, err := plain.Forward(input); err != nil {
+		t.Fatalf("untracked eval forward failed: %v", err)
+	}
+	if _, err := plain.Forward(tensor.Zeros(1, 1, 4)); err == nil {
+		t.Fatalf("expected rank error for InstanceNorm2d")
+	}
+	state := map[string]*tensor.Tensor{}
+	plain.StateDict("", state)
+	if len(state) != 0 {
+		t.Fatalf("expected empty state for untracked, non-affine instancenorm, got %v", stateKeys(state))
+	}
+
+	tracked := NewInstanceNorm2d(1, 0.5, 1e-5, true, true)
+	if _, err := tracked.Forward(input); err != nil {
+		t.Fatalf("tracked forward failed: %v", err)
+	}
+	// instance means 2 and 4, biased variances 2.5 and 4
+	if !floatsAlmostEqual(tracked.RunningMean().Data(), []float64{1.5}, 1e-9) {
+		t.Fatalf("unexpected running mean %v", tracked.RunningMean().Data())
+	}
+	if !floatsAlmostEqual(tracked.RunningVar().Data(), []float64{2.125}, 1e-9) {
+		t.Fatalf("unexpected running var %v", tracked.RunningVar().Data())
+	}
+	tracked.Eval()
+	eval, err := tracked.Forward(input)
+	if err != nil {
+		t.Fatalf("tracked eval forward failed: %v", err)
+	}
+	refEval, _ := tensor.BatchNorm(input, tracked.runningMean.Clone(), tracked.runningVar.Clone(), tracked.weight.Detach(), tracked.bias.Detach(), 0.5, 1e-5, false)
+	if !floatsAlmostEqual(eval.Data(), refEval.Data(), 1e-9) {
+		t.Fatalf("tracked eval mismatch: got %v want %v", eval.Data(), refEval.Data())
+	}
+	state = map[string]*tensor.Tensor{}
+	tracked.StateDict("norm", state)
+	if got := stateKeys(state); got != "norm.bias,norm.running_mean,norm.running_var,norm.weight" {
+		t.Fatalf("unexpected instancenorm state keys %v", got)
+	}
+	fresh := NewInstanceNorm2d(1, 0.5, 1e-5, true, true)
+	if err := fresh.LoadState("norm", state); err != nil {
+		t.Fatalf("instancenorm load failed: %v", err)
+	}
+	if !floatsAlmostEqual(fresh.RunningVar().Data(), []float64{2.125}, 1e-9) {
+		t.Fatalf("running var not restored")
+	}
+	delete(state, "norm.running_var")
+	if err := fresh.LoadState("norm", state); err == nil {
+		t.Fatalf("expected missing running_var error")
+	}
+}
+
+func TestRMSNormWrapperMatchesTensor(t *testing.T) {
+	rn := NewRMSNorm([]int{2}, 0, true)
+	mustSetData(t, rn.weight, []float64{2, -1})
+	input := tensor.MustNew([]float64{3, 4, 1, -1}, 2, 2)
+	out, err := rn.Forward(input)
+	if err != nil {
+		t.Fatalf("rmsnorm forward failed: %v", err)
+	}
+	ref, _ := tensor.RMSNorm(input, []int{2}, rn.weight.Detach(), 1e-6)
+	if !floatsAlmostEqual(out.Data(), ref.Data(), 1e-12) {
+		t.Fatalf("rmsnorm wrapper mismatch: got %v want %v", out.Data(), ref.Data())
+	}
+	if err := tensor.Sum(out).Backward(); err != nil {
+		t.Fatalf("rmsnorm backward failed: %v", err)
+	}
+	if rn.weight.Grad() == nil {
+		t.Fatalf("expected rmsnorm weight gradient")
+	}
+	if params := NewRMSNorm([]int{2}, 0, false).Parameters(); len(params) != 0 {
+		t.Fatalf("expected no parameters without affine")
+	}
+}
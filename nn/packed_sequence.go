@@ -0,0 +1,144 @@
+package nn
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// PackedSequence stores a batch of variable-length sequences without
+// padding. Sequences are ordered by decreasing length and their steps are
+// interleaved time-major: data holds the first step of every sequence, then
+// the second step of every sequence that is long enough, and so on, so step
+// t contributes batchSizes[t] rows.
+type PackedSequence struct {
+	data          *tensor.Tensor
+	batchSizes    []int
+	sortedIndices []int
+	lengths       []int
+}
+
+// PackPaddedSequence packs a padded batch [seq, batch, features] (or
+// [batch, seq, features] with batchFirst) whose element b holds lengths[b]
+// valid steps. Lengths need not be sorted; gradients flow back to the valid
+// positions of input.
+func PackPaddedSequence(input *tensor.Tensor, lengths []int, batchFirst bool) (*PackedSequence, error) {
+	shape := input.Shape()
+	if len(shape) != 3 {
+		return nil, errors.New("PackPaddedSequence expects a rank-3 input")
+	}
+	seqLen, batch := shape[0], shape[1]
+	if batchFirst {
+		seqLen, batch = shape[1], shape[0]
+	}
+	if len(lengths) != batch {
+		return nil, fmt.Errorf("expected %d lengths, got %d", batch, len(lengths))
+	}
+	for b, l := range lengths {
+		if l < 1 || l > seqLen {
+			return nil, fmt.Errorf("length %d of sequence %d out of range [1, %d]", l, b, seqLen)
+		}
+	}
+	sorted := make([]int, batch)
+	for i := range sorted {
+		sorted[i] = i
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return lengths[sorted[i]] > lengths[sorted[j]] })
+
+	maxLen := lengths[sorted[0]]
+	batchSizes := make([]int, maxLen)
+	var rows []float64
+	for t := 0; t < maxLen; t++ {
+		for _, b := range sorted {
+			if lengths[b] <= t {
+				break
+			}
+			batchSizes[t]++
+			if batchFirst {
+				rows = append(rows, float64(b*seqLen+t))
+			} else {
+				rows = append(rows, float64(t*batch+b))
+			}
+		}
+	}
+	flat, err := input.Reshape(shape[0]*shape[1], shape[2])
+	if err != nil {
+		return nil, err
+	}
+	data, err := tensor.Embedding(flat, tensor.MustNew(rows, len(rows)))
+	if err != nil {
+		return nil, err
+	}
+	return &PackedSequence{
+		data:          data,
+		batchSizes:    batchSizes,
+		sortedIndices: sorted,
+		lengths:       append([]int(nil), lengths...),
+	}, nil
+}
+
+// PadPackedSequence is the inverse of PackPaddedSequence: it returns the
+// zero-padded batch, in the original batch order, and the lengths.
+func PadPackedSequence(seq *PackedSequence, batchFirst bool) (*tensor.Tensor, []int, error) {
+	if seq == nil {
+		return nil, nil, errors.New("PadPackedSequence requires a packed sequence")
+	}
+	features := seq.data.Shape()[1]
+	total := seq.data.Shape()[0]
+	withZero, err := tensor.Concat(0, seq.data, tensor.Zeros(1, features))
+	if err != nil {
+		return nil, nil, err
+	}
+	batch, seqLen := len(seq.sortedIndices), len(seq.batchSizes)
+	rank := make([]int, batch)
+	for j, b := range seq.sortedIndices {
+		rank[b] = j
+	}
+	rows := make([]float64, seqLen*batch)
+	offset := 0
+	for t, size := range seq.batchSizes {
+		for b := 0; b < batch; b++ {
+			row := total // the zero row
+			if rank[b] < size {
+				row = offset + rank[b]
+			}
+			if batchFirst {
+				rows[b*seqLen+t] = float64(row)
+			} else {
+				rows[t*batch+b] = float64(row)
+			}
+		}
+		offset += size
+	}
+	index := tensor.MustNew(rows, seqLen, batch)
+	if batchFirst {
+		index = tensor.MustNew(rows, batch, seqLen)
+	}
+	out, err := tensor.Embedding(withZero, index)
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, seq.Lengths(), nil
+}
+
+// Data returns the packed steps [sum(lengths), features].
+func (p *PackedSequence) Data() *tensor.Tensor {
+	return p.data
+}
+
+// BatchSizes returns the number of sequences still running at each step.
+func (p *PackedSequence) BatchSizes() []int {
+	return append([]int(nil), p.batchSizes...)
+}
+
+// SortedIndices returns the original batch index of each packed column.
+func (p *PackedSequence) SortedIndices() []int {
+	return append([]int(nil), p.sortedIndices...)
+}
+
+// Lengths returns the sequence lengths in the original batch order.
+func (p *PackedSequence) Lengths() []int {
+	return append([]int(nil), p.lengths...)
+}
@@ -0,0 +1,120 @@
+package nn
+
+import (
+	"testing"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+func TestPackPadRoundTrip(t *testing.T) {
+	// [seq=3, batch=3, features=1] with lengths 2, 3 and 1
+	data := []float64{
+		1, 10, 100,
+		2, 20, -1,
+		-1, 30, -1,
+	}
+	input := tensor.MustNew(data, 3, 3, 1)
+	input.SetRequiresGrad(true)
+	packed, err := PackPaddedSequence(input, []int{2, 3, 1}, false)
+	if err != nil {
+		t.Fatalf("pack failed: %v", err)
+	}
+	if got := packed.BatchSizes(); len(got) != 3 || got[0] != 3 || got[1] != 2 || got[2] != 1 {
+		t.Fatalf("unexpected batch sizes %v", got)
+	}
+	if got := packed.SortedIndices(); got[0] != 1 || got[1] != 0 || got[2] != 2 {
+		t.Fatalf("unexpected sorted indices %v", got)
+	}
+	if !floatsAlmostEqual(packed.Data().Data(), []float64{10, 1, 100, 20, 2, 30}, 0) {
+		t.Fatalf("unexpected packed data %v", packed.Data().Data())
+	}
+
+	padded, lengths, err := PadPackedSequence(packed, true)
+	if err != nil {
+		t.Fatalf("pad failed: %v", err)
+	}
+	want := []float64{1, 2, 0, 10, 20, 30, 100, 0, 0}
+	if !equalShape(padded.Shape(), []int{3, 3, 1}) || !floatsAlmostEqual(padded.Data(), want, 0) {
+		t.Fatalf("unexpected padded batch %v", padded.Data())
+	}
+	if lengths[0] != 2 || lengths[1] != 3 || lengths[2] != 1 {
+		t.Fatalf("unexpected lengths %v", lengths)
+	}
+	if err := tensor.Sum(padded).Backward(); err != nil {
+		t.Fatalf("backward failed: %v", err)
+	}
+	if !floatsAlmostEqual(input.Grad().Data(), []float64{1, 1, 1, 1, 1, 0, 0, 1, 0}, 0) {
+		t.Fatalf("gradient must reach only valid positions, got %v", input.Grad().Data())
+	}
+
+	if _, err := PackPaddedSequence(input, []int{2, 4, 1}, false); err == nil {
+		t.Fatalf("expected length out of range error")
+	}
+	if _, err := PackPaddedSequence(input, []int{2, 3}, false); err == nil {
+		t.Fatalf("expected length count error")
+	}
+}
+
+func TestLSTMForwardPackedMatchesUnpadded(t *testing.T) {
+	lstm := NewLSTMWithConfig(2, 3, true, RecurrentConfig{NumLayers: 2, Bidirectional: true, BatchFirst: true})
+	lengths := []int{2, 4, 3}
+	input := tensor.Randn(3, 4, 2)
+	packed, err := PackPaddedSequence(input, lengths, true)
+	if err != nil {
+		t.Fatalf("pack failed: %v", err)
+	}
+	out, h, c, err := lstm.ForwardPacked(packed, nil, nil)
+	if err != nil {
+		t.Fatalf("ForwardPacked failed: %v", err)
+	}
+	padded, _, err := PadPackedSequence(out, true)
+	if err != nil {
+		t.Fatalf("pad failed: %v", err)
+	}
+	if !equalShape(padded.Shape(), []int{3, 4, 6}) || !equalShape(h.Shape(), []int{4, 3, 3}) {
+		t.Fatalf("unexpected shapes %v and %v", padded.Shape(), h.Shape())
+	}
+
+	inData, outData, hData, cData := input.Data(), padded.Data(), h.Data(), c.Data()
+	for b, l := range lengths {
+		alone := tensor.MustNew(inData[b*8:b*8+l*2], 1, l, 2)
+		refOut, refH, refC, err := lstm.ForwardWithState(alone, nil, nil)
+		if err != nil {
+			t.Fatalf("reference forward failed: %v", err)
+		}
+		if !floatsAlmostEqual(outData[b*24:b*24+l*6], refOut.Data(), 1e-12) {
+			t.Fatalf("sequence %d output differs from its unpadded run", b)
+		}
+		for i := l * 6; i < 24; i++ {
+			if outData[b*24+i] != 0 {
+				t.Fatalf("sequence %d output is not zero past its end", b)
+			}
+		}
+		refHData, refCData := refH.Data(), refC.Data()
+		for cell := 0; cell < 4; cell++ {
+			got := hData[(cell*3+b)*3 : (cell*3+b)*3+3]
+			gotC := cData[(cell*3+b)*3 : (cell*3+b)*3+3]
+			if !floatsAlmostEqual(got, refHData[cell*3:cell*3+3], 1e-12) || !floatsAlmostEqual(gotC, refCData[cell*3:cell*3+3], 1e-12) {
+				t.Fatalf("sequence %d final state of cell %d differs", b, cell)
+			}
+		}
+	}
+}
+
+func TestGRUForwardPackedFinalState(t *testing.T) {
+	gru := NewGRU(2, 3, true)
+	input := tensor.Randn(3, 2, 2)
+	packed, _ := PackPaddedSequence(input, []int{1, 3}, false)
+	_, h, err := gru.ForwardPacked(packed, nil)
+	if err != nil {
+		t.Fatalf("ForwardPacked failed: %v", err)
+	}
+	if !equalShape(h.Shape(), []int{2, 3}) {
+		t.Fatalf("unexpected state shape %v", h.Shape())
+	}
+	first := tensor.MustNew(input.Data()[:2], 1, 1, 2)
+	_, want, _ := gru.ForwardWithState(first, nil)
+	if !floatsAlmostEqual(h.Data()[:3], want.Data(), 1e-12) {
+		t.Fatalf("short sequence state kept updating past its end")
+	}
+}
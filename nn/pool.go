@@ -3,12 +3,13 @@ package nn
 import "github.com/fumitoshi0524/ixeoriNet/tensor"
 
 type MaxPool2d struct {
-	kernelH int
-	kernelW int
-	strideH int
-	strideW int
-	padH    int
-	padW    int
+	kernelH  int
+	kernelW  int
+	strideH  int
+	strideW  int
+	padH     int
+	padW     int
+	ceilMode bool
 }
 
 func NewMaxPool2d(kernelH, kernelW, strideH, strideW, padH, padW int) *MaxPool2d {
@@ -29,9 +30,21 @@ func NewMaxPool2d(kernelH, kernelW, strideH, strideW, padH, padW int) *MaxPool2d
 }
 
 func (m *MaxPool2d) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	if m.ceilMode {
+		out, _, err := m.ForwardWithIndices(input)
+		return out, err
+	}
 	return tensor.MaxPool2D(input, m.kernelH, m.kernelW, m.strideH, m.strideW, m.padH, m.padW)
 }
 
+// ForwardWithIndices also returns the argmax positions for MaxUnpool2d.
+func (m *MaxPool2d) ForwardWithIndices(input *tensor.Tensor) (*tensor.Tensor, []int, error) {
+	return tensor.MaxPoolWithIndices(input, []int{m.kernelH, m.kernelW}, []int{m.strideH, m.strideW}, []int{m.padH, m.padW}, m.ceilMode)
+}
+
+// SetCeilMode keeps partial windows at the end of each axis when enabled.
+func (m *MaxPool2d) SetCeilMode(enabled bool) { m.ceilMode = enabled }
+
 func (m *MaxPool2d) Parameters() []*tensor.Tensor {
 	return nil
 }
@@ -39,12 +52,13 @@ func (m *MaxPool2d) Parameters() []*tensor.Tensor {
 func (m *MaxPool2d) ZeroGrad() {}
 
 type AvgPool2d struct {
-	kernelH int
-	kernelW int
-	strideH int
-	strideW int
-	padH    int
-	padW    int
+	kernelH  int
+	kernelW  int
+	strideH  int
+	strideW  int
+	padH     int
+	padW     int
+	ceilMode bool
 }
 
 func NewAvgPool2d(kernelH, kernelW, strideH, strideW, padH, padW int) *AvgPool2d {
@@ -65,9 +79,15 @@ func NewAvgPool2d(kernelH, kernelW, strideH, strideW, padH, padW int) *AvgPool2d
 }
 
 func (a *AvgPool2d) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	if a.ceilMode {
+		return tensor.AvgPool(input, []int{a.kernelH, a.kernelW}, []int{a.strideH, a.strideW}, []int{a.padH, a.padW}, true)
+	}
 	return tensor.AvgPool2D(input, a.kernelH, a.kernelW, a.strideH, a.strideW, a.padH, a.padW)
 }
 
+// SetCeilMode keeps partial windows at the end of each axis when enabled.
+func (a *AvgPool2d) SetCeilMode(enabled bool) { a.ceilMode = enabled }
+
 func (a *AvgPool2d) Parameters() []*tensor.Tensor {
 	return nil
 }
@@ -0,0 +1,152 @@
+package nn
+
+import (
+	"errors"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// poolWindow holds the kernel, stride and padding shared by the 1D and 3D
+// pooling modules.
+type poolWindow struct {
+	kernel   []int
+	stride   []int
+	padding  []int
+	ceilMode bool
+}
+
+func newPoolWindow(kernel, stride, padding []int) poolWindow {
+	for i := range stride {
+		if stride[i] <= 0 {
+			stride[i] = kernel[i]
+		}
+	}
+	return poolWindow{kernel: kernel, stride: stride, padding: padding}
+}
+
+// SetCeilMode keeps partial windows at the end of each axis when enabled.
+func (w *poolWindow) SetCeilMode(enabled bool) { w.ceilMode = enabled }
+
+func (w *poolWindow) Parameters() []*tensor.Tensor {
+	return nil
+}
+
+func (w *poolWindow) ZeroGrad() {}
+
+type MaxPool1d struct {
+	poolWindow
+}
+
+func NewMaxPool1d(kernel, stride, pad int) *MaxPool1d {
+	return &MaxPool1d{newPoolWindow([]int{kernel}, []int{stride}, []int{pad})}
+}
+
+func (m *MaxPool1d) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	return tensor.MaxPool(input, m.kernel, m.stride, m.padding, m.ceilMode)
+}
+
+// ForwardWithIndices also returns the argmax positions for MaxUnpool1d.
+func (m *MaxPool1d) ForwardWithIndices(input *tensor.Tensor) (*tensor.Tensor, []int, error) {
+	return tensor.MaxPoolWithIndices(input, m.kernel, m.stride, m.padding, m.ceilMode)
+}
+
+type MaxPool3d struct {
+	poolWindow
+}
+
+func NewMaxPool3d(kernelD, kernelH, kernelW, strideD, strideH, strideW, padD, padH, padW int) *MaxPool3d {
+	return &MaxPool3d{newPoolWindow([]int{kernelD, kernelH, kernelW}, []int{strideD, strideH, strideW}, []int{padD, padH, padW})}
+}
+
+func (m *MaxPool3d) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	return tensor.MaxPool(input, m.kernel, m.stride, m.padding, m.ceilMode)
+}
+
+// ForwardWithIndices also returns the argmax positions for MaxUnpool3d.
+func (m *MaxPool3d) ForwardWithIndices(input *tensor.Tensor) (*tensor.Tensor, []int, error) {
+	return tensor.MaxPoolWithIndices(input, m.kernel, m.stride, m.padding, m.ceilMode)
+}
+
+type AvgPool1d struct {
+	poolWindow
+}
+
+func NewAvgPool1d(kernel, stride, pad int) *AvgPool1d {
+	return &AvgPool1d{newPoolWindow([]int{kernel}, []int{stride}, []int{pad})}
+}
+
+func (a *AvgPool1d) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	return tensor.AvgPool(input, a.kernel, a.stride, a.padding, a.ceilMode)
+}
+
+type AvgPool3d struct {
+	poolWindow
+}
+
+func NewAvgPool3d(kernelD, kernelH, kernelW, strideD, strideH, strideW, padD, padH, padW int) *AvgPool3d {
+	return &AvgPool3d{newPoolWindow([]int{kernelD, kernelH, kernelW}, []int{strideD, strideH, strideW}, []int{padD, padH, padW})}
+}
+
+func (a *AvgPool3d) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	return tensor.AvgPool(input, a.kernel, a.stride, a.padding, a.ceilMode)
+}
+
+// LPPool computes (sum x^p)^(1/p) over each window. Build it with
+// NewLPPool1d or NewLPPool2d.
+type LPPool struct {
+	poolWindow
+	normType float64
+}
+
+func NewLPPool1d(normType float64, kernel, stride int) *LPPool {
+	return &LPPool{poolWindow: newPoolWindow([]int{kernel}, []int{stride}, nil), normType: normType}
+}
+
+func NewLPPool2d(normType float64, kernelH, kernelW, strideH, strideW int) *LPPool {
+	return &LPPool{poolWindow: newPoolWindow([]int{kernelH, kernelW}, []int{strideH, strideW}, nil), normType: normType}
+}
+
+func (l *LPPool) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	return tensor.LPPool(input, l.normType, l.kernel, l.stride, l.ceilMode)
+}
+
+// MaxUnpool inverts max pooling using the indices returned by a pooling
+// module's ForwardWithIndices. Build it with NewMaxUnpool1d, NewMaxUnpool2d
+// or NewMaxUnpool3d using the same window as the pooling layer.
+type MaxUnpool struct {
+	poolWindow
+}
+
+func NewMaxUnpool1d(kernel, stride, pad int) *MaxUnpool {
+	return &MaxUnpool{newPoolWindow([]int{kernel}, []int{stride}, []int{pad})}
+}
+
+func NewMaxUnpool2d(kernelH, kernelW, strideH, strideW, padH, padW int) *MaxUnpool {
+	return &MaxUnpool{newPoolWindow([]int{kernelH, kernelW}, []int{strideH, strideW}, []int{padH, padW})}
+}
+
+func NewMaxUnpool3d(kernelD, kernelH, kernelW, strideD, strideH, strideW, padD, padH, padW int) *MaxUnpool {
+	return &MaxUnpool{newPoolWindow([]int{kernelD, kernelH, kernelW}, []int{strideD, strideH, strideW}, []int{padD, padH, padW})}
+}
+
+// Forward always fails because unpooling needs the pooling indices; use
+// ForwardWithIndices instead.
+func (u *MaxUnpool) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	return nil, errors.New("MaxUnpool requires pooling indices; use ForwardWithIndices")
+}
+
+// ForwardWithIndices scatters input to the recorded indices. A nil
+// outputSize selects (in-1)*stride - 2*pad + kernel along each axis.
+func (u *MaxUnpool) ForwardWithIndices(input *tensor.Tensor, indices []int, outputSize []int) (*tensor.Tensor, error) {
+	if outputSize == nil {
+		shape := input.Shape()
+		if len(shape) != len(u.kernel)+2 {
+			return nil, errors.New("MaxUnpool input rank does not match kernel")
+		}
+		outputSize = make([]int, len(u.kernel))
+		for i := range u.kernel {
+			outputSize[i] = (shape[i+2]-1)*u.stride[i] - 2*u.padding[i] + u.kernel[i]
+		}
+	}
+	return tensor.MaxUnpool(input, indices, outputSize)
+}
@@ -0,0 +1,209 @@
+package nn
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// SinusoidalPositionalEncoding adds the fixed sine/cosine encoding of
+// "Attention Is All You Need" to [batch, seq, dModel] inputs, followed by
+// dropout. The table is computed per call, so any sequence length works.
+type SinusoidalPositionalEncoding struct {
+	dModel  int
+	base    float64
+	dropout *Dropout
+}
+
+func NewSinusoidalPositionalEncoding(dModel int, dropout float64) *SinusoidalPositionalEncoding {
+	return &SinusoidalPositionalEncoding{dModel: dModel, base: 10000, dropout: NewDropout(dropout)}
+}
+
+func (s *SinusoidalPositionalEncoding) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	return s.ForwardOffset(input, 0)
+}
+
+// ForwardOffset encodes positions offset, offset+1, ... which is what
+// incremental decoding needs after offset tokens have been processed.
+func (s *SinusoidalPositionalEncoding) ForwardOffset(input *tensor.Tensor, offset int) (*tensor.Tensor, error) {
+	shape := input.Shape()
+	if len(shape) != 3 || shape[2] != s.dModel {
+		return nil, fmt.Errorf("positional encoding expects [batch, seq, %d] input, got %v", s.dModel, shape)
+	}
+	table := s.Encoding(shape[1], offset).Data()
+	repeated := make([]float64, 0, shape[0]*len(table))
+	for b := 0; b < shape[0]; b++ {
+		repeated = append(repeated, table...)
+	}
+	out, err := tensor.Add(input, tensor.MustNew(repeated, shape...))
+	if err != nil {
+		return nil, err
+	}
+	return s.dropout.Forward(out)
+}
+
+// Encoding returns the [length, dModel] table for positions starting at
+// offset.
+func (s *SinusoidalPositionalEncoding) Encoding(length, offset int) *tensor.Tensor {
+	data := make([]float64, length*s.dModel)
+	for p := 0; p < length; p++ {
+		pos := float64(p + offset)
+		for i := 0; i < s.dModel; i += 2 {
+			freq := math.Pow(s.base, -float64(i)/float64(s.dModel))
+			data[p*s.dModel+i] = math.Sin(pos * freq)
+			if i+1 < s.dModel {
+				data[p*s.dModel+i+1] = math.Cos(pos * freq)
+			}
+		}
+	}
+	return tensor.MustNew(data, length, s.dModel)
+}
+
+func (s *SinusoidalPositionalEncoding) Parameters() []*tensor.Tensor {
+	return nil
+}
+
+func (s *SinusoidalPositionalEncoding) ZeroGrad() {}
+
+func (s *SinusoidalPositionalEncoding) Train() {
+	s.dropout.Train()
+}
+
+func (s *SinusoidalPositionalEncoding) Eval() {
+	s.dropout.Eval()
+}
+
+func (s *SinusoidalPositionalEncoding) IsTraining() bool {
+	return s.dropout.IsTraining()
+}
+
+// LearnedPositionalEmbedding adds a trained embedding of each position to
+// [batch, seq, dModel] inputs. Positions beyond maxLen have no embedding, so
+// longer inputs are rejected.
+type LearnedPositionalEmbedding struct {
+	*Embedding
+}
+
+func NewLearnedPositionalEmbedding(maxLen, dModel int) *LearnedPositionalEmbedding {
+	return &LearnedPositionalEmbedding{Embedding: NewEmbedding(maxLen, dModel)}
+}
+
+func (l *LearnedPositionalEmbedding) MaxLen() int {
+	return l.numEmbeddings
+}
+
+func (l *LearnedPositionalEmbedding) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	return l.ForwardOffset(input, 0)
+}
+
+// ForwardOffset adds the embeddings of positions offset, offset+1, ...
+func (l *LearnedPositionalEmbedding) ForwardOffset(input *tensor.Tensor, offset int) (*tensor.Tensor, error) {
+	shape := input.Shape()
+	if len(shape) != 3 || shape[2] != l.embeddingDim {
+		return nil, fmt.Errorf("positional embedding expects [batch, seq, %d] input, got %v", l.embeddingDim, shape)
+	}
+	if offset < 0 || offset+shape[1] > l.numEmbeddings {
+		return nil, fmt.Errorf("positions %d..%d exceed max length %d", offset, offset+shape[1]-1, l.numEmbeddings)
+	}
+	positions := make([]float64, shape[0]*shape[1])
+	for i := range positions {
+		positions[i] = float64(offset + i%shape[1])
+	}
+	pe, err := l.Embedding.Forward(tensor.MustNew(positions, shape[0], shape[1]))
+	if err != nil {
+		return nil, err
+	}
+	return tensor.Add(input, pe)
+}
+
+// RotaryEmbedding rotates query and key features by their position (RoPE),
+// so attention scores depend on relative offsets. It works on
+// [batch, heads, seq, headDim] tensors and extends to any length. Attach it
+// to attention with MultiheadAttention.SetRotary.
+type RotaryEmbedding struct {
+	headDim int
+	base    float64
+}
+
+func NewRotaryEmbedding(headDim int, base float64) (*RotaryEmbedding, error) {
+	if headDim <= 0 || headDim%2 != 0 {
+		return nil, errors.New("rotary embedding requires a positive even head dimension")
+	}
+	if base <= 0 {
+		base = 10000
+	}
+	return &RotaryEmbedding{headDim: headDim, base: base}, nil
+}
+
+func (r *RotaryEmbedding) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	return r.Rotate(input, 0)
+}
+
+// Rotate applies the rotation for positions offset, offset+1, ... along the
+// second-to-last dimension.
+func (r *RotaryEmbedding) Rotate(input *tensor.Tensor, offset int) (*tensor.Tensor, error) {
+	shape := input.Shape()
+	if len(shape) < 2 || shape[len(shape)-1] != r.headDim {
+		return nil, fmt.Errorf("rotary embedding expects last dimension %d, got shape %v", r.headDim, shape)
+	}
+	return tensor.ApplyRotary(input, offset, r.base)
+}
+
+func (r *RotaryEmbedding) Parameters() []*tensor.Tensor {
+	return nil
+}
+
+func (r *RotaryEmbedding) ZeroGrad() {}
+
+// ALiBi biases attention scores by -slope*|i-j| with one fixed slope per
+// head (attention with linear biases). Having no learned table, it
+// extrapolates to sequences longer than those seen in training. Attach it to
+// attention with MultiheadAttention.SetALiBi.
+type ALiBi struct {
+	slopes []float64
+}
+
+func NewALiBi(numHeads int) (*ALiBi, error) {
+	if numHeads <= 0 {
+		return nil, errors.New("ALiBi requires a positive number of heads")
+	}
+	return &ALiBi{slopes: alibiSlopes(numHeads)}, nil
+}
+
+// Slopes returns the per-head slopes.
+func (a *ALiBi) Slopes() []float64 {
+	return append([]float64(nil), a.slopes...)
+}
+
+// Bias returns the additive bias for head h between query position qPos and
+// key position kPos.
+func (a *ALiBi) Bias(h, qPos, kPos int) float64 {
+	return -a.slopes[h] * math.Abs(float64(qPos-kPos))
+}
+
+// alibiSlopes follows the reference geometric sequence 2^(-8/n), 2^(-16/n),
+// ... for a power of two n, interleaving the next power's slopes otherwise.
+func alibiSlopes(numHeads int) []float64 {
+	pow2 := func(n int) []float64 {
+		start := math.Pow(2, -8/float64(n))
+		slopes := make([]float64, n)
+		for i := range slopes {
+			slopes[i] = math.Pow(start, float64(i+1))
+		}
+		return slopes
+	}
+	closest := 1
+	for closest*2 <= numHeads {
+		closest *= 2
+	}
+	slopes := pow2(closest)
+	if closest < numHeads {
+		extra := pow2(2 * closest)
+		for i := 0; i < 2*closest && len(slopes) < numHeads; i += 2 {
+			slopes = append(slopes, extra[i])
+		}
+	}
+	return slopes
+}
@@ -0,0 +1,149 @@
+package nn
+
+import (
+	"math"
+	"testing"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+func TestSinusoidalPositionalEncoding(t *testing.T) {
+	pe := NewSinusoidalPositionalEncoding(4, 0)
+	table := pe.Encoding(3, 0).Data()
+	want := []float64{
+		0, 1, 0, 1,
+		math.Sin(1), math.Cos(1), math.Sin(0.01), math.Cos(0.01),
+		math.Sin(2), math.Cos(2), math.Sin(0.02), math.Cos(0.02),
+	}
+	if !floatsAlmostEqual(table, want, 1e-12) {
+		t.Fatalf("unexpected encoding table %v", table)
+	}
+	// offsets continue the same table, so long sequences need no setup
+	if !floatsAlmostEqual(pe.Encoding(1, 2).Data(), want[8:], 1e-12) {
+		t.Fatalf("offset encoding mismatch")
+	}
+	out, err := pe.Forward(tensor.Zeros(2, 3, 4))
+	if err != nil {
+		t.Fatalf("forward failed: %v", err)
+	}
+	if !floatsAlmostEqual(out.Data()[12:], want, 1e-12) {
+		t.Fatalf("encoding not added to every batch element")
+	}
+	if _, err := pe.Forward(tensor.Zeros(2, 3, 5)); err == nil {
+		t.Fatalf("expected dimension mismatch error")
+	}
+}
+
+func TestLearnedPositionalEmbedding(t *testing.T) {
+	pe := NewLearnedPositionalEmbedding(4, 3)
+	x := tensor.Zeros(2, 3, 3)
+	out, err := pe.Forward(x)
+	if err != nil {
+		t.Fatalf("forward failed: %v", err)
+	}
+	weight := pe.Parameters()[0].Data()
+	if !floatsAlmostEqual(out.Data()[:9], weight[:9], 0) || !floatsAlmostEqual(out.Data()[9:], weight[:9], 0) {
+		t.Fatalf("expected position rows added to each sequence")
+	}
+	if err := tensor.Sum(out).Backward(); err != nil {
+		t.Fatalf("backward failed: %v", err)
+	}
+	if !floatsAlmostEqual(pe.Parameters()[0].Grad().Data(), []float64{2, 2, 2, 2, 2, 2, 2, 2, 2, 0, 0, 0}, 1e-12) {
+		t.Fatalf("unexpected embedding gradient %v", pe.Parameters()[0].Grad().Data())
+	}
+	if _, err := pe.ForwardOffset(x, 2); err == nil {
+		t.Fatalf("expected error beyond max length")
+	}
+	state := map[string]*tensor.Tensor{}
+	pe.StateDict("pos", state)
+	if got := stateKeys(state); got != "pos.weight" {
+		t.Fatalf("unexpected state keys %s", got)
+	}
+}
+
+func TestRotaryScoresDependOnRelativePosition(t *testing.T) {
+	rope, err := NewRotaryEmbedding(4, 0)
+	if err != nil {
+		t.Fatalf("NewRotaryEmbedding failed: %v", err)
+	}
+	q := tensor.MustNew([]float64{0.3, -0.2, 0.5, 0.9}, 1, 1, 1, 4)
+	k := tensor.MustNew([]float64{-0.4, 0.8, 0.1, 0.6}, 1, 1, 1, 4)
+	score := func(qPos, kPos int) float64 {
+		rq, _ := rope.Rotate(q, qPos)
+		rk, _ := rope.Rotate(k, kPos)
+		a, b := rq.Data(), rk.Data()
+		s := 0.0
+		for i := range a {
+			s += a[i] * b[i]
+		}
+		return s
+	}
+	if math.Abs(score(5, 2)-score(103, 100)) > 1e-9 {
+		t.Fatalf("rotary score depends on absolute position")
+	}
+	if _, err := NewRotaryEmbedding(3, 0); err == nil {
+		t.Fatalf("expected odd head dimension error")
+	}
+}
+
+func TestALiBiAttention(t *testing.T) {
+	alibi, _ := NewALiBi(8)
+	slopes := alibi.Slopes()
+	for h, s := range slopes {
+		if want := math.Pow(2, -float64(h+1)); math.Abs(s-want) > 1e-12 {
+			t.Fatalf("slope %d = %v, want %v", h, s, want)
+		}
+	}
+	if odd, _ := NewALiBi(6); len(odd.Slopes()) != 6 {
+		t.Fatalf("expected 6 slopes for 6 heads")
+	}
+
+	mha, _ := NewMultiheadAttention(8, 2, 0, true)
+	two, _ := NewALiBi(2)
+	if err := mha.SetALiBi(two); err != nil {
+		t.Fatalf("SetALiBi failed: %v", err)
+	}
+	if err := mha.SetALiBi(alibi); err == nil {
+		t.Fatalf("expected head count mismatch error")
+	}
+	// zero queries and keys isolate the bias: weights decay with distance
+	for _, p := range []*Linear{mha.qProj, mha.kProj} {
+		p.Weight().SetData(make([]float64, 64))
+		p.Bias().SetData(make([]float64, 8))
+	}
+	x := tensor.Randn(1, 12, 8)
+	_, weights, err := mha.Attend(x, x, x, AttentionOptions{Causal: true})
+	if err != nil {
+		t.Fatalf("Attend failed: %v", err)
+	}
+	w := weights.Data()
+	row := w[11*12 : 12*12] // head 0, last query
+	for j := 1; j < 12; j++ {
+		if row[j] <= row[j-1] {
+			t.Fatalf("expected weights to grow towards the query position: %v", row)
+		}
+	}
+	if ratio := row[11] / row[10]; math.Abs(ratio-math.Exp(two.Slopes()[0])) > 1e-9 {
+		t.Fatalf("unexpected neighbour weight ratio %v", ratio)
+	}
+}
+
+func TestTransformerPositionOptions(t *testing.T) {
+	for _, pos := range []string{"rope", "alibi"} {
+		layer, err := NewTransformerEncoderLayer(TransformerConfig{DModel: 8, NumHeads: 2, Position: pos})
+		if err != nil {
+			t.Fatalf("%s layer failed: %v", pos, err)
+		}
+		// longer than anything used before; no table limits the length
+		out, err := layer.ForwardMasked(tensor.Randn(1, 40, 8), AttentionOptions{Causal: true})
+		if err != nil {
+			t.Fatalf("%s forward failed: %v", pos, err)
+		}
+		if shape := out.Shape(); shape[1] != 40 {
+			t.Fatalf("unexpected %s output shape %v", pos, shape)
+		}
+	}
+	if _, err := NewTransformerEncoderLayer(TransformerConfig{DModel: 8, NumHeads: 2, Position: "xpos"}); err == nil {
+		t.Fatalf("expected unsupported position error")
+	}
+}
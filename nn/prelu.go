@@ -0,0 +1,56 @@
+package nn
+
+import (
+	"fmt"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// PReLU is a leaky ReLU whose negative slope is learned, either shared
+// (numParameters 1) or per channel along dimension 1 of the input.
+type PReLU struct {
+	weight *tensor.Tensor
+}
+
+// NewPReLU creates numParameters slopes initialised to 0.25.
+func NewPReLU(numParameters int) (*PReLU, error) {
+	return NewPReLUWithInit(numParameters, 0.25)
+}
+
+// NewPReLUWithInit creates numParameters slopes set to init.
+func NewPReLUWithInit(numParameters int, init float64) (*PReLU, error) {
+	if numParameters <= 0 {
+		return nil, fmt.Errorf("PReLU requires a positive number of parameters, got %d", numParameters)
+	}
+	weight := tensor.Full(init, numParameters)
+	weight.SetRequiresGrad(true)
+	return &PReLU{weight: weight}, nil
+}
+
+func (p *PReLU) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	return tensor.PReLU(input, p.weight)
+}
+
+func (p *PReLU) Parameters() []*tensor.Tensor {
+	return []*tensor.Tensor{p.weight}
+}
+
+func (p *PReLU) ZeroGrad() {
+	p.weight.ZeroGrad()
+}
+
+func (p *PReLU) Weight() *tensor.Tensor {
+	return p.weight
+}
+
+func (p *PReLU) NamedParameters() []NamedParameter {
+	return []NamedParameter{{Name: "weight", Param: p.weight}}
+}
+
+func (p *PReLU) StateDict(prefix string, state map[string]*tensor.Tensor) {
+	namedStateDict(prefix, p.NamedParameters(), state)
+}
+
+func (p *PReLU) LoadState(prefix string, state map[string]*tensor.Tensor) error {
+	return loadNamedState("PReLU", prefix, p.NamedParameters(), state)
+}
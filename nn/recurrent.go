@@ -0,0 +1,406 @@
+package nn
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// RecurrentConfig holds the stacking options shared by LSTM, GRU and
+// SimpleRNN.
+type RecurrentConfig struct {
+	// NumLayers stacks recurrent layers, each consuming the previous
+	// layer's output (default 1).
+	NumLayers int
+	// Bidirectional adds a second direction per layer that reads the
+	// sequence backwards; outputs of both directions are concatenated.
+	Bidirectional bool
+	// Dropout is applied to the output of every layer but the last while
+	// training.
+	Dropout float64
+	// BatchFirst switches input and output to [batch, seq, features].
+	BatchFirst bool
+}
+
+// recurrentStep advances cell by one time step from state (h, plus c for an
+// LSTM) and returns the new state, whose first entry is the output.
+type recurrentStep func(cell int, x *tensor.Tensor, state []*tensor.Tensor) ([]*tensor.Tensor, error)
+
+// recurrentSequence runs cell over a whole sequence x [seq, batch, features]
+// from state, honouring lengths (nil for full sequences), and returns the
+// output [seq, batch, hidden] and the final state.
+type recurrentSequence func(cell int, x *tensor.Tensor, state []*tensor.Tensor, lengths []int, reverse bool) (*tensor.Tensor, []*tensor.Tensor, error)
+
+// recurrentLayout runs the layers and directions of a recurrent module. Cells
+// are indexed layer*directions + direction.
+type recurrentLayout struct {
+	name       string
+	inputSize  int
+	hiddenSize int
+	cfg        RecurrentConfig
+	training   bool
+}
+
+func newRecurrentLayout(name string, inputSize, hiddenSize int, cfg RecurrentConfig) recurrentLayout {
+	if cfg.NumLayers <= 0 {
+		cfg.NumLayers = 1
+	}
+	if cfg.Dropout < 0 {
+		cfg.Dropout = 0
+	}
+	if cfg.Dropout >= 1 {
+		cfg.Dropout = 0.999 // clamp to avoid invalid probability
+	}
+	return recurrentLayout{name: name, inputSize: inputSize, hiddenSize: hiddenSize, cfg: cfg, training: true}
+}
+
+func (r *recurrentLayout) Train() { r.training = true }
+
+func (r *recurrentLayout) Eval() { r.training = false }
+
+func (r *recurrentLayout) IsTraining() bool { return r.training }
+
+func (r *recurrentLayout) NumLayers() int { return r.cfg.NumLayers }
+
+func (r *recurrentLayout) Bidirectional() bool { return r.cfg.Bidirectional }
+
+func (r *recurrentLayout) BatchFirst() bool { return r.cfg.BatchFirst }
+
+func (r *recurrentLayout) directions() int {
+	if r.cfg.Bidirectional {
+		return 2
+	}
+	return 1
+}
+
+func (r *recurrentLayout) numCells() int {
+	return r.cfg.NumLayers * r.directions()
+}
+
+// cellInputSize is the feature size seen by the cells of layer.
+func (r *recurrentLayout) cellInputSize(layer int) int {
+	if layer == 0 {
+		return r.inputSize
+	}
+	return r.hiddenSize * r.directions()
+}
+
+// cellSuffix names the parameters of a cell. The first layer's forward
+// direction keeps the historical unsuffixed names, so a key means the same
+// weight in every configuration: "_l1" marks layer 1 and "_reverse" the
+// backward direction.
+func (r *recurrentLayout) cellSuffix(cell int) string {
+	layer, dir := cell/r.directions(), cell%r.directions()
+	suffix := ""
+	if layer > 0 {
+		suffix = fmt.Sprintf("_l%d", layer)
+	}
+	if dir == 1 {
+		suffix += "_reverse"
+	}
+	return suffix
+}
+
+// run applies the module to input. init holds one initial state per state
+// kind (nil for zeros), each [numCells, batch, hidden], or [batch, hidden]
+// for a single cell. It returns the output and the final states in the same
+// layout. With lengths, batch element b only has lengths[b] valid steps: its
+// state is left unchanged and its output is zero at the padded steps, so the
+// forward direction ends and the reverse direction starts at its last step.
+func (r *recurrentLayout) run(input *tensor.Tensor, lengths []int, init []*tensor.Tensor, seq recurrentSequence) (*tensor.Tensor, []*tensor.Tensor, error) {
+	shape := input.Shape()
+	if len(shape) != 3 {
+		if r.cfg.BatchFirst {
+			return nil, nil, fmt.Errorf("%s expects input shape [batch, seq, features]", r.name)
+		}
+		return nil, nil, fmt.Errorf("%s expects input shape [seq, batch, features]", r.name)
+	}
+	x := input
+	var err error
+	if r.cfg.BatchFirst {
+		if x, err = tensor.Permute(input, 1, 0, 2); err != nil {
+			return nil, nil, err
+		}
+		shape = x.Shape()
+	}
+	seqLen, batch := shape[0], shape[1]
+	if shape[2] != r.inputSize {
+		return nil, nil, errors.New("input feature mismatch")
+	}
+	if err := checkLengths(lengths, seqLen, batch); err != nil {
+		return nil, nil, err
+	}
+	cells := r.numCells()
+	states := make([][]*tensor.Tensor, cells)
+	for kind, s := range init {
+		perCell, err := r.splitState(s, batch, kind)
+		if err != nil {
+			return nil, nil, err
+		}
+		for c := range states {
+			states[c] = append(states[c], perCell[c])
+		}
+	}
+
+	dirs := r.directions()
+	for layer := 0; layer < r.cfg.NumLayers; layer++ {
+		dirOutputs := make([]*tensor.Tensor, dirs)
+		for dir := 0; dir < dirs; dir++ {
+			cell := layer*dirs + dir
+			if dirOutputs[dir], states[cell], err = seq(cell, x, states[cell], lengths, dir == 1); err != nil {
+				return nil, nil, err
+			}
+		}
+		if x, err = tensor.Concat(2, dirOutputs...); err != nil {
+			return nil, nil, err
+		}
+		if layer < r.cfg.NumLayers-1 && r.cfg.Dropout > 0 {
+			if x, err = tensor.Dropout(x, r.cfg.Dropout, r.training); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if r.cfg.BatchFirst {
+		if x, err = tensor.Permute(x, 1, 0, 2); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	final := make([]*tensor.Tensor, len(init))
+	for kind := range final {
+		if cells == 1 {
+			final[kind] = states[0][kind]
+			continue
+		}
+		perCell := make([]*tensor.Tensor, cells)
+		for c := range perCell {
+			perCell[c] = states[c][kind]
+		}
+		if final[kind], err = tensor.Stack(0, perCell...); err != nil {
+			return nil, nil, err
+		}
+	}
+	return x, final, nil
+}
+
+// runPacked applies the module to a packed batch; see run.
+func (r *recurrentLayout) runPacked(packed *PackedSequence, init []*tensor.Tensor, seq recurrentSequence) (*PackedSequence, []*tensor.Tensor, error) {
+	padded, lengths, err := PadPackedSequence(packed, r.cfg.BatchFirst)
+	if err != nil {
+		return nil, nil, err
+	}
+	out, states, err := r.run(padded, lengths, init, seq)
+	if err != nil {
+		return nil, nil, err
+	}
+	repacked, err := PackPaddedSequence(out, lengths, r.cfg.BatchFirst)
+	if err != nil {
+		return nil, nil, err
+	}
+	return repacked, states, nil
+}
+
+// stepwise builds a recurrentSequence from a single-step function, recording
+// every step in the autograd graph.
+func (r *recurrentLayout) stepwise(step recurrentStep) recurrentSequence {
+	return func(cell int, x *tensor.Tensor, state []*tensor.Tensor, lengths []int, reverse bool) (*tensor.Tensor, []*tensor.Tensor, error) {
+		shape := x.Shape()
+		seqLen, batch, features := shape[0], shape[1], shape[2]
+		sizes := make([]int, seqLen)
+		for i := range sizes {
+			sizes[i] = 1
+		}
+		steps, err := tensor.Split(0, sizes, x)
+		if err != nil {
+			return nil, nil, err
+		}
+		frames := make([]*tensor.Tensor, seqLen)
+		for i := 0; i < seqLen; i++ {
+			t := i
+			if reverse {
+				t = seqLen - 1 - i
+			}
+			xt, err := steps[t].Reshape(batch, features)
+			if err != nil {
+				return nil, nil, err
+			}
+			next, err := step(cell, xt, state)
+			if err != nil {
+				return nil, nil, err
+			}
+			frame := next[0]
+			if lengths != nil {
+				mask := r.stepMask(lengths, t, batch)
+				if next, err = maskedState(mask, state, next); err != nil {
+					return nil, nil, err
+				}
+				if frame, err = tensor.Mul(mask, frame); err != nil {
+					return nil, nil, err
+				}
+			}
+			state = next
+			if frames[t], err = tensor.Unsqueeze(frame, 0); err != nil {
+				return nil, nil, err
+			}
+		}
+		out, err := tensor.Concat(0, frames...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return out, state, nil
+	}
+}
+
+func checkLengths(lengths []int, seqLen, batch int) error {
+	if lengths == nil {
+		return nil
+	}
+	if len(lengths) != batch {
+		return fmt.Errorf("expected %d lengths, got %d", batch, len(lengths))
+	}
+	for b, l := range lengths {
+		if l < 1 || l > seqLen {
+			return fmt.Errorf("length %d of sequence %d out of range [1, %d]", l, b, seqLen)
+		}
+	}
+	return nil
+}
+
+// stepMask returns a [batch, hidden] tensor that is 1 for the batch elements
+// still running at step t and 0 past their end.
+func (r *recurrentLayout) stepMask(lengths []int, t, batch int) *tensor.Tensor {
+	data := make([]float64, batch*r.hiddenSize)
+	for b, l := range lengths {
+		if t < l {
+			for j := 0; j < r.hiddenSize; j++ {
+				data[b*r.hiddenSize+j] = 1
+			}
+		}
+	}
+	return tensor.MustNew(data, batch, r.hiddenSize)
+}
+
+// maskedState keeps next where mask is 1 and prev where it is 0.
+func maskedState(mask *tensor.Tensor, prev, next []*tensor.Tensor) ([]*tensor.Tensor, error) {
+	out := make([]*tensor.Tensor, len(next))
+	for i := range next {
+		delta, err := tensor.Sub(next[i], prev[i])
+		if err != nil {
+			return nil, err
+		}
+		if delta, err = tensor.Mul(mask, delta); err != nil {
+			return nil, err
+		}
+		if out[i], err = tensor.Add(prev[i], delta); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// splitState turns an initial state into one [batch, hidden] tensor per cell.
+func (r *recurrentLayout) splitState(s *tensor.Tensor, batch, kind int) ([]*tensor.Tensor, error) {
+	cells := r.numCells()
+	perCell := make([]*tensor.Tensor, cells)
+	if s == nil {
+		for c := range perCell {
+			perCell[c] = tensor.Zeros(batch, r.hiddenSize)
+		}
+		return perCell, nil
+	}
+	label := "hidden"
+	if kind == 1 {
+		label = "cell"
+	}
+	shape := s.Shape()
+	if cells == 1 && len(shape) == 2 {
+		if shape[0] != batch || shape[1] != r.hiddenSize {
+			return nil, fmt.Errorf("%s state shape mismatch", label)
+		}
+		perCell[0] = s
+		return perCell, nil
+	}
+	if len(shape) != 3 || shape[0] != cells || shape[1] != batch || shape[2] != r.hiddenSize {
+		return nil, fmt.Errorf("%s state shape mismatch: want [%d, %d, %d], got %v", label, cells, batch, r.hiddenSize, shape)
+	}
+	if cells == 1 {
+		reshaped, err := s.Reshape(batch, r.hiddenSize)
+		if err != nil {
+			return nil, err
+		}
+		perCell[0] = reshaped
+		return perCell, nil
+	}
+	sizes := make([]int, cells)
+	for i := range sizes {
+		sizes[i] = 1
+	}
+	parts, err := tensor.Split(0, sizes, s)
+	if err != nil {
+		return nil, err
+	}
+	for c, p := range parts {
+		if perCell[c], err = p.Reshape(batch, r.hiddenSize); err != nil {
+			return nil, err
+		}
+	}
+	return perCell, nil
+}
+
+// fuseGates stacks per-gate parameters into the fused layout of the
+// recurrent kernels: weights along the gate axis and a single bias summing
+// the input and hidden biases (nil without bias). Gradients flow back to the
+// per-gate tensors, which keep the state-dict format unchanged.
+func fuseGates(weightIH, weightHH, biasIH, biasHH []*tensor.Tensor, withBias bool) (*tensor.Tensor, *tensor.Tensor, *tensor.Tensor, error) {
+	wIH, err := tensor.Concat(0, weightIH...)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	wHH, err := tensor.Concat(0, weightHH...)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if !withBias {
+		return wIH, wHH, nil, nil
+	}
+	bIH, err := tensor.Concat(0, biasIH...)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	bHH, err := tensor.Concat(0, biasHH...)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	bias, err := tensor.Add(bIH, bHH)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return wIH, wHH, bias, nil
+}
+
+// checkCellInput validates a [batch, features] cell input and returns the
+// batch size.
+func checkCellInput(name string, input *tensor.Tensor, inputSize int) (int, error) {
+	shape := input.Shape()
+	if len(shape) != 2 {
+		return 0, fmt.Errorf("%s expects input shape [batch, features]", name)
+	}
+	if shape[1] != inputSize {
+		return 0, errors.New("input feature mismatch")
+	}
+	return shape[0], nil
+}
+
+// cellState returns s, or zeros when nil, after checking it is
+// [batch, hidden].
+func cellState(s *tensor.Tensor, batch, hidden int, label string) (*tensor.Tensor, error) {
+	if s == nil {
+		return tensor.Zeros(batch, hidden), nil
+	}
+	shape := s.Shape()
+	if len(shape) != 2 || shape[0] != batch || shape[1] != hidden {
+		return nil, fmt.Errorf("%s state shape mismatch", label)
+	}
+	return s, nil
+}
@@ -9,10 +9,10 @@ import (
 
 func TestSimpleRNNForwardBackward(t *testing.T) {
 	rnn := NewSimpleRNN(1, 1, "tanh", true)
-	mustSetData(t, rnn.weightIH, []float64{0.8})
-	mustSetData(t, rnn.weightHH, []float64{0.1})
-	mustSetData(t, rnn.biasIH, []float64{0.05})
-	mustSetData(t, rnn.biasHH, []float64{-0.02})
+	mustSetData(t, rnn.cells[0].weightIH, []float64{0.8})
+	mustSetData(t, rnn.cells[0].weightHH, []float64{0.1})
+	mustSetData(t, rnn.cells[0].biasIH, []float64{0.05})
+	mustSetData(t, rnn.cells[0].biasHH, []float64{-0.02})
 
 	inputs := []float64{0.2, -0.1, 0.3}
 	inputTensor := tensor.MustNew([]float64{0.2, -0.1, 0.3}, 3, 1, 1)
@@ -47,12 +47,12 @@ func TestSimpleRNNForwardBackward(t *testing.T) {
 
 func TestGRUForwardBackward(t *testing.T) {
 	gru := NewGRU(1, 1, false)
-	mustSetData(t, gru.weightIH[gruGateUpdate], []float64{0.15})
-	mustSetData(t, gru.weightHH[gruGateUpdate], []float64{0.05})
-	mustSetData(t, gru.weightIH[gruGateReset], []float64{-0.2})
-	mustSetData(t, gru.weightHH[gruGateReset], []float64{0.1})
-	mustSetData(t, gru.weightIH[gruGateNew], []float64{0.4})
-	mustSetData(t, gru.weightHH[gruGateNew], []float64{0.3})
+	mustSetData(t, gru.cells[0].weightIH[gruGateUpdate], []float64{0.15})
+	mustSetData(t, gru.cells[0].weightHH[gruGateUpdate], []float64{0.05})
+	mustSetData(t, gru.cells[0].weightIH[gruGateReset], []float64{-0.2})
+	mustSetData(t, gru.cells[0].weightHH[gruGateReset], []float64{0.1})
+	mustSetData(t, gru.cells[0].weightIH[gruGateNew], []float64{0.4})
+	mustSetData(t, gru.cells[0].weightHH[gruGateNew], []float64{0.3})
 
 	inputs := []float64{0.2, -0.1, 0.3}
 	inputTensor := tensor.MustNew([]float64{0.2, -0.1, 0.3}, 3, 1, 1)
@@ -87,14 +87,14 @@ func TestGRUForwardBackward(t *testing.T) {
 
 func TestLSTMForwardBackward(t *testing.T) {
 	lstm := NewLSTM(1, 1, false)
-	mustSetData(t, lstm.weightIH[lstmGateInput], []float64{0.25})
-	mustSetData(t, lstm.weightHH[lstmGateInput], []float64{0.1})
-	mustSetData(t, lstm.weightIH[lstmGateForget], []float64{-0.3})
-	mustSetData(t, lstm.weightHH[lstmGateForget], []float64{0.2})
-	mustSetData(t, lstm.weightIH[lstmGateCell], []float64{0.45})
-	mustSetData(t, lstm.weightHH[lstmGateCell], []float64{0.15})
-	mustSetData(t, lstm.weightIH[lstmGateOutput], []float64{0.35})
-	mustSetData(t, lstm.weightHH[lstmGateOutput], []float64{0.05})
+	mustSetData(t, lstm.cells[0].weightIH[lstmGateInput], []float64{0.25})
+	mustSetData(t, lstm.cells[0].weightHH[lstmGateInput], []float64{0.1})
+	mustSetData(t, lstm.cells[0].weightIH[lstmGateForget], []float64{-0.3})
+	mustSetData(t, lstm.cells[0].weightHH[lstmGateForget], []float64{0.2})
+	mustSetData(t, lstm.cells[0].weightIH[lstmGateCell], []float64{0.45})
+	mustSetData(t, lstm.cells[0].weightHH[lstmGateCell], []float64{0.15})
+	mustSetData(t, lstm.cells[0].weightIH[lstmGateOutput], []float64{0.35})
+	mustSetData(t, lstm.cells[0].weightHH[lstmGateOutput], []float64{0.05})
 
 	inputs := []float64{0.2, -0.1, 0.3}
 	inputTensor := tensor.MustNew([]float64{0.2, -0.1, 0.3}, 3, 1, 1)
@@ -133,6 +133,241 @@ func TestLSTMForwardBackward(t *testing.T) {
 	}
 }
 
+func TestLSTMStackedBidirectional(t *testing.T) {
+	lstm := NewLSTMWithConfig(3, 4, true, RecurrentConfig{NumLayers: 2, Bidirectional: true})
+	input := tensor.Randn(5, 2, 3)
+	input.SetRequiresGrad(true)
+	out, h, c, err := lstm.ForwardWithState(input, nil, nil)
+	if err != nil {
+		t.Fatalf("stacked lstm forward failed: %v", err)
+	}
+	if !equalShape(out.Shape(), []int{5, 2, 8}) {
+		t.Fatalf("unexpected output shape %v", out.Shape())
+	}
+	if !equalShape(h.Shape(), []int{4, 2, 4}) || !equalShape(c.Shape(), []int{4, 2, 4}) {
+		t.Fatalf("unexpected state shapes %v and %v", h.Shape(), c.Shape())
+	}
+	// the last layer's final states are the ends of its output sequence
+	outData, hData := out.Data(), h.Data()
+	for b := 0; b < 2; b++ {
+		fwdLast := outData[(4*2+b)*8 : (4*2+b)*8+4]
+		bwdFirst := outData[b*8+4 : b*8+8]
+		if !floatsAlmostEqual(hData[(2*2+b)*4:(2*2+b)*4+4], fwdLast, 1e-12) ||
+			!floatsAlmostEqual(hData[(3*2+b)*4:(3*2+b)*4+4], bwdFirst, 1e-12) {
+			t.Fatalf("final hidden state does not match the output sequence")
+		}
+	}
+	if err := tensor.Sum(out).Backward(); err != nil {
+		t.Fatalf("stacked lstm backward failed: %v", err)
+	}
+	for _, np := range lstm.NamedParameters() {
+		if np.Param.Grad() == nil {
+			t.Fatalf("parameter %s missing gradient", np.Name)
+		}
+	}
+
+	state := map[string]*tensor.Tensor{}
+	lstm.StateDict("", state)
+	if w := state["weight_ih_input_l1_reverse"]; w == nil || !equalShape(w.Shape(), []int{4, 8}) {
+		t.Fatalf("missing or misshaped second-layer reverse weight")
+	}
+	if _, ok := state["weight_ih_input"]; !ok {
+		t.Fatalf("first layer must keep the single-layer key names")
+	}
+	// a single-layer LSTM loads the first layer of a stacked state dict
+	single := NewLSTM(3, 4, true)
+	if err := single.LoadState("", state); err != nil {
+		t.Fatalf("load into single layer failed: %v", err)
+	}
+	if !floatsAlmostEqual(single.cells[0].weightHH[lstmGateCell].Data(), lstm.cells[0].weightHH[lstmGateCell].Data(), 0) {
+		t.Fatalf("first layer weights were not loaded")
+	}
+	if err := NewLSTMWithConfig(3, 4, true, RecurrentConfig{NumLayers: 3}).LoadState("", state); err == nil {
+		t.Fatalf("expected missing key error for a deeper LSTM")
+	}
+	if _, _, _, err := lstm.ForwardWithState(input, tensor.Zeros(2, 4), nil); err == nil {
+		t.Fatalf("expected state shape error")
+	}
+}
+
+func TestSimpleRNNReverseDirection(t *testing.T) {
+	bi := NewSimpleRNNWithConfig(2, 3, "tanh", true, RecurrentConfig{Bidirectional: true})
+	state := map[string]*tensor.Tensor{}
+	bi.StateDict("", state)
+	reverse := NewSimpleRNN(2, 3, "tanh", true)
+	for _, name := range []string{"weight_ih", "weight_hh", "bias_ih", "bias_hh"} {
+		state[name] = state[name+"_reverse"]
+	}
+	if err := reverse.LoadState("", state); err != nil {
+		t.Fatalf("load reverse weights failed: %v", err)
+	}
+
+	data := []float64{0.1, -0.4, 0.3, 0.2, -0.5, 0.6, 0.9, -0.1, 0.4, 0.05, -0.2, 0.7}
+	flipped := make([]float64, 0, len(data))
+	for step := 2; step >= 0; step-- {
+		flipped = append(flipped, data[step*4:(step+1)*4]...)
+	}
+	out, h, err := bi.ForwardWithState(tensor.MustNew(data, 3, 2, 2), nil)
+	if err != nil {
+		t.Fatalf("bidirectional forward failed: %v", err)
+	}
+	refOut, refH, err := reverse.ForwardWithState(tensor.MustNew(flipped, 3, 2, 2), nil)
+	if err != nil {
+		t.Fatalf("reference forward failed: %v", err)
+	}
+	outData, refData := out.Data(), refOut.Data()
+	for step := 0; step < 3; step++ {
+		for b := 0; b < 2; b++ {
+			got := outData[(step*2+b)*6+3 : (step*2+b)*6+6]
+			want := refData[((2-step)*2+b)*3 : ((2-step)*2+b)*3+3]
+			if !floatsAlmostEqual(got, want, 1e-12) {
+				t.Fatalf("reverse direction at step %d differs from the flipped sequence", step)
+			}
+		}
+	}
+	if !floatsAlmostEqual(h.Data()[6:], refH.Data(), 1e-12) {
+		t.Fatalf("reverse final state mismatch")
+	}
+}
+
+func TestGRUBatchFirstAndDropout(t *testing.T) {
+	cfg := RecurrentConfig{NumLayers: 2, Dropout: 0.5}
+	seqFirst := NewGRUWithConfig(3, 2, true, cfg)
+	cfg.BatchFirst = true
+	batchFirst := NewGRUWithConfig(3, 2, true, cfg)
+	state := map[string]*tensor.Tensor{}
+	seqFirst.StateDict("", state)
+	if err := batchFirst.LoadState("", state); err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	seqFirst.Eval()
+	batchFirst.Eval()
+
+	input := tensor.Randn(4, 2, 3)
+	want, wantH, err := seqFirst.ForwardWithState(input, nil)
+	if err != nil {
+		t.Fatalf("forward failed: %v", err)
+	}
+	swapped, _ := tensor.Permute(input, 1, 0, 2)
+	got, gotH, err := batchFirst.ForwardWithState(swapped, nil)
+	if err != nil {
+		t.Fatalf("batch-first forward failed: %v", err)
+	}
+	gotSeq, _ := tensor.Permute(got, 1, 0, 2)
+	if !equalShape(got.Shape(), []int{2, 4, 2}) || !floatsAlmostEqual(gotSeq.Data(), want.Data(), 1e-12) {
+		t.Fatalf("batch-first output differs from sequence-first output")
+	}
+	if !floatsAlmostEqual(gotH.Data(), wantH.Data(), 1e-12) {
+		t.Fatalf("batch-first state differs from sequence-first state")
+	}
+
+	again, _, _ := seqFirst.ForwardWithState(input, nil)
+	if !floatsAlmostEqual(again.Data(), want.Data(), 0) {
+		t.Fatalf("dropout must be inactive in evaluation")
+	}
+	seqFirst.Train()
+	trained, _, _ := seqFirst.ForwardWithState(input, nil)
+	if floatsAlmostEqual(trained.Data(), want.Data(), 1e-12) {
+		t.Fatalf("expected inter-layer dropout in training")
+	}
+}
+
+func TestRecurrentCellsMatchModules(t *testing.T) {
+	input := tensor.Randn(3, 2, 4)
+	steps, _ := tensor.Split(0, []int{1, 1, 1}, input)
+	frame := func(i int) *tensor.Tensor {
+		x, err := steps[i].Reshape(2, 4)
+		if err != nil {
+			t.Fatalf("reshape failed: %v", err)
+		}
+		return x
+	}
+
+	lstm := NewLSTM(4, 3, true)
+	lstmCell := NewLSTMCell(4, 3, true)
+	state := map[string]*tensor.Tensor{}
+	lstm.StateDict("", state)
+	if err := lstmCell.LoadState("", state); err != nil {
+		t.Fatalf("LSTMCell load failed: %v", err)
+	}
+	cellState := map[string]*tensor.Tensor{}
+	lstmCell.StateDict("", cellState)
+	if len(cellState) != len(state) {
+		t.Fatalf("LSTMCell keys %v differ from LSTM keys", stateKeys(cellState))
+	}
+	want, _, _, _ := lstm.ForwardWithState(input, nil, nil)
+	var h, c *tensor.Tensor
+	var outs []*tensor.Tensor
+	for i := 0; i < 3; i++ {
+		var err error
+		if h, c, err = lstmCell.ForwardWithState(frame(i), h, c); err != nil {
+			t.Fatalf("LSTMCell step failed: %v", err)
+		}
+		outs = append(outs, h)
+	}
+	got, _ := tensor.Stack(0, outs...)
+	if !floatsAlmostEqual(got.Data(), want.Data(), 1e-12) {
+		t.Fatalf("LSTMCell steps differ from LSTM")
+	}
+
+	gru := NewGRUWithConfig(4, 3, true, RecurrentConfig{Bidirectional: true})
+	gruCell := NewGRUCell(4, 3, true)
+	state = map[string]*tensor.Tensor{}
+	gru.StateDict("", state)
+	if err := gruCell.LoadState("", state); err != nil {
+		t.Fatalf("GRUCell load failed: %v", err)
+	}
+	wantGRU, _, _ := gru.ForwardWithState(input, nil)
+	var gh *tensor.Tensor
+	for i := 0; i < 3; i++ {
+		outs, err := gruCell.ForwardMulti(frame(i), gh)
+		if err != nil {
+			t.Fatalf("GRUCell step failed: %v", err)
+		}
+		gh = outs[0]
+	}
+	// the forward half of the last bidirectional output
+	last := wantGRU.Data()[2*2*6:]
+	if !floatsAlmostEqual(gh.Data()[:3], last[:3], 1e-12) || !floatsAlmostEqual(gh.Data()[3:], last[6:9], 1e-12) {
+		t.Fatalf("GRUCell steps differ from the GRU forward direction")
+	}
+
+	rnnCell := NewRNNCell(4, 3, "relu", false)
+	h0 := tensor.Randn(2, 3)
+	h0.SetRequiresGrad(true)
+	out, err := rnnCell.ForwardWithState(frame(0), h0)
+	if err != nil {
+		t.Fatalf("RNNCell step failed: %v", err)
+	}
+	if err := tensor.Sum(out).Backward(); err != nil {
+		t.Fatalf("RNNCell backward failed: %v", err)
+	}
+	if h0.Grad() == nil || rnnCell.weightHH.Grad() == nil {
+		t.Fatalf("RNNCell gradients missing")
+	}
+	if len(rnnCell.Parameters()) != 2 {
+		t.Fatalf("expected 2 parameters without bias, got %d", len(rnnCell.Parameters()))
+	}
+	if _, err := rnnCell.Forward(input); err == nil {
+		t.Fatalf("expected input rank error")
+	}
+	if _, err := rnnCell.ForwardWithState(frame(0), tensor.Zeros(3, 3)); err == nil {
+		t.Fatalf("expected state shape error")
+	}
+}
+
+func equalShape(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func mustSetData(t *testing.T, tt *tensor.Tensor, vals []float64) {
 	t.Helper()
 	if tt == nil {
@@ -8,7 +8,9 @@ import (
 	"github.com/fumitoshi0524/ixeoriNet/tensor"
 )
 
-type SimpleRNN struct {
+// RNNCell computes a single SimpleRNN time step on [batch, features]
+// inputs; see LSTMCell.
+type RNNCell struct {
 	inputSize    int
 	hiddenSize   int
 	nonlinearity string
@@ -18,7 +20,7 @@ type SimpleRNN struct {
 	biasHH       *tensor.Tensor
 }
 
-func NewSimpleRNN(inputSize, hiddenSize int, nonlinearity string, withBias bool) *SimpleRNN {
+func NewRNNCell(inputSize, hiddenSize int, nonlinearity string, withBias bool, opts ...InitOption) *RNNCell {
 	if nonlinearity == "" {
 		nonlinearity = "tanh"
 	}
@@ -38,7 +40,8 @@ func NewSimpleRNN(inputSize, hiddenSize int, nonlinearity string, withBias bool)
 		biasIH.SetRequiresGrad(true)
 		biasHH.SetRequiresGrad(true)
 	}
-	return &SimpleRNN{
+	applyInit(opts, []*tensor.Tensor{weightIH, weightHH}, []*tensor.Tensor{biasIH, biasHH})
+	return &RNNCell{
 		inputSize:    inputSize,
 		hiddenSize:   hiddenSize,
 		nonlinearity: nonlinearity,
@@ -49,86 +52,69 @@ func NewSimpleRNN(inputSize, hiddenSize int, nonlinearity string, withBias bool)
 	}
 }
 
-func (r *SimpleRNN) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
-	out, _, err := r.ForwardWithState(input, nil)
-	return out, err
+func (r *RNNCell) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	return r.ForwardWithState(input, nil)
 }
 
-func (r *SimpleRNN) ForwardWithState(input *tensor.Tensor, hx *tensor.Tensor) (*tensor.Tensor, *tensor.Tensor, error) {
-	shape := input.Shape()
-	if len(shape) != 3 {
-		return nil, nil, errors.New("SimpleRNN expects input shape [seq, batch, features]")
+// ForwardMulti runs one step on (input[, h]) and returns [h].
+func (r *RNNCell) ForwardMulti(inputs ...*tensor.Tensor) ([]*tensor.Tensor, error) {
+	if len(inputs) < 1 || len(inputs) > 2 {
+		return nil, fmt.Errorf("RNNCell expects 1 or 2 inputs, got %d", len(inputs))
 	}
-	seqLen := shape[0]
-	batch := shape[1]
-	features := shape[2]
-	if features != r.inputSize {
-		return nil, nil, errors.New("input feature mismatch")
+	var hx *tensor.Tensor
+	if len(inputs) > 1 {
+		hx = inputs[1]
 	}
-	if hx == nil {
-		hx = tensor.Zeros(batch, r.hiddenSize)
+	h, err := r.ForwardWithState(inputs[0], hx)
+	if err != nil {
+		return nil, err
 	}
-	if len(hx.Shape()) != 2 || hx.Shape()[0] != batch || hx.Shape()[1] != r.hiddenSize {
-		return nil, nil, errors.New("hidden state shape mismatch")
+	return []*tensor.Tensor{h}, nil
+}
+
+// ForwardWithState advances the hidden state [batch, hidden] (nil for zeros)
+// by one step of input [batch, features].
+func (r *RNNCell) ForwardWithState(input, hx *tensor.Tensor) (*tensor.Tensor, error) {
+	batch, err := checkCellInput("RNNCell", input, r.inputSize)
+	if err != nil {
+		return nil, err
 	}
-	sizes := make([]int, seqLen)
-	for i := range sizes {
-		sizes[i] = 1
+	if hx, err = cellState(hx, batch, r.hiddenSize, "hidden"); err != nil {
+		return nil, err
 	}
-	steps, err := tensor.Split(0, sizes, input)
+	return r.step(input, hx)
+}
+
+// step advances the cell by one time step.
+func (r *RNNCell) step(x, current *tensor.Tensor) (*tensor.Tensor, error) {
+	linear, err := tensor.MatMul(x, r.weightIH.MustTranspose())
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	current := hx
-	frames := make([]*tensor.Tensor, 0, seqLen)
-	for _, step := range steps {
-		var x *tensor.Tensor
-		x, err = step.Reshape(batch, features)
-		if err != nil {
-			return nil, nil, err
-		}
-		linear, err := tensor.MatMul(x, r.weightIH.MustTranspose())
-		if err != nil {
-			return nil, nil, err
-		}
-		hiddenLinear, err := tensor.MatMul(current, r.weightHH.MustTranspose())
-		if err != nil {
-			return nil, nil, err
-		}
-		summed, err := tensor.Add(linear, hiddenLinear)
-		if err != nil {
-			return nil, nil, err
-		}
-		if r.biasIH != nil {
-			summed, err = tensor.AddBias2D(summed, r.biasIH)
-			if err != nil {
-				return nil, nil, err
-			}
-		}
-		if r.biasHH != nil {
-			summed, err = tensor.AddBias2D(summed, r.biasHH)
-			if err != nil {
-				return nil, nil, err
-			}
-		}
-		current, err = r.activate(summed)
+	hiddenLinear, err := tensor.MatMul(current, r.weightHH.MustTranspose())
+	if err != nil {
+		return nil, err
+	}
+	summed, err := tensor.Add(linear, hiddenLinear)
+	if err != nil {
+		return nil, err
+	}
+	if r.biasIH != nil {
+		summed, err = tensor.AddBias2D(summed, r.biasIH)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
-		frame, err := tensor.Unsqueeze(current, 0)
+	}
+	if r.biasHH != nil {
+		summed, err = tensor.AddBias2D(summed, r.biasHH)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
-		frames = append(frames, frame)
 	}
-	output, err := tensor.Concat(0, frames...)
-	if err != nil {
-		return nil, nil, err
-	}
-	return output, current, nil
+	return r.activate(summed)
 }
 
-func (r *SimpleRNN) activate(t *tensor.Tensor) (*tensor.Tensor, error) {
+func (r *RNNCell) activate(t *tensor.Tensor) (*tensor.Tensor, error) {
 	switch r.nonlinearity {
 	case "relu":
 		return tensor.Relu(t), nil
@@ -139,7 +125,7 @@ func (r *SimpleRNN) activate(t *tensor.Tensor) (*tensor.Tensor, error) {
 	}
 }
 
-func (r *SimpleRNN) Parameters() []*tensor.Tensor {
+func (r *RNNCell) Parameters() []*tensor.Tensor {
 	params := []*tensor.Tensor{r.weightIH, r.weightHH}
 	if r.biasIH != nil {
 		params = append(params, r.biasIH, r.biasHH)
@@ -147,81 +133,145 @@ func (r *SimpleRNN) Parameters() []*tensor.Tensor {
 	return params
 }
 
-func (r *SimpleRNN) ZeroGrad() {
+func (r *RNNCell) ZeroGrad() {
 	for _, p := range r.Parameters() {
 		p.ZeroGrad()
 	}
 }
 
-func (r *SimpleRNN) WeightIH() *tensor.Tensor {
-	return r.weightIH
+func (r *RNNCell) NamedParameters() []NamedParameter {
+	return r.namedParameters("")
 }
 
-func (r *SimpleRNN) WeightHH() *tensor.Tensor {
-	return r.weightHH
+func (r *RNNCell) StateDict(prefix string, state map[string]*tensor.Tensor) {
+	namedStateDict(prefix, r.NamedParameters(), state)
 }
 
-func (r *SimpleRNN) BiasIH() *tensor.Tensor {
-	return r.biasIH
+func (r *RNNCell) LoadState(prefix string, state map[string]*tensor.Tensor) error {
+	return loadNamedState("RNNCell", prefix, r.NamedParameters(), state)
 }
 
-func (r *SimpleRNN) BiasHH() *tensor.Tensor {
-	return r.biasHH
+func (r *RNNCell) namedParameters(suffix string) []NamedParameter {
+	return namedOrNil(
+		NamedParameter{Name: "weight_ih" + suffix, Param: r.weightIH},
+		NamedParameter{Name: "weight_hh" + suffix, Param: r.weightHH},
+		NamedParameter{Name: "bias_ih" + suffix, Param: r.biasIH},
+		NamedParameter{Name: "bias_hh" + suffix, Param: r.biasHH},
+	)
 }
 
-func (r *SimpleRNN) StateDict(prefix string, state map[string]*tensor.Tensor) {
-	if state == nil {
-		return
+// SimpleRNN is an Elman network (tanh or relu) with optional stacking,
+// bidirectionality, inter-layer dropout and batch-first layout.
+type SimpleRNN struct {
+	recurrentLayout
+	cells []*RNNCell
+}
+
+func NewSimpleRNN(inputSize, hiddenSize int, nonlinearity string, withBias bool, opts ...InitOption) *SimpleRNN {
+	return NewSimpleRNNWithConfig(inputSize, hiddenSize, nonlinearity, withBias, RecurrentConfig{}, opts...)
+}
+
+func NewSimpleRNNWithConfig(inputSize, hiddenSize int, nonlinearity string, withBias bool, cfg RecurrentConfig, opts ...InitOption) *SimpleRNN {
+	r := &SimpleRNN{recurrentLayout: newRecurrentLayout("SimpleRNN", inputSize, hiddenSize, cfg)}
+	for cell := 0; cell < r.numCells(); cell++ {
+		r.cells = append(r.cells, NewRNNCell(r.cellInputSize(cell/r.directions()), hiddenSize, nonlinearity, withBias, opts...))
 	}
-	state[joinPrefix(prefix, "weight_ih")] = r.weightIH.Clone()
-	state[joinPrefix(prefix, "weight_hh")] = r.weightHH.Clone()
-	if r.biasIH != nil {
-		state[joinPrefix(prefix, "bias_ih")] = r.biasIH.Clone()
+	return r
+}
+
+func (r *SimpleRNN) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	out, _, err := r.ForwardWithState(input, nil)
+	return out, err
+}
+
+// ForwardMulti runs the RNN on (input[, h0]) and returns [output, hN].
+func (r *SimpleRNN) ForwardMulti(inputs ...*tensor.Tensor) ([]*tensor.Tensor, error) {
+	if len(inputs) < 1 || len(inputs) > 2 {
+		return nil, fmt.Errorf("SimpleRNN expects 1 or 2 inputs, got %d", len(inputs))
 	}
-	if r.biasHH != nil {
-		state[joinPrefix(prefix, "bias_hh")] = r.biasHH.Clone()
+	var hx *tensor.Tensor
+	if len(inputs) > 1 {
+		hx = inputs[1]
+	}
+	out, h, err := r.ForwardWithState(inputs[0], hx)
+	if err != nil {
+		return nil, err
 	}
+	return []*tensor.Tensor{out, h}, nil
 }
 
-func (r *SimpleRNN) LoadState(prefix string, state map[string]*tensor.Tensor) error {
-	if state == nil {
-		return fmt.Errorf("state dict is nil")
-	}
-	wIHKey := joinPrefix(prefix, "weight_ih")
-	wIH, ok := state[wIHKey]
-	if !ok {
-		return fmt.Errorf("SimpleRNN missing %s", wIHKey)
+// ForwardWithState runs the RNN from the initial hidden state (nil for
+// zeros). States follow the layout described on LSTM.ForwardWithState.
+func (r *SimpleRNN) ForwardWithState(input *tensor.Tensor, hx *tensor.Tensor) (*tensor.Tensor, *tensor.Tensor, error) {
+	out, states, err := r.run(input, nil, []*tensor.Tensor{hx}, r.stepwise(r.step))
+	if err != nil {
+		return nil, nil, err
 	}
-	if err := tensor.CopyInto(r.weightIH, wIH); err != nil {
-		return fmt.Errorf("load %s: %w", wIHKey, err)
+	return out, states[0], nil
+}
+
+// ForwardPacked runs the RNN over variable-length sequences; see
+// LSTM.ForwardPacked.
+func (r *SimpleRNN) ForwardPacked(input *PackedSequence, hx *tensor.Tensor) (*PackedSequence, *tensor.Tensor, error) {
+	out, states, err := r.runPacked(input, []*tensor.Tensor{hx}, r.stepwise(r.step))
+	if err != nil {
+		return nil, nil, err
 	}
-	wHHKey := joinPrefix(prefix, "weight_hh")
-	wHH, ok := state[wHHKey]
-	if !ok {
-		return fmt.Errorf("SimpleRNN missing %s", wHHKey)
+	return out, states[0], nil
+}
+
+func (r *SimpleRNN) step(cell int, x *tensor.Tensor, state []*tensor.Tensor) ([]*tensor.Tensor, error) {
+	h, err := r.cells[cell].step(x, state[0])
+	if err != nil {
+		return nil, err
 	}
-	if err := tensor.CopyInto(r.weightHH, wHH); err != nil {
-		return fmt.Errorf("load %s: %w", wHHKey, err)
+	return []*tensor.Tensor{h}, nil
+}
+
+func (r *SimpleRNN) Parameters() []*tensor.Tensor {
+	var params []*tensor.Tensor
+	for _, cell := range r.cells {
+		params = append(params, cell.Parameters()...)
 	}
-	if r.biasIH != nil {
-		bIHKey := joinPrefix(prefix, "bias_ih")
-		bIH, ok := state[bIHKey]
-		if !ok {
-			return fmt.Errorf("SimpleRNN missing %s", bIHKey)
-		}
-		if err := tensor.CopyInto(r.biasIH, bIH); err != nil {
-			return fmt.Errorf("load %s: %w", bIHKey, err)
-		}
+	return params
+}
+
+func (r *SimpleRNN) ZeroGrad() {
+	for _, p := range r.Parameters() {
+		p.ZeroGrad()
 	}
-	if r.biasHH != nil {
-		bHHKey := joinPrefix(prefix, "bias_hh")
-		bHH, ok := state[bHHKey]
-		if !ok {
-			return fmt.Errorf("SimpleRNN missing %s", bHHKey)
-		}
-		if err := tensor.CopyInto(r.biasHH, bHH); err != nil {
-			return fmt.Errorf("load %s: %w", bHHKey, err)
-		}
+}
+
+// WeightIH, WeightHH, BiasIH and BiasHH return the parameters of the first
+// layer's forward direction.
+func (r *SimpleRNN) WeightIH() *tensor.Tensor {
+	return r.cells[0].weightIH
+}
+
+func (r *SimpleRNN) WeightHH() *tensor.Tensor {
+	return r.cells[0].weightHH
+}
+
+func (r *SimpleRNN) BiasIH() *tensor.Tensor {
+	return r.cells[0].biasIH
+}
+
+func (r *SimpleRNN) BiasHH() *tensor.Tensor {
+	return r.cells[0].biasHH
+}
+
+func (r *SimpleRNN) NamedParameters() []NamedParameter {
+	var named []NamedParameter
+	for idx, cell := range r.cells {
+		named = append(named, cell.namedParameters(r.cellSuffix(idx))...)
 	}
-	return nil
+	return named
+}
+
+func (r *SimpleRNN) StateDict(prefix string, state map[string]*tensor.Tensor) {
+	namedStateDict(prefix, r.NamedParameters(), state)
+}
+
+func (r *SimpleRNN) LoadState(prefix string, state map[string]*tensor.Tensor) error {
+	return loadNamedState("SimpleRNN", prefix, r.NamedParameters(), state)
 }
@@ -7,13 +7,33 @@ import (
 )
 
 type Sequential struct {
-	modules []Module
+	modules  []Module
+	names    []string
+	training bool
 }
 
 func NewSequential(mods ...Module) *Sequential {
 	copyMods := make([]Module, len(mods))
 	copy(copyMods, mods)
-	return &Sequential{modules: copyMods}
+	return &Sequential{modules: copyMods, training: true}
+}
+
+// NewNamedSequential chains modules like NewSequential but names each child,
+// so StateDict keys read "<name>.weight" instead of "<index>.weight".
+func NewNamedSequential(mods ...NamedModule) (*Sequential, error) {
+	s := &Sequential{training: true}
+	seen := make(map[string]bool, len(mods))
+	for _, nm := range mods {
+		if err := validChildName(nm.Name, seen); err != nil {
+			return nil, err
+		}
+		if nm.Module == nil {
+			return nil, fmt.Errorf("Sequential entry %q: nil module", nm.Name)
+		}
+		s.modules = append(s.modules, nm.Module)
+		s.names = append(s.names, nm.Name)
+	}
+	return s, nil
 }
 
 func (s *Sequential) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
@@ -42,29 +62,52 @@ func (s *Sequential) ZeroGrad() {
 	}
 }
 
-func (s *Sequential) StateDict(prefix string, state map[string]*tensor.Tensor) {
+func (s *Sequential) Children() []Module {
+	children := make([]Module, len(s.modules))
+	copy(children, s.modules)
+	return children
+}
+
+func (s *Sequential) NamedChildren() []NamedModule {
+	named := make([]NamedModule, len(s.modules))
 	for idx, mod := range s.modules {
-		childPrefix := joinPrefix(prefix, fmt.Sprintf("%d", idx))
-		if sm, ok := mod.(StatefulModule); ok {
-			sm.StateDict(childPrefix, state)
-		} else if len(mod.Parameters()) > 0 {
-			captureParameters(childPrefix, mod, state)
+		name := fmt.Sprintf("%d", idx)
+		if s.names != nil {
+			name = s.names[idx]
 		}
+		named[idx] = NamedModule{Name: name, Module: mod}
 	}
+	return named
 }
 
-func (s *Sequential) LoadState(prefix string, state map[string]*tensor.Tensor) error {
-	for idx, mod := range s.modules {
-		childPrefix := joinPrefix(prefix, fmt.Sprintf("%d", idx))
-		if sm, ok := mod.(StatefulModule); ok {
-			if err := sm.LoadState(childPrefix, state); err != nil {
-				return err
-			}
-		} else if len(mod.Parameters()) > 0 {
-			if err := loadParameters(childPrefix, mod, state); err != nil {
-				return err
-			}
-		}
+func (s *Sequential) NamedParameters() []NamedParameter {
+	return childrenNamedParameters(s.NamedChildren())
+}
+
+// Train puts the container and every submodule into training mode.
+func (s *Sequential) Train() {
+	s.training = true
+	for _, m := range s.modules {
+		SetTraining(m, true)
 	}
-	return nil
+}
+
+// Eval puts the container and every submodule into evaluation mode.
+func (s *Sequential) Eval() {
+	s.training = false
+	for _, m := range s.modules {
+		SetTraining(m, false)
+	}
+}
+
+func (s *Sequential) IsTraining() bool {
+	return s.training
+}
+
+func (s *Sequential) StateDict(prefix string, state map[string]*tensor.Tensor) {
+	childrenStateDict(prefix, s.NamedChildren(), state)
+}
+
+func (s *Sequential) LoadState(prefix string, state map[string]*tensor.Tensor) error {
+	return childrenLoadState(prefix, s.NamedChildren(), state)
 }
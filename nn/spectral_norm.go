@@ -0,0 +1,176 @@
+package nn
+
+import (
+	"math"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// SpectralNormWrapper divides the weight of a module by an estimate of its
+// largest singular value, keeping the layer roughly 1-Lipschitz. The
+// estimate comes from power iteration on persistent singular-vector
+// buffers, refined during training only.
+type SpectralNormWrapper struct {
+	reparameterization
+	nIter    int
+	eps      float64
+	training bool
+	orig     *tensor.Tensor
+	u        *tensor.Tensor
+	v        *tensor.Tensor
+}
+
+// SpectralNorm wraps the weight of a Linear, convolution or Embedding
+// module, viewed as a [shape[0], rest] matrix. nIter power-iteration steps
+// (at least 1) run on every training forward pass. The state dict stores
+// weight_orig and the weight_u and weight_v buffers instead of weight.
+func SpectralNorm(mod Module, nIter int) (*SpectralNormWrapper, error) {
+	r, err := newReparameterization("SpectralNorm", mod, "weight")
+	if err != nil {
+		return nil, err
+	}
+	if nIter <= 0 {
+		nIter = 1
+	}
+	orig := *r.slot
+	rows := orig.Shape()[0]
+	cols := orig.Numel() / rows
+	s := &SpectralNormWrapper{
+		reparameterization: r,
+		nIter:              nIter,
+		eps:                1e-12,
+		training:           true,
+		orig:               orig,
+		u:                  tensor.MustNew(normalized(tensor.Randn(rows).Data(), 1e-12), rows),
+		v:                  tensor.MustNew(normalized(tensor.Randn(cols).Data(), 1e-12), cols),
+	}
+	// start from a settled estimate so evaluation before training is sound
+	s.powerIteration(15)
+	computed, err := s.compute()
+	if err != nil {
+		return nil, err
+	}
+	*r.slot = computed.Detach()
+	return s, nil
+}
+
+func normalized(x []float64, eps float64) []float64 {
+	sq := 0.0
+	for _, v := range x {
+		sq += v * v
+	}
+	norm := math.Max(math.Sqrt(sq), eps)
+	for i := range x {
+		x[i] /= norm
+	}
+	return x
+}
+
+// powerIteration refines u and v towards the leading singular vectors of
+// the weight matrix: v ← Wᵀu/‖Wᵀu‖, u ← Wv/‖Wv‖.
+func (s *SpectralNormWrapper) powerIteration(steps int) {
+	w := s.orig.Data()
+	u, v := s.u.Data(), s.v.Data()
+	rows, cols := len(u), len(v)
+	for step := 0; step < steps; step++ {
+		for j := range v {
+			v[j] = 0
+		}
+		for i := 0; i < rows; i++ {
+			for j := 0; j < cols; j++ {
+				v[j] += w[i*cols+j] * u[i]
+			}
+		}
+		normalized(v, s.eps)
+		for i := 0; i < rows; i++ {
+			sum := 0.0
+			for j := 0; j < cols; j++ {
+				sum += w[i*cols+j] * v[j]
+			}
+			u[i] = sum
+		}
+		normalized(u, s.eps)
+	}
+	_ = s.u.SetData(u)
+	_ = s.v.SetData(v)
+}
+
+func (s *SpectralNormWrapper) compute() (*tensor.Tensor, error) {
+	return tensor.SpectralNorm(s.orig, s.u, s.v)
+}
+
+func (s *SpectralNormWrapper) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	if s.training {
+		s.powerIteration(s.nIter)
+	}
+	computed, err := s.compute()
+	if err != nil {
+		return nil, err
+	}
+	*s.slot = computed
+	return s.module.Forward(input)
+}
+
+func (s *SpectralNormWrapper) replacement() []NamedParameter {
+	return []NamedParameter{{Name: s.name + "_orig", Param: s.orig}}
+}
+
+func (s *SpectralNormWrapper) stateTensors() []NamedParameter {
+	return append(s.replacement(),
+		NamedParameter{Name: s.name + "_u", Param: s.u},
+		NamedParameter{Name: s.name + "_v", Param: s.v},
+	)
+}
+
+func (s *SpectralNormWrapper) Parameters() []*tensor.Tensor {
+	return s.parameters(s.replacement())
+}
+
+func (s *SpectralNormWrapper) ZeroGrad() {
+	for _, p := range s.Parameters() {
+		p.ZeroGrad()
+	}
+}
+
+func (s *SpectralNormWrapper) NamedParameters() []NamedParameter {
+	return s.namedParameters(s.replacement())
+}
+
+func (s *SpectralNormWrapper) StateDict(prefix string, state map[string]*tensor.Tensor) {
+	s.stateDict(prefix, state, s.stateTensors())
+}
+
+func (s *SpectralNormWrapper) LoadState(prefix string, state map[string]*tensor.Tensor) error {
+	return s.loadState("SpectralNorm", prefix, state, s.stateTensors(), s.compute)
+}
+
+func (s *SpectralNormWrapper) Train() {
+	s.training = true
+	SetTraining(s.module, true)
+}
+
+func (s *SpectralNormWrapper) Eval() {
+	s.training = false
+	SetTraining(s.module, false)
+}
+
+func (s *SpectralNormWrapper) IsTraining() bool {
+	return s.training
+}
+
+// Sigma returns the current spectral-norm estimate uᵀ·W·v.
+func (s *SpectralNormWrapper) Sigma() float64 {
+	w, u, v := s.orig.Data(), s.u.Data(), s.v.Data()
+	sigma := 0.0
+	for i := range u {
+		for j := range v {
+			sigma += u[i] * w[i*len(v)+j] * v[j]
+		}
+	}
+	return sigma
+}
+
+// Remove bakes W/σ into the wrapped module and returns it.
+func (s *SpectralNormWrapper) Remove() (Module, error) {
+	return s.remove(s.compute, s.orig.RequiresGrad())
+}
@@ -0,0 +1,504 @@
+package nn
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// bytesPerElement is the storage size of one tensor element (float64).
+const bytesPerElement = 8
+
+// SummaryRow describes one module of a model summary.
+type SummaryRow struct {
+	// Name is the dotted path from the summarised module ("" for itself).
+	Name  string
+	Type  string
+	Depth int
+	// OutputShapes holds one shape per output, or nil when the input of the
+	// module could not be inferred from its parent.
+	OutputShapes [][]int
+	// Params and TrainableParams count elements, including submodules.
+	Params          int
+	TrainableParams int
+	// MultAdds estimates the multiply-accumulate operations of the module
+	// itself, excluding those of rows below it.
+	MultAdds int64
+	// OutputBytes is the storage of the outputs.
+	OutputBytes int64
+}
+
+// FrozenParams counts the parameters that do not require gradients.
+func (r SummaryRow) FrozenParams() int {
+	return r.Params - r.TrainableParams
+}
+
+// ModelSummary is the result of a dry forward pass through a model.
+type ModelSummary struct {
+	Rows            []SummaryRow
+	InputShapes     [][]int
+	TotalParams     int
+	TrainableParams int
+	TotalMultAdds   int64
+	// InputBytes, ActivationBytes (outputs of every leaf module, kept for
+	// the backward pass, counted twice for their gradients) and ParamBytes
+	// make up the estimated memory of one training step.
+	InputBytes      int64
+	ActivationBytes int64
+	ParamBytes      int64
+}
+
+// FrozenParams counts the model parameters that do not require gradients.
+func (s *ModelSummary) FrozenParams() int {
+	return s.TotalParams - s.TrainableParams
+}
+
+// TotalBytes estimates the memory of one training step.
+func (s *ModelSummary) TotalBytes() int64 {
+	return s.InputBytes + s.ActivationBytes + s.ParamBytes
+}
+
+// Summary runs a dry forward pass of mod on a zero tensor of inputShape and
+// reports every submodule. Zeros are valid indices, so embedding models can
+// be summarised too. The model runs in evaluation mode with embedding
+// max-norm renormalisation off, so its weights are left untouched; both are
+// restored afterwards.
+func Summary(mod Module, inputShape ...int) (*ModelSummary, error) {
+	return SummaryMulti(mod, inputShape)
+}
+
+// SummaryMulti is Summary for modules taking several inputs through
+// ForwardMulti, such as a Transformer given (src, tgt).
+func SummaryMulti(mod Module, inputShapes ...[]int) (*ModelSummary, error) {
+	if mod == nil {
+		return nil, fmt.Errorf("Summary requires a module")
+	}
+	if len(inputShapes) == 0 {
+		return nil, fmt.Errorf("Summary requires an input shape")
+	}
+	inputs := make([]*tensor.Tensor, len(inputShapes))
+	s := &ModelSummary{}
+	for i, shape := range inputShapes {
+		if len(shape) == 0 {
+			return nil, fmt.Errorf("Summary input %d has no shape", i)
+		}
+		inputs[i] = tensor.Zeros(shape...)
+		s.InputShapes = append(s.InputShapes, append([]int(nil), shape...))
+		s.InputBytes += int64(inputs[i].Numel()) * bytesPerElement
+	}
+
+	restore := prepareSummary(mod)
+	defer restore()
+	if _, err := s.trace("", 0, mod, inputs); err != nil {
+		return nil, err
+	}
+
+	root := s.Rows[0]
+	s.TotalParams = root.Params
+	s.TrainableParams = root.TrainableParams
+	s.ParamBytes = int64(root.Params) * bytesPerElement
+	for i, row := range s.Rows {
+		s.TotalMultAdds += row.MultAdds
+		leaf := i+1 == len(s.Rows) || s.Rows[i+1].Depth <= row.Depth
+		if leaf {
+			s.ActivationBytes += 2 * row.OutputBytes
+		}
+	}
+	return s, nil
+}
+
+// prepareSummary switches mod to evaluation mode, so dropout is inactive
+// and normalisation layers keep their running statistics, and turns off the
+// in-place max-norm renormalisation of embeddings. It returns a function
+// restoring the previous mode and max norm of every module.
+func prepareSummary(mod Module) func() {
+	type mode struct {
+		mod      Module
+		training bool
+	}
+	var modes []mode
+	maxNorms := map[*Embedding]float64{}
+	_ = Walk(mod, func(_ string, m Module) error {
+		if t, ok := m.(Trainable); ok {
+			modes = append(modes, mode{m, t.IsTraining()})
+		}
+		var emb *Embedding
+		switch e := m.(type) {
+		case *Embedding:
+			emb = e
+		case *EmbeddingBag:
+			emb = e.Embedding
+		}
+		if _, seen := maxNorms[emb]; emb != nil && !seen {
+			maxNorms[emb] = emb.maxNorm
+			emb.maxNorm = 0
+		}
+		return nil
+	})
+	SetTraining(mod, false)
+	return func() {
+		// parents first, so children can override what their parent set
+		for _, m := range modes {
+			SetTraining(m.mod, m.training)
+		}
+		for emb, maxNorm := range maxNorms {
+			emb.maxNorm = maxNorm
+		}
+	}
+}
+
+// trace adds the row of mod and of its descendants. inputs is nil when the
+// input of mod is unknown. Only a failure of the top-level module is
+// returned; submodules that cannot run are reported without shapes.
+func (s *ModelSummary) trace(name string, depth int, mod Module, inputs []*tensor.Tensor) ([]*tensor.Tensor, error) {
+	idx := len(s.Rows)
+	row := SummaryRow{Name: name, Type: moduleTypeName(mod), Depth: depth}
+	for _, np := range NamedParameters(mod) {
+		row.Params += np.Param.Numel()
+		if np.Param.RequiresGrad() {
+			row.TrainableParams += np.Param.Numel()
+		}
+	}
+	s.Rows = append(s.Rows, row)
+
+	var outputs []*tensor.Tensor
+	var err error
+	chain := isSummaryChain(mod)
+	if inputs != nil && !chain {
+		outputs, err = summaryForward(mod, inputs)
+		if err != nil {
+			if depth == 0 {
+				return nil, err
+			}
+			inputs, outputs = nil, nil
+		}
+	}
+
+	traced := map[string][]*tensor.Tensor{}
+	chainOut := inputs
+	for _, child := range NamedChildren(mod) {
+		var childInputs []*tensor.Tensor
+		if chain {
+			childInputs = chainOut
+		} else if inputs != nil {
+			childInputs = summaryChildInputs(mod, child.Name, inputs, traced)
+		}
+		out, _ := s.trace(joinPrefix(name, child.Name), depth+1, child.Module, childInputs)
+		traced[child.Name] = out
+		if chain {
+			chainOut = chainInputs(out, chainOut)
+		}
+	}
+	if chain && chainOut != nil {
+		outputs = chainOut[:1]
+	}
+	if chain && outputs == nil && depth == 0 && inputs != nil {
+		return nil, fmt.Errorf("Summary: %s could not run on the given input", row.Type)
+	}
+
+	if outputs != nil {
+		row := &s.Rows[idx]
+		for _, out := range outputs {
+			row.OutputShapes = append(row.OutputShapes, out.Shape())
+			row.OutputBytes += int64(out.Numel()) * bytesPerElement
+		}
+		row.MultAdds = layerMultAdds(mod, inputs, outputs)
+	}
+	return outputs, nil
+}
+
+// isSummaryChain reports containers whose children run one after another.
+// A ModuleList has no Forward but holds sequential stacks such as the layers
+// of a TransformerEncoder.
+func isSummaryChain(mod Module) bool {
+	switch mod.(type) {
+	case *Sequential, *ModuleList:
+		return true
+	}
+	return false
+}
+
+// chainInputs feeds the outputs of one chained module to the next, keeping
+// extra inputs such as a decoder's memory.
+func chainInputs(outputs, inputs []*tensor.Tensor) []*tensor.Tensor {
+	if outputs == nil || inputs == nil {
+		return nil
+	}
+	next := []*tensor.Tensor{outputs[0]}
+	return append(next, inputs[1:]...)
+}
+
+func summaryForward(mod Module, inputs []*tensor.Tensor) ([]*tensor.Tensor, error) {
+	if len(inputs) == 1 {
+		out, err := mod.Forward(inputs[0])
+		if err == nil {
+			return []*tensor.Tensor{out}, nil
+		}
+		if _, ok := mod.(MultiModule); !ok {
+			return nil, err
+		}
+	}
+	return AsMulti(mod).ForwardMulti(inputs...)
+}
+
+// summaryChildInputs infers the input of a child of a built-in composite
+// module from the inputs of the parent and the outputs of the children
+// traced before it. It returns nil for children of unknown modules.
+func summaryChildInputs(mod Module, child string, inputs []*tensor.Tensor, traced map[string][]*tensor.Tensor) []*tensor.Tensor {
+	first := inputs[:1]
+	switch mod.(type) {
+	case *MultiheadAttention:
+		// projections see the tokens flattened to [batch*len, embedDim]
+		if (child == "k_proj" || child == "v_proj") && len(inputs) > 1 {
+			return summaryTokens(inputs[1])
+		}
+		return summaryTokens(inputs[0])
+	case *Attention:
+		return first
+	case *TransformerEncoderLayer, *TransformerDecoderLayer:
+		switch child {
+		case "linear1":
+			return summaryTokens(inputs[0])
+		case "dropout", "linear2":
+			return traced["linear1"]
+		case "multihead_attn":
+			return inputs
+		}
+		return first
+	case *TransformerEncoder, *TransformerDecoder:
+		if child == "norm" {
+			return firstOutput(traced["layers"])
+		}
+		return inputs
+	case *Transformer:
+		if len(inputs) < 2 {
+			return nil
+		}
+		if child == "encoder" {
+			return first
+		}
+		memory := firstOutput(traced["encoder"])
+		if memory == nil {
+			return nil
+		}
+		return []*tensor.Tensor{inputs[1], memory[0]}
+	}
+	return nil
+}
+
+// summaryTokens reshapes [..., features] to [tokens, features].
+func summaryTokens(x *tensor.Tensor) []*tensor.Tensor {
+	shape := x.Shape()
+	features := shape[len(shape)-1]
+	flat, err := x.Reshape(x.Numel()/features, features)
+	if err != nil {
+		return nil
+	}
+	return []*tensor.Tensor{flat}
+}
+
+func firstOutput(outputs []*tensor.Tensor) []*tensor.Tensor {
+	if len(outputs) == 0 {
+		return nil
+	}
+	return outputs[:1]
+}
+
+// layerMultAdds estimates the multiply-accumulates of mod itself; work done
+// by submodules is counted on their own rows.
+func layerMultAdds(mod Module, inputs, outputs []*tensor.Tensor) int64 {
+	in, out := inputs[0], outputs[0]
+	switch m := mod.(type) {
+	case *Linear:
+		return int64(out.Numel()) * int64(m.inFeatures)
+	case *Conv1d, *Conv2d, *Conv3d:
+		w := *m.(reparameterizable).parameterSlot("weight")
+		return int64(out.Numel()) * int64(w.Numel()/w.Shape()[0])
+	case *ConvTranspose1d, *ConvTranspose2d, *ConvTranspose3d:
+		w := *m.(reparameterizable).parameterSlot("weight")
+		return int64(in.Numel()) * int64(w.Numel()/w.Shape()[0])
+	case *BatchNorm, *LayerNorm, *GroupNorm, *InstanceNorm, *RMSNorm, *PReLU:
+		if len(mod.Parameters()) == 0 {
+			return 0
+		}
+		return int64(out.Numel())
+	case *EmbeddingBag:
+		return int64(in.Numel()) * int64(m.embeddingDim)
+	case *LSTMCell:
+		return recurrentMultAdds(in.Shape()[0], 4, m.inputSize, m.hiddenSize)
+	case *GRUCell:
+		return recurrentMultAdds(in.Shape()[0], 3, m.inputSize, m.hiddenSize)
+	case *RNNCell:
+		return recurrentMultAdds(in.Shape()[0], 1, m.inputSize, m.hiddenSize)
+	case *LSTM:
+		steps := recurrentSteps(in)
+		var total int64
+		for _, c := range m.cells {
+			total += recurrentMultAdds(steps, 4, c.inputSize, c.hiddenSize)
+		}
+		return total
+	case *GRU:
+		steps := recurrentSteps(in)
+		var total int64
+		for _, c := range m.cells {
+			total += recurrentMultAdds(steps, 3, c.inputSize, c.hiddenSize)
+		}
+		return total
+	case *SimpleRNN:
+		steps := recurrentSteps(in)
+		var total int64
+		for _, c := range m.cells {
+			total += recurrentMultAdds(steps, 1, c.inputSize, c.hiddenSize)
+		}
+		return total
+	case *MultiheadAttention:
+		// scores Q·Kᵀ and the weighted sum of V; projections are children
+		keyLen := in.Shape()[1]
+		if len(inputs) > 1 {
+			keyLen = inputs[1].Shape()[1]
+		}
+		return 2 * int64(out.Numel()) * int64(keyLen)
+	case *Attention:
+		shape := in.Shape()
+		tokens := int64(shape[0] * shape[1])
+		return 4*tokens*int64(m.dim*m.dim) + 2*tokens*int64(shape[1]*m.dim)
+	case *WeightNormWrapper:
+		return layerMultAdds(m.module, inputs, outputs)
+	case *SpectralNormWrapper:
+		return layerMultAdds(m.module, inputs, outputs)
+	case *LoRAWrapper:
+		total := layerMultAdds(m.module, inputs, outputs)
+		if mha, ok := m.module.(*MultiheadAttention); ok {
+			// the projections run inside the wrapper, not as rows of their own
+			total += 4 * int64(in.Numel()/mha.embedDim) * int64(mha.embedDim*mha.embedDim)
+		}
+		for _, ad := range m.adapters {
+			total += int64(ad.b.Numel()) * int64(ad.a.Shape()[1])
+		}
+		return total
+	}
+	return 0
+}
+
+// recurrentSteps returns seq·batch for a [seq, batch, features] or
+// [batch, seq, features] input.
+func recurrentSteps(in *tensor.Tensor) int {
+	shape := in.Shape()
+	if len(shape) < 2 {
+		return 1
+	}
+	return shape[0] * shape[1]
+}
+
+func recurrentMultAdds(steps, gates, inputSize, hiddenSize int) int64 {
+	return int64(steps) * int64(gates*hiddenSize*(inputSize+hiddenSize))
+}
+
+func moduleTypeName(mod Module) string {
+	name := fmt.Sprintf("%T", mod)
+	name = strings.TrimPrefix(name, "*")
+	if dot := strings.LastIndex(name, "."); dot >= 0 {
+		name = name[dot+1:]
+	}
+	return name
+}
+
+// String renders the summary as a table followed by totals.
+func (s *ModelSummary) String() string {
+	headers := []string{"Layer (type)", "Output Shape", "Param #", "Trainable", "Mult-Adds", "Memory"}
+	cells := make([][]string, len(s.Rows))
+	for i, row := range s.Rows {
+		label := row.Type
+		if row.Name != "" {
+			label = row.Name[strings.LastIndex(row.Name, ".")+1:] + " (" + row.Type + ")"
+		}
+		shape, memory := "--", "--"
+		if row.OutputShapes != nil {
+			parts := make([]string, len(row.OutputShapes))
+			for j, sh := range row.OutputShapes {
+				parts[j] = fmt.Sprint(sh)
+			}
+			shape = strings.Join(parts, ", ")
+			memory = formatBytes(row.OutputBytes)
+		}
+		cells[i] = []string{
+			strings.Repeat("  ", row.Depth) + label,
+			shape,
+			formatCount(int64(row.Params)),
+			formatCount(int64(row.TrainableParams)),
+			formatCount(row.MultAdds),
+			memory,
+		}
+	}
+	widths := make([]int, len(headers))
+	for j, h := range headers {
+		widths[j] = len(h)
+		for _, c := range cells {
+			if len(c[j]) > widths[j] {
+				widths[j] = len(c[j])
+			}
+		}
+	}
+	total := len(widths) - 1
+	for _, w := range widths {
+		total += w + 1
+	}
+	rule := strings.Repeat("=", total) + "\n"
+	var b strings.Builder
+	writeRow := func(c []string) {
+		for j, v := range c {
+			if j == 0 {
+				fmt.Fprintf(&b, "%-*s", widths[j], v)
+			} else {
+				fmt.Fprintf(&b, "  %*s", widths[j], v)
+			}
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(rule)
+	writeRow(headers)
+	b.WriteString(rule)
+	for _, c := range cells {
+		writeRow(c)
+	}
+	b.WriteString(rule)
+	fmt.Fprintf(&b, "Total params: %s\n", formatCount(int64(s.TotalParams)))
+	fmt.Fprintf(&b, "Trainable params: %s\n", formatCount(int64(s.TrainableParams)))
+	fmt.Fprintf(&b, "Frozen params: %s\n", formatCount(int64(s.FrozenParams())))
+	fmt.Fprintf(&b, "Total mult-adds: %s\n", formatCount(s.TotalMultAdds))
+	b.WriteString(rule)
+	fmt.Fprintf(&b, "Input size: %s\n", formatBytes(s.InputBytes))
+	fmt.Fprintf(&b, "Forward/backward pass size: %s\n", formatBytes(s.ActivationBytes))
+	fmt.Fprintf(&b, "Params size: %s\n", formatBytes(s.ParamBytes))
+	fmt.Fprintf(&b, "Estimated total size: %s\n", formatBytes(s.TotalBytes()))
+	b.WriteString(rule)
+	return b.String()
+}
+
+// formatCount groups digits in thousands: 1234567 -> "1,234,567".
+func formatCount(n int64) string {
+	digits := fmt.Sprint(n)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
+	return b.String()
+}
+
+func formatBytes(n int64) string {
+	units := []string{"B", "KB", "MB", "GB"}
+	value := float64(n)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d B", n)
+	}
+	return fmt.Sprintf("%.2f %s", value, units[unit])
+}
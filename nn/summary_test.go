@@ -0,0 +1,143 @@
+package nn
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+func summaryRow(t *testing.T, s *ModelSummary, name string) SummaryRow {
+	t.Helper()
+	for _, row := range s.Rows {
+		if row.Name == name {
+			return row
+		}
+	}
+	t.Fatalf("summary has no row %q", name)
+	return SummaryRow{}
+}
+
+func TestSummarySequential(t *testing.T) {
+	first := NewLinear(4, 8, true)
+	model := NewSequential(first, Relu(), NewLinear(8, 2, false))
+	Freeze(first)
+	s, err := Summary(model, 3, 4)
+	if err != nil {
+		t.Fatalf("Summary failed: %v", err)
+	}
+	if len(s.Rows) != 4 {
+		t.Fatalf("expected 4 rows, got %d", len(s.Rows))
+	}
+	root := s.Rows[0]
+	if root.Type != "Sequential" || !equalShape(root.OutputShapes[0], []int{3, 2}) {
+		t.Fatalf("unexpected root row %+v", root)
+	}
+	hidden := summaryRow(t, s, "0")
+	if hidden.Type != "Linear" || hidden.Depth != 1 || !equalShape(hidden.OutputShapes[0], []int{3, 8}) {
+		t.Fatalf("unexpected first layer row %+v", hidden)
+	}
+	if hidden.Params != 40 || hidden.FrozenParams() != 40 {
+		t.Fatalf("first layer should have 40 frozen params, got %d/%d", hidden.Params, hidden.FrozenParams())
+	}
+	if s.TotalParams != 56 || s.TrainableParams != 16 || s.FrozenParams() != 40 {
+		t.Fatalf("unexpected totals %d/%d/%d", s.TotalParams, s.TrainableParams, s.FrozenParams())
+	}
+	if s.TotalMultAdds != 3*8*4+3*2*8 {
+		t.Fatalf("unexpected mult-adds %d", s.TotalMultAdds)
+	}
+	if s.ParamBytes != 56*8 || s.InputBytes != 12*8 || s.ActivationBytes != 2*8*(24+24+6) {
+		t.Fatalf("unexpected memory %d/%d/%d", s.ParamBytes, s.InputBytes, s.ActivationBytes)
+	}
+	table := s.String()
+	for _, want := range []string{"Layer (type)", "0 (Linear)", "[3 8]", "Trainable params: 16", "Frozen params: 40", "Total mult-adds: 144"} {
+		if !strings.Contains(table, want) {
+			t.Fatalf("summary table misses %q:\n%s", want, table)
+		}
+	}
+
+	if _, err := Summary(model, 3, 5); err == nil {
+		t.Fatalf("expected an error for a mismatched input")
+	}
+}
+
+func TestSummaryLayers(t *testing.T) {
+	conv := NewSequential(NewConv2d(3, 4, 3, 3, 1, 1, 1, 1, true), NewBatchNorm2d(4, 0.1, 1e-5, true))
+	conv.Train()
+	bn := conv.Children()[1].(*BatchNorm)
+	before := bn.RunningMean().Data()
+	s, err := Summary(conv, 2, 3, 5, 5)
+	if err != nil {
+		t.Fatalf("conv Summary failed: %v", err)
+	}
+	if got := summaryRow(t, s, "0").MultAdds; got != 2*4*5*5*27 {
+		t.Fatalf("unexpected conv mult-adds %d", got)
+	}
+	if !IsTraining(conv) || !floatsAlmostEqual(bn.RunningMean().Data(), before, 0) {
+		t.Fatalf("Summary must not change the mode or running statistics")
+	}
+
+	emb, _ := NewEmbeddingWithConfig(4, 3, EmbeddingConfig{MaxNorm: 0.5})
+	mustSetData(t, emb.Weight(), []float64{3, 0, 4, 1, 1, 1, 0, 2, 0, 1, 0, 0})
+	table := emb.Weight().Data()
+	bag, _ := NewEmbeddingBag(4, 3, "sum", EmbeddingConfig{MaxNorm: 0.5})
+	mustSetData(t, bag.Weight(), table)
+	if _, err := Summary(NewSequential(emb), 2, 2); err != nil {
+		t.Fatalf("embedding Summary failed: %v", err)
+	}
+	if _, err := Summary(bag, 2, 2); err != nil {
+		t.Fatalf("embedding bag Summary failed: %v", err)
+	}
+	if !floatsAlmostEqual(emb.Weight().Data(), table, 0) || !floatsAlmostEqual(bag.Weight().Data(), table, 0) {
+		t.Fatalf("Summary must not renormalise embedding rows")
+	}
+	if out, _ := emb.Forward(tensor.MustNew([]float64{0}, 1)); !floatsAlmostEqual(out.Data(), []float64{0.3, 0, 0.4}, 1e-6) {
+		t.Fatalf("max norm must be restored after Summary: %v", out.Data())
+	}
+
+	lstm := NewLSTM(3, 5, true)
+	s, err = Summary(lstm, 6, 2, 3)
+	if err != nil {
+		t.Fatalf("LSTM Summary failed: %v", err)
+	}
+	if s.TotalMultAdds != 6*2*4*5*(3+5) {
+		t.Fatalf("unexpected LSTM mult-adds %d", s.TotalMultAdds)
+	}
+
+	cfg := TransformerConfig{DModel: 8, NumHeads: 2, DimFeedforward: 16}
+	enc, err := NewTransformerEncoder(cfg, 2, true)
+	if err != nil {
+		t.Fatalf("NewTransformerEncoder failed: %v", err)
+	}
+	s, err = Summary(enc, 2, 5, 8)
+	if err != nil {
+		t.Fatalf("encoder Summary failed: %v", err)
+	}
+	if q := summaryRow(t, s, "layers.1.self_attn.q_proj"); !equalShape(q.OutputShapes[0], []int{10, 8}) || q.MultAdds != 10*8*8 {
+		t.Fatalf("unexpected projection row %+v", q)
+	}
+	if attn := summaryRow(t, s, "layers.0.self_attn"); attn.MultAdds != 2*2*5*5*8 {
+		t.Fatalf("unexpected attention mult-adds %d", attn.MultAdds)
+	}
+	if ff := summaryRow(t, s, "layers.0.linear2"); !equalShape(ff.OutputShapes[0], []int{10, 8}) {
+		t.Fatalf("unexpected feed-forward row %+v", ff)
+	}
+	if norm := summaryRow(t, s, "norm"); !equalShape(norm.OutputShapes[0], []int{2, 5, 8}) {
+		t.Fatalf("unexpected final norm row %+v", norm)
+	}
+
+	model, err := NewTransformer(cfg, 1, 1)
+	if err != nil {
+		t.Fatalf("NewTransformer failed: %v", err)
+	}
+	s, err = SummaryMulti(model, []int{2, 4, 8}, []int{2, 3, 8})
+	if err != nil {
+		t.Fatalf("transformer Summary failed: %v", err)
+	}
+	if k := summaryRow(t, s, "decoder.layers.0.multihead_attn.k_proj"); !equalShape(k.OutputShapes[0], []int{8, 8}) {
+		t.Fatalf("cross-attention keys should come from the memory: %+v", k)
+	}
+	if out := s.Rows[0].OutputShapes[0]; !equalShape(out, []int{2, 3, 8}) {
+		t.Fatalf("unexpected transformer output %v", out)
+	}
+}
@@ -0,0 +1,867 @@
+package nn
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// TransformerConfig describes one encoder or decoder layer. Zero values pick
+// the defaults noted on each field.
+type TransformerConfig struct {
+	DModel   int
+	NumHeads int
+	// DimFeedforward is the hidden width of the feed-forward block
+	// (default 4*DModel).
+	DimFeedforward int
+	Dropout        float64
+	// Activation is "relu" (default) or "gelu".
+	Activation string
+	// NormFirst applies layer norm before each sub-block (pre-norm) instead
+	// of after the residual connection (post-norm).
+	NormFirst    bool
+	LayerNormEps float64
+	// Position adds "rope" or "alibi" position information to the
+	// self-attention scores; empty leaves positions to the caller.
+	Position string
+}
+
+func (c TransformerConfig) withDefaults() (TransformerConfig, error) {
+	if c.DModel <= 0 || c.NumHeads <= 0 {
+		return c, errors.New("transformer requires positive DModel and NumHeads")
+	}
+	if c.DimFeedforward <= 0 {
+		c.DimFeedforward = 4 * c.DModel
+	}
+	if c.LayerNormEps <= 0 {
+		c.LayerNormEps = 1e-5
+	}
+	switch c.Activation {
+	case "":
+		c.Activation = "relu"
+	case "relu", "gelu":
+	default:
+		return c, fmt.Errorf("unsupported transformer activation %q", c.Activation)
+	}
+	switch c.Position {
+	case "", "rope", "alibi":
+	default:
+		return c, fmt.Errorf("unsupported transformer position encoding %q", c.Position)
+	}
+	return c, nil
+}
+
+// selfAttention builds a self-attention block with the configured position
+// scheme.
+func (c TransformerConfig) selfAttention() (*MultiheadAttention, error) {
+	attn, err := NewMultiheadAttention(c.DModel, c.NumHeads, c.Dropout, true)
+	if err != nil {
+		return nil, err
+	}
+	switch c.Position {
+	case "rope":
+		rotary, err := NewRotaryEmbedding(c.DModel/c.NumHeads, 0)
+		if err != nil {
+			return nil, err
+		}
+		if err := attn.SetRotary(rotary); err != nil {
+			return nil, err
+		}
+	case "alibi":
+		alibi, err := NewALiBi(c.NumHeads)
+		if err != nil {
+			return nil, err
+		}
+		if err := attn.SetALiBi(alibi); err != nil {
+			return nil, err
+		}
+	}
+	return attn, nil
+}
+
+func (c TransformerConfig) activation() Module {
+	if c.Activation == "gelu" {
+		return GELU("none")
+	}
+	return Relu()
+}
+
+// TransformerEncoderLayer is self-attention followed by a position-wise
+// feed-forward block, each wrapped in a residual connection and layer norm.
+// Inputs are batch-first [batch, seq, dModel].
+type TransformerEncoderLayer struct {
+	normFirst  bool
+	training   bool
+	selfAttn   *MultiheadAttention
+	linear1    *Linear
+	linear2    *Linear
+	activation Module
+	dropout    *Dropout
+	dropout1   *Dropout
+	dropout2   *Dropout
+	norm1      *LayerNorm
+	norm2      *LayerNorm
+}
+
+func NewTransformerEncoderLayer(cfg TransformerConfig) (*TransformerEncoderLayer, error) {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	attn, err := cfg.selfAttention()
+	if err != nil {
+		return nil, err
+	}
+	return &TransformerEncoderLayer{
+		normFirst:  cfg.NormFirst,
+		training:   true,
+		selfAttn:   attn,
+		linear1:    NewLinear(cfg.DModel, cfg.DimFeedforward, true),
+		linear2:    NewLinear(cfg.DimFeedforward, cfg.DModel, true),
+		activation: cfg.activation(),
+		dropout:    NewDropout(cfg.Dropout),
+		dropout1:   NewDropout(cfg.Dropout),
+		dropout2:   NewDropout(cfg.Dropout),
+		norm1:      NewLayerNorm([]int{cfg.DModel}, cfg.LayerNormEps, true),
+		norm2:      NewLayerNorm([]int{cfg.DModel}, cfg.LayerNormEps, true),
+	}, nil
+}
+
+func (l *TransformerEncoderLayer) Forward(src *tensor.Tensor) (*tensor.Tensor, error) {
+	return l.ForwardMasked(src, AttentionOptions{})
+}
+
+// ForwardMasked applies the layer with the given self-attention masks.
+func (l *TransformerEncoderLayer) ForwardMasked(src *tensor.Tensor, opts AttentionOptions) (*tensor.Tensor, error) {
+	x, err := residualBlock(src, l.norm1, l.dropout1, l.normFirst, func(in *tensor.Tensor) (*tensor.Tensor, error) {
+		out, _, err := l.selfAttn.Attend(in, in, in, opts)
+		return out, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return residualBlock(x, l.norm2, l.dropout2, l.normFirst, func(in *tensor.Tensor) (*tensor.Tensor, error) {
+		return feedForward(in, l.linear1, l.activation, l.dropout, l.linear2)
+	})
+}
+
+// ForwardCached processes only the new positions of src, attending to them
+// and to the positions held in cache, which it extends. With Causal set this
+// makes the layer a decoder-only block for incremental generation.
+func (l *TransformerEncoderLayer) ForwardCached(src *tensor.Tensor, cache *AttentionCache, opts AttentionOptions) (*tensor.Tensor, error) {
+	x, err := residualBlock(src, l.norm1, l.dropout1, l.normFirst, func(in *tensor.Tensor) (*tensor.Tensor, error) {
+		out, _, err := l.selfAttn.AttendCached(in, in, in, cache, opts)
+		return out, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return residualBlock(x, l.norm2, l.dropout2, l.normFirst, func(in *tensor.Tensor) (*tensor.Tensor, error) {
+		return feedForward(in, l.linear1, l.activation, l.dropout, l.linear2)
+	})
+}
+
+func (l *TransformerEncoderLayer) Parameters() []*tensor.Tensor {
+	return childrenParameters(l.Children())
+}
+
+func (l *TransformerEncoderLayer) ZeroGrad() {
+	for _, child := range l.Children() {
+		child.ZeroGrad()
+	}
+}
+
+func (l *TransformerEncoderLayer) Children() []Module {
+	return namedModules(l.NamedChildren())
+}
+
+func (l *TransformerEncoderLayer) NamedChildren() []NamedModule {
+	return []NamedModule{
+		{Name: "self_attn", Module: l.selfAttn},
+		{Name: "linear1", Module: l.linear1},
+		{Name: "dropout", Module: l.dropout},
+		{Name: "linear2", Module: l.linear2},
+		{Name: "norm1", Module: l.norm1},
+		{Name: "norm2", Module: l.norm2},
+		{Name: "dropout1", Module: l.dropout1},
+		{Name: "dropout2", Module: l.dropout2},
+	}
+}
+
+func (l *TransformerEncoderLayer) NamedParameters() []NamedParameter {
+	return childrenNamedParameters(l.NamedChildren())
+}
+
+func (l *TransformerEncoderLayer) Train() {
+	l.training = true
+	for _, child := range l.Children() {
+		SetTraining(child, true)
+	}
+}
+
+func (l *TransformerEncoderLayer) Eval() {
+	l.training = false
+	for _, child := range l.Children() {
+		SetTraining(child, false)
+	}
+}
+
+func (l *TransformerEncoderLayer) IsTraining() bool {
+	return l.training
+}
+
+func (l *TransformerEncoderLayer) StateDict(prefix string, state map[string]*tensor.Tensor) {
+	childrenStateDict(prefix, l.NamedChildren(), state)
+}
+
+func (l *TransformerEncoderLayer) LoadState(prefix string, state map[string]*tensor.Tensor) error {
+	return childrenLoadState(prefix, l.NamedChildren(), state)
+}
+
+// TransformerDecoderLayer is masked self-attention, attention over the
+// encoder memory and a feed-forward block, each with a residual connection
+// and layer norm.
+type TransformerDecoderLayer struct {
+	normFirst     bool
+	training      bool
+	selfAttn      *MultiheadAttention
+	multiheadAttn *MultiheadAttention
+	linear1       *Linear
+	linear2       *Linear
+	activation    Module
+	dropout       *Dropout
+	dropout1      *Dropout
+	dropout2      *Dropout
+	dropout3      *Dropout
+	norm1         *LayerNorm
+	norm2         *LayerNorm
+	norm3         *LayerNorm
+}
+
+func NewTransformerDecoderLayer(cfg TransformerConfig) (*TransformerDecoderLayer, error) {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	selfAttn, err := cfg.selfAttention()
+	if err != nil {
+		return nil, err
+	}
+	crossAttn, err := NewMultiheadAttention(cfg.DModel, cfg.NumHeads, cfg.Dropout, true)
+	if err != nil {
+		return nil, err
+	}
+	return &TransformerDecoderLayer{
+		normFirst:     cfg.NormFirst,
+		training:      true,
+		selfAttn:      selfAttn,
+		multiheadAttn: crossAttn,
+		linear1:       NewLinear(cfg.DModel, cfg.DimFeedforward, true),
+		linear2:       NewLinear(cfg.DimFeedforward, cfg.DModel, true),
+		activation:    cfg.activation(),
+		dropout:       NewDropout(cfg.Dropout),
+		dropout1:      NewDropout(cfg.Dropout),
+		dropout2:      NewDropout(cfg.Dropout),
+		dropout3:      NewDropout(cfg.Dropout),
+		norm1:         NewLayerNorm([]int{cfg.DModel}, cfg.LayerNormEps, true),
+		norm2:         NewLayerNorm([]int{cfg.DModel}, cfg.LayerNormEps, true),
+		norm3:         NewLayerNorm([]int{cfg.DModel}, cfg.LayerNormEps, true),
+	}, nil
+}
+
+// Forward always fails: a decoder layer needs the encoder memory. Use
+// ForwardDecoder or ForwardMulti instead.
+func (l *TransformerDecoderLayer) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	return nil, errors.New("TransformerDecoderLayer requires memory; use ForwardDecoder")
+}
+
+// ForwardMulti takes (tgt, memory) and applies causal self-attention.
+func (l *TransformerDecoderLayer) ForwardMulti(inputs ...*tensor.Tensor) ([]*tensor.Tensor, error) {
+	if len(inputs) != 2 {
+		return nil, fmt.Errorf("TransformerDecoderLayer expects (tgt, memory), got %d inputs", len(inputs))
+	}
+	out, err := l.ForwardDecoder(inputs[0], inputs[1], AttentionOptions{Causal: true}, AttentionOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return []*tensor.Tensor{out}, nil
+}
+
+// ForwardDecoder attends tgt [batch, tLen, dModel] to itself using tgtOpts
+// and to memory [batch, sLen, dModel] using memoryOpts.
+func (l *TransformerDecoderLayer) ForwardDecoder(tgt, memory *tensor.Tensor, tgtOpts, memoryOpts AttentionOptions) (*tensor.Tensor, error) {
+	x, err := residualBlock(tgt, l.norm1, l.dropout1, l.normFirst, func(in *tensor.Tensor) (*tensor.Tensor, error) {
+		out, _, err := l.selfAttn.Attend(in, in, in, tgtOpts)
+		return out, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	x, err = residualBlock(x, l.norm2, l.dropout2, l.normFirst, func(in *tensor.Tensor) (*tensor.Tensor, error) {
+		out, _, err := l.multiheadAttn.Attend(in, memory, memory, memoryOpts)
+		return out, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return residualBlock(x, l.norm3, l.dropout3, l.normFirst, func(in *tensor.Tensor) (*tensor.Tensor, error) {
+		return feedForward(in, l.linear1, l.activation, l.dropout, l.linear2)
+	})
+}
+
+// ForwardDecoderCached processes only the new target positions. selfCache
+// accumulates the target keys and values; crossCache projects memory on the
+// first call and reuses it afterwards.
+func (l *TransformerDecoderLayer) ForwardDecoderCached(tgt, memory *tensor.Tensor, selfCache, crossCache *AttentionCache, tgtOpts, memoryOpts AttentionOptions) (*tensor.Tensor, error) {
+	x, err := residualBlock(tgt, l.norm1, l.dropout1, l.normFirst, func(in *tensor.Tensor) (*tensor.Tensor, error) {
+		out, _, err := l.selfAttn.AttendCached(in, in, in, selfCache, tgtOpts)
+		return out, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	x, err = residualBlock(x, l.norm2, l.dropout2, l.normFirst, func(in *tensor.Tensor) (*tensor.Tensor, error) {
+		mem := memory
+		if crossCache.Len() > 0 {
+			mem = nil
+		}
+		out, _, err := l.multiheadAttn.AttendCached(in, mem, mem, crossCache, memoryOpts)
+		return out, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return residualBlock(x, l.norm3, l.dropout3, l.normFirst, func(in *tensor.Tensor) (*tensor.Tensor, error) {
+		return feedForward(in, l.linear1, l.activation, l.dropout, l.linear2)
+	})
+}
+
+func (l *TransformerDecoderLayer) Parameters() []*tensor.Tensor {
+	return childrenParameters(l.Children())
+}
+
+func (l *TransformerDecoderLayer) ZeroGrad() {
+	for _, child := range l.Children() {
+		child.ZeroGrad()
+	}
+}
+
+func (l *TransformerDecoderLayer) Children() []Module {
+	return namedModules(l.NamedChildren())
+}
+
+func (l *TransformerDecoderLayer) NamedChildren() []NamedModule {
+	return []NamedModule{
+		{Name: "self_attn", Module: l.selfAttn},
+		{Name: "multihead_attn", Module: l.multiheadAttn},
+		{Name: "linear1", Module: l.linear1},
+		{Name: "dropout", Module: l.dropout},
+		{Name: "linear2", Module: l.linear2},
+		{Name: "norm1", Module: l.norm1},
+		{Name: "norm2", Module: l.norm2},
+		{Name: "norm3", Module: l.norm3},
+		{Name: "dropout1", Module: l.dropout1},
+		{Name: "dropout2", Module: l.dropout2},
+		{Name: "dropout3", Module: l.dropout3},
+	}
+}
+
+func (l *TransformerDecoderLayer) NamedParameters() []NamedParameter {
+	return childrenNamedParameters(l.NamedChildren())
+}
+
+func (l *TransformerDecoderLayer) Train() {
+	l.training = true
+	for _, child := range l.Children() {
+		SetTraining(child, true)
+	}
+}
+
+func (l *TransformerDecoderLayer) Eval() {
+	l.training = false
+	for _, child := range l.Children() {
+		SetTraining(child, false)
+	}
+}
+
+func (l *TransformerDecoderLayer) IsTraining() bool {
+	return l.training
+}
+
+func (l *TransformerDecoderLayer) StateDict(prefix string, state map[string]*tensor.Tensor) {
+	childrenStateDict(prefix, l.NamedChildren(), state)
+}
+
+func (l *TransformerDecoderLayer) LoadState(prefix string, state map[string]*tensor.Tensor) error {
+	return childrenLoadState(prefix, l.NamedChildren(), state)
+}
+
+// TransformerEncoder stacks numLayers encoder layers, optionally followed by
+// a final layer norm (useful with pre-norm layers).
+type TransformerEncoder struct {
+	layers   *ModuleList
+	norm     *LayerNorm
+	training bool
+}
+
+func NewTransformerEncoder(cfg TransformerConfig, numLayers int, finalNorm bool) (*TransformerEncoder, error) {
+	if numLayers <= 0 {
+		return nil, errors.New("transformer encoder requires at least one layer")
+	}
+	layers, _ := NewModuleList()
+	for i := 0; i < numLayers; i++ {
+		layer, err := NewTransformerEncoderLayer(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if err := layers.Append(layer); err != nil {
+			return nil, err
+		}
+	}
+	enc := &TransformerEncoder{layers: layers, training: true}
+	if finalNorm {
+		cfg, _ = cfg.withDefaults()
+		enc.norm = NewLayerNorm([]int{cfg.DModel}, cfg.LayerNormEps, true)
+	}
+	return enc, nil
+}
+
+// Layer returns the i-th encoder layer, or nil when i is out of range.
+func (e *TransformerEncoder) Layer(i int) *TransformerEncoderLayer {
+	layer, _ := e.layers.Get(i)
+	enc, _ := layer.(*TransformerEncoderLayer)
+	return enc
+}
+
+func (e *TransformerEncoder) NumLayers() int {
+	return e.layers.Len()
+}
+
+func (e *TransformerEncoder) Forward(src *tensor.Tensor) (*tensor.Tensor, error) {
+	return e.ForwardMasked(src, AttentionOptions{})
+}
+
+// ForwardMasked runs every layer with the same self-attention masks.
+func (e *TransformerEncoder) ForwardMasked(src *tensor.Tensor, opts AttentionOptions) (*tensor.Tensor, error) {
+	x := src
+	for i := 0; i < e.layers.Len(); i++ {
+		var err error
+		x, err = e.Layer(i).ForwardMasked(x, opts)
+		if err != nil {
+			return nil, fmt.Errorf("encoder layer %d: %w", i, err)
+		}
+	}
+	if e.norm != nil {
+		return e.norm.Forward(x)
+	}
+	return x, nil
+}
+
+// ForwardCached runs every layer incrementally; layer i uses
+// cache.Layer(i).
+func (e *TransformerEncoder) ForwardCached(src *tensor.Tensor, cache *KVCache, opts AttentionOptions) (*tensor.Tensor, error) {
+	if cache == nil {
+		return nil, errors.New("ForwardCached requires a cache")
+	}
+	x := src
+	for i := 0; i < e.layers.Len(); i++ {
+		var err error
+		x, err = e.Layer(i).ForwardCached(x, cache.Layer(i), opts)
+		if err != nil {
+			return nil, fmt.Errorf("encoder layer %d: %w", i, err)
+		}
+	}
+	if e.norm != nil {
+		return e.norm.Forward(x)
+	}
+	return x, nil
+}
+
+func (e *TransformerEncoder) Parameters() []*tensor.Tensor {
+	return childrenParameters(e.Children())
+}
+
+func (e *TransformerEncoder) ZeroGrad() {
+	for _, child := range e.Children() {
+		child.ZeroGrad()
+	}
+}
+
+func (e *TransformerEncoder) Children() []Module {
+	return namedModules(e.NamedChildren())
+}
+
+func (e *TransformerEncoder) NamedChildren() []NamedModule {
+	named := []NamedModule{{Name: "layers", Module: e.layers}}
+	if e.norm != nil {
+		named = append(named, NamedModule{Name: "norm", Module: e.norm})
+	}
+	return named
+}
+
+func (e *TransformerEncoder) NamedParameters() []NamedParameter {
+	return childrenNamedParameters(e.NamedChildren())
+}
+
+func (e *TransformerEncoder) Train() {
+	e.training = true
+	SetTraining(e.layers, true)
+}
+
+func (e *TransformerEncoder) Eval() {
+	e.training = false
+	SetTraining(e.layers, false)
+}
+
+func (e *TransformerEncoder) IsTraining() bool {
+	return e.training
+}
+
+func (e *TransformerEncoder) StateDict(prefix string, state map[string]*tensor.Tensor) {
+	childrenStateDict(prefix, e.NamedChildren(), state)
+}
+
+func (e *TransformerEncoder) LoadState(prefix string, state map[string]*tensor.Tensor) error {
+	return childrenLoadState(prefix, e.NamedChildren(), state)
+}
+
+// TransformerDecoder stacks numLayers decoder layers, optionally followed by
+// a final layer norm.
+type TransformerDecoder struct {
+	layers   *ModuleList
+	norm     *LayerNorm
+	training bool
+}
+
+func NewTransformerDecoder(cfg TransformerConfig, numLayers int, finalNorm bool) (*TransformerDecoder, error) {
+	if numLayers <= 0 {
+		return nil, errors.New("transformer decoder requires at least one layer")
+	}
+	layers, _ := NewModuleList()
+	for i := 0; i < numLayers; i++ {
+		layer, err := NewTransformerDecoderLayer(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if err := layers.Append(layer); err != nil {
+			return nil, err
+		}
+	}
+	dec := &TransformerDecoder{layers: layers, training: true}
+	if finalNorm {
+		cfg, _ = cfg.withDefaults()
+		dec.norm = NewLayerNorm([]int{cfg.DModel}, cfg.LayerNormEps, true)
+	}
+	return dec, nil
+}
+
+// Layer returns the i-th decoder layer, or nil when i is out of range.
+func (d *TransformerDecoder) Layer(i int) *TransformerDecoderLayer {
+	layer, _ := d.layers.Get(i)
+	dec, _ := layer.(*TransformerDecoderLayer)
+	return dec
+}
+
+func (d *TransformerDecoder) NumLayers() int {
+	return d.layers.Len()
+}
+
+// Forward always fails: a decoder needs the encoder memory. Use
+// ForwardDecoder or ForwardMulti instead.
+func (d *TransformerDecoder) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	return nil, errors.New("TransformerDecoder requires memory; use ForwardDecoder")
+}
+
+// ForwardMulti takes (tgt, memory) and applies causal self-attention.
+func (d *TransformerDecoder) ForwardMulti(inputs ...*tensor.Tensor) ([]*tensor.Tensor, error) {
+	if len(inputs) != 2 {
+		return nil, fmt.Errorf("TransformerDecoder expects (tgt, memory), got %d inputs", len(inputs))
+	}
+	out, err := d.ForwardDecoder(inputs[0], inputs[1], AttentionOptions{Causal: true}, AttentionOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return []*tensor.Tensor{out}, nil
+}
+
+// ForwardDecoder runs every layer with the same target and memory masks.
+func (d *TransformerDecoder) ForwardDecoder(tgt, memory *tensor.Tensor, tgtOpts, memoryOpts AttentionOptions) (*tensor.Tensor, error) {
+	x := tgt
+	for i := 0; i < d.layers.Len(); i++ {
+		var err error
+		x, err = d.Layer(i).ForwardDecoder(x, memory, tgtOpts, memoryOpts)
+		if err != nil {
+			return nil, fmt.Errorf("decoder layer %d: %w", i, err)
+		}
+	}
+	if d.norm != nil {
+		return d.norm.Forward(x)
+	}
+	return x, nil
+}
+
+// ForwardDecoderCached runs every layer incrementally; layer i keeps its
+// self-attention in cache.Layer(2i) and its memory in cache.Static(2i+1).
+func (d *TransformerDecoder) ForwardDecoderCached(tgt, memory *tensor.Tensor, cache *KVCache, tgtOpts, memoryOpts AttentionOptions) (*tensor.Tensor, error) {
+	if cache == nil {
+		return nil, errors.New("ForwardDecoderCached requires a cache")
+	}
+	x := tgt
+	for i := 0; i < d.layers.Len(); i++ {
+		var err error
+		x, err = d.Layer(i).ForwardDecoderCached(x, memory, cache.Layer(2*i), cache.Static(2*i+1), tgtOpts, memoryOpts)
+		if err != nil {
+			return nil, fmt.Errorf("decoder layer %d: %w", i, err)
+		}
+	}
+	if d.norm != nil {
+		return d.norm.Forward(x)
+	}
+	return x, nil
+}
+
+func (d *TransformerDecoder) Parameters() []*tensor.Tensor {
+	return childrenParameters(d.Children())
+}
+
+func (d *TransformerDecoder) ZeroGrad() {
+	for _, child := range d.Children() {
+		child.ZeroGrad()
+	}
+}
+
+func (d *TransformerDecoder) Children() []Module {
+	return namedModules(d.NamedChildren())
+}
+
+func (d *TransformerDecoder) NamedChildren() []NamedModule {
+	named := []NamedModule{{Name: "layers", Module: d.layers}}
+	if d.norm != nil {
+		named = append(named, NamedModule{Name: "norm", Module: d.norm})
+	}
+	return named
+}
+
+func (d *TransformerDecoder) NamedParameters() []NamedParameter {
+	return childrenNamedParameters(d.NamedChildren())
+}
+
+func (d *TransformerDecoder) Train() {
+	d.training = true
+	SetTraining(d.layers, true)
+}
+
+func (d *TransformerDecoder) Eval() {
+	d.training = false
+	SetTraining(d.layers, false)
+}
+
+func (d *TransformerDecoder) IsTraining() bool {
+	return d.training
+}
+
+func (d *TransformerDecoder) StateDict(prefix string, state map[string]*tensor.Tensor) {
+	childrenStateDict(prefix, d.NamedChildren(), state)
+}
+
+func (d *TransformerDecoder) LoadState(prefix string, state map[string]*tensor.Tensor) error {
+	return childrenLoadState(prefix, d.NamedChildren(), state)
+}
+
+// TransformerOptions carries the masks of a full Transformer call. The source
+// padding mask is also applied when the decoder attends to the memory.
+type TransformerOptions struct {
+	SrcKeyPaddingMask [][]bool
+	TgtKeyPaddingMask [][]bool
+	SrcMask           *tensor.Tensor
+	TgtMask           *tensor.Tensor
+	// TgtCausal blocks decoder self-attention to later target positions.
+	TgtCausal bool
+}
+
+// Transformer is an encoder-decoder model; both stacks end with a layer norm.
+type Transformer struct {
+	encoder  *TransformerEncoder
+	decoder  *TransformerDecoder
+	training bool
+}
+
+func NewTransformer(cfg TransformerConfig, numEncoderLayers, numDecoderLayers int) (*Transformer, error) {
+	enc, err := NewTransformerEncoder(cfg, numEncoderLayers, true)
+	if err != nil {
+		return nil, err
+	}
+	dec, err := NewTransformerDecoder(cfg, numDecoderLayers, true)
+	if err != nil {
+		return nil, err
+	}
+	return &Transformer{encoder: enc, decoder: dec, training: true}, nil
+}
+
+func (t *Transformer) Encoder() *TransformerEncoder {
+	return t.encoder
+}
+
+func (t *Transformer) Decoder() *TransformerDecoder {
+	return t.decoder
+}
+
+// Forward always fails: a Transformer takes source and target sequences. Use
+// ForwardTransformer or ForwardMulti instead.
+func (t *Transformer) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	return nil, errors.New("Transformer requires source and target; use ForwardTransformer")
+}
+
+// ForwardMulti takes (src, tgt) and applies a causal target mask.
+func (t *Transformer) ForwardMulti(inputs ...*tensor.Tensor) ([]*tensor.Tensor, error) {
+	if len(inputs) != 2 {
+		return nil, fmt.Errorf("Transformer expects (src, tgt), got %d inputs", len(inputs))
+	}
+	out, err := t.ForwardTransformer(inputs[0], inputs[1], TransformerOptions{TgtCausal: true})
+	if err != nil {
+		return nil, err
+	}
+	return []*tensor.Tensor{out}, nil
+}
+
+// ForwardTransformer encodes src [batch, sLen, dModel] and decodes tgt
+// [batch, tLen, dModel] against it, returning [batch, tLen, dModel].
+func (t *Transformer) ForwardTransformer(src, tgt *tensor.Tensor, opts TransformerOptions) (*tensor.Tensor, error) {
+	memory, err := t.encoder.ForwardMasked(src, AttentionOptions{
+		KeyPaddingMask: opts.SrcKeyPaddingMask,
+		AttnMask:       opts.SrcMask,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t.decoder.ForwardDecoder(tgt, memory,
+		AttentionOptions{KeyPaddingMask: opts.TgtKeyPaddingMask, AttnMask: opts.TgtMask, Causal: opts.TgtCausal},
+		AttentionOptions{KeyPaddingMask: opts.SrcKeyPaddingMask},
+	)
+}
+
+func (t *Transformer) Parameters() []*tensor.Tensor {
+	return childrenParameters(t.Children())
+}
+
+func (t *Transformer) ZeroGrad() {
+	t.encoder.ZeroGrad()
+	t.decoder.ZeroGrad()
+}
+
+func (t *Transformer) Children() []Module {
+	return []Module{t.encoder, t.decoder}
+}
+
+func (t *Transformer) NamedChildren() []NamedModule {
+	return []NamedModule{
+		{Name: "encoder", Module: t.encoder},
+		{Name: "decoder", Module: t.decoder},
+	}
+}
+
+func (t *Transformer) NamedParameters() []NamedParameter {
+	return childrenNamedParameters(t.NamedChildren())
+}
+
+func (t *Transformer) Train() {
+	t.training = true
+	t.encoder.Train()
+	t.decoder.Train()
+}
+
+func (t *Transformer) Eval() {
+	t.training = false
+	t.encoder.Eval()
+	t.decoder.Eval()
+}
+
+func (t *Transformer) IsTraining() bool {
+	return t.training
+}
+
+func (t *Transformer) StateDict(prefix string, state map[string]*tensor.Tensor) {
+	childrenStateDict(prefix, t.NamedChildren(), state)
+}
+
+func (t *Transformer) LoadState(prefix string, state map[string]*tensor.Tensor) error {
+	return childrenLoadState(prefix, t.NamedChildren(), state)
+}
+
+// residualBlock computes norm(x + drop(f(x))) in post-norm mode and
+// x + drop(f(norm(x))) in pre-norm mode.
+func residualBlock(x *tensor.Tensor, norm *LayerNorm, drop *Dropout, normFirst bool, f func(*tensor.Tensor) (*tensor.Tensor, error)) (*tensor.Tensor, error) {
+	in := x
+	var err error
+	if normFirst {
+		if in, err = norm.Forward(x); err != nil {
+			return nil, err
+		}
+	}
+	y, err := f(in)
+	if err != nil {
+		return nil, err
+	}
+	if y, err = drop.Forward(y); err != nil {
+		return nil, err
+	}
+	sum, err := tensor.Add(x, y)
+	if err != nil {
+		return nil, err
+	}
+	if normFirst {
+		return sum, nil
+	}
+	return norm.Forward(sum)
+}
+
+// feedForward computes linear2(drop(act(linear1(x)))) position-wise on
+// x [batch, seq, dModel].
+func feedForward(x *tensor.Tensor, linear1 *Linear, act Module, drop *Dropout, linear2 *Linear) (*tensor.Tensor, error) {
+	h, err := linearOverSequence(linear1, x)
+	if err != nil {
+		return nil, err
+	}
+	if h, err = act.Forward(h); err != nil {
+		return nil, err
+	}
+	if h, err = drop.Forward(h); err != nil {
+		return nil, err
+	}
+	return linearOverSequence(linear2, h)
+}
+
+// linearOverSequence applies lin to the last dimension of x
+// [batch, seq, features].
+func linearOverSequence(lin *Linear, x *tensor.Tensor) (*tensor.Tensor, error) {
+	shape := x.Shape()
+	if len(shape) != 3 {
+		return nil, fmt.Errorf("expected [batch, seq, features] input, got shape %v", shape)
+	}
+	flat, err := x.Reshape(shape[0]*shape[1], shape[2])
+	if err != nil {
+		return nil, err
+	}
+	out, err := lin.Forward(flat)
+	if err != nil {
+		return nil, err
+	}
+	return out.Reshape(shape[0], shape[1], out.Shape()[1])
+}
+
+func childrenParameters(children []Module) []*tensor.Tensor {
+	var params []*tensor.Tensor
+	for _, child := range children {
+		params = append(params, child.Parameters()...)
+	}
+	return params
+}
+
+func namedModules(named []NamedModule) []Module {
+	mods := make([]Module, len(named))
+	for i, nm := range named {
+		mods[i] = nm.Module
+	}
+	return mods
+}
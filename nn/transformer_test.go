@@ -0,0 +1,122 @@
+package nn
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+func TestTransformerEncoderLayerVariants(t *testing.T) {
+	for _, cfg := range []TransformerConfig{
+		{DModel: 8, NumHeads: 2, DimFeedforward: 16},
+		{DModel: 8, NumHeads: 4, Activation: "gelu", NormFirst: true, Dropout: 0.1},
+	} {
+		layer, err := NewTransformerEncoderLayer(cfg)
+		if err != nil {
+			t.Fatalf("NewTransformerEncoderLayer(%+v) failed: %v", cfg, err)
+		}
+		x := tensor.Randn(2, 5, 8)
+		x.SetRequiresGrad(true)
+		out, err := layer.Forward(x)
+		if err != nil {
+			t.Fatalf("forward failed: %v", err)
+		}
+		if shape := out.Shape(); shape[0] != 2 || shape[1] != 5 || shape[2] != 8 {
+			t.Fatalf("unexpected output shape %v", shape)
+		}
+		if err := tensor.Sum(out).Backward(); err != nil {
+			t.Fatalf("backward failed: %v", err)
+		}
+		if x.Grad() == nil {
+			t.Fatalf("expected input gradient")
+		}
+		for _, p := range NamedParameters(layer) {
+			if p.Param.Grad() == nil {
+				t.Fatalf("missing gradient for %s", p.Name)
+			}
+		}
+	}
+	if _, err := NewTransformerEncoderLayer(TransformerConfig{DModel: 8, NumHeads: 2, Activation: "swish"}); err == nil {
+		t.Fatalf("expected unsupported activation error")
+	}
+}
+
+func TestTransformerDecoderIsCausal(t *testing.T) {
+	dec, err := NewTransformerDecoder(TransformerConfig{DModel: 4, NumHeads: 2, DimFeedforward: 8}, 2, true)
+	if err != nil {
+		t.Fatalf("NewTransformerDecoder failed: %v", err)
+	}
+	memory := tensor.Randn(1, 3, 4)
+	tgt := tensor.Randn(1, 4, 4)
+	outs, err := dec.ForwardMulti(tgt, memory)
+	if err != nil {
+		t.Fatalf("ForwardMulti failed: %v", err)
+	}
+	data := tgt.Data()
+	data[len(data)-1] += 2
+	changed := tensor.MustNew(data, 1, 4, 4)
+	outs2, _ := dec.ForwardMulti(changed, memory)
+	if !floatsAlmostEqual(outs[0].Data()[:12], outs2[0].Data()[:12], 1e-12) {
+		t.Fatalf("decoder output depends on future target positions")
+	}
+	if _, err := dec.Forward(tgt); err == nil {
+		t.Fatalf("expected Forward without memory to fail")
+	}
+}
+
+func TestTransformerStateAndTraining(t *testing.T) {
+	cfg := TransformerConfig{DModel: 4, NumHeads: 2, DimFeedforward: 8, Dropout: 0.2}
+	model, err := NewTransformer(cfg, 2, 1)
+	if err != nil {
+		t.Fatalf("NewTransformer failed: %v", err)
+	}
+	src, tgt := tensor.Randn(2, 6, 4), tensor.Randn(2, 3, 4)
+	opts := TransformerOptions{
+		SrcKeyPaddingMask: [][]bool{make([]bool, 6), {false, false, false, false, true, true}},
+		TgtCausal:         true,
+	}
+	SetTraining(model, false)
+	if model.Encoder().Layer(1).dropout.IsTraining() || model.Decoder().Layer(0).selfAttn.IsTraining() {
+		t.Fatalf("eval mode did not reach nested layers")
+	}
+	out, err := model.ForwardTransformer(src, tgt, opts)
+	if err != nil {
+		t.Fatalf("forward failed: %v", err)
+	}
+	if shape := out.Shape(); shape[0] != 2 || shape[1] != 3 || shape[2] != 4 {
+		t.Fatalf("unexpected output shape %v", shape)
+	}
+
+	state := map[string]*tensor.Tensor{}
+	model.StateDict("", state)
+	for _, key := range []string{
+		"encoder.layers.0.self_attn.q_proj.weight",
+		"encoder.layers.1.linear2.bias",
+		"encoder.norm.weight",
+		"decoder.layers.0.multihead_attn.out_proj.bias",
+		"decoder.layers.0.norm3.weight",
+		"decoder.norm.bias",
+	} {
+		if _, ok := state[key]; !ok {
+			t.Fatalf("missing state key %s in %s", key, stateKeys(state))
+		}
+	}
+	for key := range state {
+		if strings.Contains(key, "param_") {
+			t.Fatalf("unexpected positional key %s", key)
+		}
+	}
+	if len(state) != len(model.Parameters()) {
+		t.Fatalf("state has %d entries for %d parameters", len(state), len(model.Parameters()))
+	}
+	clone, _ := NewTransformer(cfg, 2, 1)
+	if err := clone.LoadState("", state); err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	clone.Eval()
+	again, _ := clone.ForwardTransformer(src, tgt, opts)
+	if !floatsAlmostEqual(out.Data(), again.Data(), 1e-12) {
+		t.Fatalf("loaded transformer disagrees with original")
+	}
+}
@@ -0,0 +1,151 @@
+package nn
+
+import (
+	"fmt"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// NamedModule pairs a submodule with its attribute name inside the parent.
+type NamedModule struct {
+	Name   string
+	Module Module
+}
+
+// NamedParameter pairs a parameter with its dotted name. Names match the keys
+// written by StateDict.
+type NamedParameter struct {
+	Name  string
+	Param *tensor.Tensor
+}
+
+// ParentModule is implemented by modules that contain other modules.
+type ParentModule interface {
+	Module
+	Children() []Module
+	NamedChildren() []NamedModule
+}
+
+// NamedParameterModule is implemented by modules that can name their
+// parameters, including those of their children.
+type NamedParameterModule interface {
+	Module
+	NamedParameters() []NamedParameter
+}
+
+// Children returns the direct submodules of mod, or nil for leaf modules.
+func Children(mod Module) []Module {
+	if p, ok := mod.(ParentModule); ok {
+		return p.Children()
+	}
+	return nil
+}
+
+// NamedChildren returns the direct submodules of mod with their names.
+func NamedChildren(mod Module) []NamedModule {
+	if p, ok := mod.(ParentModule); ok {
+		return p.NamedChildren()
+	}
+	return nil
+}
+
+// NamedParameters returns every parameter of mod under the key StateDict
+// would use. Modules without names fall back to "param_<i>".
+func NamedParameters(mod Module) []NamedParameter {
+	return prefixedParameters("", mod)
+}
+
+// Walk calls fn for mod and every descendant in depth-first pre-order. name
+// is the dotted path from mod ("" for mod itself). Walk stops at the first
+// error returned by fn.
+func Walk(mod Module, fn func(name string, m Module) error) error {
+	return walk("", mod, fn)
+}
+
+// Apply calls fn on every descendant of mod and then on mod itself, so
+// children are visited before their parents.
+func Apply(mod Module, fn func(Module)) {
+	if mod == nil {
+		return
+	}
+	for _, child := range Children(mod) {
+		Apply(child, fn)
+	}
+	fn(mod)
+}
+
+func walk(name string, mod Module, fn func(string, Module) error) error {
+	if mod == nil {
+		return nil
+	}
+	if err := fn(name, mod); err != nil {
+		return err
+	}
+	for _, child := range NamedChildren(mod) {
+		if err := walk(joinPrefix(name, child.Name), child.Module, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func prefixedParameters(prefix string, mod Module) []NamedParameter {
+	if mod == nil {
+		return nil
+	}
+	if np, ok := mod.(NamedParameterModule); ok {
+		named := np.NamedParameters()
+		out := make([]NamedParameter, 0, len(named))
+		for _, p := range named {
+			out = append(out, NamedParameter{Name: joinPrefix(prefix, p.Name), Param: p.Param})
+		}
+		return out
+	}
+	var out []NamedParameter
+	for idx, p := range mod.Parameters() {
+		if p == nil {
+			continue
+		}
+		out = append(out, NamedParameter{Name: joinPrefix(prefix, fmt.Sprintf("param_%d", idx)), Param: p})
+	}
+	return out
+}
+
+// namedOrNil builds a parameter list, skipping nil tensors.
+func namedOrNil(pairs ...NamedParameter) []NamedParameter {
+	out := make([]NamedParameter, 0, len(pairs))
+	for _, p := range pairs {
+		if p.Param != nil {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// namedStateDict and loadNamedState serialise a module through a list of
+// named tensors, parameters and buffers alike.
+func namedStateDict(prefix string, named []NamedParameter, state map[string]*tensor.Tensor) {
+	if state == nil {
+		return
+	}
+	for _, np := range named {
+		state[joinPrefix(prefix, np.Name)] = np.Param.Clone()
+	}
+}
+
+func loadNamedState(name, prefix string, named []NamedParameter, state map[string]*tensor.Tensor) error {
+	if state == nil {
+		return fmt.Errorf("state dict is nil")
+	}
+	for _, np := range named {
+		key := joinPrefix(prefix, np.Name)
+		t, ok := state[key]
+		if !ok {
+			return fmt.Errorf("%s missing %s", name, key)
+		}
+		if err := tensor.CopyInto(np.Param, t); err != nil {
+			return fmt.Errorf("load %s: %w", key, err)
+		}
+	}
+	return nil
+}
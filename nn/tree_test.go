@@ -0,0 +1,125 @@
+package nn
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+func TestNamedParametersMatchStateDict(t *testing.T) {
+	model := NewSequential(
+		NewLinear(4, 3, true),
+		Relu(),
+		NewSequential(NewBatchNorm(3, 0.1, 1e-5, true), NewLinear(3, 2, false)),
+		NewModuleFunc(func(x *tensor.Tensor) (*tensor.Tensor, error) { return x, nil }),
+		NewGRU(2, 2, true),
+	)
+	named := NamedParameters(model)
+	params := model.Parameters()
+	if len(named) != len(params) {
+		t.Fatalf("expected %d named parameters, got %d", len(params), len(named))
+	}
+	state := make(map[string]*tensor.Tensor)
+	model.StateDict("", state)
+	for i, p := range named {
+		if p.Param != params[i] {
+			t.Fatalf("named parameter %s out of order with Parameters()", p.Name)
+		}
+		if _, ok := state[p.Name]; !ok {
+			t.Fatalf("named parameter %s has no StateDict entry", p.Name)
+		}
+	}
+	if named[0].Name != "0.weight" || named[2].Name != "2.0.weight" || named[4].Name != "2.1.weight" {
+		t.Fatalf("unexpected names: %s, %s, %s", named[0].Name, named[2].Name, named[4].Name)
+	}
+	if named[5].Name != "4.weight_ih_update" {
+		t.Fatalf("unexpected GRU parameter name %s", named[5].Name)
+	}
+}
+
+func TestWalkAndApply(t *testing.T) {
+	inner := NewSequential(NewLinear(2, 2, true), NewAttention(3, 2))
+	model := NewSequential(NewLinear(2, 2, true), inner)
+
+	var names []string
+	if err := Walk(model, func(name string, m Module) error {
+		names = append(names, name)
+		return nil
+	}); err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	want := []string{"", "0", "1", "1.0", "1.1", "1.1.ln"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("unexpected walk order %v", names)
+	}
+
+	stop := errors.New("stop")
+	visited := 0
+	err := Walk(model, func(name string, m Module) error {
+		visited++
+		if name == "1" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || visited != 3 {
+		t.Fatalf("expected walk to stop at first error, visited %d err %v", visited, err)
+	}
+
+	var order []Module
+	Apply(model, func(m Module) { order = append(order, m) })
+	if len(order) != 6 || order[len(order)-1] != model || order[len(order)-2] != inner {
+		t.Fatalf("apply should visit children before parents")
+	}
+	Apply(model, func(m Module) {
+		if lin, ok := m.(*Linear); ok {
+			lin.Weight().SetData(make([]float64, lin.Weight().Numel()))
+		}
+	})
+	for _, p := range NamedParameters(model) {
+		if strings.HasSuffix(p.Name, "weight") && !strings.Contains(p.Name, "ln") {
+			for _, v := range p.Param.Data() {
+				if v != 0 {
+					t.Fatalf("apply did not reach %s", p.Name)
+				}
+			}
+		}
+	}
+	if len(Children(NewLinear(1, 1, true))) != 0 {
+		t.Fatalf("leaf modules should have no children")
+	}
+}
+
+func TestAttentionStateDictAcceptsLegacyKeys(t *testing.T) {
+	src := NewAttention(2, 3)
+	dst := NewAttention(2, 3)
+	state := make(map[string]*tensor.Tensor)
+	src.StateDict("attn", state)
+	var keys []string
+	for k := range state {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	want := "attn.ln.bias,attn.ln.weight,attn.wk,attn.wo,attn.wq,attn.wv"
+	if strings.Join(keys, ",") != want {
+		t.Fatalf("unexpected attention keys %v", keys)
+	}
+	if err := dst.LoadState("attn", state); err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+
+	legacy := make(map[string]*tensor.Tensor)
+	captureParameters("", src, legacy)
+	fresh := NewAttention(2, 3)
+	if err := fresh.LoadState("", legacy); err != nil {
+		t.Fatalf("legacy load failed: %v", err)
+	}
+	for i, p := range fresh.Parameters() {
+		if !floatsAlmostEqual(p.Data(), src.Parameters()[i].Data(), 0) {
+			t.Fatalf("legacy load mismatch for parameter %d", i)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package nn
+
+import "github.com/fumitoshi0524/ixeoriNet/tensor"
+
+// Upsample resizes the spatial dimensions of its input with
+// tensor.Interpolate. Exactly one of size or scaleFactor should be set.
+type Upsample struct {
+	size         []int
+	scaleFactor  []float64
+	mode         string
+	alignCorners bool
+}
+
+func NewUpsample(size []int, scaleFactor []float64, mode string, alignCorners bool) *Upsample {
+	if mode == "" {
+		mode = "nearest"
+	}
+	return &Upsample{
+		size:         append([]int(nil), size...),
+		scaleFactor:  append([]float64(nil), scaleFactor...),
+		mode:         mode,
+		alignCorners: alignCorners,
+	}
+}
+
+func (u *Upsample) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	var size []int
+	var scale []float64
+	if len(u.size) > 0 {
+		size = u.size
+	}
+	if len(u.scaleFactor) > 0 {
+		scale = u.scaleFactor
+	}
+	return tensor.Interpolate(input, size, scale, u.mode, u.alignCorners)
+}
+
+func (u *Upsample) Parameters() []*tensor.Tensor {
+	return nil
+}
+
+func (u *Upsample) ZeroGrad() {}
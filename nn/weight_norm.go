@@ -0,0 +1,220 @@
+package nn
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// reparameterizable is implemented by modules whose parameters can be
+// replaced by a tensor recomputed on every forward pass: Linear, the
+// convolutions and Embedding.
+type reparameterizable interface {
+	StatefulModule
+	parameterSlot(name string) **tensor.Tensor
+}
+
+// reparameterization is the part WeightNorm and SpectralNorm share: the
+// wrapped module and the field holding the parameter they compute.
+type reparameterization struct {
+	module reparameterizable
+	name   string
+	slot   **tensor.Tensor
+}
+
+func newReparameterization(kind string, mod Module, name string) (reparameterization, error) {
+	target, ok := mod.(reparameterizable)
+	if !ok {
+		return reparameterization{}, fmt.Errorf("%s does not support %T", kind, mod)
+	}
+	if name == "" {
+		name = "weight"
+	}
+	slot := target.parameterSlot(name)
+	if slot == nil || *slot == nil {
+		return reparameterization{}, fmt.Errorf("%s: %T has no parameter %q", kind, mod, name)
+	}
+	if len((*slot).Shape()) < 2 {
+		return reparameterization{}, fmt.Errorf("%s needs a parameter with at least 2 dimensions, %q has shape %v", kind, name, (*slot).Shape())
+	}
+	return reparameterization{module: target, name: name, slot: slot}, nil
+}
+
+// Module returns the wrapped module, whose parameter is overwritten by the
+// recomputed tensor on every forward pass.
+func (r *reparameterization) Module() Module {
+	return r.module
+}
+
+// namedParameters lists the wrapped module's parameters with the computed
+// one replaced by the tensors it is derived from.
+func (r *reparameterization) namedParameters(replacement []NamedParameter) []NamedParameter {
+	var out []NamedParameter
+	for _, np := range NamedParameters(r.module) {
+		if np.Name == r.name {
+			out = append(out, replacement...)
+			continue
+		}
+		out = append(out, np)
+	}
+	return out
+}
+
+func (r *reparameterization) parameters(replacement []NamedParameter) []*tensor.Tensor {
+	named := r.namedParameters(replacement)
+	params := make([]*tensor.Tensor, len(named))
+	for i, np := range named {
+		params[i] = np.Param
+	}
+	return params
+}
+
+func (r *reparameterization) stateDict(prefix string, state map[string]*tensor.Tensor, extra []NamedParameter) {
+	if state == nil {
+		return
+	}
+	r.module.StateDict(prefix, state)
+	delete(state, joinPrefix(prefix, r.name))
+	namedStateDict(prefix, extra, state)
+}
+
+// loadState restores the extra tensors, recomputes the parameter from them
+// and hands it to the wrapped module in place of the missing key.
+func (r *reparameterization) loadState(kind, prefix string, state map[string]*tensor.Tensor, extra []NamedParameter, compute func() (*tensor.Tensor, error)) error {
+	if err := loadNamedState(kind, prefix, extra, state); err != nil {
+		return err
+	}
+	computed, err := compute()
+	if err != nil {
+		return err
+	}
+	*r.slot = computed.Detach()
+	rest := make(map[string]*tensor.Tensor, len(state)+1)
+	for k, v := range state {
+		rest[k] = v
+	}
+	rest[joinPrefix(prefix, r.name)] = *r.slot
+	return r.module.LoadState(prefix, rest)
+}
+
+// remove bakes the current value of the parameter into the wrapped module
+// as a plain tensor, trainable unless the wrapper's parameters are frozen,
+// and returns the module.
+func (r *reparameterization) remove(compute func() (*tensor.Tensor, error), trainable bool) (Module, error) {
+	computed, err := compute()
+	if err != nil {
+		return nil, err
+	}
+	baked := computed.Detach()
+	baked.SetRequiresGrad(trainable)
+	*r.slot = baked
+	return r.module, nil
+}
+
+// WeightNormWrapper reparameterises a parameter w of a module as
+// w = g · v / ‖v‖, decoupling each output row's magnitude g from its
+// direction v. Both are learned and the weight is rebuilt on every forward
+// pass.
+type WeightNormWrapper struct {
+	reparameterization
+	g *tensor.Tensor
+	v *tensor.Tensor
+}
+
+// WeightNorm wraps the parameter called name ("weight" when empty) of a
+// Linear, convolution or Embedding module. Norms are taken over every
+// dimension but the first. The state dict stores name_g and name_v instead
+// of name.
+func WeightNorm(mod Module, name string) (*WeightNormWrapper, error) {
+	r, err := newReparameterization("WeightNorm", mod, name)
+	if err != nil {
+		return nil, err
+	}
+	v := *r.slot
+	shape := v.Shape()
+	gShape := make([]int, len(shape))
+	for i := range gShape {
+		gShape[i] = 1
+	}
+	gShape[0] = shape[0]
+	rows := shape[0]
+	cols := v.Numel() / rows
+	data := v.Data()
+	norms := make([]float64, rows)
+	for i := range norms {
+		sq := 0.0
+		for _, x := range data[i*cols : (i+1)*cols] {
+			sq += x * x
+		}
+		norms[i] = math.Sqrt(sq)
+	}
+	g := tensor.MustNew(norms, gShape...)
+	// g inherits the gradient flag of the wrapped weight, so frozen weights
+	// stay frozen
+	g.SetRequiresGrad(v.RequiresGrad())
+	w := &WeightNormWrapper{reparameterization: r, g: g, v: v}
+	computed, err := w.compute()
+	if err != nil {
+		return nil, err
+	}
+	*r.slot = computed.Detach()
+	return w, nil
+}
+
+func (w *WeightNormWrapper) compute() (*tensor.Tensor, error) {
+	return tensor.WeightNorm(w.v, w.g)
+}
+
+func (w *WeightNormWrapper) Forward(input *tensor.Tensor) (*tensor.Tensor, error) {
+	computed, err := w.compute()
+	if err != nil {
+		return nil, err
+	}
+	*w.slot = computed
+	return w.module.Forward(input)
+}
+
+func (w *WeightNormWrapper) extra() []NamedParameter {
+	return []NamedParameter{
+		{Name: w.name + "_g", Param: w.g},
+		{Name: w.name + "_v", Param: w.v},
+	}
+}
+
+func (w *WeightNormWrapper) Parameters() []*tensor.Tensor {
+	return w.parameters(w.extra())
+}
+
+func (w *WeightNormWrapper) ZeroGrad() {
+	for _, p := range w.Parameters() {
+		p.ZeroGrad()
+	}
+}
+
+func (w *WeightNormWrapper) NamedParameters() []NamedParameter {
+	return w.namedParameters(w.extra())
+}
+
+func (w *WeightNormWrapper) StateDict(prefix string, state map[string]*tensor.Tensor) {
+	w.stateDict(prefix, state, w.extra())
+}
+
+func (w *WeightNormWrapper) LoadState(prefix string, state map[string]*tensor.Tensor) error {
+	return w.loadState("WeightNorm", prefix, state, w.extra(), w.compute)
+}
+
+// G returns the per-row magnitude, shaped [out, 1, ...].
+func (w *WeightNormWrapper) G() *tensor.Tensor {
+	return w.g
+}
+
+// V returns the direction tensor.
+func (w *WeightNormWrapper) V() *tensor.Tensor {
+	return w.v
+}
+
+// Remove bakes g · v / ‖v‖ into the wrapped module and returns it.
+func (w *WeightNormWrapper) Remove() (Module, error) {
+	return w.remove(w.compute, w.g.RequiresGrad() || w.v.RequiresGrad())
+}
@@ -0,0 +1,205 @@
+package nn
+
+import (
+	"math"
+	"testing"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+func TestWeightNormWrapper(t *testing.T) {
+	lin := NewLinear(3, 2, true)
+	mustSetData(t, lin.weight, []float64{3, 0, 4, 0, -2, 0})
+	input := tensor.MustNew([]float64{1, 2, 3, -1, 0, 1}, 2, 3)
+	want, err := lin.Forward(input)
+	if err != nil {
+		t.Fatalf("linear forward failed: %v", err)
+	}
+	wn, err := WeightNorm(lin, "")
+	if err != nil {
+		t.Fatalf("WeightNorm failed: %v", err)
+	}
+	if !floatsAlmostEqual(wn.G().Data(), []float64{5, 2}, 1e-12) || !equalShape(wn.G().Shape(), []int{2, 1}) {
+		t.Fatalf("unexpected g %v shape %v", wn.G().Data(), wn.G().Shape())
+	}
+	out, err := wn.Forward(input)
+	if err != nil {
+		t.Fatalf("weight-norm forward failed: %v", err)
+	}
+	if !floatsAlmostEqual(out.Data(), want.Data(), 1e-12) {
+		t.Fatalf("reparameterisation changed the output: %v vs %v", out.Data(), want.Data())
+	}
+	if err := tensor.Sum(out).Backward(); err != nil {
+		t.Fatalf("backward failed: %v", err)
+	}
+	if wn.G().Grad() == nil || wn.V().Grad() == nil || lin.bias.Grad() == nil {
+		t.Fatalf("expected gradients on g, v and bias")
+	}
+	names := ""
+	for _, np := range wn.NamedParameters() {
+		names += np.Name + ","
+	}
+	if names != "weight_g,weight_v,bias," || len(wn.Parameters()) != 3 {
+		t.Fatalf("unexpected parameters %s", names)
+	}
+
+	state := map[string]*tensor.Tensor{}
+	wn.StateDict("fc", state)
+	if got := stateKeys(state); got != "fc.bias,fc.weight_g,fc.weight_v" {
+		t.Fatalf("unexpected state keys %s", got)
+	}
+	mustSetData(t, state["fc.weight_g"], []float64{10, 1})
+	other, _ := WeightNorm(NewLinear(3, 2, true), "weight")
+	if err := other.LoadState("fc", state); err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if !floatsAlmostEqual(other.Module().(*Linear).Weight().Data(), []float64{6, 0, 8, 0, -1, 0}, 1e-12) {
+		t.Fatalf("loaded weight not recomputed: %v", other.Module().(*Linear).Weight().Data())
+	}
+
+	baked, err := other.Remove()
+	if err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	bakedLin := baked.(*Linear)
+	if !bakedLin.Weight().RequiresGrad() || !floatsAlmostEqual(bakedLin.Weight().Data(), []float64{6, 0, 8, 0, -1, 0}, 1e-12) {
+		t.Fatalf("unexpected baked weight %v", bakedLin.Weight().Data())
+	}
+
+	// the zero padding row of an embedding keeps a zero norm
+	emb, _ := NewEmbeddingWithConfig(3, 2, EmbeddingConfig{HasPaddingIdx: true, PaddingIdx: 0})
+	embNorm, err := WeightNorm(emb, "")
+	if err != nil {
+		t.Fatalf("embedding WeightNorm failed: %v", err)
+	}
+	rows, err := embNorm.Forward(tensor.MustNew([]float64{0, 1}, 2))
+	if err != nil {
+		t.Fatalf("embedding weight-norm forward failed: %v", err)
+	}
+	if got := rows.Data(); got[0] != 0 || got[1] != 0 || math.IsNaN(got[2]) || math.IsNaN(got[3]) {
+		t.Fatalf("zero-norm row must stay zero: %v", got)
+	}
+
+	if _, err := WeightNorm(NewLinear(3, 2, true), "bias"); err == nil {
+		t.Fatalf("expected rank error for bias")
+	}
+	if _, err := WeightNorm(Relu(), ""); err == nil {
+		t.Fatalf("expected unsupported module error")
+	}
+}
+
+func TestSpectralNormWrapper(t *testing.T) {
+	conv := NewConv2d(2, 3, 2, 2, 1, 1, 0, 0, false)
+	// a dominant rank-one part keeps the power iteration's convergence fast
+	weight := make([]float64, 24)
+	for k := range weight {
+		weight[k] = float64((k/8+1)*(k%8+1))/10 + 0.05*math.Sin(float64(k))
+	}
+	mustSetData(t, conv.weight, weight)
+	sn, err := SpectralNorm(conv, 1)
+	if err != nil {
+		t.Fatalf("SpectralNorm failed: %v", err)
+	}
+	input := tensor.Randn(1, 2, 3, 3)
+	out, err := sn.Forward(input)
+	if err != nil {
+		t.Fatalf("spectral-norm forward failed: %v", err)
+	}
+	if err := tensor.Sum(out).Backward(); err != nil {
+		t.Fatalf("backward failed: %v", err)
+	}
+	if sn.orig.Grad() == nil {
+		t.Fatalf("expected gradient on weight_orig")
+	}
+	// the normalised weight matrix has spectral norm 1
+	w, _ := conv.Weight().Reshape(3, 8)
+	gram, _ := tensor.MatMul(w, w.MustTranspose())
+	maxEig := 0.0
+	vec := []float64{1, 1, 1}
+	g := gram.Data()
+	for it := 0; it < 200; it++ {
+		next := make([]float64, 3)
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				next[i] += g[i*3+j] * vec[j]
+			}
+		}
+		maxEig = math.Sqrt(next[0]*next[0] + next[1]*next[1] + next[2]*next[2])
+		for i := range next {
+			next[i] /= maxEig
+		}
+		vec = next
+	}
+	if math.Abs(math.Sqrt(maxEig)-1) > 1e-6 {
+		t.Fatalf("expected unit spectral norm, got %v", math.Sqrt(maxEig))
+	}
+
+	sn.Eval()
+	u := sn.u.Data()
+	if _, err := sn.Forward(input); err != nil {
+		t.Fatalf("eval forward failed: %v", err)
+	}
+	if !floatsAlmostEqual(sn.u.Data(), u, 0) {
+		t.Fatalf("power iteration must not run in eval mode")
+	}
+
+	state := map[string]*tensor.Tensor{}
+	sn.StateDict("", state)
+	if got := stateKeys(state); got != "weight_orig,weight_u,weight_v" {
+		t.Fatalf("unexpected state keys %s", got)
+	}
+	other, _ := SpectralNorm(NewConv2d(2, 3, 2, 2, 1, 1, 0, 0, false), 1)
+	if err := other.LoadState("", state); err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if math.Abs(other.Sigma()-sn.Sigma()) > 1e-12 {
+		t.Fatalf("sigma not restored: %v vs %v", other.Sigma(), sn.Sigma())
+	}
+	baked, err := other.Remove()
+	if err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if !floatsAlmostEqual(baked.(*Conv2d).Weight().Data(), conv.Weight().Data(), 1e-12) {
+		t.Fatalf("baked weight differs from the normalised weight")
+	}
+}
+
+func TestReparameterizationKeepsFrozenWeights(t *testing.T) {
+	lin := NewLinear(3, 2, true)
+	Freeze(lin)
+	wn, err := WeightNorm(lin, "")
+	if err != nil {
+		t.Fatalf("WeightNorm failed: %v", err)
+	}
+	if wn.g.RequiresGrad() || wn.v.RequiresGrad() || len(TrainableParameters(wn)) != 0 {
+		t.Fatalf("WeightNorm must keep a frozen weight frozen")
+	}
+	baked, err := wn.Remove()
+	if err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if baked.(*Linear).Weight().RequiresGrad() {
+		t.Fatalf("baked weight of a frozen module must stay frozen")
+	}
+
+	lin = NewLinear(3, 2, true)
+	Freeze(lin)
+	sn, err := SpectralNorm(lin, 1)
+	if err != nil {
+		t.Fatalf("SpectralNorm failed: %v", err)
+	}
+	if sn.orig.RequiresGrad() || len(TrainableParameters(sn)) != 0 {
+		t.Fatalf("SpectralNorm must keep a frozen weight frozen")
+	}
+	if baked, err = sn.Remove(); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if baked.(*Linear).Weight().RequiresGrad() {
+		t.Fatalf("baked weight of a frozen module must stay frozen")
+	}
+
+	lin = NewLinear(3, 2, true)
+	if wn, _ = WeightNorm(lin, ""); !wn.g.RequiresGrad() || !wn.v.RequiresGrad() {
+		t.Fatalf("WeightNorm parameters of a trainable weight must be trainable")
+	}
+}
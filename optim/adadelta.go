@@ -33,7 +33,7 @@ func NewAdadelta(params []*tensor.Tensor, lr, rho, eps float64) *Adadelta {
 
 func (o *Adadelta) Step() error {
 	for _, p := range o.params {
-		if p == nil {
+		if p == nil || !p.RequiresGrad() {
 			continue
 		}
 		grad := p.Grad()
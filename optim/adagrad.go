@@ -1,6 +1,8 @@
 package optim
 
 import (
+	"math"
+
 	"github.com/fumitoshi0524/ixeoriNet/tensor"
 )
 
@@ -25,7 +27,13 @@ func NewAdagrad(params []*tensor.Tensor, lr, eps float64) *Adagrad {
 
 func (o *Adagrad) Step() error {
 	for _, p := range o.params {
-		if p == nil {
+		if p == nil || !p.RequiresGrad() {
+			continue
+		}
+		if rows, values := p.SparseGrad(); rows != nil {
+			if err := o.sparseStep(p, rows, values); err != nil {
+				return err
+			}
 			continue
 		}
 		grad := p.Grad()
@@ -61,6 +69,33 @@ func (o *Adagrad) Step() error {
 	return nil
 }
 
+// sparseStep applies a row-sparse gradient. Rows without a gradient would
+// see neither their squared sum nor their value change, so only the touched
+// rows are visited.
+func (o *Adagrad) sparseStep(p *tensor.Tensor, rows []int, values *tensor.Tensor) error {
+	sum := o.sumSquares[p]
+	if sum == nil {
+		sum = tensor.Zeros(p.Shape()...)
+		o.sumSquares[p] = sum
+	}
+	sums, err := sum.RowValues(rows)
+	if err != nil {
+		return err
+	}
+	params, err := p.RowValues(rows)
+	if err != nil {
+		return err
+	}
+	for i, g := range values.Data() {
+		sums[i] += g * g
+		params[i] -= o.lr * g / (math.Sqrt(sums[i]) + o.eps)
+	}
+	if err := sum.SetRowValues(rows, sums); err != nil {
+		return err
+	}
+	return p.SetRowValues(rows, params)
+}
+
 func (o *Adagrad) ZeroGrad() {
 	for _, p := range o.params {
 		if p != nil {
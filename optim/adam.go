@@ -32,7 +32,7 @@ func NewAdam(params []*tensor.Tensor, lr, beta1, beta2, eps float64) *Adam {
 func (o *Adam) Step() error {
 	o.step++
 	for _, p := range o.params {
-		if p == nil {
+		if p == nil || !p.RequiresGrad() {
 			continue
 		}
 		grad := p.Grad()
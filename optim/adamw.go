@@ -53,7 +53,7 @@ func NewAdamWWithConfig(params []*tensor.Tensor, cfg AdamWConfig) *AdamW {
 func (o *AdamW) Step() error {
 	o.step++
 	for _, p := range o.params {
-		if p == nil {
+		if p == nil || !p.RequiresGrad() {
 			continue
 		}
 		grad := p.Grad()
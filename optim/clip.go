@@ -15,7 +15,7 @@ func ClipGradNorm(params []*tensor.Tensor, maxNorm float64, normType float64) fl
 	}
 	total := 0.0
 	for _, p := range params {
-		if p == nil {
+		if p == nil || !p.RequiresGrad() {
 			continue
 		}
 		total += p.GradPowSum(normType)
@@ -24,7 +24,7 @@ func ClipGradNorm(params []*tensor.Tensor, maxNorm float64, normType float64) fl
 	if norm > maxNorm && norm > 0 {
 		scale := maxNorm / norm
 		for _, p := range params {
-			if p == nil {
+			if p == nil || !p.RequiresGrad() {
 				continue
 			}
 			p.ScaleGrad(scale)
@@ -38,7 +38,7 @@ func ClipGradValue(params []*tensor.Tensor, clipValue float64) {
 		return
 	}
 	for _, p := range params {
-		if p == nil {
+		if p == nil || !p.RequiresGrad() {
 			continue
 		}
 		p.ClipGradValue(clipValue)
@@ -247,3 +247,90 @@ func TestMaxNormConstraint(t *testing.T) {
 		t.Fatalf("max norm constraint violated: %.6f", norm)
 	}
 }
+
+func TestOptimizersSkipFrozenParameters(t *testing.T) {
+	newPair := func() (*tensor.Tensor, *tensor.Tensor) {
+		frozen := tensor.MustNew([]float64{1, -2}, 2)
+		live := tensor.MustNew([]float64{1, -2}, 2)
+		for _, p := range []*tensor.Tensor{frozen, live} {
+			p.SetRequiresGrad(true)
+			if err := tensor.Sum(p).Backward(); err != nil {
+				t.Fatalf("backward failed: %v", err)
+			}
+		}
+		// the gradient stays behind after freezing
+		frozen.SetRequiresGrad(false)
+		return frozen, live
+	}
+	steppers := map[string]func([]*tensor.Tensor) interface{ Step() error }{
+		"sgd":      func(p []*tensor.Tensor) interface{ Step() error } { return NewSGD(p, 0.1, 0.9) },
+		"adam":     func(p []*tensor.Tensor) interface{ Step() error } { return NewAdam(p, 0.1, 0.9, 0.999, 1e-8) },
+		"adamw":    func(p []*tensor.Tensor) interface{ Step() error } { return NewAdamW(p, 0.1) },
+		"rmsprop":  func(p []*tensor.Tensor) interface{ Step() error } { return NewRMSProp(p, 0.1) },
+		"adagrad":  func(p []*tensor.Tensor) interface{ Step() error } { return NewAdagrad(p, 0.1, 1e-10) },
+		"adadelta": func(p []*tensor.Tensor) interface{ Step() error } { return NewAdadelta(p, 1, 0.9, 1e-6) },
+	}
+	for name, build := range steppers {
+		frozen, live := newPair()
+		if err := build([]*tensor.Tensor{frozen, live}).Step(); err != nil {
+			t.Fatalf("%s step failed: %v", name, err)
+		}
+		if !almostEqual(frozen.Data(), []float64{1, -2}, 0) {
+			t.Fatalf("%s updated a frozen parameter: %v", name, frozen.Data())
+		}
+		if almostEqual(live.Data(), []float64{1, -2}, 1e-12) {
+			t.Fatalf("%s did not update the trainable parameter", name)
+		}
+	}
+
+	frozen, live := newPair()
+	if norm := ClipGradNorm([]*tensor.Tensor{frozen, live}, 10, 2); math.Abs(norm-math.Sqrt2) > 1e-9 {
+		t.Fatalf("clip norm should ignore frozen gradients, got %v", norm)
+	}
+}
+
+func TestSparseGradientsMatchDenseUpdates(t *testing.T) {
+	type optimizer interface {
+		Step() error
+		ZeroGrad()
+	}
+	index := tensor.MustNew([]float64{2, 0, 2}, 3)
+	run := func(sparse bool, newOpt func([]*tensor.Tensor) optimizer) []float64 {
+		weight := tensor.MustNew([]float64{1, 2, 3, 4, 5, 6, 7, 8}, 4, 2)
+		weight.SetRequiresGrad(true)
+		opt := newOpt([]*tensor.Tensor{weight})
+		for step := 0; step < 3; step++ {
+			opt.ZeroGrad()
+			out, err := tensor.EmbeddingWithOptions(weight, index, -1, sparse)
+			if err != nil {
+				t.Fatalf("embedding failed: %v", err)
+			}
+			if err := tensor.Sum(tensor.Pow(out, 2)).Backward(); err != nil {
+				t.Fatalf("backward failed: %v", err)
+			}
+			if rows, _ := weight.SparseGrad(); sparse != (rows != nil) {
+				t.Fatalf("sparse=%v but SparseGrad rows %v", sparse, rows)
+			}
+			if err := opt.Step(); err != nil {
+				t.Fatalf("step failed: %v", err)
+			}
+		}
+		return weight.Data()
+	}
+	optimizers := map[string]func([]*tensor.Tensor) optimizer{
+		"sgd":      func(p []*tensor.Tensor) optimizer { return NewSGD(p, 0.01, 0) },
+		"momentum": func(p []*tensor.Tensor) optimizer { return NewSGD(p, 0.01, 0.9) },
+		"clipped": func(p []*tensor.Tensor) optimizer {
+			return NewSGDWithConfig(p, SGDConfig{LR: 0.01, MaxGradNorm: 1, GradValueClip: 0.5})
+		},
+		"adagrad": func(p []*tensor.Tensor) optimizer { return NewAdagrad(p, 0.1, 0) },
+		"adam":    func(p []*tensor.Tensor) optimizer { return NewAdam(p, 0.1, 0.9, 0.999, 1e-8) },
+	}
+	for name, newOpt := range optimizers {
+		dense := run(false, newOpt)
+		sparse := run(true, newOpt)
+		if !almostEqual(sparse, dense, 1e-12) {
+			t.Fatalf("%s: sparse update %v differs from dense %v", name, sparse, dense)
+		}
+	}
+}
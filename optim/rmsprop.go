@@ -46,7 +46,7 @@ func NewRMSPropWithConfig(params []*tensor.Tensor, cfg RMSPropConfig) *RMSProp {
 
 func (o *RMSProp) Step() error {
 	for _, p := range o.params {
-		if p == nil {
+		if p == nil || !p.RequiresGrad() {
 			continue
 		}
 		grad := p.Grad()
@@ -54,7 +54,19 @@ func (o *SGD) Step() error {
 		ClipGradValue(o.params, o.gradValueClip)
 	}
 	for _, p := range o.params {
-		if p == nil {
+		if p == nil || !p.RequiresGrad() {
+			continue
+		}
+		if rows, values := p.SparseGrad(); rows != nil && o.momentum == 0 && o.weightDecay == 0 {
+			// plain SGD on a row-sparse gradient only moves the touched rows
+			if err := addRowsScaled(p, rows, values, -o.lr); err != nil {
+				return err
+			}
+			for _, c := range o.constraints {
+				if err := c.Apply(p); err != nil {
+					return err
+				}
+			}
 			continue
 		}
 		grad := p.Grad()
@@ -149,3 +161,16 @@ func (o *SGD) ZeroGrad() {
 		}
 	}
 }
+
+// addRowsScaled adds alpha·values to the listed rows of p, the in-place
+// update for a row-sparse gradient as returned by Tensor.SparseGrad.
+func addRowsScaled(p *tensor.Tensor, rows []int, values *tensor.Tensor, alpha float64) error {
+	current, err := p.RowValues(rows)
+	if err != nil {
+		return err
+	}
+	for i, v := range values.Data() {
+		current[i] += alpha * v
+	}
+	return p.SetRowValues(rows, current)
+}
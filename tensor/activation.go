@@ -1,6 +1,7 @@
 package tensor
 
 import (
+	"errors"
 	"math"
 
 	"github.com/fumitoshi0524/ixeoriNet/internal/parallel"
@@ -218,3 +219,246 @@ func GELU(a *Tensor) *Tensor {
 	}
 	return out
 }
+
+// pointwise builds an element-wise activation from f, which returns the
+// activation and its derivative at x. The derivative is evaluated once in
+// the forward pass and reused by backward.
+func pointwise(a *Tensor, f func(x float64) (y, dy float64)) *Tensor {
+	out := Zeros(a.shape...)
+	var deriv *Tensor
+	if a.requiresGrad {
+		deriv = Zeros(a.shape...)
+	}
+	parallel.For(len(out.data), func(start, end int) {
+		for i := start; i < end; i++ {
+			y, dy := f(a.data[i])
+			out.data[i] = y
+			if deriv != nil {
+				deriv.data[i] = dy
+			}
+		}
+	})
+	if a.requiresGrad {
+		out.requiresGrad = true
+		out.parents = []*Tensor{a}
+		out.node = &node{
+			backward: func(grad *Tensor, grads map[*Tensor]*Tensor) {
+				accumulate(grads, a, hadamard(grad, deriv))
+			},
+		}
+	}
+	return out
+}
+
+func sigmoidValue(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+// GELUTanh is the tanh approximation of GELU,
+// 0.5·x·(1 + tanh(√(2/π)·(x + 0.044715·x³))).
+func GELUTanh(a *Tensor) *Tensor {
+	c := math.Sqrt(2 / math.Pi)
+	return pointwise(a, func(x float64) (float64, float64) {
+		inner := c * (x + 0.044715*x*x*x)
+		t := math.Tanh(inner)
+		dInner := c * (1 + 3*0.044715*x*x)
+		return 0.5 * x * (1 + t), 0.5*(1+t) + 0.5*x*(1-t*t)*dInner
+	})
+}
+
+// SiLU (swish) computes x·σ(x).
+func SiLU(a *Tensor) *Tensor {
+	return pointwise(a, func(x float64) (float64, float64) {
+		s := sigmoidValue(x)
+		return x * s, s * (1 + x*(1-s))
+	})
+}
+
+// Mish computes x·tanh(softplus(x)).
+func Mish(a *Tensor) *Tensor {
+	return pointwise(a, func(x float64) (float64, float64) {
+		sp := math.Log1p(math.Exp(x))
+		if x > 20 {
+			sp = x
+		}
+		t := math.Tanh(sp)
+		return x * t, t + x*(1-t*t)*sigmoidValue(x)
+	})
+}
+
+// Hardsigmoid is the piecewise-linear sigmoid relu6(x+3)/6.
+func Hardsigmoid(a *Tensor) *Tensor {
+	return pointwise(a, func(x float64) (float64, float64) {
+		switch {
+		case x <= -3:
+			return 0, 0
+		case x >= 3:
+			return 1, 0
+		}
+		return x/6 + 0.5, 1.0 / 6
+	})
+}
+
+// Hardswish computes x·relu6(x+3)/6.
+func Hardswish(a *Tensor) *Tensor {
+	return pointwise(a, func(x float64) (float64, float64) {
+		switch {
+		case x <= -3:
+			return 0, 0
+		case x >= 3:
+			return x, 1
+		}
+		return x * (x + 3) / 6, (2*x + 3) / 6
+	})
+}
+
+// Relu6 clamps x to [0, 6].
+func Relu6(a *Tensor) *Tensor {
+	return pointwise(a, func(x float64) (float64, float64) {
+		switch {
+		case x <= 0:
+			return 0, 0
+		case x >= 6:
+			return 6, 0
+		}
+		return x, 1
+	})
+}
+
+// SELU constants from Klambauer et al., chosen so activations self-normalise.
+const (
+	seluAlpha = 1.6732632423543772848170429916717
+	seluScale = 1.0507009873554804934193349852946
+)
+
+// SELU computes scale·(x if x > 0 else α·(eˣ − 1)).
+func SELU(a *Tensor) *Tensor {
+	return pointwise(a, func(x float64) (float64, float64) {
+		if x > 0 {
+			return seluScale * x, seluScale
+		}
+		e := math.Exp(x)
+		return seluScale * seluAlpha * (e - 1), seluScale * seluAlpha * e
+	})
+}
+
+// CELU computes max(0, x) + min(0, α·(exp(x/α) − 1)); alpha defaults to 1.
+func CELU(a *Tensor, alpha float64) *Tensor {
+	if alpha == 0 {
+		alpha = 1
+	}
+	return pointwise(a, func(x float64) (float64, float64) {
+		if x > 0 {
+			return x, 1
+		}
+		e := math.Exp(x / alpha)
+		return alpha * (e - 1), e
+	})
+}
+
+// Softsign computes x / (1 + |x|).
+func Softsign(a *Tensor) *Tensor {
+	return pointwise(a, func(x float64) (float64, float64) {
+		d := 1 + math.Abs(x)
+		return x / d, 1 / (d * d)
+	})
+}
+
+// GLU splits the input in two halves a and b along axis and returns a·σ(b).
+// The axis length must be even.
+func GLU(a *Tensor, axis int) (*Tensor, error) {
+	rank := len(a.shape)
+	if axis < 0 {
+		axis += rank
+	}
+	if axis < 0 || axis >= rank {
+		return nil, errors.New("axis out of range")
+	}
+	if a.shape[axis]%2 != 0 {
+		return nil, errors.New("GLU needs an even size along the split axis")
+	}
+	half := a.shape[axis] / 2
+	outer := 1
+	for _, dim := range a.shape[:axis] {
+		outer *= dim
+	}
+	inner := 1
+	for _, dim := range a.shape[axis+1:] {
+		inner *= dim
+	}
+	shape := append([]int(nil), a.shape...)
+	shape[axis] = half
+	block := half * inner
+	data := make([]float64, outer*block)
+	gates := make([]float64, len(data))
+	for o := 0; o < outer; o++ {
+		for k := 0; k < block; k++ {
+			src := o*2*block + k
+			g := sigmoidValue(a.data[src+block])
+			gates[o*block+k] = g
+			data[o*block+k] = a.data[src] * g
+		}
+	}
+	values := append([]float64(nil), a.data...)
+	return NewOp(data, shape, []*Tensor{a}, func(grad *Tensor) []*Tensor {
+		ga := Zeros(a.shape...)
+		for o := 0; o < outer; o++ {
+			for k := 0; k < block; k++ {
+				src := o*2*block + k
+				g := gates[o*block+k]
+				gd := grad.data[o*block+k]
+				ga.data[src] = gd * g
+				ga.data[src+block] = gd * values[src] * g * (1 - g)
+			}
+		}
+		return []*Tensor{ga}
+	})
+}
+
+// PReLU is LeakyRelu with a learnable slope: weight holds either a single
+// slope or one per channel, channels being dimension 1 of the input.
+func PReLU(a, weight *Tensor) (*Tensor, error) {
+	if weight == nil {
+		return nil, errors.New("PReLU requires a weight tensor")
+	}
+	slopes := weight.Numel()
+	inner := 1
+	if slopes != 1 {
+		if len(a.shape) < 2 || a.shape[1] != slopes {
+			return nil, errors.New("PReLU weight size must be 1 or the number of channels")
+		}
+		for _, dim := range a.shape[2:] {
+			inner *= dim
+		}
+	}
+	channelOf := func(idx int) int {
+		if slopes == 1 {
+			return 0
+		}
+		return (idx / inner) % slopes
+	}
+	data := make([]float64, len(a.data))
+	for i, x := range a.data {
+		if x > 0 {
+			data[i] = x
+		} else {
+			data[i] = weight.data[channelOf(i)] * x
+		}
+	}
+	values := append([]float64(nil), a.data...)
+	w := append([]float64(nil), weight.data...)
+	return NewOp(data, a.shape, []*Tensor{a, weight}, func(grad *Tensor) []*Tensor {
+		ga := Zeros(a.shape...)
+		gw := Zeros(weight.shape...)
+		for i, x := range values {
+			c := channelOf(i)
+			if x > 0 {
+				ga.data[i] = grad.data[i]
+			} else {
+				ga.data[i] = grad.data[i] * w[c]
+				gw.data[c] += grad.data[i] * x
+			}
+		}
+		return []*Tensor{ga, gw}
+	})
+}
@@ -149,3 +149,84 @@ func TestGELUForwardBackward(t *testing.T) {
 		t.Fatalf("gelu grad mismatch: %v", input.Grad().Data())
 	}
 }
+
+func TestPointwiseActivationsForwardBackward(t *testing.T) {
+	// stay clear of the kinks at 0, ±3 and 6
+	base := []float64{-4.2, -2.5, -0.7, 0.4, 1.9, 3.6, 6.8}
+	cases := []struct {
+		name string
+		fn   func(*Tensor) *Tensor
+		ref  func(x float64) float64
+	}{
+		{"silu", SiLU, func(x float64) float64 { return x / (1 + math.Exp(-x)) }},
+		{"mish", Mish, func(x float64) float64 { return x * math.Tanh(math.Log1p(math.Exp(x))) }},
+		{"hardsigmoid", Hardsigmoid, func(x float64) float64 { return math.Min(math.Max(x+3, 0), 6) / 6 }},
+		{"hardswish", Hardswish, func(x float64) float64 { return x * math.Min(math.Max(x+3, 0), 6) / 6 }},
+		{"relu6", Relu6, func(x float64) float64 { return math.Min(math.Max(x, 0), 6) }},
+		{"selu", SELU, func(x float64) float64 {
+			if x > 0 {
+				return seluScale * x
+			}
+			return seluScale * seluAlpha * (math.Exp(x) - 1)
+		}},
+		{"celu", func(a *Tensor) *Tensor { return CELU(a, 0.5) }, func(x float64) float64 {
+			return math.Max(0, x) + math.Min(0, 0.5*(math.Exp(x/0.5)-1))
+		}},
+		{"softsign", Softsign, func(x float64) float64 { return x / (1 + math.Abs(x)) }},
+		{"gelu tanh", GELUTanh, func(x float64) float64 {
+			return 0.5 * x * (1 + math.Tanh(math.Sqrt(2/math.Pi)*(x+0.044715*x*x*x)))
+		}},
+	}
+	for _, tc := range cases {
+		tc := tc
+		out := tc.fn(MustNew(base, len(base)))
+		for i, x := range base {
+			if math.Abs(out.Data()[i]-tc.ref(x)) > 1e-12 {
+				t.Fatalf("%s(%v) = %v, want %v", tc.name, x, out.Data()[i], tc.ref(x))
+			}
+		}
+		checkInterpolateGrad(t, tc.name, base, []int{len(base)}, func(in *Tensor) (*Tensor, error) {
+			return tc.fn(in), nil
+		})
+	}
+	exact := GELU(MustNew(base, len(base))).Data()
+	approx := GELUTanh(MustNew(base, len(base))).Data()
+	if !AlmostEqualSlices(exact, approx, 1e-3) {
+		t.Fatalf("tanh GELU too far from exact: %v vs %v", approx, exact)
+	}
+}
+
+func TestGLUAndPReLU(t *testing.T) {
+	input := MustNew([]float64{1, 2, 0, math.Inf(-1), 3, 4, 100, 0}, 2, 4)
+	out, err := GLU(input, 1)
+	if err != nil {
+		t.Fatalf("GLU failed: %v", err)
+	}
+	if !AlmostEqualSlices(out.Data(), []float64{0.5, 0, 3, 2}, 1e-9) || !equalShapes(out.Shape(), []int{2, 2}) {
+		t.Fatalf("unexpected GLU output %v %v", out.Data(), out.Shape())
+	}
+	base := sequenceValues(12, 0.3)
+	checkInterpolateGrad(t, "glu axis 0", base, []int{4, 3}, func(in *Tensor) (*Tensor, error) {
+		return GLU(in, 0)
+	})
+	checkInterpolateGrad(t, "glu last axis", base, []int{3, 2, 2}, func(in *Tensor) (*Tensor, error) {
+		return GLU(in, -1)
+	})
+	if _, err := GLU(MustNew(base, 3, 4), 0); err == nil {
+		t.Fatalf("expected odd-size error")
+	}
+
+	slopes := []float64{0.25, -0.5, 0.1}
+	checkInterpolateGrad(t, "prelu input", base, []int{2, 3, 2}, func(in *Tensor) (*Tensor, error) {
+		return PReLU(in, MustNew(slopes, 3))
+	})
+	checkInterpolateGrad(t, "prelu weight", slopes, []int{3}, func(w *Tensor) (*Tensor, error) {
+		return PReLU(MustNew(base, 2, 3, 2), w)
+	})
+	checkInterpolateGrad(t, "prelu shared weight", []float64{0.3}, []int{1}, func(w *Tensor) (*Tensor, error) {
+		return PReLU(MustNew(base, 4, 3), w)
+	})
+	if _, err := PReLU(MustNew(base, 4, 3), MustNew([]float64{1, 2}, 2)); err == nil {
+		t.Fatalf("expected PReLU channel mismatch")
+	}
+}
@@ -0,0 +1,231 @@
+package tensor
+
+import (
+	"errors"
+	"math"
+
+	"github.com/fumitoshi0524/ixeoriNet/internal/parallel"
+)
+
+// ScaledDotProductAttention computes softmax(q kᵀ / sqrt(d) + mask) v for
+// every batch and head.
+// Query shape: [batch, heads, q_len, d]
+// Key shape: [batch, heads, k_len, d]
+// Value shape: [batch, heads, k_len, d_v]
+// Mask (optional, additive): [q_len, k_len], [batch, 1, q_len, k_len] or
+// [batch, heads, q_len, k_len]; use -Inf to block a position.
+// It returns the attention output [batch, heads, q_len, d_v] and the
+// attention probabilities [batch, heads, q_len, k_len] before dropout. Rows
+// whose keys are all masked produce zero probabilities. Both results are
+// differentiable with respect to q, k and (for the output) v.
+func ScaledDotProductAttention(q, k, v, mask *Tensor, dropoutP float64, training bool) (*Tensor, *Tensor, error) {
+	if q == nil || k == nil || v == nil {
+		return nil, nil, errors.New("attention requires query, key and value tensors")
+	}
+	if len(q.shape) != 4 || len(k.shape) != 4 || len(v.shape) != 4 {
+		return nil, nil, errors.New("attention expects rank-4 query, key and value [batch, heads, len, dim]")
+	}
+	batch, heads, qLen, dim := q.shape[0], q.shape[1], q.shape[2], q.shape[3]
+	kLen, vDim := k.shape[2], v.shape[3]
+	if k.shape[0] != batch || k.shape[1] != heads || k.shape[3] != dim {
+		return nil, nil, errors.New("attention key shape mismatch")
+	}
+	if v.shape[0] != batch || v.shape[1] != heads || v.shape[2] != kLen {
+		return nil, nil, errors.New("attention value shape mismatch")
+	}
+	if dropoutP < 0 || dropoutP >= 1 {
+		return nil, nil, errors.New("dropout probability must be in [0, 1)")
+	}
+	maskBatchStride, maskHeadStride := 0, 0
+	if mask != nil {
+		ms := mask.shape
+		switch {
+		case len(ms) == 2 && ms[0] == qLen && ms[1] == kLen:
+		case len(ms) == 4 && ms[0] == batch && (ms[1] == 1 || ms[1] == heads) && ms[2] == qLen && ms[3] == kLen:
+			maskBatchStride = ms[1] * qLen * kLen
+			if ms[1] == heads {
+				maskHeadStride = qLen * kLen
+			}
+		default:
+			return nil, nil, errors.New("attention mask shape mismatch")
+		}
+	}
+
+	scale := 1 / math.Sqrt(float64(dim))
+	planes := batch * heads
+	probs := make([]float64, planes*qLen*kLen)
+	var keep []float64
+	if training && dropoutP > 0 {
+		keep = make([]float64, len(probs))
+		factor := 1 / (1 - dropoutP)
+		rngLock.Lock()
+		for i := range keep {
+			if rng.Float64() >= dropoutP {
+				keep[i] = factor
+			}
+		}
+		rngLock.Unlock()
+	}
+	out := Zeros(batch, heads, qLen, vDim)
+	parallel.For(planes, func(start, end int) {
+		scores := make([]float64, kLen)
+		for bh := start; bh < end; bh++ {
+			b, h := bh/heads, bh%heads
+			qBase := bh * qLen * dim
+			kBase := bh * kLen * dim
+			vBase := bh * kLen * vDim
+			for i := 0; i < qLen; i++ {
+				maxScore := math.Inf(-1)
+				for j := 0; j < kLen; j++ {
+					s := 0.0
+					for d := 0; d < dim; d++ {
+						s += q.data[qBase+i*dim+d] * k.data[kBase+j*dim+d]
+					}
+					s *= scale
+					if mask != nil {
+						s += mask.data[b*maskBatchStride+h*maskHeadStride+i*kLen+j]
+					}
+					scores[j] = s
+					if s > maxScore {
+						maxScore = s
+					}
+				}
+				row := probs[(bh*qLen+i)*kLen : (bh*qLen+i+1)*kLen]
+				if math.IsInf(maxScore, -1) {
+					continue
+				}
+				sum := 0.0
+				for j := range scores {
+					row[j] = math.Exp(scores[j] - maxScore)
+					sum += row[j]
+				}
+				outRow := out.data[(bh*qLen+i)*vDim : (bh*qLen+i+1)*vDim]
+				for j := range row {
+					row[j] /= sum
+					p := row[j]
+					if keep != nil {
+						p *= keep[(bh*qLen+i)*kLen+j]
+					}
+					if p == 0 {
+						continue
+					}
+					for d := 0; d < vDim; d++ {
+						outRow[d] += p * v.data[vBase+j*vDim+d]
+					}
+				}
+			}
+		}
+	})
+	weights := MustNew(probs, batch, heads, qLen, kLen)
+
+	// scoreBackward turns dL/dP into gradients for q and k.
+	scoreBackward := func(dP []float64, grads map[*Tensor]*Tensor) {
+		gQ := Zeros(q.shape...)
+		gK := Zeros(k.shape...)
+		parallel.For(planes, func(start, end int) {
+			dS := make([]float64, kLen)
+			for bh := start; bh < end; bh++ {
+				qBase := bh * qLen * dim
+				kBase := bh * kLen * dim
+				for i := 0; i < qLen; i++ {
+					off := (bh*qLen + i) * kLen
+					dot := 0.0
+					for j := 0; j < kLen; j++ {
+						dot += dP[off+j] * probs[off+j]
+					}
+					for j := 0; j < kLen; j++ {
+						dS[j] = probs[off+j] * (dP[off+j] - dot) * scale
+					}
+					for j := 0; j < kLen; j++ {
+						if dS[j] == 0 {
+							continue
+						}
+						for d := 0; d < dim; d++ {
+							gQ.data[qBase+i*dim+d] += dS[j] * k.data[kBase+j*dim+d]
+							gK.data[kBase+j*dim+d] += dS[j] * q.data[qBase+i*dim+d]
+						}
+					}
+				}
+			}
+		})
+		if q.requiresGrad {
+			accumulate(grads, q, gQ)
+		}
+		if k.requiresGrad {
+			accumulate(grads, k, gK)
+		}
+	}
+
+	var parents []*Tensor
+	for _, t := range []*Tensor{q, k, v} {
+		if t.requiresGrad {
+			parents = append(parents, t)
+		}
+	}
+	if len(parents) == 0 {
+		return out, weights, nil
+	}
+
+	out.requiresGrad = true
+	out.parents = parents
+	out.node = &node{
+		backward: func(grad *Tensor, grads map[*Tensor]*Tensor) {
+			dP := make([]float64, len(probs))
+			var gV *Tensor
+			if v.requiresGrad {
+				gV = Zeros(v.shape...)
+			}
+			parallel.For(planes, func(start, end int) {
+				for bh := start; bh < end; bh++ {
+					vBase := bh * kLen * vDim
+					for i := 0; i < qLen; i++ {
+						gRow := grad.data[(bh*qLen+i)*vDim : (bh*qLen+i+1)*vDim]
+						for j := 0; j < kLen; j++ {
+							idx := (bh*qLen+i)*kLen + j
+							factor := 1.0
+							if keep != nil {
+								factor = keep[idx]
+							}
+							if factor == 0 {
+								continue
+							}
+							s := 0.0
+							for d := 0; d < vDim; d++ {
+								s += gRow[d] * v.data[vBase+j*vDim+d]
+							}
+							dP[idx] = s * factor
+							if gV != nil {
+								p := probs[idx] * factor
+								for d := 0; d < vDim; d++ {
+									gV.data[vBase+j*vDim+d] += p * gRow[d]
+								}
+							}
+						}
+					}
+				}
+			})
+			if gV != nil {
+				accumulate(grads, v, gV)
+			}
+			if q.requiresGrad || k.requiresGrad {
+				scoreBackward(dP, grads)
+			}
+		},
+	}
+
+	if q.requiresGrad || k.requiresGrad {
+		weights.requiresGrad = true
+		for _, t := range []*Tensor{q, k} {
+			if t.requiresGrad {
+				weights.parents = append(weights.parents, t)
+			}
+		}
+		weights.node = &node{
+			backward: func(grad *Tensor, grads map[*Tensor]*Tensor) {
+				scoreBackward(grad.data, grads)
+			},
+		}
+	}
+
+	return out, weights, nil
+}
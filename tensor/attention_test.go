@@ -0,0 +1,172 @@
+package tensor
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPermuteForwardBackward(t *testing.T) {
+	x := MustNew([]float64{0, 1, 2, 3, 4, 5}, 1, 2, 3)
+	out, err := Permute(x, 2, 0, 1)
+	if err != nil {
+		t.Fatalf("Permute failed: %v", err)
+	}
+	if shape := out.Shape(); shape[0] != 3 || shape[1] != 1 || shape[2] != 2 {
+		t.Fatalf("unexpected shape %v", shape)
+	}
+	if !almostEqualSlices(out.Data(), []float64{0, 3, 1, 4, 2, 5}, 0) {
+		t.Fatalf("unexpected permuted data %v", out.Data())
+	}
+	base := []float64{0.1, -0.4, 0.7, 1.2, -0.3, 0.5}
+	checkInterpolateGrad(t, "permute", base, []int{1, 2, 3}, func(in *Tensor) (*Tensor, error) {
+		return Permute(in, 2, 0, 1)
+	})
+	if _, err := Permute(x, 0, 0, 1); err == nil {
+		t.Fatalf("expected repeated axis error")
+	}
+}
+
+func attentionInputs() ([]float64, []float64, []float64) {
+	q := make([]float64, 2*2*3*2)
+	k := make([]float64, 2*2*4*2)
+	v := make([]float64, 2*2*4*3)
+	for i := range q {
+		q[i] = math.Sin(float64(i)*0.7) * 0.8
+	}
+	for i := range k {
+		k[i] = math.Cos(float64(i)*0.3) * 0.9
+	}
+	for i := range v {
+		v[i] = math.Sin(float64(i)*0.45+0.2) * 1.1
+	}
+	return q, k, v
+}
+
+func TestScaledDotProductAttentionMatchesReference(t *testing.T) {
+	q := MustNew([]float64{1, 0, 0, 1}, 1, 1, 2, 2)
+	k := MustNew([]float64{1, 0, 0, 1}, 1, 1, 2, 2)
+	v := MustNew([]float64{1, 2, 3, 4}, 1, 1, 2, 2)
+	mask := MustNew([]float64{0, math.Inf(-1), 0, 0}, 2, 2)
+	out, weights, err := ScaledDotProductAttention(q, k, v, mask, 0, false)
+	if err != nil {
+		t.Fatalf("attention failed: %v", err)
+	}
+	// row 0 can only see key 0; row 1 sees both with scores 0 and 1/sqrt(2)
+	e := math.Exp(1 / math.Sqrt(2))
+	p0, p1 := 1/(1+e), e/(1+e)
+	if !almostEqualSlices(weights.Data(), []float64{1, 0, p0, p1}, 1e-12) {
+		t.Fatalf("unexpected weights %v", weights.Data())
+	}
+	want := []float64{1, 2, p0*1 + p1*3, p0*2 + p1*4}
+	if !almostEqualSlices(out.Data(), want, 1e-12) {
+		t.Fatalf("unexpected output %v", out.Data())
+	}
+
+	fullyMasked := MustNew([]float64{math.Inf(-1), math.Inf(-1), 0, 0}, 2, 2)
+	out, weights, err = ScaledDotProductAttention(q, k, v, fullyMasked, 0, false)
+	if err != nil {
+		t.Fatalf("attention failed: %v", err)
+	}
+	if !almostEqualSlices(weights.Data()[:2], []float64{0, 0}, 0) || !almostEqualSlices(out.Data()[:2], []float64{0, 0}, 0) {
+		t.Fatalf("expected zero output for a fully masked row, got %v", out.Data())
+	}
+}
+
+func TestScaledDotProductAttentionBackward(t *testing.T) {
+	qBase, kBase, vBase := attentionInputs()
+	qShape, kShape, vShape := []int{2, 2, 3, 2}, []int{2, 2, 4, 2}, []int{2, 2, 4, 3}
+	mask := make([]float64, 2*1*3*4)
+	for b := 0; b < 2; b++ {
+		mask[b*12+3] = math.Inf(-1) // padding on the last key of query row 0
+	}
+	maskT := MustNew(mask, 2, 1, 3, 4)
+	run := func(q, k, v *Tensor) (*Tensor, *Tensor) {
+		out, weights, err := ScaledDotProductAttention(q, k, v, maskT, 0, false)
+		if err != nil {
+			t.Fatalf("attention failed: %v", err)
+		}
+		return out, weights
+	}
+	checkInterpolateGrad(t, "attention q", qBase, qShape, func(q *Tensor) (*Tensor, error) {
+		out, _ := run(q, MustNew(kBase, kShape...), MustNew(vBase, vShape...))
+		return out, nil
+	})
+	checkInterpolateGrad(t, "attention k", kBase, kShape, func(k *Tensor) (*Tensor, error) {
+		out, _ := run(MustNew(qBase, qShape...), k, MustNew(vBase, vShape...))
+		return out, nil
+	})
+	checkInterpolateGrad(t, "attention v", vBase, vShape, func(v *Tensor) (*Tensor, error) {
+		out, _ := run(MustNew(qBase, qShape...), MustNew(kBase, kShape...), v)
+		return out, nil
+	})
+	checkInterpolateGrad(t, "attention weights", qBase, qShape, func(q *Tensor) (*Tensor, error) {
+		_, weights := run(q, MustNew(kBase, kShape...), MustNew(vBase, vShape...))
+		return weights, nil
+	})
+}
+
+func TestScaledDotProductAttentionDropout(t *testing.T) {
+	qBase, kBase, vBase := attentionInputs()
+	q := MustNew(qBase, 2, 2, 3, 2)
+	k := MustNew(kBase, 2, 2, 4, 2)
+	v := MustNew(vBase, 2, 2, 4, 3)
+	evalOut, _, err := ScaledDotProductAttention(q, k, v, nil, 0.5, false)
+	if err != nil {
+		t.Fatalf("attention failed: %v", err)
+	}
+	plain, _, _ := ScaledDotProductAttention(q, k, v, nil, 0, true)
+	if !almostEqualSlices(evalOut.Data(), plain.Data(), 1e-12) {
+		t.Fatalf("dropout must be inactive outside training")
+	}
+	v.SetRequiresGrad(true)
+	trainOut, weights, err := ScaledDotProductAttention(q, k, v, nil, 0.5, true)
+	if err != nil {
+		t.Fatalf("attention failed: %v", err)
+	}
+	if almostEqualSlices(trainOut.Data(), plain.Data(), 1e-12) {
+		t.Fatalf("expected dropout to change the output in training")
+	}
+	// returned weights are the probabilities before dropout
+	data := weights.Data()
+	for row := 0; row < len(data)/4; row++ {
+		sum := data[row*4] + data[row*4+1] + data[row*4+2] + data[row*4+3]
+		if math.Abs(sum-1) > 1e-12 {
+			t.Fatalf("weights row %d sums to %v", row, sum)
+		}
+	}
+	if err := Sum(trainOut).Backward(); err != nil {
+		t.Fatalf("backward failed: %v", err)
+	}
+	if v.Grad() == nil {
+		t.Fatalf("expected value gradient")
+	}
+	if _, _, err := ScaledDotProductAttention(q, k, v, MustNew(make([]float64, 9), 3, 3), 0, false); err == nil {
+		t.Fatalf("expected mask shape error")
+	}
+}
+
+func TestApplyRotaryPreservesNormAndGrad(t *testing.T) {
+	base := []float64{0.3, -0.7, 1.1, 0.4, 0.2, 0.9, -0.5, 0.8, -1.2, 0.6, 0.1, -0.3}
+	x := MustNew(base, 1, 3, 4)
+	out, err := ApplyRotary(x, 2, 0)
+	if err != nil {
+		t.Fatalf("ApplyRotary failed: %v", err)
+	}
+	data := out.Data()
+	for row := 0; row < 3; row++ {
+		// each (i, i+2) pair is rotated, so its length is unchanged
+		for i := 0; i < 2; i++ {
+			before := math.Hypot(base[row*4+i], base[row*4+i+2])
+			after := math.Hypot(data[row*4+i], data[row*4+i+2])
+			if math.Abs(before-after) > 1e-12 {
+				t.Fatalf("rotation changed pair length: %v vs %v", before, after)
+			}
+		}
+	}
+	checkInterpolateGrad(t, "rotary", base, []int{1, 3, 4}, func(in *Tensor) (*Tensor, error) {
+		return ApplyRotary(in, 2, 0)
+	})
+	if _, err := ApplyRotary(MustNew([]float64{1, 2, 3}, 1, 3), 0, 0); err == nil {
+		t.Fatalf("expected odd dimension error")
+	}
+}
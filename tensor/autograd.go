@@ -25,9 +25,12 @@ func (t *Tensor) Backward() error {
 		if current.grad == nil {
 			current.grad = grad.Clone()
 		} else {
-			addInPlace(current.grad, grad)
+			current.grad = addGrad(current.grad, grad, current.shape)
 		}
 		if current.node != nil {
+			if grad.rows != nil {
+				grad = grad.densify(current.shape)
+			}
 			current.node.backward(grad, grads)
 		}
 	}
@@ -60,7 +63,7 @@ func accumulate(grads map[*Tensor]*Tensor, target *Tensor, value *Tensor) {
 		return
 	}
 	if existing, ok := grads[target]; ok {
-		addInPlace(existing, value)
+		grads[target] = addGrad(existing, value, target.shape)
 	} else {
 		grads[target] = value.Clone()
 	}
@@ -76,3 +79,44 @@ func addInPlace(dst, src *Tensor) {
 		}
 	})
 }
+
+// NewOp builds a tensor from precomputed values whose backward pass is
+// supplied by the caller, so packages outside tensor can define
+// differentiable operations. backward receives the upstream gradient and
+// returns one gradient per input, matching each input's shape; nil entries
+// are skipped.
+func NewOp(data []float64, shape []int, inputs []*Tensor, backward func(grad *Tensor) []*Tensor) (*Tensor, error) {
+	out, err := New(data, shape...)
+	if err != nil {
+		return nil, err
+	}
+	if backward == nil {
+		return out, nil
+	}
+	parents := make([]*Tensor, 0, len(inputs))
+	for _, in := range inputs {
+		if in != nil && in.requiresGrad {
+			parents = append(parents, in)
+		}
+	}
+	if len(parents) == 0 {
+		return out, nil
+	}
+	out.requiresGrad = true
+	out.parents = parents
+	out.node = &node{
+		backward: func(grad *Tensor, grads map[*Tensor]*Tensor) {
+			gs := backward(grad)
+			for i, in := range inputs {
+				if i >= len(gs) || gs[i] == nil || in == nil || !in.requiresGrad {
+					continue
+				}
+				if err := ensureSameShape(in, gs[i]); err != nil {
+					panic("NewOp gradient shape mismatch")
+				}
+				accumulate(grads, in, gs[i])
+			}
+		},
+	}
+	return out, nil
+}
@@ -6,14 +6,15 @@ import (
 )
 
 // BatchNorm applies batch normalization to inputs.
-// Supports 2D ([batch, features]) and 4D ([batch, channels, H, W]) tensors.
+// Supports [batch, features] tensors and [batch, channels, spatial...] tensors
+// with one to three spatial dimensions (ranks 3 to 5).
 func BatchNorm(input, runningMean, runningVar, weight, bias *Tensor, momentum, eps float64, training bool) (*Tensor, error) {
 	if input == nil {
 		return nil, errors.New("BatchNorm requires input tensor")
 	}
 	rank := len(input.shape)
-	if rank != 2 && rank != 4 {
-		return nil, errors.New("BatchNorm supports rank 2 or 4 tensors")
+	if rank < 2 || rank > 5 {
+		return nil, errors.New("BatchNorm supports rank 2 to 5 tensors")
 	}
 	channels := input.shape[1]
 	if runningMean != nil && len(runningMean.shape) != 1 {
@@ -34,55 +35,32 @@ func BatchNorm(input, runningMean, runningVar, weight, bias *Tensor, momentum, e
 	varVals := make([]float64, channels)
 	invStd := make([]float64, channels)
 
-	countPerChannel := input.shape[0]
-	if rank == 4 {
-		countPerChannel *= input.shape[2] * input.shape[3]
+	batch := input.shape[0]
+	inner := 1
+	for _, dim := range input.shape[2:] {
+		inner *= dim
+	}
+	count := float64(batch * inner)
+	// each visits the flat indices of channel c
+	each := func(c int, f func(idx int)) {
+		for n := 0; n < batch; n++ {
+			base := (n*channels + c) * inner
+			for s := 0; s < inner; s++ {
+				f(base + s)
+			}
+		}
 	}
-	count := float64(countPerChannel)
 
 	if training {
-		// compute mean
-		for n := 0; n < input.shape[0]; n++ {
-			if rank == 2 {
-				for c := 0; c < channels; c++ {
-					idx := n*channels + c
-					mean[c] += input.data[idx]
-				}
-				continue
-			}
-			for c := 0; c < channels; c++ {
-				for h := 0; h < input.shape[2]; h++ {
-					for w := 0; w < input.shape[3]; w++ {
-						idx := ((n*channels+c)*input.shape[2]+h)*input.shape[3] + w
-						mean[c] += input.data[idx]
-					}
-				}
-			}
-		}
 		for c := 0; c < channels; c++ {
+			each(c, func(idx int) {
+				mean[c] += input.data[idx]
+			})
 			mean[c] /= count
-		}
-		// compute variance
-		for n := 0; n < input.shape[0]; n++ {
-			if rank == 2 {
-				for c := 0; c < channels; c++ {
-					idx := n*channels + c
-					diff := input.data[idx] - mean[c]
-					varVals[c] += diff * diff
-				}
-				continue
-			}
-			for c := 0; c < channels; c++ {
-				for h := 0; h < input.shape[2]; h++ {
-					for w := 0; w < input.shape[3]; w++ {
-						idx := ((n*channels+c)*input.shape[2]+h)*input.shape[3] + w
-						diff := input.data[idx] - mean[c]
-						varVals[c] += diff * diff
-					}
-				}
-			}
-		}
-		for c := 0; c < channels; c++ {
+			each(c, func(idx int) {
+				diff := input.data[idx] - mean[c]
+				varVals[c] += diff * diff
+			})
 			varVals[c] /= count
 			invStd[c] = 1.0 / math.Sqrt(varVals[c]+eps)
 			if runningMean != nil {
@@ -102,34 +80,17 @@ func BatchNorm(input, runningMean, runningVar, weight, bias *Tensor, momentum, e
 		}
 	}
 
-	apply := func(index int, channel int) {
-		norm := (input.data[index] - mean[channel]) * invStd[channel]
-		if weight != nil {
-			norm *= weight.data[channel]
-		}
-		if bias != nil {
-			norm += bias.data[channel]
-		}
-		o.data[index] = norm
-	}
-
-	if rank == 2 {
-		for n := 0; n < input.shape[0]; n++ {
-			for c := 0; c < channels; c++ {
-				apply(n*channels+c, c)
+	for c := 0; c < channels; c++ {
+		each(c, func(idx int) {
+			norm := (input.data[idx] - mean[c]) * invStd[c]
+			if weight != nil {
+				norm *= weight.data[c]
 			}
-		}
-	} else {
-		for n := 0; n < input.shape[0]; n++ {
-			for c := 0; c < channels; c++ {
-				for h := 0; h < input.shape[2]; h++ {
-					for w := 0; w < input.shape[3]; w++ {
-						idx := ((n*channels+c)*input.shape[2]+h)*input.shape[3] + w
-						apply(idx, c)
-					}
-				}
+			if bias != nil {
+				norm += bias.data[c]
 			}
-		}
+			o.data[idx] = norm
+		})
 	}
 
 	if !(input.requiresGrad || (weight != nil && weight.requiresGrad) || (bias != nil && bias.requiresGrad)) {
@@ -165,75 +126,37 @@ func BatchNorm(input, runningMean, runningVar, weight, bias *Tensor, momentum, e
 			sumGradOrig := make([]float64, channels)
 			sumGradOrigXhat := make([]float64, channels)
 
-			evaluate := func(idx int, c int) (float64, float64, float64) {
-				x := input.data[idx]
-				goVal := grad.data[idx]
-				scaled := goVal
-				if hasWeight {
-					scaled *= weightData[c]
-				}
-				xhat := (x - savedMean[c]) * savedInvStd[c]
-				sumGrad[c] += scaled
-				sumGradXhat[c] += scaled * xhat
-				sumGradOrig[c] += goVal
-				sumGradOrigXhat[c] += goVal * xhat
-				return scaled, xhat, goVal
-			}
-
-			if rank == 2 {
-				for n := 0; n < input.shape[0]; n++ {
-					for c := 0; c < channels; c++ {
-						idx := n*channels + c
-						evaluate(idx, c)
-					}
-				}
-			} else {
-				for n := 0; n < input.shape[0]; n++ {
-					for c := 0; c < channels; c++ {
-						for h := 0; h < input.shape[2]; h++ {
-							for w := 0; w < input.shape[3]; w++ {
-								idx := ((n*channels+c)*input.shape[2]+h)*input.shape[3] + w
-								evaluate(idx, c)
-							}
-						}
+			for c := 0; c < channels; c++ {
+				each(c, func(idx int) {
+					goVal := grad.data[idx]
+					scaled := goVal
+					if hasWeight {
+						scaled *= weightData[c]
 					}
-				}
+					xhat := (input.data[idx] - savedMean[c]) * savedInvStd[c]
+					sumGrad[c] += scaled
+					sumGradXhat[c] += scaled * xhat
+					sumGradOrig[c] += goVal
+					sumGradOrigXhat[c] += goVal * xhat
+				})
 			}
 
 			if input.requiresGrad {
 				gInput := Zeros(input.shape...)
-				if rank == 2 {
-					for n := 0; n < input.shape[0]; n++ {
-						for c := 0; c < channels; c++ {
-							idx := n*channels + c
-							goVal := grad.data[idx]
-							scaled := goVal
-							if hasWeight {
-								scaled *= weightData[c]
-							}
-							xhat := (input.data[idx] - savedMean[c]) * savedInvStd[c]
-							temp := scaled - sumGrad[c]/savedCount - xhat*sumGradXhat[c]/savedCount
-							gInput.data[idx] = temp * savedInvStd[c]
+				for c := 0; c < channels; c++ {
+					each(c, func(idx int) {
+						scaled := grad.data[idx]
+						if hasWeight {
+							scaled *= weightData[c]
 						}
-					}
-				} else {
-					for n := 0; n < input.shape[0]; n++ {
-						for c := 0; c < channels; c++ {
-							for h := 0; h < input.shape[2]; h++ {
-								for w := 0; w < input.shape[3]; w++ {
-									idx := ((n*channels+c)*input.shape[2]+h)*input.shape[3] + w
-									goVal := grad.data[idx]
-									scaled := goVal
-									if hasWeight {
-										scaled *= weightData[c]
-									}
-									xhat := (input.data[idx] - savedMean[c]) * savedInvStd[c]
-									temp := scaled - sumGrad[c]/savedCount - xhat*sumGradXhat[c]/savedCount
-									gInput.data[idx] = temp * savedInvStd[c]
-								}
-							}
+						xhat := (input.data[idx] - savedMean[c]) * savedInvStd[c]
+						temp := scaled
+						if training {
+							// batch statistics depend on every input
+							temp -= sumGrad[c]/savedCount + xhat*sumGradXhat[c]/savedCount
 						}
-					}
+						gInput.data[idx] = temp * savedInvStd[c]
+					})
 				}
 				accumulate(grads, input, gInput)
 			}
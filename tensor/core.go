@@ -12,6 +12,9 @@ type Tensor struct {
 	requiresGrad bool
 	node         *node
 	parents      []*Tensor
+	// rows marks a row-sparse gradient: data then holds only these rows
+	// (indices along dimension 0) of a tensor shaped like its target.
+	rows []int
 }
 
 type node struct {
@@ -88,6 +91,7 @@ func (t *Tensor) Clone() *Tensor {
 		data:    append([]float64(nil), t.data...),
 		shape:   append([]int(nil), t.shape...),
 		strides: append([]int(nil), t.strides...),
+		rows:    append([]int(nil), t.rows...),
 	}
 	return clone
 }
@@ -125,6 +129,9 @@ func (t *Tensor) Grad() *Tensor {
 	if t.grad == nil {
 		return nil
 	}
+	if t.grad.rows != nil {
+		return t.grad.densify(t.shape)
+	}
 	return t.grad.Clone()
 }
 
@@ -1,11 +1,22 @@
 package tensor
 
-import "errors"
+import (
+	"errors"
+	"math"
+)
 
 // Embedding looks up embeddings for given indices from weight matrix.
 // weight shape: [num_embeddings, embedding_dim...]
 // index shape: arbitrary; values are treated as integer indices.
 func Embedding(weight *Tensor, index *Tensor) (*Tensor, error) {
+	return EmbeddingWithOptions(weight, index, -1, false)
+}
+
+// EmbeddingWithOptions is Embedding with two gradient options: lookups of
+// row paddingIdx contribute no gradient (negative disables this), and sparse
+// makes the weight gradient row-sparse, holding only the rows that were
+// looked up (see SparseGrad).
+func EmbeddingWithOptions(weight *Tensor, index *Tensor, paddingIdx int, sparse bool) (*Tensor, error) {
 	if index == nil {
 		return nil, errors.New("index tensor required")
 	}
@@ -13,10 +24,7 @@ func Embedding(weight *Tensor, index *Tensor) (*Tensor, error) {
 		return nil, errors.New("weight must have rank >= 2")
 	}
 	numEmb := weight.shape[0]
-	embedSize := 1
-	for _, dim := range weight.shape[1:] {
-		embedSize *= dim
-	}
+	embedSize := rowWidth(weight.shape)
 	outShape := append([]int(nil), index.shape...)
 	outShape = append(outShape, weight.shape[1:]...)
 	out := Zeros(outShape...)
@@ -36,22 +44,250 @@ func Embedding(weight *Tensor, index *Tensor) (*Tensor, error) {
 		return out, nil
 	}
 
+	indices := append([]float64(nil), index.data...)
 	out.requiresGrad = true
 	out.parents = []*Tensor{weight}
 	out.node = &node{
 		backward: func(grad *Tensor, grads map[*Tensor]*Tensor) {
-			gWeight := Zeros(weight.shape...)
-			for idx := 0; idx < totalIndices; idx++ {
-				val := int(index.data[idx])
-				srcStart := idx * embedSize
-				dstStart := val * embedSize
-				for j := 0; j < embedSize; j++ {
-					gWeight.data[dstStart+j] += grad.data[srcStart+j]
+			gWeight := newRowGrad(weight.shape, sparse)
+			for idx, v := range indices {
+				val := int(v)
+				if val == paddingIdx {
+					continue
+				}
+				dst := gWeight.row(val)
+				for j, g := range grad.data[idx*embedSize : (idx+1)*embedSize] {
+					dst[j] += g
 				}
 			}
-			accumulate(grads, weight, gWeight)
+			accumulate(grads, weight, gWeight.tensor())
 		},
 	}
 
 	return out, nil
 }
+
+// EmbeddingRenorm rescales, in place and outside the graph, every row of
+// weight referenced by index whose normType-norm exceeds maxNorm so that its
+// norm becomes maxNorm.
+func EmbeddingRenorm(weight, index *Tensor, maxNorm, normType float64) error {
+	if weight == nil || index == nil {
+		return errors.New("EmbeddingRenorm requires weight and index tensors")
+	}
+	if len(weight.shape) < 2 {
+		return errors.New("weight must have rank >= 2")
+	}
+	if maxNorm <= 0 || normType <= 0 {
+		return errors.New("EmbeddingRenorm requires positive maxNorm and normType")
+	}
+	width := rowWidth(weight.shape)
+	seen := map[int]bool{}
+	for _, v := range index.data {
+		row := int(v)
+		if row < 0 || row >= weight.shape[0] {
+			return errors.New("embedding index out of range")
+		}
+		if seen[row] {
+			continue
+		}
+		seen[row] = true
+		values := weight.data[row*width : (row+1)*width]
+		norm := 0.0
+		for _, x := range values {
+			norm += math.Pow(math.Abs(x), normType)
+		}
+		norm = math.Pow(norm, 1/normType)
+		if norm <= maxNorm {
+			continue
+		}
+		scale := maxNorm / (norm + 1e-7)
+		for j := range values {
+			values[j] *= scale
+		}
+	}
+	return nil
+}
+
+// EmbeddingBag reduces bags of embeddings from a [num_embeddings, dim] weight
+// without materialising the individual lookups. index is either 2-D
+// [bags, length], one bag per row, with offsets nil, or 1-D with offsets
+// giving the start of each bag; the first offset must be 0. mode is "sum", "mean" or "max"; the result
+// is [bags, dim] and empty bags yield zeros. perSampleWeights, shaped like
+// index, scales each lookup in "sum" mode. Lookups of paddingIdx (negative
+// disables this) are left out of their bag, and sparse makes the weight
+// gradient row-sparse.
+func EmbeddingBag(weight, index *Tensor, offsets []int, perSampleWeights *Tensor, mode string, paddingIdx int, sparse bool) (*Tensor, error) {
+	if weight == nil || index == nil {
+		return nil, errors.New("EmbeddingBag requires weight and index tensors")
+	}
+	if len(weight.shape) != 2 {
+		return nil, errors.New("EmbeddingBag weight must have rank 2")
+	}
+	if mode != "sum" && mode != "mean" && mode != "max" {
+		return nil, errors.New("EmbeddingBag mode must be sum, mean or max")
+	}
+	var starts []int
+	switch len(index.shape) {
+	case 2:
+		if offsets != nil {
+			return nil, errors.New("EmbeddingBag offsets must be nil for 2-D index")
+		}
+		length := index.shape[1]
+		starts = make([]int, index.shape[0])
+		for b := range starts {
+			starts[b] = b * length
+		}
+	case 1:
+		if len(offsets) == 0 {
+			return nil, errors.New("EmbeddingBag requires offsets for 1-D index")
+		}
+		if offsets[0] != 0 {
+			return nil, errors.New("EmbeddingBag offsets must start at 0")
+		}
+		for b, start := range offsets {
+			if start < 0 || start > len(index.data) || (b > 0 && start < offsets[b-1]) {
+				return nil, errors.New("EmbeddingBag offsets must be non-decreasing and within the index")
+			}
+		}
+		starts = append([]int(nil), offsets...)
+	default:
+		return nil, errors.New("EmbeddingBag index must be 1-D or 2-D")
+	}
+	if perSampleWeights != nil {
+		if mode != "sum" {
+			return nil, errors.New("EmbeddingBag per-sample weights require sum mode")
+		}
+		if ensureSameShape(perSampleWeights, index) != nil {
+			return nil, errors.New("EmbeddingBag per-sample weights must match the index shape")
+		}
+	}
+	numEmb, dim := weight.shape[0], weight.shape[1]
+	bags := len(starts)
+	ends := make([]int, bags)
+	for b := range starts {
+		if b+1 < bags {
+			ends[b] = starts[b+1]
+		} else {
+			ends[b] = len(index.data)
+		}
+	}
+	rows := make([]int, len(index.data))
+	for i, v := range index.data {
+		rows[i] = int(v)
+		if rows[i] < 0 || rows[i] >= numEmb {
+			return nil, errors.New("embedding index out of range")
+		}
+	}
+	sampleWeight := func(i int) float64 {
+		if perSampleWeights == nil {
+			return 1
+		}
+		return perSampleWeights.data[i]
+	}
+
+	out := Zeros(bags, dim)
+	counts := make([]int, bags)
+	// argmax[b*dim+j] is the lookup position that won column j in "max" mode
+	var argmax []int
+	if mode == "max" {
+		argmax = make([]int, bags*dim)
+		for i := range argmax {
+			argmax[i] = -1
+		}
+	}
+	for b := 0; b < bags; b++ {
+		dst := out.data[b*dim : (b+1)*dim]
+		for i := starts[b]; i < ends[b]; i++ {
+			row := rows[i]
+			if row == paddingIdx {
+				continue
+			}
+			counts[b]++
+			src := weight.data[row*dim : (row+1)*dim]
+			if mode == "max" {
+				for j, x := range src {
+					if argmax[b*dim+j] < 0 || x > dst[j] {
+						dst[j] = x
+						argmax[b*dim+j] = i
+					}
+				}
+				continue
+			}
+			w := sampleWeight(i)
+			for j, x := range src {
+				dst[j] += w * x
+			}
+		}
+		if mode == "mean" && counts[b] > 0 {
+			for j := range dst {
+				dst[j] /= float64(counts[b])
+			}
+		}
+	}
+
+	if !weight.requiresGrad && (perSampleWeights == nil || !perSampleWeights.requiresGrad) {
+		return out, nil
+	}
+	var weightData []float64
+	var parents []*Tensor
+	if weight.requiresGrad {
+		parents = append(parents, weight)
+	}
+	if perSampleWeights != nil && perSampleWeights.requiresGrad {
+		weightData = append([]float64(nil), weight.data...)
+		parents = append(parents, perSampleWeights)
+	}
+	out.requiresGrad = true
+	out.parents = parents
+	out.node = &node{
+		backward: func(grad *Tensor, grads map[*Tensor]*Tensor) {
+			if weight.requiresGrad {
+				gWeight := newRowGrad(weight.shape, sparse)
+				for b := 0; b < bags; b++ {
+					upstream := grad.data[b*dim : (b+1)*dim]
+					if mode == "max" {
+						for j, g := range upstream {
+							if i := argmax[b*dim+j]; i >= 0 {
+								gWeight.row(rows[i])[j] += g
+							}
+						}
+						continue
+					}
+					scale := 1.0
+					if mode == "mean" && counts[b] > 0 {
+						scale = 1 / float64(counts[b])
+					}
+					for i := starts[b]; i < ends[b]; i++ {
+						if rows[i] == paddingIdx {
+							continue
+						}
+						w := scale * sampleWeight(i)
+						dst := gWeight.row(rows[i])
+						for j, g := range upstream {
+							dst[j] += w * g
+						}
+					}
+				}
+				accumulate(grads, weight, gWeight.tensor())
+			}
+			if perSampleWeights != nil && perSampleWeights.requiresGrad {
+				gSample := Zeros(perSampleWeights.shape...)
+				for b := 0; b < bags; b++ {
+					upstream := grad.data[b*dim : (b+1)*dim]
+					for i := starts[b]; i < ends[b]; i++ {
+						if rows[i] == paddingIdx {
+							continue
+						}
+						dot := 0.0
+						for j, g := range upstream {
+							dot += g * weightData[rows[i]*dim+j]
+						}
+						gSample.data[i] = dot
+					}
+				}
+				accumulate(grads, perSampleWeights, gSample)
+			}
+		},
+	}
+	return out, nil
+}
@@ -0,0 +1,139 @@
+package tensor
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEmbeddingPaddingAndSparseGrad(t *testing.T) {
+	weight := MustNew(sequenceValues(8, 0.3), 4, 2)
+	weight.SetRequiresGrad(true)
+	index := MustNew([]float64{1, 0, 1, 3}, 2, 2)
+	out, err := EmbeddingWithOptions(weight, index, 0, true)
+	if err != nil {
+		t.Fatalf("EmbeddingWithOptions failed: %v", err)
+	}
+	if !almostEqualSlices(out.Data()[2:4], weight.Data()[0:2], 1e-12) {
+		t.Fatalf("padding row must still be looked up: %v", out.Data())
+	}
+	if err := weightedLoss(out).Backward(); err != nil {
+		t.Fatalf("backward failed: %v", err)
+	}
+	rows, values := weight.SparseGrad()
+	if len(rows) != 2 || rows[0] != 1 || rows[1] != 3 {
+		t.Fatalf("unexpected sparse rows %v", rows)
+	}
+	if !equalShapes(values.Shape(), []int{2, 2}) {
+		t.Fatalf("unexpected sparse values shape %v", values.Shape())
+	}
+
+	dense := MustNew(weight.Data(), 4, 2)
+	dense.SetRequiresGrad(true)
+	ref, _ := Embedding(dense, index)
+	if err := weightedLoss(ref).Backward(); err != nil {
+		t.Fatalf("dense backward failed: %v", err)
+	}
+	want := dense.Grad().Data()
+	want[0], want[1] = 0, 0
+	if !almostEqualSlices(weight.Grad().Data(), want, 1e-12) {
+		t.Fatalf("sparse grad %v, want %v", weight.Grad().Data(), want)
+	}
+
+	// a second sparse pass merges rows; a dense contribution densifies
+	out, _ = EmbeddingWithOptions(weight, MustNew([]float64{2, 3}, 2), 0, true)
+	if err := Sum(out).Backward(); err != nil {
+		t.Fatalf("second backward failed: %v", err)
+	}
+	if rows, _ := weight.SparseGrad(); len(rows) != 3 {
+		t.Fatalf("expected merged rows, got %v", rows)
+	}
+	want[4], want[5] = want[4]+1, want[5]+1
+	want[6], want[7] = want[6]+1, want[7]+1
+	if !almostEqualSlices(weight.Grad().Data(), want, 1e-12) {
+		t.Fatalf("merged grad %v, want %v", weight.Grad().Data(), want)
+	}
+	if err := Sum(weight).Backward(); err != nil {
+		t.Fatalf("dense backward failed: %v", err)
+	}
+	if rows, _ := weight.SparseGrad(); rows != nil {
+		t.Fatalf("mixed gradient should be dense")
+	}
+	for i := range want {
+		want[i]++
+	}
+	if !almostEqualSlices(weight.Grad().Data(), want, 1e-12) {
+		t.Fatalf("mixed grad %v, want %v", weight.Grad().Data(), want)
+	}
+}
+
+func TestEmbeddingRenorm(t *testing.T) {
+	weight := MustNew([]float64{3, 4, 0.3, 0.4, 6, 8}, 3, 2)
+	if err := EmbeddingRenorm(weight, MustNew([]float64{0, 1, 0}, 3), 1, 2); err != nil {
+		t.Fatalf("EmbeddingRenorm failed: %v", err)
+	}
+	got := weight.Data()
+	if math.Abs(math.Hypot(got[0], got[1])-1) > 1e-6 || got[2] != 0.3 || got[4] != 6 {
+		t.Fatalf("unexpected renormalised weight %v", got)
+	}
+	if err := EmbeddingRenorm(weight, MustNew([]float64{5}, 1), 1, 2); err == nil {
+		t.Fatalf("expected out-of-range error")
+	}
+}
+
+func TestEmbeddingBag(t *testing.T) {
+	weight := MustNew([]float64{1, -1, 2, 5, -3, 0, 4, 2}, 4, 2)
+	index := MustNew([]float64{0, 1, 3, 2, 1}, 5)
+	offsets := []int{0, 2, 2}
+	cases := []struct {
+		mode string
+		want []float64
+	}{
+		{"sum", []float64{3, 4, 0, 0, 3, 7}},
+		{"mean", []float64{1.5, 2, 0, 0, 1, 7.0 / 3}},
+		{"max", []float64{2, 5, 0, 0, 4, 5}},
+	}
+	for _, tc := range cases {
+		out, err := EmbeddingBag(weight, index, offsets, nil, tc.mode, -1, false)
+		if err != nil {
+			t.Fatalf("%s EmbeddingBag failed: %v", tc.mode, err)
+		}
+		if !almostEqualSlices(out.Data(), tc.want, 1e-12) {
+			t.Fatalf("%s bag = %v, want %v", tc.mode, out.Data(), tc.want)
+		}
+	}
+	// padding lookups leave their bag, including its mean denominator
+	out, _ := EmbeddingBag(weight, MustNew([]float64{0, 3, 3, 3}, 2, 2), nil, nil, "mean", 3, false)
+	if !almostEqualSlices(out.Data(), []float64{1, -1, 0, 0}, 1e-12) {
+		t.Fatalf("unexpected padded bag %v", out.Data())
+	}
+
+	base := sequenceValues(8, 0.7)
+	for _, mode := range []string{"sum", "mean", "max"} {
+		mode := mode
+		checkInterpolateGrad(t, "EmbeddingBag "+mode, base, []int{4, 2}, func(w *Tensor) (*Tensor, error) {
+			return EmbeddingBag(w, index, offsets, nil, mode, 2, false)
+		})
+	}
+	checkInterpolateGrad(t, "EmbeddingBag per-sample weights", []float64{0.5, -1, 2, 0.3, 1.5}, []int{5}, func(ps *Tensor) (*Tensor, error) {
+		return EmbeddingBag(MustNew(base, 4, 2), index, offsets, ps, "sum", -1, false)
+	})
+
+	sparseWeight := MustNew(base, 4, 2)
+	sparseWeight.SetRequiresGrad(true)
+	out, _ = EmbeddingBag(sparseWeight, index, offsets, nil, "sum", 2, true)
+	if err := Sum(out).Backward(); err != nil {
+		t.Fatalf("sparse bag backward failed: %v", err)
+	}
+	if rows, _ := sparseWeight.SparseGrad(); len(rows) != 3 {
+		t.Fatalf("unexpected sparse bag rows %v", rows)
+	}
+	if _, err := EmbeddingBag(weight, index, offsets, MustNew(make([]float64, 5), 5), "max", -1, false); err == nil {
+		t.Fatalf("expected per-sample weights to require sum mode")
+	}
+	if _, err := EmbeddingBag(weight, index, []int{2, 1}, nil, "sum", -1, false); err == nil {
+		t.Fatalf("expected decreasing offsets error")
+	}
+	if _, err := EmbeddingBag(weight, index, []int{1, 2}, nil, "sum", -1, false); err == nil {
+		t.Fatalf("expected error for offsets not starting at 0")
+	}
+}
@@ -0,0 +1,179 @@
+package fft
+
+import (
+	"errors"
+	"math/cmplx"
+
+	"github.com/fumitoshi0524/ixeoriNet/internal/parallel"
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// Conv1D computes the same result as tensor.Conv1D using FFT-based
+// correlation, which is cheaper when the kernel is long.
+// Input shape: [batch, in_channels, width]
+// Weight shape: [out_channels, in_channels, kernel_w]
+// Bias shape (optional): [out_channels]
+func Conv1D(input, weight, bias *tensor.Tensor, stride, pad int) (*tensor.Tensor, error) {
+	inShape := input.Shape()
+	wShape := weight.Shape()
+	if len(inShape) != 3 {
+		return nil, errors.New("Conv1D expects input shape [batch, channels, width]")
+	}
+	if len(wShape) != 3 {
+		return nil, errors.New("Conv1D expects weight shape [out_channels, in_channels, kernel_w]")
+	}
+	if bias != nil && len(bias.Shape()) != 1 {
+		return nil, errors.New("bias for Conv1D must be rank 1")
+	}
+	batch, inChannels, inW := inShape[0], inShape[1], inShape[2]
+	outChannels, kernelChannels, kernelW := wShape[0], wShape[1], wShape[2]
+	if kernelChannels != inChannels {
+		return nil, errors.New("kernel in_channels mismatch")
+	}
+	if stride <= 0 {
+		return nil, errors.New("stride must be positive")
+	}
+	if pad < 0 {
+		return nil, errors.New("padding must be non-negative")
+	}
+	paddedW := inW + 2*pad
+	outW := (paddedW-kernelW)/stride + 1
+	if outW <= 0 {
+		return nil, errors.New("invalid output size")
+	}
+	size := nextPow2(paddedW)
+
+	xData := input.Data()
+	wData := weight.Data()
+	// spectra of the zero-padded input rows and kernels
+	xSpec := make([][]complex128, batch*inChannels)
+	parallel.For(batch*inChannels, func(start, end int) {
+		for i := start; i < end; i++ {
+			buf := make([]complex128, size)
+			for k := 0; k < inW; k++ {
+				buf[pad+k] = complex(xData[i*inW+k], 0)
+			}
+			transform(buf, false)
+			xSpec[i] = buf
+		}
+	})
+	wSpec := spectra(wData, outChannels*inChannels, kernelW, size)
+
+	var biasData []float64
+	if bias != nil {
+		biasData = bias.Data()
+	}
+	out := make([]float64, batch*outChannels*outW)
+	parallel.For(batch*outChannels, func(start, end int) {
+		acc := make([]complex128, size)
+		for idx := start; idx < end; idx++ {
+			n, oc := idx/outChannels, idx%outChannels
+			for i := range acc {
+				acc[i] = 0
+			}
+			for ic := 0; ic < inChannels; ic++ {
+				xs := xSpec[n*inChannels+ic]
+				ws := wSpec[oc*inChannels+ic]
+				for f := range acc {
+					acc[f] += xs[f] * cmplx.Conj(ws[f])
+				}
+			}
+			transform(acc, true)
+			for ow := 0; ow < outW; ow++ {
+				v := real(acc[ow*stride]) / float64(size)
+				if biasData != nil {
+					v += biasData[oc]
+				}
+				out[idx*outW+ow] = v
+			}
+		}
+	})
+
+	inputs := []*tensor.Tensor{input, weight, bias}
+	return tensor.NewOp(out, []int{batch, outChannels, outW}, inputs, func(grad *tensor.Tensor) []*tensor.Tensor {
+		g := grad.Data()
+		// spectra of the stride-upsampled output gradients
+		gUp := make([]float64, batch*outChannels*size)
+		for idx := 0; idx < batch*outChannels; idx++ {
+			for ow := 0; ow < outW; ow++ {
+				gUp[idx*size+ow*stride] = g[idx*outW+ow]
+			}
+		}
+		gSpec := spectra(gUp, batch*outChannels, size, size)
+		grads := make([]*tensor.Tensor, 3)
+		if input.RequiresGrad() {
+			gIn := make([]float64, len(xData))
+			parallel.For(batch*inChannels, func(start, end int) {
+				acc := make([]complex128, size)
+				for idx := start; idx < end; idx++ {
+					n, ic := idx/inChannels, idx%inChannels
+					for i := range acc {
+						acc[i] = 0
+					}
+					for oc := 0; oc < outChannels; oc++ {
+						gs := gSpec[n*outChannels+oc]
+						ws := wSpec[oc*inChannels+ic]
+						for f := range acc {
+							acc[f] += gs[f] * ws[f]
+						}
+					}
+					transform(acc, true)
+					for k := 0; k < inW; k++ {
+						gIn[idx*inW+k] = real(acc[pad+k]) / float64(size)
+					}
+				}
+			})
+			grads[0] = tensor.MustNew(gIn, inShape...)
+		}
+		if weight.RequiresGrad() {
+			gW := make([]float64, len(wData))
+			parallel.For(outChannels*inChannels, func(start, end int) {
+				acc := make([]complex128, size)
+				for idx := start; idx < end; idx++ {
+					oc, ic := idx/inChannels, idx%inChannels
+					for i := range acc {
+						acc[i] = 0
+					}
+					for n := 0; n < batch; n++ {
+						xs := xSpec[n*inChannels+ic]
+						gs := gSpec[n*outChannels+oc]
+						for f := range acc {
+							acc[f] += xs[f] * cmplx.Conj(gs[f])
+						}
+					}
+					transform(acc, true)
+					for k := 0; k < kernelW; k++ {
+						gW[idx*kernelW+k] = real(acc[k]) / float64(size)
+					}
+				}
+			})
+			grads[1] = tensor.MustNew(gW, wShape...)
+		}
+		if bias != nil && bias.RequiresGrad() {
+			gB := make([]float64, outChannels)
+			for idx := 0; idx < batch*outChannels; idx++ {
+				for ow := 0; ow < outW; ow++ {
+					gB[idx%outChannels] += g[idx*outW+ow]
+				}
+			}
+			grads[2] = tensor.MustNew(gB, outChannels)
+		}
+		return grads
+	})
+}
+
+// spectra zero-pads count rows of the given width to size and transforms them.
+func spectra(data []float64, count, width, size int) [][]complex128 {
+	out := make([][]complex128, count)
+	parallel.For(count, func(start, end int) {
+		for i := start; i < end; i++ {
+			buf := make([]complex128, size)
+			for k := 0; k < width; k++ {
+				buf[k] = complex(data[i*width+k], 0)
+			}
+			transform(buf, false)
+			out[i] = buf
+		}
+	})
+	return out
+}
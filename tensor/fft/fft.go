@@ -0,0 +1,408 @@
+// Package fft provides differentiable discrete Fourier transforms built on
+// top of tensor. Complex tensors are represented as real/imaginary pairs.
+package fft
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+
+	"github.com/fumitoshi0524/ixeoriNet/internal/parallel"
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// Complex holds the real and imaginary parts of a complex tensor. Imag may
+// be nil, in which case it is treated as zero.
+type Complex struct {
+	Real *tensor.Tensor
+	Imag *tensor.Tensor
+}
+
+// Abs returns the element-wise magnitude sqrt(re^2 + im^2) with gradients.
+func (c Complex) Abs() (*tensor.Tensor, error) {
+	if c.Real == nil {
+		return nil, errors.New("Complex requires a real part")
+	}
+	power := tensor.Pow(c.Real, 2)
+	if c.Imag != nil {
+		var err error
+		power, err = tensor.Add(power, tensor.Pow(c.Imag, 2))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tensor.Pow(power, 0.5), nil
+}
+
+// FFT computes the one-dimensional discrete Fourier transform along axis.
+func FFT(x Complex, axis int) (Complex, error) {
+	shape, axis, err := checkComplex(x, axis, "FFT")
+	if err != nil {
+		return Complex{}, err
+	}
+	n := shape[axis]
+	forward := func(re, im []float64) ([]float64, []float64) {
+		return mapAxis(re, im, shape, axis, n, func(in, out []complex128) {
+			copy(out, in)
+			transform(out, false)
+		})
+	}
+	adjoint := func(re, im []float64) ([]float64, []float64) {
+		return mapAxis(re, im, shape, axis, n, func(in, out []complex128) {
+			copy(out, in)
+			transform(out, true)
+		})
+	}
+	return linearOp(x, shape, shape, forward, adjoint, true)
+}
+
+// IFFT computes the inverse transform of FFT along axis, scaled by 1/n.
+func IFFT(x Complex, axis int) (Complex, error) {
+	shape, axis, err := checkComplex(x, axis, "IFFT")
+	if err != nil {
+		return Complex{}, err
+	}
+	n := shape[axis]
+	inv := 1 / float64(n)
+	forward := func(re, im []float64) ([]float64, []float64) {
+		return mapAxis(re, im, shape, axis, n, func(in, out []complex128) {
+			copy(out, in)
+			transform(out, true)
+			scaleComplex(out, inv)
+		})
+	}
+	adjoint := func(re, im []float64) ([]float64, []float64) {
+		return mapAxis(re, im, shape, axis, n, func(in, out []complex128) {
+			copy(out, in)
+			transform(out, false)
+			scaleComplex(out, inv)
+		})
+	}
+	return linearOp(x, shape, shape, forward, adjoint, true)
+}
+
+// FFT2 computes the two-dimensional transform over the last two axes.
+func FFT2(x Complex) (Complex, error) {
+	out, err := FFT(x, -1)
+	if err != nil {
+		return Complex{}, err
+	}
+	return FFT(out, -2)
+}
+
+// IFFT2 computes the inverse of FFT2 over the last two axes.
+func IFFT2(x Complex) (Complex, error) {
+	out, err := IFFT(x, -1)
+	if err != nil {
+		return Complex{}, err
+	}
+	return IFFT(out, -2)
+}
+
+// RFFT computes the transform of a real tensor along axis and keeps the
+// n/2+1 non-negative frequency bins.
+func RFFT(x *tensor.Tensor, axis int) (Complex, error) {
+	in := Complex{Real: x}
+	shape, axis, err := checkComplex(in, axis, "RFFT")
+	if err != nil {
+		return Complex{}, err
+	}
+	n := shape[axis]
+	bins := n/2 + 1
+	outShape := append([]int(nil), shape...)
+	outShape[axis] = bins
+	forward := func(re, im []float64) ([]float64, []float64) {
+		return mapAxis(re, nil, shape, axis, bins, func(in, out []complex128) {
+			transform(in, false)
+			copy(out, in[:bins])
+		})
+	}
+	adjoint := func(re, im []float64) ([]float64, []float64) {
+		gr, _ := mapAxis(re, im, outShape, axis, n, func(in, out []complex128) {
+			for i := range out {
+				out[i] = 0
+			}
+			copy(out, in)
+			transform(out, true)
+		})
+		return gr, nil
+	}
+	return linearOp(in, shape, outShape, forward, adjoint, true)
+}
+
+// IRFFT inverts RFFT along axis and returns a real tensor of length n along
+// that axis. When n <= 0 it defaults to 2*(bins-1). The imaginary parts of
+// the zero and Nyquist bins are ignored.
+func IRFFT(x Complex, n int, axis int) (*tensor.Tensor, error) {
+	shape, axis, err := checkComplex(x, axis, "IRFFT")
+	if err != nil {
+		return nil, err
+	}
+	bins := shape[axis]
+	if n <= 0 {
+		n = 2 * (bins - 1)
+	}
+	if n <= 0 {
+		return nil, errors.New("IRFFT output length must be positive")
+	}
+	used := n/2 + 1
+	if used > bins {
+		used = bins
+	}
+	// weights counts each bin once for its own frequency and once for the
+	// mirrored negative frequency, except DC and Nyquist.
+	weights := make([]float64, used)
+	for k := range weights {
+		weights[k] = 2
+		if k == 0 || 2*k == n {
+			weights[k] = 1
+		}
+	}
+	inv := 1 / float64(n)
+	outShape := append([]int(nil), shape...)
+	outShape[axis] = n
+	forward := func(re, im []float64) ([]float64, []float64) {
+		out, _ := mapAxis(re, im, shape, axis, n, func(in, out []complex128) {
+			for i := range out {
+				out[i] = 0
+			}
+			for k := 0; k < used; k++ {
+				v := in[k]
+				if k == 0 || 2*k == n {
+					v = complex(real(v), 0)
+				}
+				out[k] = v * complex(weights[k], 0)
+			}
+			transform(out, true)
+			for i := range out {
+				out[i] = complex(real(out[i])*inv, 0)
+			}
+		})
+		return out, nil
+	}
+	adjoint := func(re, im []float64) ([]float64, []float64) {
+		return mapAxis(re, nil, outShape, axis, bins, func(in, out []complex128) {
+			transform(in, false)
+			for k := range out {
+				out[k] = 0
+			}
+			for k := 0; k < used; k++ {
+				v := in[k] * complex(weights[k]*inv, 0)
+				if k == 0 || 2*k == n {
+					v = complex(real(v), 0)
+				}
+				out[k] = v
+			}
+		})
+	}
+	out, err := linearOp(x, shape, outShape, forward, adjoint, false)
+	if err != nil {
+		return nil, err
+	}
+	return out.Real, nil
+}
+
+func checkComplex(x Complex, axis int, name string) ([]int, int, error) {
+	if x.Real == nil {
+		return nil, 0, errors.New(name + " requires a real part")
+	}
+	shape := x.Real.Shape()
+	if x.Imag != nil {
+		imShape := x.Imag.Shape()
+		if len(imShape) != len(shape) {
+			return nil, 0, errors.New(name + " real/imag shape mismatch")
+		}
+		for i, dim := range shape {
+			if imShape[i] != dim {
+				return nil, 0, errors.New(name + " real/imag shape mismatch")
+			}
+		}
+	}
+	rank := len(shape)
+	if axis < 0 {
+		axis += rank
+	}
+	if axis < 0 || axis >= rank {
+		return nil, 0, errors.New("axis out of range")
+	}
+	return shape, axis, nil
+}
+
+// linearOp wires a real-linear map on (re, im) pairs into the autograd graph.
+// adjoint must be the transpose of forward; the real and imaginary outputs
+// each back-propagate through it independently.
+func linearOp(x Complex, inShape, outShape []int, forward, adjoint func(re, im []float64) ([]float64, []float64), wantImag bool) (Complex, error) {
+	var im []float64
+	if x.Imag != nil {
+		im = x.Imag.Data()
+	}
+	outRe, outIm := forward(x.Real.Data(), im)
+	inputs := []*tensor.Tensor{x.Real, x.Imag}
+	size := len(outRe)
+	backward := func(gr, gi []float64) []*tensor.Tensor {
+		ar, ai := adjoint(gr, gi)
+		grads := []*tensor.Tensor{tensor.MustNew(ar, inShape...), nil}
+		if x.Imag != nil && ai != nil {
+			grads[1] = tensor.MustNew(ai, inShape...)
+		}
+		return grads
+	}
+	re, err := tensor.NewOp(outRe, outShape, inputs, func(grad *tensor.Tensor) []*tensor.Tensor {
+		return backward(grad.Data(), nil)
+	})
+	if err != nil {
+		return Complex{}, err
+	}
+	if !wantImag {
+		return Complex{Real: re}, nil
+	}
+	if outIm == nil {
+		outIm = make([]float64, size)
+	}
+	imag, err := tensor.NewOp(outIm, outShape, inputs, func(grad *tensor.Tensor) []*tensor.Tensor {
+		return backward(make([]float64, size), grad.Data())
+	})
+	if err != nil {
+		return Complex{}, err
+	}
+	return Complex{Real: re, Imag: imag}, nil
+}
+
+// mapAxis applies fn to every fibre of (re, im) along axis. Input fibres have
+// length shape[axis] and output fibres have length outLen. im may be nil.
+// Each worker owns its in and out slices, so fn may use both as scratch
+// space; in is refilled before every call.
+func mapAxis(re, im []float64, shape []int, axis, outLen int, fn func(in, out []complex128)) ([]float64, []float64) {
+	n := shape[axis]
+	outer := 1
+	for i := 0; i < axis; i++ {
+		outer *= shape[i]
+	}
+	inner := 1
+	for i := axis + 1; i < len(shape); i++ {
+		inner *= shape[i]
+	}
+	outRe := make([]float64, outer*outLen*inner)
+	outIm := make([]float64, outer*outLen*inner)
+	parallel.For(outer*inner, func(start, end int) {
+		in := make([]complex128, n)
+		out := make([]complex128, outLen)
+		for f := start; f < end; f++ {
+			o := f / inner
+			i := f % inner
+			for k := 0; k < n; k++ {
+				idx := (o*n+k)*inner + i
+				v := complex(re[idx], 0)
+				if im != nil {
+					v = complex(re[idx], im[idx])
+				}
+				in[k] = v
+			}
+			fn(in, out)
+			for k := 0; k < outLen; k++ {
+				idx := (o*outLen+k)*inner + i
+				outRe[idx] = real(out[k])
+				outIm[idx] = imag(out[k])
+			}
+		}
+	})
+	return outRe, outIm
+}
+
+func scaleComplex(a []complex128, v float64) {
+	for i := range a {
+		a[i] *= complex(v, 0)
+	}
+}
+
+// transform computes the unnormalised DFT of a in place. The inverse flag
+// flips the sign of the exponent.
+func transform(a []complex128, inverse bool) {
+	n := len(a)
+	if n <= 1 {
+		return
+	}
+	if n&(n-1) == 0 {
+		radix2(a, inverse)
+		return
+	}
+	bluestein(a, inverse)
+}
+
+func radix2(a []complex128, inverse bool) {
+	n := len(a)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			a[i], a[j] = a[j], a[i]
+		}
+	}
+	sign := -1.0
+	if inverse {
+		sign = 1
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Rect(1, sign*2*math.Pi/float64(size))
+		half := size / 2
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < half; k++ {
+				u := a[start+k]
+				v := a[start+k+half] * w
+				a[start+k] = u + v
+				a[start+k+half] = u - v
+				w *= step
+			}
+		}
+	}
+}
+
+// bluestein evaluates a DFT of arbitrary length as a convolution of
+// power-of-two length.
+func bluestein(a []complex128, inverse bool) {
+	n := len(a)
+	m := nextPow2(2*n - 1)
+	sign := -1.0
+	if inverse {
+		sign = 1
+	}
+	chirp := make([]complex128, n)
+	for k := 0; k < n; k++ {
+		// k^2 mod 2n keeps the angle small for long inputs.
+		kk := (k * k) % (2 * n)
+		chirp[k] = cmplx.Rect(1, sign*math.Pi*float64(kk)/float64(n))
+	}
+	x := make([]complex128, m)
+	y := make([]complex128, m)
+	for k := 0; k < n; k++ {
+		x[k] = a[k] * chirp[k]
+	}
+	y[0] = cmplx.Conj(chirp[0])
+	for k := 1; k < n; k++ {
+		c := cmplx.Conj(chirp[k])
+		y[k] = c
+		y[m-k] = c
+	}
+	radix2(x, false)
+	radix2(y, false)
+	for i := range x {
+		x[i] *= y[i]
+	}
+	radix2(x, true)
+	inv := complex(1/float64(m), 0)
+	for k := 0; k < n; k++ {
+		a[k] = x[k] * inv * chirp[k]
+	}
+}
+
+func nextPow2(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
@@ -0,0 +1,339 @@
+package fft
+
+import (
+	"math"
+	"math/cmplx"
+	"runtime"
+	"testing"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+func almostEqual(a, b []float64, tol float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > tol {
+			return false
+		}
+	}
+	return true
+}
+
+func naiveDFT(x []complex128) []complex128 {
+	n := len(x)
+	out := make([]complex128, n)
+	for k := 0; k < n; k++ {
+		for j := 0; j < n; j++ {
+			out[k] += x[j] * cmplx.Rect(1, -2*math.Pi*float64(j*k)/float64(n))
+		}
+	}
+	return out
+}
+
+func weightedSum(t *tensor.Tensor) *tensor.Tensor {
+	w := make([]float64, t.Numel())
+	for i := range w {
+		w[i] = 0.4 + 0.13*float64(i%5) - 0.07*float64(i%3)
+	}
+	out, err := tensor.Mul(t, tensor.MustNew(w, t.Shape()...))
+	if err != nil {
+		panic(err)
+	}
+	return tensor.Sum(out)
+}
+
+func checkGrad(t *testing.T, name string, base []float64, shape []int, f func(x *tensor.Tensor) (*tensor.Tensor, error)) {
+	t.Helper()
+	x := tensor.MustNew(base, shape...)
+	x.SetRequiresGrad(true)
+	loss, err := f(x)
+	if err != nil {
+		t.Fatalf("%s forward failed: %v", name, err)
+	}
+	if err := loss.Backward(); err != nil {
+		t.Fatalf("%s backward failed: %v", name, err)
+	}
+	got := x.Grad().Data()
+	eps := 1e-6
+	want := make([]float64, len(base))
+	for i := range base {
+		plus := append([]float64(nil), base...)
+		plus[i] += eps
+		minus := append([]float64(nil), base...)
+		minus[i] -= eps
+		lp, _ := f(tensor.MustNew(plus, shape...))
+		lm, _ := f(tensor.MustNew(minus, shape...))
+		want[i] = (lp.Data()[0] - lm.Data()[0]) / (2 * eps)
+	}
+	if !almostEqual(got, want, 1e-6) {
+		t.Fatalf("%s grad mismatch:\n got %v\nwant %v", name, got, want)
+	}
+}
+
+func TestFFTMatchesNaiveDFT(t *testing.T) {
+	for _, n := range []int{1, 4, 5, 8, 12} {
+		re := make([]float64, n)
+		im := make([]float64, n)
+		in := make([]complex128, n)
+		for i := 0; i < n; i++ {
+			re[i] = math.Sin(float64(i)) + 0.1*float64(i)
+			im[i] = math.Cos(float64(2 * i))
+			in[i] = complex(re[i], im[i])
+		}
+		out, err := FFT(Complex{Real: tensor.MustNew(re, n), Imag: tensor.MustNew(im, n)}, 0)
+		if err != nil {
+			t.Fatalf("FFT failed: %v", err)
+		}
+		want := naiveDFT(in)
+		for k := 0; k < n; k++ {
+			got := complex(out.Real.Data()[k], out.Imag.Data()[k])
+			if cmplx.Abs(got-want[k]) > 1e-9 {
+				t.Fatalf("n=%d bin %d: got %v want %v", n, k, got, want[k])
+			}
+		}
+		back, err := IFFT(out, 0)
+		if err != nil {
+			t.Fatalf("IFFT failed: %v", err)
+		}
+		if !almostEqual(back.Real.Data(), re, 1e-9) || !almostEqual(back.Imag.Data(), im, 1e-9) {
+			t.Fatalf("n=%d IFFT(FFT(x)) != x", n)
+		}
+	}
+}
+
+func TestRFFTRoundTripAlongAxis(t *testing.T) {
+	data := []float64{1, 2, -1, 0.5, 3, 0, 2, -2, 1, 4, 0.5, 1, -3, 2, 0, 1, 1, 1}
+	for _, tc := range []struct {
+		shape []int
+		axis  int
+	}{
+		{[]int{3, 6}, 1},
+		{[]int{6, 3}, 0},
+		{[]int{2, 9}, -1},
+	} {
+		x := tensor.MustNew(data, tc.shape...)
+		spec, err := RFFT(x, tc.axis)
+		if err != nil {
+			t.Fatalf("RFFT failed: %v", err)
+		}
+		axis := tc.axis
+		if axis < 0 {
+			axis += len(tc.shape)
+		}
+		n := tc.shape[axis]
+		if spec.Real.Shape()[axis] != n/2+1 {
+			t.Fatalf("unexpected RFFT shape %v", spec.Real.Shape())
+		}
+		back, err := IRFFT(spec, n, tc.axis)
+		if err != nil {
+			t.Fatalf("IRFFT failed: %v", err)
+		}
+		if !almostEqual(back.Data(), data, 1e-9) {
+			t.Fatalf("IRFFT(RFFT(x)) mismatch for shape %v: %v", tc.shape, back.Data())
+		}
+	}
+}
+
+func TestRealTransformsAcrossWorkers(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(8))
+	rows, n := 64, 32
+	data := make([]float64, rows*n)
+	for i := range data {
+		data[i] = math.Sin(0.37*float64(i)) + 0.01*float64(i%7)
+	}
+	x := tensor.MustNew(data, rows, n)
+	x.SetRequiresGrad(true)
+	spec, err := RFFT(x, 1)
+	if err != nil {
+		t.Fatalf("RFFT failed: %v", err)
+	}
+	back, err := IRFFT(spec, n, 1)
+	if err != nil {
+		t.Fatalf("IRFFT failed: %v", err)
+	}
+	if !almostEqual(back.Data(), data, 1e-9) {
+		t.Fatalf("IRFFT(RFFT(x)) mismatch with several workers")
+	}
+	loss, err := tensor.Add(weightedSum(spec.Real), weightedSum(back))
+	if err != nil {
+		t.Fatalf("loss failed: %v", err)
+	}
+	if err := loss.Backward(); err != nil {
+		t.Fatalf("backward failed: %v", err)
+	}
+	grad := x.Grad().Data()
+	bins := n/2 + 1
+	for r := 0; r < rows; r++ {
+		rowSpec, err := RFFT(tensor.MustNew(data[r*n:(r+1)*n], n), 0)
+		if err != nil {
+			t.Fatalf("row RFFT failed: %v", err)
+		}
+		if !almostEqual(spec.Real.Data()[r*bins:(r+1)*bins], rowSpec.Real.Data(), 1e-9) ||
+			!almostEqual(spec.Imag.Data()[r*bins:(r+1)*bins], rowSpec.Imag.Data(), 1e-9) {
+			t.Fatalf("row %d of the batched RFFT differs from the row transform", r)
+		}
+	}
+	// the batched loss weights elements by index, so compare the gradient
+	// with a single-worker run of the same graph
+	runtime.GOMAXPROCS(1)
+	ref := tensor.MustNew(data, rows, n)
+	ref.SetRequiresGrad(true)
+	refSpec, _ := RFFT(ref, 1)
+	refBack, _ := IRFFT(refSpec, n, 1)
+	refLoss, _ := tensor.Add(weightedSum(refSpec.Real), weightedSum(refBack))
+	if err := refLoss.Backward(); err != nil {
+		t.Fatalf("reference backward failed: %v", err)
+	}
+	if !almostEqual(grad, ref.Grad().Data(), 1e-9) {
+		t.Fatalf("gradients differ between one and several workers")
+	}
+}
+
+func TestTransformGradients(t *testing.T) {
+	base := []float64{0.5, -1, 2, 0.3, 1.5, -0.7}
+	checkGrad(t, "FFT real", base, []int{2, 3}, func(x *tensor.Tensor) (*tensor.Tensor, error) {
+		out, err := FFT(Complex{Real: x, Imag: tensor.MustNew([]float64{1, 0, -1, 2, 0.5, 0}, 2, 3)}, 1)
+		if err != nil {
+			return nil, err
+		}
+		return tensor.Add(weightedSum(out.Real), weightedSum(tensor.Pow(out.Imag, 2)))
+	})
+	checkGrad(t, "FFT imag", base, []int{2, 3}, func(x *tensor.Tensor) (*tensor.Tensor, error) {
+		out, err := FFT(Complex{Real: tensor.Ones(2, 3), Imag: x}, 0)
+		if err != nil {
+			return nil, err
+		}
+		return tensor.Add(weightedSum(out.Real), weightedSum(out.Imag))
+	})
+	checkGrad(t, "IFFT", base, []int{6}, func(x *tensor.Tensor) (*tensor.Tensor, error) {
+		out, err := IFFT(Complex{Real: x}, 0)
+		if err != nil {
+			return nil, err
+		}
+		return tensor.Add(weightedSum(out.Real), weightedSum(out.Imag))
+	})
+	checkGrad(t, "FFT2", base, []int{2, 3}, func(x *tensor.Tensor) (*tensor.Tensor, error) {
+		out, err := FFT2(Complex{Real: x})
+		if err != nil {
+			return nil, err
+		}
+		mag, err := out.Abs()
+		if err != nil {
+			return nil, err
+		}
+		return weightedSum(mag), nil
+	})
+	checkGrad(t, "RFFT", base, []int{6}, func(x *tensor.Tensor) (*tensor.Tensor, error) {
+		out, err := RFFT(x, 0)
+		if err != nil {
+			return nil, err
+		}
+		return tensor.Add(weightedSum(out.Real), weightedSum(out.Imag))
+	})
+	for _, n := range []int{4, 5} {
+		n := n
+		checkGrad(t, "IRFFT", base, []int{2, 3}, func(x *tensor.Tensor) (*tensor.Tensor, error) {
+			out, err := IRFFT(Complex{Real: x, Imag: tensor.MustNew([]float64{0.2, 1, -1, 0.5, 0, 3}, 2, 3)}, n, 1)
+			if err != nil {
+				return nil, err
+			}
+			return weightedSum(out), nil
+		})
+	}
+}
+
+func TestSTFTInverse(t *testing.T) {
+	length := 32
+	data := make([]float64, 2*length)
+	for i := range data {
+		data[i] = math.Sin(0.3*float64(i)) + 0.2*math.Cos(1.7*float64(i))
+	}
+	x := tensor.MustNew(data, 2, length)
+	spec, err := STFT(x, 8, 2, nil)
+	if err != nil {
+		t.Fatalf("STFT failed: %v", err)
+	}
+	if got := spec.Real.Shape(); got[0] != 2 || got[1] != 13 || got[2] != 5 {
+		t.Fatalf("unexpected STFT shape %v", got)
+	}
+	back, err := ISTFT(spec, 8, 2, nil, length)
+	if err != nil {
+		t.Fatalf("ISTFT failed: %v", err)
+	}
+	// the first sample sits under a zero window weight and cannot be recovered
+	got := back.Data()
+	for b := 0; b < 2; b++ {
+		for i := 1; i < length; i++ {
+			if math.Abs(got[b*length+i]-data[b*length+i]) > 1e-9 {
+				t.Fatalf("ISTFT sample %d mismatch: got %v want %v", i, got[b*length+i], data[b*length+i])
+			}
+		}
+	}
+	checkGrad(t, "STFT", data[:12], []int{12}, func(x *tensor.Tensor) (*tensor.Tensor, error) {
+		s, err := STFT(x, 4, 2, nil)
+		if err != nil {
+			return nil, err
+		}
+		mag, err := s.Abs()
+		if err != nil {
+			return nil, err
+		}
+		return weightedSum(mag), nil
+	})
+}
+
+func TestConv1DMatchesDirect(t *testing.T) {
+	inData := make([]float64, 2*2*11)
+	for i := range inData {
+		inData[i] = math.Sin(float64(i)*0.7) + 0.05*float64(i)
+	}
+	wData := make([]float64, 3*2*5)
+	for i := range wData {
+		wData[i] = math.Cos(float64(i)*1.3) * 0.5
+	}
+	bias := tensor.MustNew([]float64{0.1, -0.2, 0.3}, 3)
+	for _, cfg := range [][2]int{{1, 0}, {2, 1}, {3, 2}} {
+		stride, pad := cfg[0], cfg[1]
+		input := tensor.MustNew(inData, 2, 2, 11)
+		weight := tensor.MustNew(wData, 3, 2, 5)
+		input.SetRequiresGrad(true)
+		weight.SetRequiresGrad(true)
+		b := bias.Clone()
+		b.SetRequiresGrad(true)
+		out, err := Conv1D(input, weight, b, stride, pad)
+		if err != nil {
+			t.Fatalf("Conv1D failed: %v", err)
+		}
+		if err := weightedSum(out).Backward(); err != nil {
+			t.Fatalf("Conv1D backward failed: %v", err)
+		}
+
+		refIn := tensor.MustNew(inData, 2, 2, 11)
+		refW := tensor.MustNew(wData, 3, 2, 5)
+		refIn.SetRequiresGrad(true)
+		refW.SetRequiresGrad(true)
+		refB := bias.Clone()
+		refB.SetRequiresGrad(true)
+		ref, err := tensor.Conv1D(refIn, refW, refB, stride, pad)
+		if err != nil {
+			t.Fatalf("tensor.Conv1D failed: %v", err)
+		}
+		if err := weightedSum(ref).Backward(); err != nil {
+			t.Fatalf("tensor.Conv1D backward failed: %v", err)
+		}
+		if !almostEqual(out.Data(), ref.Data(), 1e-9) {
+			t.Fatalf("stride %d pad %d output mismatch:\n got %v\nwant %v", stride, pad, out.Data(), ref.Data())
+		}
+		if !almostEqual(input.Grad().Data(), refIn.Grad().Data(), 1e-9) {
+			t.Fatalf("stride %d pad %d input grad mismatch", stride, pad)
+		}
+		if !almostEqual(weight.Grad().Data(), refW.Grad().Data(), 1e-9) {
+			t.Fatalf("stride %d pad %d weight grad mismatch", stride, pad)
+		}
+		if !almostEqual(b.Grad().Data(), refB.Grad().Data(), 1e-9) {
+			t.Fatalf("stride %d pad %d bias grad mismatch", stride, pad)
+		}
+	}
+}
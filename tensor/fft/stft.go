@@ -0,0 +1,181 @@
+package fft
+
+import (
+	"errors"
+	"math"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// HannWindow returns a periodic Hann window of length n.
+func HannWindow(n int) []float64 {
+	w := make([]float64, n)
+	for i := range w {
+		w[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n))
+	}
+	return w
+}
+
+// STFT computes the short-time Fourier transform of x [..., time]. Frames of
+// nFFT samples are taken every hop samples without padding, multiplied by
+// window (a Hann window when nil) and transformed with RFFT. The result has
+// shape [..., frames, nFFT/2+1].
+func STFT(x *tensor.Tensor, nFFT, hop int, window []float64) (Complex, error) {
+	if x == nil {
+		return Complex{}, errors.New("STFT requires input tensor")
+	}
+	if nFFT <= 0 || hop <= 0 {
+		return Complex{}, errors.New("STFT nFFT and hop must be positive")
+	}
+	if window == nil {
+		window = HannWindow(nFFT)
+	}
+	if len(window) != nFFT {
+		return Complex{}, errors.New("STFT window length must equal nFFT")
+	}
+	shape := x.Shape()
+	length := shape[len(shape)-1]
+	if length < nFFT {
+		return Complex{}, errors.New("STFT input shorter than nFFT")
+	}
+	frames := (length-nFFT)/hop + 1
+	framed, err := frame(x, nFFT, hop, frames)
+	if err != nil {
+		return Complex{}, err
+	}
+	windowed, err := tensor.Mul(framed, tileWindow(window, framed.Shape()))
+	if err != nil {
+		return Complex{}, err
+	}
+	return RFFT(windowed, -1)
+}
+
+// ISTFT inverts STFT with windowed overlap-add. spec has shape
+// [..., frames, nFFT/2+1]; the result has shape [..., length], where length
+// <= 0 selects the natural length (frames-1)*hop + nFFT. Samples not covered
+// by any window are returned as zero.
+func ISTFT(spec Complex, nFFT, hop int, window []float64, length int) (*tensor.Tensor, error) {
+	if spec.Real == nil {
+		return nil, errors.New("ISTFT requires a real part")
+	}
+	if nFFT <= 0 || hop <= 0 {
+		return nil, errors.New("ISTFT nFFT and hop must be positive")
+	}
+	if window == nil {
+		window = HannWindow(nFFT)
+	}
+	if len(window) != nFFT {
+		return nil, errors.New("ISTFT window length must equal nFFT")
+	}
+	shape := spec.Real.Shape()
+	if len(shape) < 2 {
+		return nil, errors.New("ISTFT expects spectrum of shape [..., frames, bins]")
+	}
+	frames := shape[len(shape)-2]
+	framed, err := IRFFT(spec, nFFT, -1)
+	if err != nil {
+		return nil, err
+	}
+	windowed, err := tensor.Mul(framed, tileWindow(window, framed.Shape()))
+	if err != nil {
+		return nil, err
+	}
+	natural := (frames-1)*hop + nFFT
+	if length <= 0 {
+		length = natural
+	}
+	summed, err := overlapAdd(windowed, hop, length)
+	if err != nil {
+		return nil, err
+	}
+	envelope := make([]float64, length)
+	for f := 0; f < frames; f++ {
+		for k := 0; k < nFFT; k++ {
+			if t := f*hop + k; t < length {
+				envelope[t] += window[k] * window[k]
+			}
+		}
+	}
+	outShape := summed.Shape()
+	norm := make([]float64, summed.Numel())
+	for i := range norm {
+		e := envelope[i%length]
+		if e > 1e-11 {
+			norm[i] = 1 / e
+		}
+	}
+	return tensor.Mul(summed, tensor.MustNew(norm, outShape...))
+}
+
+func tileWindow(window []float64, shape []int) *tensor.Tensor {
+	size := 1
+	for _, dim := range shape {
+		size *= dim
+	}
+	data := make([]float64, size)
+	for i := range data {
+		data[i] = window[i%len(window)]
+	}
+	return tensor.MustNew(data, shape...)
+}
+
+// frame slices x [..., time] into overlapping frames [..., frames, size].
+func frame(x *tensor.Tensor, size, hop, frames int) (*tensor.Tensor, error) {
+	shape := x.Shape()
+	length := shape[len(shape)-1]
+	outer := x.Numel() / length
+	src := x.Data()
+	data := make([]float64, outer*frames*size)
+	for o := 0; o < outer; o++ {
+		for f := 0; f < frames; f++ {
+			copy(data[(o*frames+f)*size:(o*frames+f+1)*size], src[o*length+f*hop:o*length+f*hop+size])
+		}
+	}
+	outShape := append(append([]int(nil), shape[:len(shape)-1]...), frames, size)
+	return tensor.NewOp(data, outShape, []*tensor.Tensor{x}, func(grad *tensor.Tensor) []*tensor.Tensor {
+		g := grad.Data()
+		gx := make([]float64, len(src))
+		for o := 0; o < outer; o++ {
+			for f := 0; f < frames; f++ {
+				for k := 0; k < size; k++ {
+					gx[o*length+f*hop+k] += g[(o*frames+f)*size+k]
+				}
+			}
+		}
+		return []*tensor.Tensor{tensor.MustNew(gx, shape...)}
+	})
+}
+
+// overlapAdd sums frames [..., frames, size] spaced hop apart into a signal
+// [..., length], dropping samples past length.
+func overlapAdd(x *tensor.Tensor, hop, length int) (*tensor.Tensor, error) {
+	shape := x.Shape()
+	frames, size := shape[len(shape)-2], shape[len(shape)-1]
+	outer := x.Numel() / (frames * size)
+	src := x.Data()
+	data := make([]float64, outer*length)
+	for o := 0; o < outer; o++ {
+		for f := 0; f < frames; f++ {
+			for k := 0; k < size; k++ {
+				if t := f*hop + k; t < length {
+					data[o*length+t] += src[(o*frames+f)*size+k]
+				}
+			}
+		}
+	}
+	outShape := append(append([]int(nil), shape[:len(shape)-2]...), length)
+	return tensor.NewOp(data, outShape, []*tensor.Tensor{x}, func(grad *tensor.Tensor) []*tensor.Tensor {
+		g := grad.Data()
+		gx := make([]float64, len(src))
+		for o := 0; o < outer; o++ {
+			for f := 0; f < frames; f++ {
+				for k := 0; k < size; k++ {
+					if t := f*hop + k; t < length {
+						gx[(o*frames+f)*size+k] = g[o*length+t]
+					}
+				}
+			}
+		}
+		return []*tensor.Tensor{tensor.MustNew(gx, shape...)}
+	})
+}
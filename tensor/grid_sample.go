@@ -0,0 +1,299 @@
+package tensor
+
+import (
+	"errors"
+	"math"
+
+	"github.com/fumitoshi0524/ixeoriNet/internal/parallel"
+)
+
+// AffineGrid generates a sampling grid of shape [batch, out_h, out_w, 2] from
+// affine matrices theta [batch, 2, 3] for use with GridSample. size is the
+// target output shape [batch, channels, out_h, out_w]. Grid coordinates are
+// (x, y) pairs normalised to [-1, 1].
+func AffineGrid(theta *Tensor, size []int, alignCorners bool) (*Tensor, error) {
+	if theta == nil {
+		return nil, errors.New("AffineGrid requires theta tensor")
+	}
+	if len(size) != 4 {
+		return nil, errors.New("AffineGrid expects size [batch, channels, height, width]")
+	}
+	batch, outH, outW := size[0], size[2], size[3]
+	if len(theta.shape) != 3 || theta.shape[0] != batch || theta.shape[1] != 2 || theta.shape[2] != 3 {
+		return nil, errors.New("AffineGrid expects theta shape [batch, 2, 3]")
+	}
+	if outH <= 0 || outW <= 0 {
+		return nil, errors.New("invalid output size")
+	}
+	xs := linspaceGrid(outW, alignCorners)
+	ys := linspaceGrid(outH, alignCorners)
+
+	out := Zeros(batch, outH, outW, 2)
+	parallel.For(batch, func(start, end int) {
+		for n := start; n < end; n++ {
+			th := theta.data[n*6 : n*6+6]
+			for h := 0; h < outH; h++ {
+				for w := 0; w < outW; w++ {
+					idx := ((n*outH+h)*outW + w) * 2
+					out.data[idx] = th[0]*xs[w] + th[1]*ys[h] + th[2]
+					out.data[idx+1] = th[3]*xs[w] + th[4]*ys[h] + th[5]
+				}
+			}
+		}
+	})
+
+	if !theta.requiresGrad {
+		return out, nil
+	}
+
+	out.requiresGrad = true
+	out.parents = []*Tensor{theta}
+	out.node = &node{
+		backward: func(grad *Tensor, grads map[*Tensor]*Tensor) {
+			gTheta := Zeros(theta.shape...)
+			for n := 0; n < batch; n++ {
+				gt := gTheta.data[n*6 : n*6+6]
+				for h := 0; h < outH; h++ {
+					for w := 0; w < outW; w++ {
+						idx := ((n*outH+h)*outW + w) * 2
+						gx, gy := grad.data[idx], grad.data[idx+1]
+						gt[0] += gx * xs[w]
+						gt[1] += gx * ys[h]
+						gt[2] += gx
+						gt[3] += gy * xs[w]
+						gt[4] += gy * ys[h]
+						gt[5] += gy
+					}
+				}
+			}
+			accumulate(grads, theta, gTheta)
+		},
+	}
+
+	return out, nil
+}
+
+// GridSample samples input [batch, channels, in_h, in_w] at the normalised
+// (x, y) locations in grid [batch, out_h, out_w, 2], producing
+// [batch, channels, out_h, out_w]. mode is "bilinear" (default) or "nearest";
+// paddingMode is "zeros" (default) or "border". Gradients flow to both input
+// and grid (the grid gradient is zero in nearest mode).
+func GridSample(input, grid *Tensor, mode, paddingMode string, alignCorners bool) (*Tensor, error) {
+	if input == nil || grid == nil {
+		return nil, errors.New("GridSample requires input and grid tensors")
+	}
+	if len(input.shape) != 4 {
+		return nil, errors.New("GridSample expects input shape [batch, channels, height, width]")
+	}
+	if len(grid.shape) != 4 || grid.shape[0] != input.shape[0] || grid.shape[3] != 2 {
+		return nil, errors.New("GridSample expects grid shape [batch, out_h, out_w, 2]")
+	}
+	if mode == "" {
+		mode = "bilinear"
+	}
+	if mode != "bilinear" && mode != "nearest" {
+		return nil, errors.New("unsupported grid sample mode")
+	}
+	if paddingMode == "" {
+		paddingMode = "zeros"
+	}
+	if paddingMode != "zeros" && paddingMode != "border" {
+		return nil, errors.New("unsupported grid sample padding mode")
+	}
+	batch, channels, inH, inW := input.shape[0], input.shape[1], input.shape[2], input.shape[3]
+	outH, outW := grid.shape[1], grid.shape[2]
+	border := paddingMode == "border"
+
+	// source coordinates and their derivatives with respect to the grid values
+	count := batch * outH * outW
+	srcX := make([]float64, count)
+	srcY := make([]float64, count)
+	dX := make([]float64, count)
+	dY := make([]float64, count)
+	for i := 0; i < count; i++ {
+		srcX[i], dX[i] = unnormalizeCoord(grid.data[2*i], inW, alignCorners, border)
+		srcY[i], dY[i] = unnormalizeCoord(grid.data[2*i+1], inH, alignCorners, border)
+	}
+
+	out := Zeros(batch, channels, outH, outW)
+	parallel.For(batch*channels, func(start, end int) {
+		for nc := start; nc < end; nc++ {
+			n := nc / channels
+			plane := input.data[nc*inH*inW : (nc+1)*inH*inW]
+			for p := 0; p < outH*outW; p++ {
+				i := n*outH*outW + p
+				out.data[nc*outH*outW+p] = samplePoint(plane, inH, inW, srcX[i], srcY[i], mode, nil, 0)
+			}
+		}
+	})
+
+	if !input.requiresGrad && !grid.requiresGrad {
+		return out, nil
+	}
+
+	out.requiresGrad = true
+	if input.requiresGrad {
+		out.parents = append(out.parents, input)
+	}
+	if grid.requiresGrad {
+		out.parents = append(out.parents, grid)
+	}
+	out.node = &node{
+		backward: func(grad *Tensor, grads map[*Tensor]*Tensor) {
+			if input.requiresGrad {
+				gInput := Zeros(input.shape...)
+				parallel.For(batch*channels, func(start, end int) {
+					for nc := start; nc < end; nc++ {
+						n := nc / channels
+						gPlane := gInput.data[nc*inH*inW : (nc+1)*inH*inW]
+						for p := 0; p < outH*outW; p++ {
+							g := grad.data[nc*outH*outW+p]
+							if g == 0 {
+								continue
+							}
+							i := n*outH*outW + p
+							samplePoint(nil, inH, inW, srcX[i], srcY[i], mode, gPlane, g)
+						}
+					}
+				})
+				accumulate(grads, input, gInput)
+			}
+			if grid.requiresGrad {
+				gGrid := Zeros(grid.shape...)
+				if mode == "bilinear" {
+					parallel.For(batch, func(start, end int) {
+						for n := start; n < end; n++ {
+							for p := 0; p < outH*outW; p++ {
+								i := n*outH*outW + p
+								gx, gy := 0.0, 0.0
+								for c := 0; c < channels; c++ {
+									nc := n*channels + c
+									g := grad.data[nc*outH*outW+p]
+									if g == 0 {
+										continue
+									}
+									plane := input.data[nc*inH*inW : (nc+1)*inH*inW]
+									wx, wy := bilinearCoordGrad(plane, inH, inW, srcX[i], srcY[i])
+									gx += g * wx
+									gy += g * wy
+								}
+								gGrid.data[2*i] = gx * dX[i]
+								gGrid.data[2*i+1] = gy * dY[i]
+							}
+						}
+					})
+				}
+				accumulate(grads, grid, gGrid)
+			}
+		},
+	}
+
+	return out, nil
+}
+
+// linspaceGrid returns the normalised coordinates of n pixel positions.
+func linspaceGrid(n int, alignCorners bool) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		if alignCorners {
+			if n > 1 {
+				out[i] = -1 + 2*float64(i)/float64(n-1)
+			}
+		} else {
+			out[i] = (2*float64(i)+1)/float64(n) - 1
+		}
+	}
+	return out
+}
+
+// unnormalizeCoord maps a normalised coordinate to pixel space and returns
+// the derivative of that mapping.
+func unnormalizeCoord(c float64, size int, alignCorners, border bool) (float64, float64) {
+	var pos, scale float64
+	if alignCorners {
+		scale = float64(size-1) / 2
+		pos = (c + 1) * scale
+	} else {
+		scale = float64(size) / 2
+		pos = ((c+1)*float64(size) - 1) / 2
+	}
+	if border {
+		if pos < 0 {
+			return 0, 0
+		}
+		if pos > float64(size-1) {
+			return float64(size - 1), 0
+		}
+	}
+	return pos, scale
+}
+
+// samplePoint reads the interpolated value at (x, y) from plane, or, when
+// gPlane is non-nil, scatters g into gPlane with the same weights.
+func samplePoint(plane []float64, h, w int, x, y float64, mode string, gPlane []float64, g float64) float64 {
+	if mode == "nearest" {
+		ix := int(math.RoundToEven(x))
+		iy := int(math.RoundToEven(y))
+		if ix < 0 || ix >= w || iy < 0 || iy >= h {
+			return 0
+		}
+		if gPlane != nil {
+			gPlane[iy*w+ix] += g
+			return 0
+		}
+		return plane[iy*w+ix]
+	}
+	x0 := int(math.Floor(x))
+	y0 := int(math.Floor(y))
+	fx := x - float64(x0)
+	fy := y - float64(y0)
+	sum := 0.0
+	for dy := 0; dy < 2; dy++ {
+		iy := y0 + dy
+		if iy < 0 || iy >= h {
+			continue
+		}
+		wy := 1 - fy
+		if dy == 1 {
+			wy = fy
+		}
+		for dx := 0; dx < 2; dx++ {
+			ix := x0 + dx
+			if ix < 0 || ix >= w {
+				continue
+			}
+			wx := 1 - fx
+			if dx == 1 {
+				wx = fx
+			}
+			if gPlane != nil {
+				gPlane[iy*w+ix] += wx * wy * g
+			} else {
+				sum += wx * wy * plane[iy*w+ix]
+			}
+		}
+	}
+	return sum
+}
+
+// bilinearCoordGrad returns the derivatives of the bilinear sample at (x, y)
+// with respect to x and y.
+func bilinearCoordGrad(plane []float64, h, w int, x, y float64) (float64, float64) {
+	x0 := int(math.Floor(x))
+	y0 := int(math.Floor(y))
+	fx := x - float64(x0)
+	fy := y - float64(y0)
+	value := func(iy, ix int) float64 {
+		if iy < 0 || iy >= h || ix < 0 || ix >= w {
+			return 0
+		}
+		return plane[iy*w+ix]
+	}
+	v00 := value(y0, x0)
+	v01 := value(y0, x0+1)
+	v10 := value(y0+1, x0)
+	v11 := value(y0+1, x0+1)
+	gx := (1-fy)*(v01-v00) + fy*(v11-v10)
+	gy := (1-fx)*(v10-v00) + fx*(v11-v01)
+	return gx, gy
+}
@@ -0,0 +1,260 @@
+package tensor
+
+import (
+	"errors"
+	"math"
+)
+
+// GroupNorm normalizes [batch, channels, spatial...] inputs over groups of
+// numGroups consecutive channels together with their spatial positions,
+// independently for every sample, then applies the per-channel weight and
+// bias (either may be nil). numGroups equal to channels gives instance
+// normalization and 1 normalizes each sample over all its features.
+func GroupNorm(input *Tensor, numGroups int, weight, bias *Tensor, eps float64) (*Tensor, error) {
+	if input == nil {
+		return nil, errors.New("GroupNorm requires input tensor")
+	}
+	if len(input.shape) < 2 {
+		return nil, errors.New("GroupNorm expects input shape [batch, channels, ...]")
+	}
+	channels := input.shape[1]
+	if numGroups <= 0 || channels%numGroups != 0 {
+		return nil, errors.New("GroupNorm channels must be divisible by the number of groups")
+	}
+	if weight != nil && (len(weight.shape) != 1 || weight.shape[0] != channels) {
+		return nil, errors.New("weight size mismatch")
+	}
+	if bias != nil && (len(bias.shape) != 1 || bias.shape[0] != channels) {
+		return nil, errors.New("bias size mismatch")
+	}
+	if eps <= 0 {
+		eps = 1e-5
+	}
+	inner := 1
+	for _, dim := range input.shape[2:] {
+		inner *= dim
+	}
+	groupSize := channels / numGroups * inner
+	blocks := input.Numel() / groupSize
+	channelOf := func(idx int) int {
+		return (idx / inner) % channels
+	}
+
+	out := Zeros(input.shape...)
+	xhat := make([]float64, input.Numel())
+	invStds := make([]float64, blocks)
+	for blk := 0; blk < blocks; blk++ {
+		offset := blk * groupSize
+		sum := 0.0
+		for j := 0; j < groupSize; j++ {
+			sum += input.data[offset+j]
+		}
+		mean := sum / float64(groupSize)
+		varSum := 0.0
+		for j := 0; j < groupSize; j++ {
+			diff := input.data[offset+j] - mean
+			varSum += diff * diff
+		}
+		invStd := 1.0 / math.Sqrt(varSum/float64(groupSize)+eps)
+		invStds[blk] = invStd
+		for j := 0; j < groupSize; j++ {
+			idx := offset + j
+			xh := (input.data[idx] - mean) * invStd
+			xhat[idx] = xh
+			c := channelOf(idx)
+			if weight != nil {
+				xh *= weight.data[c]
+			}
+			if bias != nil {
+				xh += bias.data[c]
+			}
+			out.data[idx] = xh
+		}
+	}
+
+	parents := make([]*Tensor, 0, 3)
+	for _, t := range []*Tensor{input, weight, bias} {
+		if t != nil && t.requiresGrad {
+			parents = append(parents, t)
+		}
+	}
+	if len(parents) == 0 {
+		return out, nil
+	}
+	var weightData []float64
+	if weight != nil {
+		weightData = append([]float64(nil), weight.data...)
+	}
+	out.requiresGrad = true
+	out.parents = parents
+	out.node = &node{
+		backward: func(grad *Tensor, grads map[*Tensor]*Tensor) {
+			var gInput, gWeight, gBias *Tensor
+			if input.requiresGrad {
+				gInput = Zeros(input.shape...)
+			}
+			if weight != nil && weight.requiresGrad {
+				gWeight = Zeros(weight.shape...)
+			}
+			if bias != nil && bias.requiresGrad {
+				gBias = Zeros(bias.shape...)
+			}
+			n := float64(groupSize)
+			for blk := 0; blk < blocks; blk++ {
+				offset := blk * groupSize
+				sumGrad, sumGradXhat := 0.0, 0.0
+				for j := 0; j < groupSize; j++ {
+					idx := offset + j
+					c := channelOf(idx)
+					g := grad.data[idx]
+					if gWeight != nil {
+						gWeight.data[c] += g * xhat[idx]
+					}
+					if gBias != nil {
+						gBias.data[c] += g
+					}
+					if weightData != nil {
+						g *= weightData[c]
+					}
+					sumGrad += g
+					sumGradXhat += g * xhat[idx]
+				}
+				if gInput == nil {
+					continue
+				}
+				for j := 0; j < groupSize; j++ {
+					idx := offset + j
+					g := grad.data[idx]
+					if weightData != nil {
+						g *= weightData[channelOf(idx)]
+					}
+					gInput.data[idx] = (g - sumGrad/n - xhat[idx]*sumGradXhat/n) * invStds[blk]
+				}
+			}
+			if gInput != nil {
+				accumulate(grads, input, gInput)
+			}
+			if gWeight != nil {
+				accumulate(grads, weight, gWeight)
+			}
+			if gBias != nil {
+				accumulate(grads, bias, gBias)
+			}
+		},
+	}
+	return out, nil
+}
+
+// RMSNorm scales the last len(normalizedShape) dimensions of the input by
+// the inverse of their root mean square, x / sqrt(mean(x²) + eps), and by
+// weight (nil for none). Unlike LayerNorm it neither centres the input nor
+// adds a bias.
+func RMSNorm(input *Tensor, normalizedShape []int, weight *Tensor, eps float64) (*Tensor, error) {
+	if input == nil {
+		return nil, errors.New("RMSNorm requires input tensor")
+	}
+	if len(normalizedShape) == 0 {
+		return nil, errors.New("normalized shape required")
+	}
+	rank := len(input.shape)
+	normRank := len(normalizedShape)
+	if normRank > rank {
+		return nil, errors.New("normalized shape rank exceeds input rank")
+	}
+	normSize := 1
+	for i, dim := range normalizedShape {
+		if input.shape[rank-normRank+i] != dim {
+			return nil, errors.New("normalized shape mismatch")
+		}
+		normSize *= dim
+	}
+	if weight != nil && weight.Numel() != normSize {
+		return nil, errors.New("weight size mismatch")
+	}
+	if eps <= 0 {
+		eps = 1e-6
+	}
+	out := Zeros(input.shape...)
+	outer := input.Numel() / normSize
+	invRMS := make([]float64, outer)
+	for o := 0; o < outer; o++ {
+		offset := o * normSize
+		sumSq := 0.0
+		for j := 0; j < normSize; j++ {
+			v := input.data[offset+j]
+			sumSq += v * v
+		}
+		inv := 1.0 / math.Sqrt(sumSq/float64(normSize)+eps)
+		invRMS[o] = inv
+		for j := 0; j < normSize; j++ {
+			val := input.data[offset+j] * inv
+			if weight != nil {
+				val *= weight.data[j]
+			}
+			out.data[offset+j] = val
+		}
+	}
+
+	parents := make([]*Tensor, 0, 2)
+	for _, t := range []*Tensor{input, weight} {
+		if t != nil && t.requiresGrad {
+			parents = append(parents, t)
+		}
+	}
+	if len(parents) == 0 {
+		return out, nil
+	}
+	var weightData []float64
+	if weight != nil {
+		weightData = append([]float64(nil), weight.data...)
+	}
+	out.requiresGrad = true
+	out.parents = parents
+	out.node = &node{
+		backward: func(grad *Tensor, grads map[*Tensor]*Tensor) {
+			var gInput, gWeight *Tensor
+			if input.requiresGrad {
+				gInput = Zeros(input.shape...)
+			}
+			if weight != nil && weight.requiresGrad {
+				gWeight = Zeros(weight.shape...)
+			}
+			for o := 0; o < outer; o++ {
+				offset := o * normSize
+				inv := invRMS[o]
+				dot := 0.0
+				for j := 0; j < normSize; j++ {
+					idx := offset + j
+					g := grad.data[idx]
+					if gWeight != nil {
+						gWeight.data[j] += g * input.data[idx] * inv
+					}
+					if weightData != nil {
+						g *= weightData[j]
+					}
+					dot += g * input.data[idx]
+				}
+				if gInput == nil {
+					continue
+				}
+				// d(x·inv)/dx = inv·(g - x·inv²·mean(g·x))
+				scale := inv * inv * dot / float64(normSize)
+				for j := 0; j < normSize; j++ {
+					idx := offset + j
+					g := grad.data[idx]
+					if weightData != nil {
+						g *= weightData[j]
+					}
+					gInput.data[idx] = inv * (g - input.data[idx]*scale)
+				}
+			}
+			if gInput != nil {
+				accumulate(grads, input, gInput)
+			}
+			if gWeight != nil {
+				accumulate(grads, weight, gWeight)
+			}
+		},
+	}
+	return out, nil
+}
@@ -0,0 +1,88 @@
+package tensor
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGroupNormForwardBackward(t *testing.T) {
+	// [batch=2, channels=4, length=3] in 2 groups
+	base := sequenceValues(24, 0.3)
+	input := MustNew(base, 2, 4, 3)
+	out, err := GroupNorm(input, 2, nil, nil, 1e-5)
+	if err != nil {
+		t.Fatalf("GroupNorm failed: %v", err)
+	}
+	data := out.Data()
+	for blk := 0; blk < 4; blk++ {
+		mean, sq := 0.0, 0.0
+		for _, v := range data[blk*6 : (blk+1)*6] {
+			mean += v / 6
+			sq += v * v / 6
+		}
+		if math.Abs(mean) > 1e-9 || math.Abs(sq-1) > 1e-3 {
+			t.Fatalf("group %d not normalized: mean %v, mean square %v", blk, mean, sq)
+		}
+	}
+
+	weightBase := []float64{0.5, -1.2, 0.8, 1.1}
+	biasBase := []float64{0.1, 0.2, -0.3, 0.05}
+	checkInterpolateGrad(t, "groupnorm input", base, []int{2, 4, 3}, func(in *Tensor) (*Tensor, error) {
+		return GroupNorm(in, 2, MustNew(weightBase, 4), MustNew(biasBase, 4), 1e-5)
+	})
+	checkInterpolateGrad(t, "groupnorm weight", weightBase, []int{4}, func(w *Tensor) (*Tensor, error) {
+		return GroupNorm(MustNew(base, 2, 4, 3), 2, w, MustNew(biasBase, 4), 1e-5)
+	})
+	checkInterpolateGrad(t, "groupnorm bias", biasBase, []int{4}, func(b *Tensor) (*Tensor, error) {
+		return GroupNorm(MustNew(base, 2, 4, 3), 2, MustNew(weightBase, 4), b, 1e-5)
+	})
+	if _, err := GroupNorm(input, 3, nil, nil, 1e-5); err == nil {
+		t.Fatalf("expected divisibility error")
+	}
+}
+
+func TestRMSNormForwardBackward(t *testing.T) {
+	input := MustNew([]float64{3, 4, 0, -2}, 2, 2)
+	out, err := RMSNorm(input, []int{2}, nil, 1e-12)
+	if err != nil {
+		t.Fatalf("RMSNorm failed: %v", err)
+	}
+	rms := math.Sqrt(12.5)
+	if !almostEqualSlices(out.Data(), []float64{3 / rms, 4 / rms, 0, -math.Sqrt2}, 1e-9) {
+		t.Fatalf("unexpected RMSNorm output %v", out.Data())
+	}
+	base := sequenceValues(12, 0.7)
+	weightBase := []float64{0.9, -0.4, 1.3}
+	checkInterpolateGrad(t, "rmsnorm input", base, []int{4, 3}, func(in *Tensor) (*Tensor, error) {
+		return RMSNorm(in, []int{3}, MustNew(weightBase, 3), 1e-6)
+	})
+	checkInterpolateGrad(t, "rmsnorm weight", weightBase, []int{3}, func(w *Tensor) (*Tensor, error) {
+		return RMSNorm(MustNew(base, 4, 3), []int{3}, w, 1e-6)
+	})
+	if _, err := RMSNorm(nil, []int{3}, nil, 1e-6); err == nil {
+		t.Fatalf("expected nil input error")
+	}
+}
+
+func TestBatchNormSequenceAndVolume(t *testing.T) {
+	// with a single sample, batch statistics are per-channel instance statistics
+	base := sequenceValues(12, 0.1)
+	seq, err := BatchNorm(MustNew(base, 1, 3, 4), Zeros(3), Ones(3), nil, nil, 0.1, 1e-5, true)
+	if err != nil {
+		t.Fatalf("rank-3 BatchNorm failed: %v", err)
+	}
+	inst, _ := GroupNorm(MustNew(base, 1, 3, 4), 3, nil, nil, 1e-5)
+	if !almostEqualSlices(seq.Data(), inst.Data(), 1e-12) {
+		t.Fatalf("rank-3 BatchNorm differs from per-channel normalization")
+	}
+
+	volume := sequenceValues(2*2*2*2*3, 0.5)
+	shape := []int{2, 2, 2, 2, 3}
+	weight := []float64{0.7, -1.1}
+	checkInterpolateGrad(t, "batchnorm rank 5", volume, shape, func(in *Tensor) (*Tensor, error) {
+		return BatchNorm(in, Zeros(2), Ones(2), MustNew(weight, 2), nil, 0.1, 1e-5, true)
+	})
+	if _, err := BatchNorm(Zeros(1, 1, 1, 1, 1, 1), Zeros(1), Ones(1), nil, nil, 0.1, 1e-5, true); err == nil {
+		t.Fatalf("expected rank error")
+	}
+}
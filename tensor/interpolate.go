@@ -0,0 +1,234 @@
+package tensor
+
+import (
+	"errors"
+	"math"
+
+	"github.com/fumitoshi0524/ixeoriNet/internal/parallel"
+)
+
+// tap is one source sample contributing to an interpolated output position.
+type tap struct {
+	index  int
+	weight float64
+}
+
+// Interpolate resizes the spatial dimensions of input, which has shape
+// [batch, channels, spatial...] with one to three spatial dimensions.
+// Exactly one of size or scaleFactor must be given; a single entry applies to
+// every spatial dimension. Supported modes are "nearest" (any rank),
+// "linear" (1D), "bilinear" and "bicubic" (2D) and "trilinear" (3D).
+// alignCorners maps the corner samples of input and output onto each other
+// and is ignored in nearest mode.
+func Interpolate(input *Tensor, size []int, scaleFactor []float64, mode string, alignCorners bool) (*Tensor, error) {
+	if input == nil {
+		return nil, errors.New("Interpolate requires input tensor")
+	}
+	rank := len(input.shape)
+	if rank < 3 || rank > 5 {
+		return nil, errors.New("Interpolate expects input shape [batch, channels, spatial...] with 1 to 3 spatial dims")
+	}
+	spatial := rank - 2
+	if mode == "" {
+		mode = "nearest"
+	}
+	switch mode {
+	case "nearest":
+	case "linear":
+		if spatial != 1 {
+			return nil, errors.New("linear interpolation expects 3D input")
+		}
+	case "bilinear", "bicubic":
+		if spatial != 2 {
+			return nil, errors.New(mode + " interpolation expects 4D input")
+		}
+	case "trilinear":
+		if spatial != 3 {
+			return nil, errors.New("trilinear interpolation expects 5D input")
+		}
+	default:
+		return nil, errors.New("unsupported interpolation mode")
+	}
+	if (size == nil) == (scaleFactor == nil) {
+		return nil, errors.New("Interpolate requires exactly one of size or scaleFactor")
+	}
+	if size != nil && len(size) != 1 && len(size) != spatial {
+		return nil, errors.New("Interpolate size length must match spatial dims")
+	}
+	if scaleFactor != nil && len(scaleFactor) != 1 && len(scaleFactor) != spatial {
+		return nil, errors.New("Interpolate scaleFactor length must match spatial dims")
+	}
+
+	inSpatial := input.shape[2:]
+	outSpatial := make([]int, spatial)
+	taps := make([][][]tap, spatial)
+	for d := 0; d < spatial; d++ {
+		in := inSpatial[d]
+		var out int
+		var scale float64
+		if size != nil {
+			out = size[0]
+			if len(size) > 1 {
+				out = size[d]
+			}
+			scale = float64(in) / float64(out)
+		} else {
+			factor := scaleFactor[0]
+			if len(scaleFactor) > 1 {
+				factor = scaleFactor[d]
+			}
+			if factor <= 0 {
+				return nil, errors.New("Interpolate scaleFactor must be positive")
+			}
+			out = int(math.Floor(float64(in) * factor))
+			scale = 1 / factor
+		}
+		if out <= 0 {
+			return nil, errors.New("invalid output size")
+		}
+		outSpatial[d] = out
+		taps[d] = axisTaps(mode, in, out, scale, alignCorners)
+	}
+
+	batch, channels := input.shape[0], input.shape[1]
+	inPlane := 1
+	for _, dim := range inSpatial {
+		inPlane *= dim
+	}
+	outPlane := 1
+	for _, dim := range outSpatial {
+		outPlane *= dim
+	}
+	// flatten the per-axis taps into one tap list per output position
+	inStrides := makeStrides(inSpatial)
+	outStrides := makeStrides(outSpatial)
+	plan := make([][]tap, outPlane)
+	for pos := 0; pos < outPlane; pos++ {
+		combined := []tap{{index: 0, weight: 1}}
+		for d := 0; d < spatial; d++ {
+			o := (pos / outStrides[d]) % outSpatial[d]
+			next := make([]tap, 0, len(combined)*len(taps[d][o]))
+			for _, c := range combined {
+				for _, t := range taps[d][o] {
+					next = append(next, tap{index: c.index + t.index*inStrides[d], weight: c.weight * t.weight})
+				}
+			}
+			combined = next
+		}
+		plan[pos] = combined
+	}
+
+	outShape := append([]int{batch, channels}, outSpatial...)
+	out := Zeros(outShape...)
+	parallel.For(batch*channels, func(start, end int) {
+		for nc := start; nc < end; nc++ {
+			inBase := nc * inPlane
+			outBase := nc * outPlane
+			for pos, list := range plan {
+				sum := 0.0
+				for _, t := range list {
+					sum += t.weight * input.data[inBase+t.index]
+				}
+				out.data[outBase+pos] = sum
+			}
+		}
+	})
+
+	if !input.requiresGrad {
+		return out, nil
+	}
+
+	out.requiresGrad = true
+	out.parents = []*Tensor{input}
+	out.node = &node{
+		backward: func(grad *Tensor, grads map[*Tensor]*Tensor) {
+			gInput := Zeros(input.shape...)
+			parallel.For(batch*channels, func(start, end int) {
+				for nc := start; nc < end; nc++ {
+					inBase := nc * inPlane
+					outBase := nc * outPlane
+					for pos, list := range plan {
+						g := grad.data[outBase+pos]
+						if g == 0 {
+							continue
+						}
+						for _, t := range list {
+							gInput.data[inBase+t.index] += t.weight * g
+						}
+					}
+				}
+			})
+			accumulate(grads, input, gInput)
+		},
+	}
+
+	return out, nil
+}
+
+// axisTaps returns the source samples and weights for every output position
+// along one axis.
+func axisTaps(mode string, in, out int, scale float64, alignCorners bool) [][]tap {
+	taps := make([][]tap, out)
+	for o := 0; o < out; o++ {
+		if mode == "nearest" {
+			src := int(math.Floor(float64(o) * scale))
+			if src > in-1 {
+				src = in - 1
+			}
+			taps[o] = []tap{{index: src, weight: 1}}
+			continue
+		}
+		var src float64
+		if alignCorners {
+			if out > 1 {
+				src = float64(o) * float64(in-1) / float64(out-1)
+			}
+		} else {
+			src = (float64(o)+0.5)*scale - 0.5
+		}
+		if mode == "bicubic" {
+			base := int(math.Floor(src))
+			frac := src - float64(base)
+			coeffs := cubicCoefficients(frac)
+			list := make([]tap, 4)
+			for k := 0; k < 4; k++ {
+				list[k] = tap{index: clampIndex(base-1+k, in), weight: coeffs[k]}
+			}
+			taps[o] = list
+			continue
+		}
+		if src < 0 {
+			src = 0
+		}
+		lo := int(math.Floor(src))
+		if lo > in-1 {
+			lo = in - 1
+		}
+		hi := lo + 1
+		if hi > in-1 {
+			hi = in - 1
+		}
+		frac := src - float64(lo)
+		taps[o] = []tap{{index: lo, weight: 1 - frac}, {index: hi, weight: frac}}
+	}
+	return taps
+}
+
+// cubicCoefficients returns the Keys cubic convolution weights (a = -0.75)
+// for the four samples around a fractional offset t in [0, 1).
+func cubicCoefficients(t float64) [4]float64 {
+	const a = -0.75
+	near := func(x float64) float64 { return ((a+2)*x-(a+3))*x*x + 1 }
+	far := func(x float64) float64 { return ((a*x-5*a)*x+8*a)*x - 4*a }
+	return [4]float64{far(t + 1), near(t), near(1 - t), far(2 - t)}
+}
+
+func clampIndex(i, n int) int {
+	if i < 0 {
+		return 0
+	}
+	if i > n-1 {
+		return n - 1
+	}
+	return i
+}
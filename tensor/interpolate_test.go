@@ -0,0 +1,182 @@
+package tensor
+
+import (
+	"math"
+	"testing"
+)
+
+// weightedLoss reduces out to a scalar with fixed non-uniform weights so that
+// gradient checks see every output position.
+func weightedLoss(out *Tensor) *Tensor {
+	w := make([]float64, out.Numel())
+	for i := range w {
+		w[i] = 0.3 + 0.11*float64(i%7) - 0.05*float64(i%3)
+	}
+	prod, err := Mul(out, MustNew(w, out.Shape()...))
+	if err != nil {
+		panic(err)
+	}
+	return Sum(prod)
+}
+
+func checkInterpolateGrad(t *testing.T, name string, base []float64, shape []int, f func(*Tensor) (*Tensor, error)) {
+	t.Helper()
+	input := MustNew(append([]float64(nil), base...), shape...)
+	input.SetRequiresGrad(true)
+	out, err := f(input)
+	if err != nil {
+		t.Fatalf("%s forward failed: %v", name, err)
+	}
+	if err := weightedLoss(out).Backward(); err != nil {
+		t.Fatalf("%s backward failed: %v", name, err)
+	}
+	want := numericalGrad(func(vals []float64) float64 {
+		res, err := f(MustNew(vals, shape...))
+		if err != nil {
+			t.Fatalf("%s forward failed: %v", name, err)
+		}
+		return weightedLoss(res).Data()[0]
+	}, base, 1e-6)
+	if !almostEqualSlices(input.Grad().Data(), want, 1e-6) {
+		t.Fatalf("%s grad mismatch:\n got %v\nwant %v", name, input.Grad().Data(), want)
+	}
+}
+
+func TestInterpolateForward(t *testing.T) {
+	out, err := Interpolate(MustNew([]float64{1, 2}, 1, 1, 2), nil, []float64{2}, "nearest", false)
+	if err != nil {
+		t.Fatalf("nearest Interpolate failed: %v", err)
+	}
+	if !almostEqualSlices(out.Data(), []float64{1, 1, 2, 2}, 1e-12) {
+		t.Fatalf("unexpected nearest output: %v", out.Data())
+	}
+
+	out, err = Interpolate(MustNew([]float64{0, 3}, 1, 1, 2), []int{4}, nil, "linear", true)
+	if err != nil {
+		t.Fatalf("linear Interpolate failed: %v", err)
+	}
+	if !almostEqualSlices(out.Data(), []float64{0, 1, 2, 3}, 1e-12) {
+		t.Fatalf("unexpected aligned linear output: %v", out.Data())
+	}
+
+	out, err = Interpolate(MustNew([]float64{1, 2, 3, 4}, 1, 1, 2, 2), []int{4, 4}, nil, "bilinear", false)
+	if err != nil {
+		t.Fatalf("bilinear Interpolate failed: %v", err)
+	}
+	expected := []float64{
+		1, 1.25, 1.75, 2,
+		1.5, 1.75, 2.25, 2.5,
+		2.5, 2.75, 3.25, 3.5,
+		3, 3.25, 3.75, 4,
+	}
+	if !almostEqualSlices(out.Data(), expected, 1e-12) {
+		t.Fatalf("unexpected bilinear output: %v", out.Data())
+	}
+
+	out, err = Interpolate(Full(2.5, 1, 2, 3, 3), nil, []float64{1.5, 2}, "bicubic", false)
+	if err != nil {
+		t.Fatalf("bicubic Interpolate failed: %v", err)
+	}
+	if got := out.Shape(); got[2] != 4 || got[3] != 6 {
+		t.Fatalf("unexpected bicubic shape %v", got)
+	}
+	for _, v := range out.Data() {
+		if math.Abs(v-2.5) > 1e-12 {
+			t.Fatalf("bicubic should preserve constants, got %v", v)
+		}
+	}
+
+	out, err = Interpolate(Ones(2, 1, 2, 3, 2), []int{3, 4, 5}, nil, "trilinear", true)
+	if err != nil {
+		t.Fatalf("trilinear Interpolate failed: %v", err)
+	}
+	if got := out.Shape(); got[0] != 2 || got[2] != 3 || got[3] != 4 || got[4] != 5 {
+		t.Fatalf("unexpected trilinear shape %v", got)
+	}
+
+	if _, err := Interpolate(Ones(1, 1, 4), []int{8}, nil, "bilinear", false); err == nil {
+		t.Fatalf("expected error for bilinear on 3D input")
+	}
+	if _, err := Interpolate(Ones(1, 1, 4), []int{8}, []float64{2}, "nearest", false); err == nil {
+		t.Fatalf("expected error when both size and scaleFactor are set")
+	}
+}
+
+func TestInterpolateBackward(t *testing.T) {
+	base := []float64{0.5, -1, 2, 0.3, 1.5, -0.7, 0.9, 0.1, -0.4, 1.2, 0.8, -1.5}
+	checkInterpolateGrad(t, "nearest", base[:6], []int{1, 2, 3}, func(x *Tensor) (*Tensor, error) {
+		return Interpolate(x, []int{5}, nil, "nearest", false)
+	})
+	checkInterpolateGrad(t, "linear", base[:6], []int{1, 2, 3}, func(x *Tensor) (*Tensor, error) {
+		return Interpolate(x, []int{7}, nil, "linear", false)
+	})
+	checkInterpolateGrad(t, "bilinear", base, []int{1, 1, 3, 4}, func(x *Tensor) (*Tensor, error) {
+		return Interpolate(x, []int{5, 3}, nil, "bilinear", true)
+	})
+	checkInterpolateGrad(t, "bicubic", base, []int{1, 1, 3, 4}, func(x *Tensor) (*Tensor, error) {
+		return Interpolate(x, nil, []float64{2}, "bicubic", false)
+	})
+	checkInterpolateGrad(t, "trilinear", base, []int{1, 1, 2, 3, 2}, func(x *Tensor) (*Tensor, error) {
+		return Interpolate(x, nil, []float64{1.5}, "trilinear", false)
+	})
+}
+
+func TestAffineGridIdentitySampling(t *testing.T) {
+	inputVals := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+	input := MustNew(inputVals, 1, 2, 2, 3)
+	theta := MustNew([]float64{1, 0, 0, 0, 1, 0}, 1, 2, 3)
+	for _, align := range []bool{false, true} {
+		grid, err := AffineGrid(theta, []int{1, 2, 2, 3}, align)
+		if err != nil {
+			t.Fatalf("AffineGrid failed: %v", err)
+		}
+		for _, mode := range []string{"bilinear", "nearest"} {
+			out, err := GridSample(input, grid, mode, "zeros", align)
+			if err != nil {
+				t.Fatalf("GridSample failed: %v", err)
+			}
+			if !almostEqualSlices(out.Data(), inputVals, 1e-12) {
+				t.Fatalf("identity %s sampling (align=%v) mismatch: %v", mode, align, out.Data())
+			}
+		}
+	}
+
+	// shifting past the right edge samples only padding
+	shift := MustNew([]float64{1, 0, 3, 0, 1, 0}, 1, 2, 3)
+	grid, _ := AffineGrid(shift, []int{1, 2, 2, 3}, false)
+	out, err := GridSample(input, grid, "bilinear", "zeros", false)
+	if err != nil {
+		t.Fatalf("GridSample failed: %v", err)
+	}
+	for _, v := range out.Data() {
+		if v != 0 {
+			t.Fatalf("expected zero padding, got %v", out.Data())
+		}
+	}
+	out, _ = GridSample(input, grid, "bilinear", "border", false)
+	expected := []float64{3, 3, 3, 6, 6, 6, 9, 9, 9, 12, 12, 12}
+	if !almostEqualSlices(out.Data(), expected, 1e-12) {
+		t.Fatalf("unexpected border sampling: %v", out.Data())
+	}
+}
+
+func TestGridSampleBackward(t *testing.T) {
+	inputVals := []float64{0.5, -1, 2, 0.3, 1.5, -0.7, 0.9, 0.1, -0.4, 1.2, 0.8, -1.5}
+	thetaVals := []float64{0.8, 0.15, 0.1, -0.2, 0.9, -0.05}
+	sample := func(x, th *Tensor, padding string) (*Tensor, error) {
+		grid, err := AffineGrid(th, []int{1, 2, 3, 2}, false)
+		if err != nil {
+			return nil, err
+		}
+		return GridSample(x, grid, "bilinear", padding, false)
+	}
+	for _, padding := range []string{"zeros", "border"} {
+		padding := padding
+		checkInterpolateGrad(t, "GridSample input "+padding, inputVals, []int{1, 2, 2, 3}, func(x *Tensor) (*Tensor, error) {
+			return sample(x, MustNew(thetaVals, 1, 2, 3), padding)
+		})
+		checkInterpolateGrad(t, "GridSample theta "+padding, thetaVals, []int{1, 2, 3}, func(th *Tensor) (*Tensor, error) {
+			return sample(MustNew(inputVals, 1, 2, 2, 3), th, padding)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package linalg
+
+import (
+	"errors"
+	"math"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// Cholesky returns the lower-triangular factor L with A = L L^T for each
+// symmetric positive-definite matrix in a [..., n, n]. Only the lower
+// triangle of A is read; the gradient is returned symmetrised.
+func Cholesky(a *tensor.Tensor) (*tensor.Tensor, error) {
+	ab, err := unpackSquare(a, "Cholesky")
+	if err != nil {
+		return nil, err
+	}
+	n := ab.mats[0].rows
+	count := len(ab.mats)
+	ls := make([]matrix, count)
+	for i, m := range ab.mats {
+		l, ok := choleskyDecompose(m)
+		if !ok {
+			return nil, errors.New("Cholesky matrix is not positive definite")
+		}
+		ls[i] = l
+	}
+	data, shape := pack(ab.shape, ls)
+	return tensor.NewOp(data, shape, []*tensor.Tensor{a}, func(grad *tensor.Tensor) []*tensor.Tensor {
+		gs := gradBatch(grad, count, n, n)
+		gA := make([]matrix, count)
+		for i := 0; i < count; i++ {
+			l := ls[i]
+			// phi = tril(L^T gL) with the diagonal halved
+			phi := tril(mul(l.t(), tril(gs[i])))
+			for j := 0; j < n; j++ {
+				phi.data[j*n+j] *= 0.5
+			}
+			// S = L^{-T} phi L^{-1}
+			lt := l.t()
+			y := solveUpper(lt, phi)
+			s := solveUpper(lt, y.t()).t()
+			gA[i] = symmetrize(s)
+		}
+		return []*tensor.Tensor{tensorFrom(ab.shape, gA)}
+	})
+}
+
+func choleskyDecompose(a matrix) (matrix, bool) {
+	n := a.rows
+	l := newMatrix(n, n)
+	for j := 0; j < n; j++ {
+		sum := a.at(j, j)
+		for k := 0; k < j; k++ {
+			sum -= l.at(j, k) * l.at(j, k)
+		}
+		if sum <= 0 || math.IsNaN(sum) {
+			return matrix{}, false
+		}
+		d := math.Sqrt(sum)
+		l.set(j, j, d)
+		for i := j + 1; i < n; i++ {
+			s := a.at(i, j)
+			for k := 0; k < j; k++ {
+				s -= l.at(i, k) * l.at(j, k)
+			}
+			l.set(i, j, s/d)
+		}
+	}
+	return l, true
+}
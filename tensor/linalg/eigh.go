@@ -0,0 +1,145 @@
+package linalg
+
+import (
+	"math"
+	"sort"
+
+	"github.com/fumitoshi0524/ixeoriNet/internal/parallel"
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// Eigh computes the eigenvalues and eigenvectors of each symmetric matrix in
+// a [..., n, n]. The input is symmetrised as (A + A^T) / 2. Eigenvalues
+// [..., n] are returned in ascending order and the columns of the
+// eigenvector tensor [..., n, n] are the matching unit eigenvectors.
+// Gradients assume distinct eigenvalues.
+func Eigh(a *tensor.Tensor) (*tensor.Tensor, *tensor.Tensor, error) {
+	ab, err := unpackSquare(a, "Eigh")
+	if err != nil {
+		return nil, nil, err
+	}
+	n := ab.mats[0].rows
+	count := len(ab.mats)
+	ws := make([][]float64, count)
+	vs := make([]matrix, count)
+	parallel.For(count, func(start, end int) {
+		for i := start; i < end; i++ {
+			ws[i], vs[i] = eighDecompose(symmetrize(ab.mats[i]))
+		}
+	})
+	backward := func(gW, gV []matrix) []*tensor.Tensor {
+		gA := make([]matrix, count)
+		for i := 0; i < count; i++ {
+			w, v := ws[i], vs[i]
+			// gA = V (diag(gW) + F o (V^T gV)) V^T with F_ij = 1/(w_j - w_i)
+			inner := mul(v.t(), gV[i])
+			for r := 0; r < n; r++ {
+				for c := 0; c < n; c++ {
+					if r == c {
+						inner.data[r*n+c] = gW[i].data[r]
+						continue
+					}
+					d := w[c] - w[r]
+					if d == 0 {
+						inner.data[r*n+c] = 0
+						continue
+					}
+					inner.data[r*n+c] /= d
+				}
+			}
+			gA[i] = symmetrize(mul(mul(v, inner), v.t()))
+		}
+		return []*tensor.Tensor{tensorFrom(ab.shape, gA)}
+	}
+	zeros := func(rows, cols int) []matrix {
+		out := make([]matrix, count)
+		for i := range out {
+			out[i] = newMatrix(rows, cols)
+		}
+		return out
+	}
+	data, shape := packVectors(ab.shape, ws)
+	values, err := tensor.NewOp(data, shape, []*tensor.Tensor{a}, func(grad *tensor.Tensor) []*tensor.Tensor {
+		return backward(gradBatch(grad, count, n, 1), zeros(n, n))
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	data, shape = pack(ab.shape, vs)
+	vectors, err := tensor.NewOp(data, shape, []*tensor.Tensor{a}, func(grad *tensor.Tensor) []*tensor.Tensor {
+		return backward(zeros(n, 1), gradBatch(grad, count, n, n))
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return values, vectors, nil
+}
+
+// eighDecompose diagonalises a symmetric matrix with cyclic Jacobi rotations.
+func eighDecompose(a matrix) ([]float64, matrix) {
+	n := a.rows
+	m := a.clone()
+	v := identity(n)
+	for sweep := 0; sweep < jacobiMaxSweeps; sweep++ {
+		off := 0.0
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				off += m.at(p, q) * m.at(p, q)
+			}
+		}
+		if off < 1e-30 {
+			break
+		}
+		for p := 0; p < n-1; p++ {
+			for q := p + 1; q < n; q++ {
+				apq := m.at(p, q)
+				if apq == 0 {
+					continue
+				}
+				theta := (m.at(q, q) - m.at(p, p)) / (2 * apq)
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < n; k++ {
+					mkp := m.at(k, p)
+					mkq := m.at(k, q)
+					m.set(k, p, c*mkp-s*mkq)
+					m.set(k, q, s*mkp+c*mkq)
+				}
+				for k := 0; k < n; k++ {
+					mpk := m.at(p, k)
+					mqk := m.at(q, k)
+					m.set(p, k, c*mpk-s*mqk)
+					m.set(q, k, s*mpk+c*mqk)
+				}
+				for k := 0; k < n; k++ {
+					vkp := v.at(k, p)
+					vkq := v.at(k, q)
+					v.set(k, p, c*vkp-s*vkq)
+					v.set(k, q, s*vkp+c*vkq)
+				}
+			}
+		}
+	}
+	w := make([]float64, n)
+	for i := range w {
+		w[i] = m.at(i, i)
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(x, y int) bool { return w[order[x]] < w[order[y]] })
+	sorted := make([]float64, n)
+	vecs := newMatrix(n, n)
+	for dst, src := range order {
+		sorted[dst] = w[src]
+		for i := 0; i < n; i++ {
+			vecs.set(i, dst, v.at(i, src))
+		}
+	}
+	return sorted, vecs
+}
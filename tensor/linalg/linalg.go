@@ -0,0 +1,331 @@
+// Package linalg provides differentiable linear-algebra routines built on
+// top of tensor. Every function treats the last two dimensions of its
+// inputs as matrices and batches over any leading dimensions.
+package linalg
+
+import (
+	"errors"
+	"math"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// matrix is a dense row-major matrix used internally by the decompositions.
+type matrix struct {
+	rows int
+	cols int
+	data []float64
+}
+
+func newMatrix(rows, cols int) matrix {
+	return matrix{rows: rows, cols: cols, data: make([]float64, rows*cols)}
+}
+
+func identity(n int) matrix {
+	m := newMatrix(n, n)
+	for i := 0; i < n; i++ {
+		m.data[i*n+i] = 1
+	}
+	return m
+}
+
+func (m matrix) at(i, j int) float64 {
+	return m.data[i*m.cols+j]
+}
+
+func (m matrix) set(i, j int, v float64) {
+	m.data[i*m.cols+j] = v
+}
+
+func (m matrix) clone() matrix {
+	return matrix{rows: m.rows, cols: m.cols, data: append([]float64(nil), m.data...)}
+}
+
+func (m matrix) t() matrix {
+	out := newMatrix(m.cols, m.rows)
+	for i := 0; i < m.rows; i++ {
+		for j := 0; j < m.cols; j++ {
+			out.data[j*m.rows+i] = m.data[i*m.cols+j]
+		}
+	}
+	return out
+}
+
+func mul(a, b matrix) matrix {
+	if a.cols != b.rows {
+		panic("linalg: matrix multiply shape mismatch")
+	}
+	out := newMatrix(a.rows, b.cols)
+	for i := 0; i < a.rows; i++ {
+		for k := 0; k < a.cols; k++ {
+			aik := a.data[i*a.cols+k]
+			if aik == 0 {
+				continue
+			}
+			for j := 0; j < b.cols; j++ {
+				out.data[i*b.cols+j] += aik * b.data[k*b.cols+j]
+			}
+		}
+	}
+	return out
+}
+
+func add(a, b matrix) matrix {
+	out := a.clone()
+	for i := range out.data {
+		out.data[i] += b.data[i]
+	}
+	return out
+}
+
+func sub(a, b matrix) matrix {
+	out := a.clone()
+	for i := range out.data {
+		out.data[i] -= b.data[i]
+	}
+	return out
+}
+
+func scale(a matrix, v float64) matrix {
+	out := a.clone()
+	for i := range out.data {
+		out.data[i] *= v
+	}
+	return out
+}
+
+// symmetrize returns (a + a^T) / 2.
+func symmetrize(a matrix) matrix {
+	return scale(add(a, a.t()), 0.5)
+}
+
+// tril returns the lower triangle of a, including the diagonal.
+func tril(a matrix) matrix {
+	out := newMatrix(a.rows, a.cols)
+	for i := 0; i < a.rows; i++ {
+		for j := 0; j <= i && j < a.cols; j++ {
+			out.data[i*a.cols+j] = a.data[i*a.cols+j]
+		}
+	}
+	return out
+}
+
+// solveLower solves l * x = b for lower-triangular l.
+func solveLower(l, b matrix) matrix {
+	n := l.rows
+	x := b.clone()
+	for c := 0; c < b.cols; c++ {
+		for i := 0; i < n; i++ {
+			s := x.data[i*b.cols+c]
+			for k := 0; k < i; k++ {
+				s -= l.data[i*n+k] * x.data[k*b.cols+c]
+			}
+			x.data[i*b.cols+c] = s / l.data[i*n+i]
+		}
+	}
+	return x
+}
+
+// solveUpper solves u * x = b for upper-triangular u.
+func solveUpper(u, b matrix) matrix {
+	n := u.rows
+	x := b.clone()
+	for c := 0; c < b.cols; c++ {
+		for i := n - 1; i >= 0; i-- {
+			s := x.data[i*b.cols+c]
+			for k := i + 1; k < n; k++ {
+				s -= u.data[i*n+k] * x.data[k*b.cols+c]
+			}
+			x.data[i*b.cols+c] = s / u.data[i*n+i]
+		}
+	}
+	return x
+}
+
+// rightSolveUpperT returns x * u^{-T} for upper-triangular u.
+func rightSolveUpperT(x, u matrix) matrix {
+	// (x u^{-T})^T = u^{-1} x^T
+	return solveUpper(u, x.t()).t()
+}
+
+// lu holds a partially pivoted LU factorisation P*A = L*U packed in one matrix.
+type lu struct {
+	packed   matrix
+	perm     []int
+	sign     float64
+	singular bool
+}
+
+func luDecompose(a matrix) lu {
+	n := a.rows
+	m := a.clone()
+	perm := make([]int, n)
+	for i := range perm {
+		perm[i] = i
+	}
+	sign := 1.0
+	singular := false
+	for k := 0; k < n; k++ {
+		pivot := k
+		best := math.Abs(m.at(k, k))
+		for i := k + 1; i < n; i++ {
+			if v := math.Abs(m.at(i, k)); v > best {
+				best = v
+				pivot = i
+			}
+		}
+		if best == 0 {
+			singular = true
+			continue
+		}
+		if pivot != k {
+			for j := 0; j < n; j++ {
+				m.data[k*n+j], m.data[pivot*n+j] = m.data[pivot*n+j], m.data[k*n+j]
+			}
+			perm[k], perm[pivot] = perm[pivot], perm[k]
+			sign = -sign
+		}
+		diag := m.at(k, k)
+		for i := k + 1; i < n; i++ {
+			factor := m.at(i, k) / diag
+			m.set(i, k, factor)
+			if factor == 0 {
+				continue
+			}
+			for j := k + 1; j < n; j++ {
+				m.data[i*n+j] -= factor * m.data[k*n+j]
+			}
+		}
+	}
+	return lu{packed: m, perm: perm, sign: sign, singular: singular}
+}
+
+func (f lu) det() float64 {
+	if f.singular {
+		return 0
+	}
+	d := f.sign
+	for i := 0; i < f.packed.rows; i++ {
+		d *= f.packed.at(i, i)
+	}
+	return d
+}
+
+func (f lu) solve(b matrix) matrix {
+	n := f.packed.rows
+	x := newMatrix(n, b.cols)
+	for i, p := range f.perm {
+		copy(x.data[i*b.cols:(i+1)*b.cols], b.data[p*b.cols:(p+1)*b.cols])
+	}
+	for c := 0; c < b.cols; c++ {
+		for i := 0; i < n; i++ {
+			s := x.data[i*b.cols+c]
+			for k := 0; k < i; k++ {
+				s -= f.packed.data[i*n+k] * x.data[k*b.cols+c]
+			}
+			x.data[i*b.cols+c] = s
+		}
+		for i := n - 1; i >= 0; i-- {
+			s := x.data[i*b.cols+c]
+			for k := i + 1; k < n; k++ {
+				s -= f.packed.data[i*n+k] * x.data[k*b.cols+c]
+			}
+			x.data[i*b.cols+c] = s / f.packed.data[i*n+i]
+		}
+	}
+	return x
+}
+
+// batch describes a stack of matrices taken from the trailing two
+// dimensions of a tensor.
+type batch struct {
+	shape []int
+	mats  []matrix
+}
+
+func unpack(t *tensor.Tensor, name string) (batch, error) {
+	if t == nil {
+		return batch{}, errors.New(name + " requires non-nil tensor")
+	}
+	shape := t.Shape()
+	if len(shape) < 2 {
+		return batch{}, errors.New(name + " expects tensor of rank >= 2")
+	}
+	rows, cols := shape[len(shape)-2], shape[len(shape)-1]
+	data := t.Data()
+	count := len(data) / (rows * cols)
+	mats := make([]matrix, count)
+	for i := range mats {
+		mats[i] = matrix{rows: rows, cols: cols, data: data[i*rows*cols : (i+1)*rows*cols]}
+	}
+	return batch{shape: shape[:len(shape)-2], mats: mats}, nil
+}
+
+func unpackSquare(t *tensor.Tensor, name string) (batch, error) {
+	b, err := unpack(t, name)
+	if err != nil {
+		return batch{}, err
+	}
+	if b.mats[0].rows != b.mats[0].cols {
+		return batch{}, errors.New(name + " expects square matrices")
+	}
+	return b, nil
+}
+
+func sameBatch(a, b batch) bool {
+	if len(a.shape) != len(b.shape) {
+		return false
+	}
+	for i, dim := range a.shape {
+		if dim != b.shape[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// pack flattens a stack of equally sized matrices into tensor data and
+// shape with the given batch dimensions.
+func pack(batchShape []int, mats []matrix) ([]float64, []int) {
+	rows, cols := mats[0].rows, mats[0].cols
+	data := make([]float64, 0, len(mats)*rows*cols)
+	for _, m := range mats {
+		data = append(data, m.data...)
+	}
+	shape := append(append([]int(nil), batchShape...), rows, cols)
+	return data, shape
+}
+
+// packVectors flattens per-matrix vectors into tensor data; with an empty
+// batch the result is rank 1.
+func packVectors(batchShape []int, vecs [][]float64) ([]float64, []int) {
+	data := make([]float64, 0, len(vecs)*len(vecs[0]))
+	for _, v := range vecs {
+		data = append(data, v...)
+	}
+	shape := append(append([]int(nil), batchShape...), len(vecs[0]))
+	return data, shape
+}
+
+// scalarShape returns the output shape for one value per matrix; with an
+// empty batch the result follows the package convention of shape [1].
+func scalarShape(batchShape []int) []int {
+	if len(batchShape) == 0 {
+		return []int{1}
+	}
+	return append([]int(nil), batchShape...)
+}
+
+func tensorFrom(batchShape []int, mats []matrix) *tensor.Tensor {
+	data, shape := pack(batchShape, mats)
+	return tensor.MustNew(data, shape...)
+}
+
+func gradBatch(grad *tensor.Tensor, count, rows, cols int) []matrix {
+	data := grad.Data()
+	mats := make([]matrix, count)
+	for i := range mats {
+		mats[i] = matrix{rows: rows, cols: cols, data: data[i*rows*cols : (i+1)*rows*cols]}
+	}
+	return mats
+}
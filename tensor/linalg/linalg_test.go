@@ -0,0 +1,440 @@
+package linalg
+
+import (
+	"math"
+	"testing"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+func almostEqual(a, b []float64, tol float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > tol {
+			return false
+		}
+	}
+	return true
+}
+
+// weightedSum reduces t to a scalar with fixed, distinct weights so that
+// gradient checks exercise every output element.
+func weightedSum(t *tensor.Tensor) *tensor.Tensor {
+	n := t.Numel()
+	w := make([]float64, n)
+	for i := range w {
+		w[i] = 0.3 + 0.17*float64(i%7) - 0.05*float64(i%3)
+	}
+	out, err := tensor.Mul(t, tensor.MustNew(w, t.Shape()...))
+	if err != nil {
+		panic(err)
+	}
+	return tensor.Sum(out)
+}
+
+// checkGrad compares the autograd gradient of f at base with central
+// finite differences.
+func checkGrad(t *testing.T, name string, base []float64, shape []int, f func(x *tensor.Tensor) (*tensor.Tensor, error)) {
+	t.Helper()
+	x := tensor.MustNew(base, shape...)
+	x.SetRequiresGrad(true)
+	loss, err := f(x)
+	if err != nil {
+		t.Fatalf("%s forward failed: %v", name, err)
+	}
+	if err := loss.Backward(); err != nil {
+		t.Fatalf("%s backward failed: %v", name, err)
+	}
+	got := x.Grad().Data()
+	eps := 1e-6
+	want := make([]float64, len(base))
+	for i := range base {
+		plus := append([]float64(nil), base...)
+		plus[i] += eps
+		minus := append([]float64(nil), base...)
+		minus[i] -= eps
+		lp, err := f(tensor.MustNew(plus, shape...))
+		if err != nil {
+			t.Fatalf("%s forward failed: %v", name, err)
+		}
+		lm, err := f(tensor.MustNew(minus, shape...))
+		if err != nil {
+			t.Fatalf("%s forward failed: %v", name, err)
+		}
+		want[i] = (lp.Data()[0] - lm.Data()[0]) / (2 * eps)
+	}
+	if !almostEqual(got, want, 1e-5) {
+		t.Fatalf("%s grad mismatch:\n got %v\nwant %v", name, got, want)
+	}
+}
+
+// spd builds the symmetric positive-definite matrix X X^T + n I from x.
+func spd(x *tensor.Tensor, n int) (*tensor.Tensor, error) {
+	xxt, err := tensor.MatMul(x, x.MustTranspose())
+	if err != nil {
+		return nil, err
+	}
+	eye := tensor.Zeros(n, n)
+	data := eye.Data()
+	for i := 0; i < n; i++ {
+		data[i*n+i] = float64(n)
+	}
+	return tensor.Add(xxt, tensor.MustNew(data, n, n))
+}
+
+var square3 = []float64{
+	4, -2, 1,
+	3, 6, -4,
+	2, 1, 8,
+}
+
+func TestSolveAndInv(t *testing.T) {
+	a := tensor.MustNew(square3, 3, 3)
+	b := tensor.MustNew([]float64{1, 2, 3, 4, 5, 6}, 3, 2)
+	x, err := Solve(a, b)
+	if err != nil {
+		t.Fatalf("Solve failed: %v", err)
+	}
+	ax, err := tensor.MatMul(a, x)
+	if err != nil {
+		t.Fatalf("matmul failed: %v", err)
+	}
+	if !almostEqual(ax.Data(), b.Data(), 1e-9) {
+		t.Fatalf("A X != B: %v", ax.Data())
+	}
+	inv, err := Inv(a)
+	if err != nil {
+		t.Fatalf("Inv failed: %v", err)
+	}
+	prod, _ := tensor.MatMul(a, inv)
+	if !almostEqual(prod.Data(), []float64{1, 0, 0, 0, 1, 0, 0, 0, 1}, 1e-9) {
+		t.Fatalf("A A^-1 != I: %v", prod.Data())
+	}
+	checkGrad(t, "Solve(A)", square3, []int{3, 3}, func(x *tensor.Tensor) (*tensor.Tensor, error) {
+		out, err := Solve(x, b)
+		if err != nil {
+			return nil, err
+		}
+		return weightedSum(out), nil
+	})
+	checkGrad(t, "Solve(B)", b.Data(), []int{3, 2}, func(x *tensor.Tensor) (*tensor.Tensor, error) {
+		out, err := Solve(a, x)
+		if err != nil {
+			return nil, err
+		}
+		return weightedSum(out), nil
+	})
+	checkGrad(t, "Inv", square3, []int{3, 3}, func(x *tensor.Tensor) (*tensor.Tensor, error) {
+		out, err := Inv(x)
+		if err != nil {
+			return nil, err
+		}
+		return weightedSum(out), nil
+	})
+	if _, err := Inv(tensor.MustNew([]float64{1, 2, 2, 4}, 2, 2)); err == nil {
+		t.Fatalf("expected singular matrix error")
+	}
+}
+
+func TestDetAndSlogDetBatched(t *testing.T) {
+	data := append(append([]float64(nil), square3...), 1, 2, 3, 0, -1, 4, 5, 6, 0)
+	a := tensor.MustNew(data, 2, 3, 3)
+	det, err := Det(a)
+	if err != nil {
+		t.Fatalf("Det failed: %v", err)
+	}
+	if !almostEqual(det.Data(), []float64{263, 31}, 1e-9) {
+		t.Fatalf("unexpected determinants: %v", det.Data())
+	}
+	sign, logAbs, err := SlogDet(tensor.MustNew([]float64{0, 1, 1, 0}, 2, 2))
+	if err != nil {
+		t.Fatalf("SlogDet failed: %v", err)
+	}
+	if sign.Data()[0] != -1 || math.Abs(logAbs.Data()[0]) > 1e-12 {
+		t.Fatalf("unexpected slogdet: %v %v", sign.Data(), logAbs.Data())
+	}
+	checkGrad(t, "Det", data, []int{2, 3, 3}, func(x *tensor.Tensor) (*tensor.Tensor, error) {
+		out, err := Det(x)
+		if err != nil {
+			return nil, err
+		}
+		return weightedSum(out), nil
+	})
+	checkGrad(t, "Det singular", []float64{1, 2, 2, 4}, []int{2, 2}, func(x *tensor.Tensor) (*tensor.Tensor, error) {
+		return Det(x)
+	})
+	checkGrad(t, "SlogDet", square3, []int{3, 3}, func(x *tensor.Tensor) (*tensor.Tensor, error) {
+		_, out, err := SlogDet(x)
+		return out, err
+	})
+}
+
+func TestCholesky(t *testing.T) {
+	base := []float64{1, 0.5, -0.3, 0.2, 0.7, 1.1, -0.4, 0.9, 0.3}
+	x := tensor.MustNew(base, 3, 3)
+	a, err := spd(x, 3)
+	if err != nil {
+		t.Fatalf("spd failed: %v", err)
+	}
+	l, err := Cholesky(a)
+	if err != nil {
+		t.Fatalf("Cholesky failed: %v", err)
+	}
+	llt, _ := tensor.MatMul(l, l.MustTranspose())
+	if !almostEqual(llt.Data(), a.Data(), 1e-9) {
+		t.Fatalf("L L^T != A: %v", llt.Data())
+	}
+	if ld := l.Data(); ld[1] != 0 || ld[2] != 0 || ld[5] != 0 {
+		t.Fatalf("L is not lower triangular: %v", ld)
+	}
+	checkGrad(t, "Cholesky", base, []int{3, 3}, func(x *tensor.Tensor) (*tensor.Tensor, error) {
+		a, err := spd(x, 3)
+		if err != nil {
+			return nil, err
+		}
+		out, err := Cholesky(a)
+		if err != nil {
+			return nil, err
+		}
+		return weightedSum(out), nil
+	})
+	if _, err := Cholesky(tensor.MustNew([]float64{1, 2, 2, 1}, 2, 2)); err == nil {
+		t.Fatalf("expected not positive definite error")
+	}
+}
+
+func TestQR(t *testing.T) {
+	tall := []float64{1, 2, -1, 0.5, 3, 1, 0.2, -2, 4, 1, 1, 1}
+	wide := []float64{1, 2, -1, 0.5, 3, 1, 0.2, -2}
+	for _, tc := range []struct {
+		name  string
+		data  []float64
+		shape []int
+	}{
+		{"tall", tall, []int{4, 3}},
+		{"wide", wide, []int{2, 4}},
+	} {
+		a := tensor.MustNew(tc.data, tc.shape...)
+		q, r, err := QR(a)
+		if err != nil {
+			t.Fatalf("QR %s failed: %v", tc.name, err)
+		}
+		qr, _ := tensor.MatMul(q, r)
+		if !almostEqual(qr.Data(), tc.data, 1e-9) {
+			t.Fatalf("QR %s does not reconstruct A: %v", tc.name, qr.Data())
+		}
+		qtq, _ := tensor.MatMul(q.MustTranspose(), q)
+		k := q.Shape()[1]
+		for i := 0; i < k; i++ {
+			for j := 0; j < k; j++ {
+				want := 0.0
+				if i == j {
+					want = 1
+				}
+				if math.Abs(qtq.Data()[i*k+j]-want) > 1e-9 {
+					t.Fatalf("QR %s Q is not orthonormal: %v", tc.name, qtq.Data())
+				}
+			}
+		}
+		checkGrad(t, "QR "+tc.name+" Q", tc.data, tc.shape, func(x *tensor.Tensor) (*tensor.Tensor, error) {
+			q, _, err := QR(x)
+			if err != nil {
+				return nil, err
+			}
+			return weightedSum(q), nil
+		})
+		checkGrad(t, "QR "+tc.name+" R", tc.data, tc.shape, func(x *tensor.Tensor) (*tensor.Tensor, error) {
+			_, r, err := QR(x)
+			if err != nil {
+				return nil, err
+			}
+			return weightedSum(r), nil
+		})
+	}
+}
+
+func TestSVD(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		data  []float64
+		shape []int
+	}{
+		{"tall", []float64{3, 1, 0.5, 2, -1, 4, 0.3, 0.7, 1.5, -2, 1, 0}, []int{4, 3}},
+		{"wide", []float64{3, 1, 0.5, 2, -1, 4, 0.3, 0.7}, []int{2, 4}},
+	} {
+		a := tensor.MustNew(tc.data, tc.shape...)
+		u, s, vh, err := SVD(a)
+		if err != nil {
+			t.Fatalf("SVD %s failed: %v", tc.name, err)
+		}
+		sv := s.Data()
+		for i := 1; i < len(sv); i++ {
+			if sv[i] > sv[i-1] {
+				t.Fatalf("SVD %s singular values not sorted: %v", tc.name, sv)
+			}
+		}
+		k := len(sv)
+		us := u.Data()
+		m := tc.shape[0]
+		for i := 0; i < m; i++ {
+			for j := 0; j < k; j++ {
+				us[i*k+j] *= sv[j]
+			}
+		}
+		recon, _ := tensor.MatMul(tensor.MustNew(us, m, k), vh)
+		if !almostEqual(recon.Data(), tc.data, 1e-9) {
+			t.Fatalf("SVD %s does not reconstruct A: %v", tc.name, recon.Data())
+		}
+		checkGrad(t, "SVD "+tc.name+" S", tc.data, tc.shape, func(x *tensor.Tensor) (*tensor.Tensor, error) {
+			_, s, _, err := SVD(x)
+			if err != nil {
+				return nil, err
+			}
+			return weightedSum(s), nil
+		})
+		// U diag(w) Vh is invariant to the sign ambiguity of singular vectors.
+		checkGrad(t, "SVD "+tc.name+" U/Vh", tc.data, tc.shape, func(x *tensor.Tensor) (*tensor.Tensor, error) {
+			u, _, vh, err := SVD(x)
+			if err != nil {
+				return nil, err
+			}
+			w := make([]float64, k*k)
+			for i := 0; i < k; i++ {
+				w[i*k+i] = float64(i + 1)
+			}
+			uw, err := tensor.MatMul(u, tensor.MustNew(w, k, k))
+			if err != nil {
+				return nil, err
+			}
+			out, err := tensor.MatMul(uw, vh)
+			if err != nil {
+				return nil, err
+			}
+			return weightedSum(out), nil
+		})
+	}
+}
+
+func TestEigh(t *testing.T) {
+	base := []float64{2, 1, 0.5, 1, 3, -0.4, 0.5, -0.4, 1}
+	a := tensor.MustNew(base, 3, 3)
+	w, v, err := Eigh(a)
+	if err != nil {
+		t.Fatalf("Eigh failed: %v", err)
+	}
+	wv := w.Data()
+	if !(wv[0] < wv[1] && wv[1] < wv[2]) {
+		t.Fatalf("eigenvalues not ascending: %v", wv)
+	}
+	av, _ := tensor.MatMul(a, v)
+	vd := v.Data()
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if math.Abs(av.Data()[i*3+j]-wv[j]*vd[i*3+j]) > 1e-9 {
+				t.Fatalf("A v != w v: %v", av.Data())
+			}
+		}
+	}
+	sym := func(x *tensor.Tensor) (*tensor.Tensor, error) {
+		return tensor.Add(x, x.MustTranspose())
+	}
+	checkGrad(t, "Eigh values", base, []int{3, 3}, func(x *tensor.Tensor) (*tensor.Tensor, error) {
+		s, err := sym(x)
+		if err != nil {
+			return nil, err
+		}
+		w, _, err := Eigh(s)
+		if err != nil {
+			return nil, err
+		}
+		return weightedSum(w), nil
+	})
+	checkGrad(t, "Eigh vectors", base, []int{3, 3}, func(x *tensor.Tensor) (*tensor.Tensor, error) {
+		s, err := sym(x)
+		if err != nil {
+			return nil, err
+		}
+		_, v, err := Eigh(s)
+		if err != nil {
+			return nil, err
+		}
+		// V diag(c) V^T removes the sign ambiguity of each eigenvector.
+		c := tensor.MustNew([]float64{1, 0, 0, 0, 2, 0, 0, 0, 5}, 3, 3)
+		vc, err := tensor.MatMul(v, c)
+		if err != nil {
+			return nil, err
+		}
+		out, err := tensor.MatMul(vc, v.MustTranspose())
+		if err != nil {
+			return nil, err
+		}
+		return weightedSum(out), nil
+	})
+}
+
+func TestPinvAndLstsq(t *testing.T) {
+	data := []float64{1, 2, 3, 4, 5, 7, 0.5, -1}
+	a := tensor.MustNew(data, 4, 2)
+	p, err := Pinv(a, 0)
+	if err != nil {
+		t.Fatalf("Pinv failed: %v", err)
+	}
+	pa, _ := tensor.MatMul(p, a)
+	if !almostEqual(pa.Data(), []float64{1, 0, 0, 1}, 1e-9) {
+		t.Fatalf("pinv(A) A != I: %v", pa.Data())
+	}
+	b := tensor.MustNew([]float64{1, 0, 2, 1}, 4, 1)
+	x, err := Lstsq(a, b)
+	if err != nil {
+		t.Fatalf("Lstsq failed: %v", err)
+	}
+	// normal equations: A^T (A x - b) = 0
+	ax, _ := tensor.MatMul(a, x)
+	resid, _ := tensor.Sub(ax, b)
+	normal, _ := tensor.MatMul(a.MustTranspose(), resid)
+	if !almostEqual(normal.Data(), []float64{0, 0}, 1e-9) {
+		t.Fatalf("Lstsq residual not orthogonal: %v", normal.Data())
+	}
+	checkGrad(t, "Pinv", data, []int{4, 2}, func(x *tensor.Tensor) (*tensor.Tensor, error) {
+		out, err := Pinv(x, 0)
+		if err != nil {
+			return nil, err
+		}
+		return weightedSum(out), nil
+	})
+	checkGrad(t, "Lstsq", data, []int{4, 2}, func(x *tensor.Tensor) (*tensor.Tensor, error) {
+		out, err := Lstsq(x, b)
+		if err != nil {
+			return nil, err
+		}
+		return weightedSum(out), nil
+	})
+}
+
+func TestMatrixNorm(t *testing.T) {
+	data := []float64{1, -2, 3, 0.5, 4, -1}
+	a := tensor.MustNew(data, 2, 3)
+	fro, err := MatrixNorm(a, "")
+	if err != nil {
+		t.Fatalf("MatrixNorm failed: %v", err)
+	}
+	if math.Abs(fro.Data()[0]-math.Sqrt(31.25)) > 1e-9 {
+		t.Fatalf("unexpected frobenius norm: %v", fro.Data())
+	}
+	one, _ := MatrixNorm(a, "1")
+	inf, _ := MatrixNorm(a, "inf")
+	if one.Data()[0] != 6 || inf.Data()[0] != 6 {
+		t.Fatalf("unexpected 1/inf norms: %v %v", one.Data(), inf.Data())
+	}
+	for _, ord := range []string{"fro", "nuc", "2", "1", "inf"} {
+		ord := ord
+		checkGrad(t, "MatrixNorm "+ord, data, []int{2, 3}, func(x *tensor.Tensor) (*tensor.Tensor, error) {
+			return MatrixNorm(x, ord)
+		})
+	}
+	if _, err := MatrixNorm(a, "bogus"); err == nil {
+		t.Fatalf("expected unsupported order error")
+	}
+}
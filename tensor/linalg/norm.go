@@ -0,0 +1,120 @@
+package linalg
+
+import (
+	"errors"
+	"math"
+
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// MatrixNorm computes a matrix norm of each matrix in a [..., m, n].
+// Supported orders are "fro" (default when empty), "nuc" (sum of singular
+// values), "2" (largest singular value), "1" (max column abs sum) and "inf"
+// (max row abs sum). The result has the batch shape of a, or [1].
+func MatrixNorm(a *tensor.Tensor, ord string) (*tensor.Tensor, error) {
+	if ord == "" {
+		ord = "fro"
+	}
+	switch ord {
+	case "fro", "nuc", "2", "1", "inf":
+	default:
+		return nil, errors.New("MatrixNorm unsupported order " + ord)
+	}
+	ab, err := unpack(a, "MatrixNorm")
+	if err != nil {
+		return nil, err
+	}
+	count := len(ab.mats)
+	values := make([]float64, count)
+	// derivs holds d norm / dA for each matrix.
+	derivs := make([]matrix, count)
+	for i, mat := range ab.mats {
+		values[i], derivs[i] = matrixNorm(mat, ord)
+	}
+	return tensor.NewOp(values, scalarShape(ab.shape), []*tensor.Tensor{a}, func(grad *tensor.Tensor) []*tensor.Tensor {
+		g := grad.Data()
+		gA := make([]matrix, count)
+		for i := 0; i < count; i++ {
+			gA[i] = scale(derivs[i], g[i])
+		}
+		return []*tensor.Tensor{tensorFrom(ab.shape, gA)}
+	})
+}
+
+func matrixNorm(a matrix, ord string) (float64, matrix) {
+	m, n := a.rows, a.cols
+	deriv := newMatrix(m, n)
+	switch ord {
+	case "fro":
+		sum := 0.0
+		for _, v := range a.data {
+			sum += v * v
+		}
+		norm := math.Sqrt(sum)
+		if norm > 0 {
+			for i, v := range a.data {
+				deriv.data[i] = v / norm
+			}
+		}
+		return norm, deriv
+	case "nuc", "2":
+		u, s, v := svdDecompose(a)
+		if ord == "2" {
+			if len(s) == 0 {
+				return 0, deriv
+			}
+			for i := 0; i < m; i++ {
+				for j := 0; j < n; j++ {
+					deriv.data[i*n+j] = u.at(i, 0) * v.at(j, 0)
+				}
+			}
+			return s[0], deriv
+		}
+		sum := 0.0
+		for _, x := range s {
+			sum += x
+		}
+		return sum, mul(u, v.t())
+	case "1":
+		best, bestCol := -1.0, 0
+		for j := 0; j < n; j++ {
+			s := 0.0
+			for i := 0; i < m; i++ {
+				s += math.Abs(a.at(i, j))
+			}
+			if s > best {
+				best, bestCol = s, j
+			}
+		}
+		for i := 0; i < m; i++ {
+			deriv.set(i, bestCol, sign(a.at(i, bestCol)))
+		}
+		return best, deriv
+	default:
+		best, bestRow := -1.0, 0
+		for i := 0; i < m; i++ {
+			s := 0.0
+			for j := 0; j < n; j++ {
+				s += math.Abs(a.at(i, j))
+			}
+			if s > best {
+				best, bestRow = s, i
+			}
+		}
+		for j := 0; j < n; j++ {
+			deriv.set(bestRow, j, sign(a.at(bestRow, j)))
+		}
+		return best, deriv
+	}
+}
+
+func sign(v float64) float64 {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,173 @@
+package linalg
+
+import (
+	"math"
+
+	"github.com/fumitoshi0524/ixeoriNet/internal/parallel"
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// QR computes the reduced QR decomposition A = Q R of each matrix in
+// a [..., m, n]. With k = min(m, n), Q has shape [..., m, k] with orthonormal
+// columns and R has shape [..., k, n] and is upper triangular. Gradients
+// assume the leading k x k block of R is non-singular.
+func QR(a *tensor.Tensor) (*tensor.Tensor, *tensor.Tensor, error) {
+	ab, err := unpack(a, "QR")
+	if err != nil {
+		return nil, nil, err
+	}
+	m, n := ab.mats[0].rows, ab.mats[0].cols
+	k := m
+	if n < k {
+		k = n
+	}
+	count := len(ab.mats)
+	qs := make([]matrix, count)
+	rs := make([]matrix, count)
+	parallel.For(count, func(start, end int) {
+		for i := start; i < end; i++ {
+			qs[i], rs[i] = qrDecompose(ab.mats[i])
+		}
+	})
+	backward := func(gQ, gR []matrix) []*tensor.Tensor {
+		gA := make([]matrix, count)
+		for i := 0; i < count; i++ {
+			gA[i] = qrBackward(ab.mats[i], qs[i], rs[i], gQ[i], gR[i])
+		}
+		return []*tensor.Tensor{tensorFrom(ab.shape, gA)}
+	}
+	zeros := func(rows, cols int) []matrix {
+		out := make([]matrix, count)
+		for i := range out {
+			out[i] = newMatrix(rows, cols)
+		}
+		return out
+	}
+	data, shape := pack(ab.shape, qs)
+	q, err := tensor.NewOp(data, shape, []*tensor.Tensor{a}, func(grad *tensor.Tensor) []*tensor.Tensor {
+		return backward(gradBatch(grad, count, m, k), zeros(k, n))
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	data, shape = pack(ab.shape, rs)
+	r, err := tensor.NewOp(data, shape, []*tensor.Tensor{a}, func(grad *tensor.Tensor) []*tensor.Tensor {
+		return backward(zeros(m, k), gradBatch(grad, count, k, n))
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return q, r, nil
+}
+
+// qrDecompose factors a with Householder reflections and returns the
+// reduced Q [m, k] and R [k, n]. The diagonal of R is made non-negative.
+func qrDecompose(a matrix) (matrix, matrix) {
+	m, n := a.rows, a.cols
+	k := m
+	if n < k {
+		k = n
+	}
+	r := a.clone()
+	q := identity(m)
+	v := make([]float64, m)
+	for j := 0; j < k; j++ {
+		norm := 0.0
+		for i := j; i < m; i++ {
+			norm += r.at(i, j) * r.at(i, j)
+		}
+		norm = math.Sqrt(norm)
+		if norm == 0 {
+			continue
+		}
+		alpha := -norm
+		if r.at(j, j) < 0 {
+			alpha = norm
+		}
+		vnorm := 0.0
+		for i := j; i < m; i++ {
+			v[i] = r.at(i, j)
+			if i == j {
+				v[i] -= alpha
+			}
+			vnorm += v[i] * v[i]
+		}
+		if vnorm == 0 {
+			continue
+		}
+		// R = (I - 2vv^T/|v|^2) R, Q = Q (I - 2vv^T/|v|^2)
+		for c := 0; c < n; c++ {
+			dot := 0.0
+			for i := j; i < m; i++ {
+				dot += v[i] * r.at(i, c)
+			}
+			f := 2 * dot / vnorm
+			for i := j; i < m; i++ {
+				r.data[i*n+c] -= f * v[i]
+			}
+		}
+		for row := 0; row < m; row++ {
+			dot := 0.0
+			for i := j; i < m; i++ {
+				dot += q.at(row, i) * v[i]
+			}
+			f := 2 * dot / vnorm
+			for i := j; i < m; i++ {
+				q.data[row*m+i] -= f * v[i]
+			}
+		}
+	}
+	qOut := newMatrix(m, k)
+	rOut := newMatrix(k, n)
+	for j := 0; j < k; j++ {
+		sign := 1.0
+		if r.at(j, j) < 0 {
+			sign = -1
+		}
+		for i := 0; i < m; i++ {
+			qOut.set(i, j, sign*q.at(i, j))
+		}
+		for c := j; c < n; c++ {
+			rOut.set(j, c, sign*r.at(j, c))
+		}
+	}
+	return qOut, rOut
+}
+
+func qrBackward(a, q, r, gQ, gR matrix) matrix {
+	m, n := a.rows, a.cols
+	if m >= n {
+		return qrBackwardDeep(q, r, gQ, gR)
+	}
+	// A = [X | Y] with X square: X = Q U, Y = Q V.
+	u := newMatrix(m, m)
+	gU := newMatrix(m, m)
+	for i := 0; i < m; i++ {
+		copy(u.data[i*m:(i+1)*m], r.data[i*n:i*n+m])
+		copy(gU.data[i*m:(i+1)*m], gR.data[i*n:i*n+m])
+	}
+	y := newMatrix(m, n-m)
+	gV := newMatrix(m, n-m)
+	for i := 0; i < m; i++ {
+		copy(y.data[i*(n-m):(i+1)*(n-m)], a.data[i*n+m:(i+1)*n])
+		copy(gV.data[i*(n-m):(i+1)*(n-m)], gR.data[i*n+m:(i+1)*n])
+	}
+	gX := qrBackwardDeep(q, u, add(gQ, mul(y, gV.t())), gU)
+	gY := mul(q, gV)
+	out := newMatrix(m, n)
+	for i := 0; i < m; i++ {
+		copy(out.data[i*n:i*n+m], gX.data[i*m:(i+1)*m])
+		copy(out.data[i*n+m:(i+1)*n], gY.data[i*(n-m):(i+1)*(n-m)])
+	}
+	return out
+}
+
+// qrBackwardDeep handles m >= n, where R is square and invertible.
+func qrBackwardDeep(q, r, gQ, gR matrix) matrix {
+	qdq := mul(q.t(), gQ)
+	rdr := mul(r, gR.t())
+	lower := tril(add(sub(qdq, qdq.t()), sub(rdr, rdr.t())))
+	gradA := mul(q, add(gR, rightSolveUpperT(lower, r)))
+	gradB := rightSolveUpperT(sub(gQ, mul(q, qdq)), r)
+	return add(gradA, gradB)
+}
@@ -0,0 +1,187 @@
+package linalg
+
+import (
+	"errors"
+	"math"
+
+	"github.com/fumitoshi0524/ixeoriNet/internal/parallel"
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+// Solve returns X such that A X = B.
+// A shape: [..., n, n], B shape: [..., n, k] with matching batch dimensions.
+func Solve(a, b *tensor.Tensor) (*tensor.Tensor, error) {
+	ab, err := unpackSquare(a, "Solve")
+	if err != nil {
+		return nil, err
+	}
+	bb, err := unpack(b, "Solve")
+	if err != nil {
+		return nil, err
+	}
+	if !sameBatch(ab, bb) {
+		return nil, errors.New("Solve batch dimensions mismatch")
+	}
+	n := ab.mats[0].rows
+	k := bb.mats[0].cols
+	if bb.mats[0].rows != n {
+		return nil, errors.New("Solve right-hand side rows mismatch")
+	}
+	count := len(ab.mats)
+	factors := make([]lu, count)
+	xs := make([]matrix, count)
+	for i := 0; i < count; i++ {
+		factors[i] = luDecompose(ab.mats[i])
+		if factors[i].singular {
+			return nil, errors.New("Solve matrix is singular")
+		}
+	}
+	parallel.For(count, func(start, end int) {
+		for i := start; i < end; i++ {
+			xs[i] = factors[i].solve(bb.mats[i])
+		}
+	})
+	data, shape := pack(ab.shape, xs)
+	return tensor.NewOp(data, shape, []*tensor.Tensor{a, b}, func(grad *tensor.Tensor) []*tensor.Tensor {
+		gs := gradBatch(grad, count, n, k)
+		gA := make([]matrix, count)
+		gB := make([]matrix, count)
+		for i := 0; i < count; i++ {
+			// gB = A^{-T} G, gA = -gB X^T
+			gB[i] = luDecompose(ab.mats[i].t()).solve(gs[i])
+			gA[i] = scale(mul(gB[i], xs[i].t()), -1)
+		}
+		return []*tensor.Tensor{tensorFrom(ab.shape, gA), tensorFrom(bb.shape, gB)}
+	})
+}
+
+// Inv returns the inverse of each square matrix in a [..., n, n].
+func Inv(a *tensor.Tensor) (*tensor.Tensor, error) {
+	ab, err := unpackSquare(a, "Inv")
+	if err != nil {
+		return nil, err
+	}
+	n := ab.mats[0].rows
+	count := len(ab.mats)
+	inv := make([]matrix, count)
+	for i, m := range ab.mats {
+		f := luDecompose(m)
+		if f.singular {
+			return nil, errors.New("Inv matrix is singular")
+		}
+		inv[i] = f.solve(identity(n))
+	}
+	data, shape := pack(ab.shape, inv)
+	return tensor.NewOp(data, shape, []*tensor.Tensor{a}, func(grad *tensor.Tensor) []*tensor.Tensor {
+		gs := gradBatch(grad, count, n, n)
+		gA := make([]matrix, count)
+		for i := 0; i < count; i++ {
+			// gA = -X^T G X^T
+			xt := inv[i].t()
+			gA[i] = scale(mul(mul(xt, gs[i]), xt), -1)
+		}
+		return []*tensor.Tensor{tensorFrom(ab.shape, gA)}
+	})
+}
+
+// Det returns the determinant of each square matrix in a [..., n, n]. The
+// result has the batch shape of a, or shape [1] for a single matrix.
+func Det(a *tensor.Tensor) (*tensor.Tensor, error) {
+	ab, err := unpackSquare(a, "Det")
+	if err != nil {
+		return nil, err
+	}
+	n := ab.mats[0].rows
+	count := len(ab.mats)
+	dets := make([]float64, count)
+	factors := make([]lu, count)
+	for i, m := range ab.mats {
+		factors[i] = luDecompose(m)
+		dets[i] = factors[i].det()
+	}
+	return tensor.NewOp(dets, scalarShape(ab.shape), []*tensor.Tensor{a}, func(grad *tensor.Tensor) []*tensor.Tensor {
+		g := grad.Data()
+		gA := make([]matrix, count)
+		for i := 0; i < count; i++ {
+			// d det / dA = adj(A)^T = det * A^{-T}
+			var cof matrix
+			if factors[i].singular {
+				cof = cofactor(ab.mats[i])
+			} else {
+				cof = scale(factors[i].solve(identity(n)).t(), dets[i])
+			}
+			gA[i] = scale(cof, g[i])
+		}
+		return []*tensor.Tensor{tensorFrom(ab.shape, gA)}
+	})
+}
+
+// SlogDet returns the sign and the natural log of the absolute determinant
+// of each square matrix in a [..., n, n]. Only logAbsDet carries gradients.
+func SlogDet(a *tensor.Tensor) (*tensor.Tensor, *tensor.Tensor, error) {
+	ab, err := unpackSquare(a, "SlogDet")
+	if err != nil {
+		return nil, nil, err
+	}
+	n := ab.mats[0].rows
+	count := len(ab.mats)
+	signs := make([]float64, count)
+	logs := make([]float64, count)
+	factors := make([]lu, count)
+	for i, m := range ab.mats {
+		factors[i] = luDecompose(m)
+		if factors[i].singular {
+			logs[i] = math.Inf(-1)
+			continue
+		}
+		s := factors[i].sign
+		sum := 0.0
+		for j := 0; j < n; j++ {
+			d := factors[i].packed.at(j, j)
+			if d < 0 {
+				s = -s
+			}
+			sum += math.Log(math.Abs(d))
+		}
+		signs[i] = s
+		logs[i] = sum
+	}
+	shape := scalarShape(ab.shape)
+	sign := tensor.MustNew(signs, shape...)
+	logAbsDet, err := tensor.NewOp(logs, shape, []*tensor.Tensor{a}, func(grad *tensor.Tensor) []*tensor.Tensor {
+		g := grad.Data()
+		gA := make([]matrix, count)
+		for i := 0; i < count; i++ {
+			if factors[i].singular {
+				gA[i] = newMatrix(n, n)
+				continue
+			}
+			gA[i] = scale(factors[i].solve(identity(n)).t(), g[i])
+		}
+		return []*tensor.Tensor{tensorFrom(ab.shape, gA)}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return sign, logAbsDet, nil
+}
+
+// cofactor returns the cofactor matrix adj(a)^T using the SVD, which stays
+// well defined when a is singular.
+func cofactor(a matrix) matrix {
+	n := a.rows
+	u, s, v := svdDecompose(a)
+	// adj(A) = det(U) det(V) V adj(S) U^T, where adj(S)_ii = prod_{j!=i} s_j.
+	coef := luDecompose(u).det() * luDecompose(v).det()
+	adjS := newMatrix(n, n)
+	for i := 0; i < n; i++ {
+		p := coef
+		for j := 0; j < n; j++ {
+			if j != i {
+				p *= s[j]
+			}
+		}
+		adjS.set(i, i, p)
+	}
+	return mul(mul(u, adjS), v.t())
+}
@@ -0,0 +1,378 @@
+package linalg
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/fumitoshi0524/ixeoriNet/internal/parallel"
+	"github.com/fumitoshi0524/ixeoriNet/tensor"
+)
+
+const jacobiMaxSweeps = 100
+
+// SVD computes the reduced singular value decomposition A = U diag(S) Vh of
+// each matrix in a [..., m, n]. With k = min(m, n) the outputs have shapes
+// U [..., m, k], S [..., k] and Vh [..., k, n]; singular values are sorted in
+// descending order. Gradients assume distinct, non-zero singular values.
+func SVD(a *tensor.Tensor) (*tensor.Tensor, *tensor.Tensor, *tensor.Tensor, error) {
+	ab, err := unpack(a, "SVD")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	m, n := ab.mats[0].rows, ab.mats[0].cols
+	k := m
+	if n < k {
+		k = n
+	}
+	count := len(ab.mats)
+	us := make([]matrix, count)
+	ss := make([][]float64, count)
+	vs := make([]matrix, count)
+	vhs := make([]matrix, count)
+	parallel.For(count, func(start, end int) {
+		for i := start; i < end; i++ {
+			us[i], ss[i], vs[i] = svdDecompose(ab.mats[i])
+			vhs[i] = vs[i].t()
+		}
+	})
+
+	backward := func(gU, gS, gV []matrix) []*tensor.Tensor {
+		gA := make([]matrix, count)
+		for i := 0; i < count; i++ {
+			gA[i] = svdBackward(us[i], ss[i], vs[i], gU[i], gS[i], gV[i])
+		}
+		return []*tensor.Tensor{tensorFrom(ab.shape, gA)}
+	}
+	zeros := func(rows, cols int) []matrix {
+		out := make([]matrix, count)
+		for i := range out {
+			out[i] = newMatrix(rows, cols)
+		}
+		return out
+	}
+
+	data, shape := pack(ab.shape, us)
+	u, err := tensor.NewOp(data, shape, []*tensor.Tensor{a}, func(grad *tensor.Tensor) []*tensor.Tensor {
+		return backward(gradBatch(grad, count, m, k), zeros(k, 1), zeros(n, k))
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	data, shape = packVectors(ab.shape, ss)
+	s, err := tensor.NewOp(data, shape, []*tensor.Tensor{a}, func(grad *tensor.Tensor) []*tensor.Tensor {
+		return backward(zeros(m, k), gradBatch(grad, count, k, 1), zeros(n, k))
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	data, shape = pack(ab.shape, vhs)
+	vh, err := tensor.NewOp(data, shape, []*tensor.Tensor{a}, func(grad *tensor.Tensor) []*tensor.Tensor {
+		gVh := gradBatch(grad, count, k, n)
+		gV := make([]matrix, count)
+		for i := range gVh {
+			gV[i] = gVh[i].t()
+		}
+		return backward(zeros(m, k), zeros(k, 1), gV)
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return u, s, vh, nil
+}
+
+// svdBackward maps gradients of U, S (as a k x 1 column) and V back onto A.
+func svdBackward(u matrix, s []float64, v, gU, gS, gV matrix) matrix {
+	m, n, k := u.rows, v.rows, len(s)
+	f := newMatrix(k, k)
+	for i := 0; i < k; i++ {
+		for j := 0; j < k; j++ {
+			if i == j {
+				continue
+			}
+			d := s[j]*s[j] - s[i]*s[i]
+			if d != 0 {
+				f.set(i, j, 1/d)
+			}
+		}
+	}
+	sMat := newMatrix(k, k)
+	sInv := newMatrix(k, k)
+	for i := 0; i < k; i++ {
+		sMat.set(i, i, s[i])
+		if s[i] != 0 {
+			sInv.set(i, i, 1/s[i])
+		}
+	}
+	utgu := mul(u.t(), gU)
+	vtgv := mul(v.t(), gV)
+	j := hadamard(f, sub(utgu, utgu.t()))
+	kk := hadamard(f, sub(vtgv, vtgv.t()))
+	inner := add(mul(j, sMat), mul(sMat, kk))
+	for i := 0; i < k; i++ {
+		inner.data[i*k+i] += gS.data[i]
+	}
+	gA := mul(mul(u, inner), v.t())
+	if m > k {
+		proj := sub(identity(m), mul(u, u.t()))
+		gA = add(gA, mul(mul(mul(proj, gU), sInv), v.t()))
+	}
+	if n > k {
+		proj := sub(identity(n), mul(v, v.t()))
+		gA = add(gA, mul(mul(mul(u, sInv), gV.t()), proj))
+	}
+	return gA
+}
+
+func hadamard(a, b matrix) matrix {
+	out := a.clone()
+	for i := range out.data {
+		out.data[i] *= b.data[i]
+	}
+	return out
+}
+
+// svdDecompose computes a reduced SVD with one-sided Jacobi rotations and
+// returns U [m, k], the singular values and V [n, k].
+func svdDecompose(a matrix) (matrix, []float64, matrix) {
+	if a.rows < a.cols {
+		v, s, u := svdDecompose(a.t())
+		return u, s, v
+	}
+	m, n := a.rows, a.cols
+	w := a.clone()
+	v := identity(n)
+	for sweep := 0; sweep < jacobiMaxSweeps; sweep++ {
+		rotated := false
+		for p := 0; p < n-1; p++ {
+			for q := p + 1; q < n; q++ {
+				alpha, beta, gamma := 0.0, 0.0, 0.0
+				for i := 0; i < m; i++ {
+					wp := w.data[i*n+p]
+					wq := w.data[i*n+q]
+					alpha += wp * wp
+					beta += wq * wq
+					gamma += wp * wq
+				}
+				if gamma == 0 || math.Abs(gamma) <= 1e-15*math.Sqrt(alpha*beta) {
+					continue
+				}
+				rotated = true
+				zeta := (beta - alpha) / (2 * gamma)
+				t := 1 / (math.Abs(zeta) + math.Sqrt(1+zeta*zeta))
+				if zeta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(1+t*t)
+				sn := c * t
+				for i := 0; i < m; i++ {
+					wp := w.data[i*n+p]
+					wq := w.data[i*n+q]
+					w.data[i*n+p] = c*wp - sn*wq
+					w.data[i*n+q] = sn*wp + c*wq
+				}
+				for i := 0; i < n; i++ {
+					vp := v.data[i*n+p]
+					vq := v.data[i*n+q]
+					v.data[i*n+p] = c*vp - sn*vq
+					v.data[i*n+q] = sn*vp + c*vq
+				}
+			}
+		}
+		if !rotated {
+			break
+		}
+	}
+	s := make([]float64, n)
+	for j := 0; j < n; j++ {
+		norm := 0.0
+		for i := 0; i < m; i++ {
+			norm += w.data[i*n+j] * w.data[i*n+j]
+		}
+		s[j] = math.Sqrt(norm)
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(x, y int) bool { return s[order[x]] > s[order[y]] })
+
+	u := newMatrix(m, n)
+	vs := newMatrix(n, n)
+	sorted := make([]float64, n)
+	tol := 1e-12
+	if len(order) > 0 {
+		tol *= math.Max(s[order[0]], 1)
+	}
+	var zeroCols []int
+	for dst, src := range order {
+		sorted[dst] = s[src]
+		for i := 0; i < n; i++ {
+			vs.data[i*n+dst] = v.data[i*n+src]
+		}
+		if s[src] <= tol {
+			zeroCols = append(zeroCols, dst)
+			continue
+		}
+		for i := 0; i < m; i++ {
+			u.data[i*n+dst] = w.data[i*n+src] / s[src]
+		}
+	}
+	completeOrthonormal(u, zeroCols)
+	return u, sorted, vs
+}
+
+// completeOrthonormal fills the listed columns of u with unit vectors
+// orthogonal to all other columns using Gram-Schmidt on the standard basis.
+func completeOrthonormal(u matrix, cols []int) {
+	if len(cols) == 0 {
+		return
+	}
+	m, n := u.rows, u.cols
+	filled := make([]bool, n)
+	for j := range filled {
+		filled[j] = true
+	}
+	for _, c := range cols {
+		filled[c] = false
+	}
+	basis := 0
+	for _, c := range cols {
+		for ; basis < m; basis++ {
+			vec := make([]float64, m)
+			vec[basis] = 1
+			for j := 0; j < n; j++ {
+				if !filled[j] {
+					continue
+				}
+				dot := 0.0
+				for i := 0; i < m; i++ {
+					dot += u.data[i*n+j] * vec[i]
+				}
+				for i := 0; i < m; i++ {
+					vec[i] -= dot * u.data[i*n+j]
+				}
+			}
+			norm := 0.0
+			for _, x := range vec {
+				norm += x * x
+			}
+			norm = math.Sqrt(norm)
+			if norm < 1e-8 {
+				continue
+			}
+			for i := 0; i < m; i++ {
+				u.data[i*n+c] = vec[i] / norm
+			}
+			filled[c] = true
+			basis++
+			break
+		}
+	}
+}
+
+// Pinv returns the Moore-Penrose pseudo-inverse of each matrix in
+// a [..., m, n] as a [..., n, m] tensor. Singular values below
+// rcond * max(S) are treated as zero; rcond <= 0 selects 1e-15.
+// Gradients assume the rank is constant around a.
+func Pinv(a *tensor.Tensor, rcond float64) (*tensor.Tensor, error) {
+	ab, err := unpack(a, "Pinv")
+	if err != nil {
+		return nil, err
+	}
+	m, n := ab.mats[0].rows, ab.mats[0].cols
+	count := len(ab.mats)
+	ps := make([]matrix, count)
+	parallel.For(count, func(start, end int) {
+		for i := start; i < end; i++ {
+			ps[i] = pinvMatrix(ab.mats[i], rcond)
+		}
+	})
+	data, shape := pack(ab.shape, ps)
+	return tensor.NewOp(data, shape, []*tensor.Tensor{a}, func(grad *tensor.Tensor) []*tensor.Tensor {
+		gs := gradBatch(grad, count, n, m)
+		gA := make([]matrix, count)
+		for i := 0; i < count; i++ {
+			gA[i] = pinvBackward(ab.mats[i], ps[i], gs[i])
+		}
+		return []*tensor.Tensor{tensorFrom(ab.shape, gA)}
+	})
+}
+
+// Lstsq returns the minimum-norm least-squares solution X of A X = B.
+// A shape: [..., m, n], B shape: [..., m, k]; X has shape [..., n, k].
+func Lstsq(a, b *tensor.Tensor) (*tensor.Tensor, error) {
+	ab, err := unpack(a, "Lstsq")
+	if err != nil {
+		return nil, err
+	}
+	bb, err := unpack(b, "Lstsq")
+	if err != nil {
+		return nil, err
+	}
+	if !sameBatch(ab, bb) {
+		return nil, errors.New("Lstsq batch dimensions mismatch")
+	}
+	m, n := ab.mats[0].rows, ab.mats[0].cols
+	k := bb.mats[0].cols
+	if bb.mats[0].rows != m {
+		return nil, errors.New("Lstsq right-hand side rows mismatch")
+	}
+	count := len(ab.mats)
+	ps := make([]matrix, count)
+	xs := make([]matrix, count)
+	parallel.For(count, func(start, end int) {
+		for i := start; i < end; i++ {
+			ps[i] = pinvMatrix(ab.mats[i], 0)
+			xs[i] = mul(ps[i], bb.mats[i])
+		}
+	})
+	data, shape := pack(ab.shape, xs)
+	return tensor.NewOp(data, shape, []*tensor.Tensor{a, b}, func(grad *tensor.Tensor) []*tensor.Tensor {
+		gs := gradBatch(grad, count, n, k)
+		gA := make([]matrix, count)
+		gB := make([]matrix, count)
+		for i := 0; i < count; i++ {
+			gB[i] = mul(ps[i].t(), gs[i])
+			gA[i] = pinvBackward(ab.mats[i], ps[i], mul(gs[i], bb.mats[i].t()))
+		}
+		return []*tensor.Tensor{tensorFrom(ab.shape, gA), tensorFrom(bb.shape, gB)}
+	})
+}
+
+func pinvMatrix(a matrix, rcond float64) matrix {
+	if rcond <= 0 {
+		rcond = 1e-15
+	}
+	u, s, v := svdDecompose(a)
+	cutoff := 0.0
+	if len(s) > 0 {
+		cutoff = rcond * s[0]
+	}
+	k := len(s)
+	scaled := v.clone()
+	for j := 0; j < k; j++ {
+		inv := 0.0
+		if s[j] > cutoff {
+			inv = 1 / s[j]
+		}
+		for i := 0; i < v.rows; i++ {
+			scaled.data[i*k+j] *= inv
+		}
+	}
+	return mul(scaled, u.t())
+}
+
+// pinvBackward returns the gradient with respect to A of <G, pinv(A)>:
+// -P^T G P^T + (I - A P) G^T P P^T + P^T P G^T (I - P A).
+func pinvBackward(a, p, g matrix) matrix {
+	m, n := a.rows, a.cols
+	pt := p.t()
+	gt := g.t()
+	out := scale(mul(mul(pt, g), pt), -1)
+	left := sub(identity(m), mul(a, p))
+	out = add(out, mul(mul(mul(left, gt), p), pt))
+	right := sub(identity(n), mul(p, a))
+	out = add(out, mul(mul(mul(pt, p), gt), right))
+	return out
+}
@@ -0,0 +1,411 @@
+package tensor
+
+import (
+	"errors"
+	"math"
+
+	"github.com/fumitoshi0524/ixeoriNet/internal/parallel"
+)
+
+type poolKind int
+
+const (
+	poolMax poolKind = iota
+	poolAvg
+	poolSum
+)
+
+// poolWindow is the half-open input range [start, end) covered by one output
+// position along a single axis, already clipped to the input.
+type poolWindow struct {
+	start int
+	end   int
+}
+
+// MaxPool1D applies 1D max pooling. Input shape: [batch, channels, width]
+func MaxPool1D(input *Tensor, kernel, stride, pad int) (*Tensor, error) {
+	return MaxPool(input, []int{kernel}, []int{stride}, []int{pad}, false)
+}
+
+// MaxPool3D applies 3D max pooling.
+// Input shape: [batch, channels, depth, height, width]
+func MaxPool3D(input *Tensor, kernelD, kernelH, kernelW, strideD, strideH, strideW, padD, padH, padW int) (*Tensor, error) {
+	return MaxPool(input, []int{kernelD, kernelH, kernelW}, []int{strideD, strideH, strideW}, []int{padD, padH, padW}, false)
+}
+
+// AvgPool1D applies 1D average pooling. Input shape: [batch, channels, width]
+func AvgPool1D(input *Tensor, kernel, stride, pad int) (*Tensor, error) {
+	return AvgPool(input, []int{kernel}, []int{stride}, []int{pad}, false)
+}
+
+// AvgPool3D applies 3D average pooling.
+// Input shape: [batch, channels, depth, height, width]
+func AvgPool3D(input *Tensor, kernelD, kernelH, kernelW, strideD, strideH, strideW, padD, padH, padW int) (*Tensor, error) {
+	return AvgPool(input, []int{kernelD, kernelH, kernelW}, []int{strideD, strideH, strideW}, []int{padD, padH, padW}, false)
+}
+
+// MaxPool applies max pooling over the trailing len(kernel) dimensions of an
+// input shaped [batch, channels, spatial...] (1 to 3 spatial dims). A nil
+// stride defaults to the kernel size and a nil padding to zero. ceilMode
+// rounds the output size up so partial windows at the end are kept.
+func MaxPool(input *Tensor, kernel, stride, padding []int, ceilMode bool) (*Tensor, error) {
+	out, _, err := MaxPoolWithIndices(input, kernel, stride, padding, ceilMode)
+	return out, err
+}
+
+// MaxPoolWithIndices is MaxPool that also returns, for every output element,
+// the flat index of the selected value within its input spatial plane, as
+// expected by MaxUnpool.
+func MaxPoolWithIndices(input *Tensor, kernel, stride, padding []int, ceilMode bool) (*Tensor, []int, error) {
+	windows, err := slidingWindows("MaxPool", input, kernel, stride, padding, ceilMode)
+	if err != nil {
+		return nil, nil, err
+	}
+	return poolOp(input, windows, poolMax)
+}
+
+// AvgPool applies average pooling over the trailing len(kernel) dimensions.
+// Padded positions are excluded from the average, matching AvgPool2D.
+func AvgPool(input *Tensor, kernel, stride, padding []int, ceilMode bool) (*Tensor, error) {
+	windows, err := slidingWindows("AvgPool", input, kernel, stride, padding, ceilMode)
+	if err != nil {
+		return nil, err
+	}
+	out, _, err := poolOp(input, windows, poolAvg)
+	return out, err
+}
+
+// LPPool applies power-average pooling (sum x^p)^(1/p) over the trailing
+// len(kernel) dimensions without padding.
+func LPPool(input *Tensor, p float64, kernel, stride []int, ceilMode bool) (*Tensor, error) {
+	if p <= 0 {
+		return nil, errors.New("LPPool norm type must be positive")
+	}
+	windows, err := slidingWindows("LPPool", input, kernel, stride, nil, ceilMode)
+	if err != nil {
+		return nil, err
+	}
+	summed, _, err := poolOp(Pow(input, p), windows, poolSum)
+	if err != nil {
+		return nil, err
+	}
+	return Pow(summed, 1/p), nil
+}
+
+// AdaptiveAvgPool averages over windows chosen so that the spatial output
+// has exactly outputSize, whatever the input size. A single entry applies to
+// every spatial dimension.
+func AdaptiveAvgPool(input *Tensor, outputSize []int) (*Tensor, error) {
+	windows, err := adaptiveWindows("AdaptiveAvgPool", input, outputSize)
+	if err != nil {
+		return nil, err
+	}
+	out, _, err := poolOp(input, windows, poolAvg)
+	return out, err
+}
+
+// AdaptiveMaxPool takes the maximum over windows chosen so that the spatial
+// output has exactly outputSize.
+func AdaptiveMaxPool(input *Tensor, outputSize []int) (*Tensor, error) {
+	out, _, err := AdaptiveMaxPoolWithIndices(input, outputSize)
+	return out, err
+}
+
+// AdaptiveMaxPoolWithIndices is AdaptiveMaxPool that also returns the
+// selected indices within each input spatial plane.
+func AdaptiveMaxPoolWithIndices(input *Tensor, outputSize []int) (*Tensor, []int, error) {
+	windows, err := adaptiveWindows("AdaptiveMaxPool", input, outputSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	return poolOp(input, windows, poolMax)
+}
+
+// MaxUnpool scatters input values back to the positions recorded by
+// MaxPoolWithIndices, filling everything else with zeros. outputSize gives
+// the spatial shape of the result.
+func MaxUnpool(input *Tensor, indices []int, outputSize []int) (*Tensor, error) {
+	if input == nil {
+		return nil, errors.New("MaxUnpool requires input tensor")
+	}
+	rank := len(input.shape)
+	if rank < 3 || rank > 5 {
+		return nil, errors.New("MaxUnpool expects input shape [batch, channels, spatial...] with 1 to 3 spatial dims")
+	}
+	if len(outputSize) != rank-2 {
+		return nil, errors.New("MaxUnpool outputSize length must match spatial dims")
+	}
+	if len(indices) != len(input.data) {
+		return nil, errors.New("MaxUnpool indices length must match input size")
+	}
+	outPlane := 1
+	for _, dim := range outputSize {
+		if dim <= 0 {
+			return nil, errors.New("invalid output size")
+		}
+		outPlane *= dim
+	}
+	for _, idx := range indices {
+		if idx < -1 || idx >= outPlane {
+			return nil, errors.New("MaxUnpool index out of range")
+		}
+	}
+	batch, channels := input.shape[0], input.shape[1]
+	inPlane := len(input.data) / (batch * channels)
+	out := Zeros(append([]int{batch, channels}, outputSize...)...)
+	parallel.For(batch*channels, func(start, end int) {
+		for nc := start; nc < end; nc++ {
+			for i := 0; i < inPlane; i++ {
+				if idx := indices[nc*inPlane+i]; idx >= 0 {
+					out.data[nc*outPlane+idx] = input.data[nc*inPlane+i]
+				}
+			}
+		}
+	})
+
+	if !input.requiresGrad {
+		return out, nil
+	}
+
+	out.requiresGrad = true
+	out.parents = []*Tensor{input}
+	out.node = &node{
+		backward: func(grad *Tensor, grads map[*Tensor]*Tensor) {
+			gInput := Zeros(input.shape...)
+			parallel.For(batch*channels, func(start, end int) {
+				for nc := start; nc < end; nc++ {
+					for i := 0; i < inPlane; i++ {
+						if idx := indices[nc*inPlane+i]; idx >= 0 {
+							gInput.data[nc*inPlane+i] = grad.data[nc*outPlane+idx]
+						}
+					}
+				}
+			})
+			accumulate(grads, input, gInput)
+		},
+	}
+
+	return out, nil
+}
+
+// PoolOutputSize returns the pooled length of an axis of size in, following
+// the same rules as MaxPool and AvgPool.
+func PoolOutputSize(in, kernel, stride, pad int, ceilMode bool) int {
+	span := in + 2*pad - kernel
+	if span < 0 {
+		return 0
+	}
+	out := span/stride + 1
+	if ceilMode {
+		out = (span+stride-1)/stride + 1
+		// the last window must start inside the input or left padding
+		if (out-1)*stride >= in+pad {
+			out--
+		}
+	}
+	return out
+}
+
+func slidingWindows(name string, input *Tensor, kernel, stride, padding []int, ceilMode bool) ([][]poolWindow, error) {
+	if input == nil {
+		return nil, errors.New(name + " requires input tensor")
+	}
+	spatial := len(kernel)
+	if spatial < 1 || spatial > 3 {
+		return nil, errors.New(name + " supports 1 to 3 spatial dims")
+	}
+	if len(input.shape) != spatial+2 {
+		return nil, errors.New(name + " expects input shape [batch, channels, spatial...] matching the kernel rank")
+	}
+	if stride == nil {
+		stride = kernel
+	}
+	if padding == nil {
+		padding = make([]int, spatial)
+	}
+	if len(stride) != spatial || len(padding) != spatial {
+		return nil, errors.New(name + " kernel, stride and padding must have the same length")
+	}
+	windows := make([][]poolWindow, spatial)
+	for d := 0; d < spatial; d++ {
+		if kernel[d] <= 0 {
+			return nil, errors.New("kernel size must be positive")
+		}
+		if stride[d] <= 0 {
+			return nil, errors.New("stride must be positive")
+		}
+		if padding[d] < 0 {
+			return nil, errors.New("padding must be non-negative")
+		}
+		in := input.shape[d+2]
+		out := PoolOutputSize(in, kernel[d], stride[d], padding[d], ceilMode)
+		if out <= 0 {
+			return nil, errors.New("invalid output size")
+		}
+		windows[d] = make([]poolWindow, out)
+		for o := range windows[d] {
+			start := o*stride[d] - padding[d]
+			end := start + kernel[d]
+			if start < 0 {
+				start = 0
+			}
+			if end > in {
+				end = in
+			}
+			windows[d][o] = poolWindow{start: start, end: end}
+		}
+	}
+	return windows, nil
+}
+
+func adaptiveWindows(name string, input *Tensor, outputSize []int) ([][]poolWindow, error) {
+	if input == nil {
+		return nil, errors.New(name + " requires input tensor")
+	}
+	rank := len(input.shape)
+	if rank < 3 || rank > 5 {
+		return nil, errors.New(name + " expects input shape [batch, channels, spatial...] with 1 to 3 spatial dims")
+	}
+	spatial := rank - 2
+	if len(outputSize) != 1 && len(outputSize) != spatial {
+		return nil, errors.New(name + " outputSize length must match spatial dims")
+	}
+	windows := make([][]poolWindow, spatial)
+	for d := 0; d < spatial; d++ {
+		out := outputSize[0]
+		if len(outputSize) > 1 {
+			out = outputSize[d]
+		}
+		if out <= 0 {
+			return nil, errors.New("invalid output size")
+		}
+		in := input.shape[d+2]
+		windows[d] = make([]poolWindow, out)
+		for o := range windows[d] {
+			windows[d][o] = poolWindow{
+				start: o * in / out,
+				end:   ((o+1)*in + out - 1) / out,
+			}
+		}
+	}
+	return windows, nil
+}
+
+// poolOp reduces every window of every [batch, channel] plane. For max
+// pooling it also returns the argmax of each window within its input plane
+// (-1 for empty windows).
+func poolOp(input *Tensor, windows [][]poolWindow, kind poolKind) (*Tensor, []int, error) {
+	spatial := len(windows)
+	inSpatial := input.shape[2:]
+	outSpatial := make([]int, spatial)
+	for d := range windows {
+		outSpatial[d] = len(windows[d])
+	}
+	inStrides := makeStrides(inSpatial)
+	outStrides := makeStrides(outSpatial)
+	inPlane := 1
+	for _, dim := range inSpatial {
+		inPlane *= dim
+	}
+	outPlane := 1
+	for _, dim := range outSpatial {
+		outPlane *= dim
+	}
+
+	// input offsets covered by each output position, shared by all planes
+	plan := make([][]int, outPlane)
+	for pos := 0; pos < outPlane; pos++ {
+		offsets := []int{0}
+		for d := 0; d < spatial; d++ {
+			w := windows[d][(pos/outStrides[d])%outSpatial[d]]
+			next := make([]int, 0, len(offsets)*(w.end-w.start))
+			for _, base := range offsets {
+				for i := w.start; i < w.end; i++ {
+					next = append(next, base+i*inStrides[d])
+				}
+			}
+			offsets = next
+		}
+		if kind == poolAvg && len(offsets) == 0 {
+			return nil, nil, errors.New("AvgPool kernel has no overlap with input")
+		}
+		plan[pos] = offsets
+	}
+
+	batch, channels := input.shape[0], input.shape[1]
+	out := Zeros(append([]int{batch, channels}, outSpatial...)...)
+	var indices []int
+	if kind == poolMax {
+		indices = make([]int, len(out.data))
+	}
+	parallel.For(batch*channels, func(start, end int) {
+		for nc := start; nc < end; nc++ {
+			inBase := nc * inPlane
+			outBase := nc * outPlane
+			for pos, offsets := range plan {
+				switch kind {
+				case poolMax:
+					best := math.Inf(-1)
+					bestIdx := -1
+					for _, off := range offsets {
+						if v := input.data[inBase+off]; v > best || bestIdx < 0 {
+							best = v
+							bestIdx = off
+						}
+					}
+					out.data[outBase+pos] = best
+					indices[outBase+pos] = bestIdx
+				default:
+					sum := 0.0
+					for _, off := range offsets {
+						sum += input.data[inBase+off]
+					}
+					if kind == poolAvg {
+						sum /= float64(len(offsets))
+					}
+					out.data[outBase+pos] = sum
+				}
+			}
+		}
+	})
+
+	if !input.requiresGrad {
+		return out, indices, nil
+	}
+
+	out.requiresGrad = true
+	out.parents = []*Tensor{input}
+	out.node = &node{
+		backward: func(grad *Tensor, grads map[*Tensor]*Tensor) {
+			gInput := Zeros(input.shape...)
+			parallel.For(batch*channels, func(start, end int) {
+				for nc := start; nc < end; nc++ {
+					inBase := nc * inPlane
+					outBase := nc * outPlane
+					for pos, offsets := range plan {
+						g := grad.data[outBase+pos]
+						if g == 0 {
+							continue
+						}
+						switch kind {
+						case poolMax:
+							if idx := indices[outBase+pos]; idx >= 0 {
+								gInput.data[inBase+idx] += g
+							}
+						default:
+							if kind == poolAvg {
+								g /= float64(len(offsets))
+							}
+							for _, off := range offsets {
+								gInput.data[inBase+off] += g
+							}
+						}
+					}
+				}
+			})
+			accumulate(grads, input, gInput)
+		},
+	}
+
+	return out, indices, nil
+}
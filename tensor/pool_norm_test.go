@@ -320,3 +320,26 @@ func TestBatchNormTrainingAndEval(t *testing.T) {
 		t.Fatalf("BatchNorm eval mismatch: got %v want %v", evalOut.Data(), expectedEval)
 	}
 }
+
+func TestBatchNormEvalGradient(t *testing.T) {
+	// running statistics are constants, so eval mode is an affine map per channel
+	input := MustNew([]float64{1, 2, 3, 6}, 2, 2)
+	input.SetRequiresGrad(true)
+	runningVar := MustNew([]float64{4, 0.25}, 2)
+	out, err := BatchNorm(input, MustNew([]float64{0.5, -1}, 2), runningVar, MustNew([]float64{0.5, -1}, 2), nil, 0.1, 0, false)
+	if err != nil {
+		t.Fatalf("BatchNorm eval failed: %v", err)
+	}
+	if err := Sum(out).Backward(); err != nil {
+		t.Fatalf("backward failed: %v", err)
+	}
+	if !almostEqualSlices(input.Grad().Data(), []float64{0.25, -2, 0.25, -2}, 1e-12) {
+		t.Fatalf("unexpected eval input gradient %v", input.Grad().Data())
+	}
+
+	volume := sequenceValues(2*2*2*2*3, 0.5)
+	weight := []float64{0.7, -1.1}
+	checkInterpolateGrad(t, "batchnorm rank 5 eval", volume, []int{2, 2, 2, 2, 3}, func(in *Tensor) (*Tensor, error) {
+		return BatchNorm(in, MustNew([]float64{0.2, -0.1}, 2), MustNew([]float64{1.5, 0.8}, 2), MustNew(weight, 2), nil, 0.1, 1e-5, false)
+	})
+}
@@ -0,0 +1,168 @@
+package tensor
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPool1DAnd3DForwardBackward(t *testing.T) {
+	input := MustNew([]float64{1, 5, 2, 4, 3, 0}, 1, 1, 6)
+	input.SetRequiresGrad(true)
+	out, err := MaxPool1D(input, 2, 2, 0)
+	if err != nil {
+		t.Fatalf("MaxPool1D failed: %v", err)
+	}
+	if !almostEqualSlices(out.Data(), []float64{5, 4, 3}, 1e-12) {
+		t.Fatalf("unexpected MaxPool1D output: %v", out.Data())
+	}
+	if err := Sum(out).Backward(); err != nil {
+		t.Fatalf("backward failed: %v", err)
+	}
+	if !almostEqualSlices(input.Grad().Data(), []float64{0, 1, 0, 1, 1, 0}, 1e-12) {
+		t.Fatalf("unexpected MaxPool1D grad: %v", input.Grad().Data())
+	}
+
+	avg, err := AvgPool1D(MustNew([]float64{1, 5, 2, 4, 3, 0}, 1, 1, 6), 3, 3, 0)
+	if err != nil {
+		t.Fatalf("AvgPool1D failed: %v", err)
+	}
+	if !almostEqualSlices(avg.Data(), []float64{8.0 / 3, 7.0 / 3}, 1e-12) {
+		t.Fatalf("unexpected AvgPool1D output: %v", avg.Data())
+	}
+
+	vals := make([]float64, 2*2*2*2)
+	for i := range vals {
+		vals[i] = math.Sin(float64(i))
+	}
+	checkInterpolateGrad(t, "MaxPool3D", vals, []int{1, 2, 2, 2, 2}, func(x *Tensor) (*Tensor, error) {
+		return MaxPool3D(x, 2, 1, 2, 1, 1, 2, 0, 0, 1)
+	})
+	checkInterpolateGrad(t, "AvgPool3D", vals, []int{1, 2, 2, 2, 2}, func(x *Tensor) (*Tensor, error) {
+		return AvgPool3D(x, 2, 2, 1, 1, 1, 1, 1, 0, 0)
+	})
+
+	// the generic path agrees with the dedicated 2D kernels
+	img := make([]float64, 2*3*5)
+	for i := range img {
+		img[i] = math.Cos(0.7 * float64(i))
+	}
+	x := MustNew(img, 1, 2, 3, 5)
+	ref, _ := MaxPool2D(x, 2, 3, 1, 2, 1, 1)
+	got, err := MaxPool(x, []int{2, 3}, []int{1, 2}, []int{1, 1}, false)
+	if err != nil || !almostEqualSlices(got.Data(), ref.Data(), 1e-12) {
+		t.Fatalf("MaxPool mismatch with MaxPool2D: %v vs %v (%v)", got, ref, err)
+	}
+	ref, _ = AvgPool2D(x, 2, 2, 2, 2, 1, 0)
+	got, err = AvgPool(x, []int{2, 2}, nil, []int{1, 0}, false)
+	if err != nil || !almostEqualSlices(got.Data(), ref.Data(), 1e-12) {
+		t.Fatalf("AvgPool mismatch with AvgPool2D: %v vs %v (%v)", got, ref, err)
+	}
+}
+
+func TestPoolCeilMode(t *testing.T) {
+	input := MustNew([]float64{1, 2, 3, 4, 5}, 1, 1, 5)
+	floor, err := MaxPool(input, []int{2}, nil, nil, false)
+	if err != nil {
+		t.Fatalf("MaxPool failed: %v", err)
+	}
+	if !almostEqualSlices(floor.Data(), []float64{2, 4}, 1e-12) {
+		t.Fatalf("unexpected floor-mode output: %v", floor.Data())
+	}
+	ceil, err := MaxPool(input, []int{2}, nil, nil, true)
+	if err != nil {
+		t.Fatalf("MaxPool failed: %v", err)
+	}
+	if !almostEqualSlices(ceil.Data(), []float64{2, 4, 5}, 1e-12) {
+		t.Fatalf("unexpected ceil-mode output: %v", ceil.Data())
+	}
+	avg, err := AvgPool(input, []int{2}, nil, nil, true)
+	if err != nil {
+		t.Fatalf("AvgPool failed: %v", err)
+	}
+	if !almostEqualSlices(avg.Data(), []float64{1.5, 3.5, 5}, 1e-12) {
+		t.Fatalf("unexpected ceil-mode average: %v", avg.Data())
+	}
+	// a window starting in the right padding is dropped
+	if got := PoolOutputSize(4, 2, 2, 1, true); got != 3 {
+		t.Fatalf("unexpected ceil-mode output size %d", got)
+	}
+}
+
+func TestAdaptiveAndLPPool(t *testing.T) {
+	vals := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	input := MustNew(vals, 1, 1, 3, 5)
+	out, err := AdaptiveAvgPool(input, []int{2, 3})
+	if err != nil {
+		t.Fatalf("AdaptiveAvgPool failed: %v", err)
+	}
+	// rows [0,2) and [1,3); columns [0,2), [1,4), [3,5)
+	expected := []float64{4, 5.5, 7, 9, 10.5, 12}
+	if !almostEqualSlices(out.Data(), expected, 1e-12) {
+		t.Fatalf("unexpected adaptive avg output: %v", out.Data())
+	}
+	mx, err := AdaptiveMaxPool(input, []int{1})
+	if err != nil {
+		t.Fatalf("AdaptiveMaxPool failed: %v", err)
+	}
+	if mx.Numel() != 1 || mx.Data()[0] != 15 {
+		t.Fatalf("unexpected global max: %v", mx.Data())
+	}
+	checkInterpolateGrad(t, "AdaptiveAvgPool", vals, []int{1, 1, 3, 5}, func(x *Tensor) (*Tensor, error) {
+		return AdaptiveAvgPool(x, []int{2, 3})
+	})
+	checkInterpolateGrad(t, "AdaptiveMaxPool", vals, []int{1, 1, 3, 5}, func(x *Tensor) (*Tensor, error) {
+		return AdaptiveMaxPool(x, []int{2, 2})
+	})
+
+	lp, err := LPPool(MustNew([]float64{3, 4, 1, 2}, 1, 1, 4), 2, []int{2}, nil, false)
+	if err != nil {
+		t.Fatalf("LPPool failed: %v", err)
+	}
+	if !almostEqualSlices(lp.Data(), []float64{5, math.Sqrt(5)}, 1e-12) {
+		t.Fatalf("unexpected LPPool output: %v", lp.Data())
+	}
+	checkInterpolateGrad(t, "LPPool", []float64{0.5, 1, 2, 0.3, 1.5, 0.7, 0.9, 0.1}, []int{1, 2, 2, 2}, func(x *Tensor) (*Tensor, error) {
+		return LPPool(x, 3, []int{2, 1}, []int{1, 1}, false)
+	})
+}
+
+func TestMaxUnpoolRoundTrip(t *testing.T) {
+	vals := []float64{
+		1, 7, 2, 3,
+		4, 0, 9, 5,
+		6, 8, 1, 2,
+		3, 2, 4, 10,
+	}
+	input := MustNew(vals, 1, 1, 4, 4)
+	pooled, indices, err := MaxPoolWithIndices(input, []int{2, 2}, nil, nil, false)
+	if err != nil {
+		t.Fatalf("MaxPoolWithIndices failed: %v", err)
+	}
+	if want := []int{1, 6, 9, 15}; len(indices) != 4 || indices[0] != want[0] || indices[1] != want[1] || indices[2] != want[2] || indices[3] != want[3] {
+		t.Fatalf("unexpected indices %v", indices)
+	}
+	pooled.SetRequiresGrad(true)
+	unpooled, err := MaxUnpool(pooled, indices, []int{4, 4})
+	if err != nil {
+		t.Fatalf("MaxUnpool failed: %v", err)
+	}
+	expected := []float64{
+		0, 7, 0, 0,
+		0, 0, 9, 0,
+		0, 8, 0, 0,
+		0, 0, 0, 10,
+	}
+	if !almostEqualSlices(unpooled.Data(), expected, 1e-12) {
+		t.Fatalf("unexpected unpooled output: %v", unpooled.Data())
+	}
+	if err := weightedLoss(unpooled).Backward(); err != nil {
+		t.Fatalf("backward failed: %v", err)
+	}
+	w := func(i int) float64 { return 0.3 + 0.11*float64(i%7) - 0.05*float64(i%3) }
+	if !almostEqualSlices(pooled.Grad().Data(), []float64{w(1), w(6), w(9), w(15)}, 1e-12) {
+		t.Fatalf("unexpected unpool grad: %v", pooled.Grad().Data())
+	}
+	if _, err := MaxUnpool(pooled, []int{1, 2, 3, 99}, []int{4, 4}); err == nil {
+		t.Fatalf("expected out-of-range index error")
+	}
+}
@@ -22,3 +22,18 @@ func Randn(shape ...int) *Tensor {
 	rngLock.Unlock()
 	return MustNew(data, shape...)
 }
+
+// Rand returns a tensor of samples drawn uniformly from [0, 1).
+func Rand(shape ...int) *Tensor {
+	size := 1
+	for _, dim := range shape {
+		size *= dim
+	}
+	data := make([]float64, size)
+	rngLock.Lock()
+	for i := range data {
+		data[i] = rng.Float64()
+	}
+	rngLock.Unlock()
+	return MustNew(data, shape...)
+}
@@ -49,3 +49,18 @@ func TestRandnProducesDifferentSamples(t *testing.T) {
 		t.Fatalf("consecutive Randn calls produced identical samples")
 	}
 }
+
+func TestRandRangeAndMean(t *testing.T) {
+	data := Rand(2000).Data()
+	mean := 0.0
+	for _, v := range data {
+		if v < 0 || v >= 1 {
+			t.Fatalf("rand sample %v outside [0, 1)", v)
+		}
+		mean += v
+	}
+	mean /= float64(len(data))
+	if math.Abs(mean-0.5) > 0.05 {
+		t.Fatalf("rand mean unexpected: %.6f", mean)
+	}
+}
@@ -0,0 +1,436 @@
+package tensor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/fumitoshi0524/ixeoriNet/internal/parallel"
+)
+
+// LSTMSequence runs one direction of an LSTM layer over a whole sequence as
+// a single differentiable operation.
+// Input shape: [seq, batch, in]; h0, c0: [batch, hidden].
+// Weights are fused over the gates in the order input, forget, cell, output:
+// weightIH [4*hidden, in], weightHH [4*hidden, hidden] and bias [4*hidden]
+// (nil for none), the latter being the sum of the input and hidden biases.
+// The input projection is computed once for all steps, leaving one hidden
+// matmul per step, and the backward pass runs through time by hand instead
+// of recording per-step graph nodes. With lengths (nil for full sequences),
+// batch element b stops after lengths[b] steps: its state is carried over
+// and its output is zero at the padded steps. reverse reads the sequence
+// from the end. It returns the output [seq, batch, hidden] and the final
+// hidden and cell states [batch, hidden].
+func LSTMSequence(x, h0, c0, weightIH, weightHH, bias *Tensor, lengths []int, reverse bool) (*Tensor, *Tensor, *Tensor, error) {
+	seqLen, batch, inSize, hidden, err := checkRecurrentSequence(x, h0, weightIH, weightHH, bias, lengths, 4)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if c0 == nil || len(c0.shape) != 2 || c0.shape[0] != batch || c0.shape[1] != hidden {
+		return nil, nil, nil, errors.New("LSTMSequence cell state shape mismatch")
+	}
+	gates := 4 * hidden
+	xProj := recurrentInputProjection(x, weightIH, bias, seqLen*batch, inSize, gates)
+
+	// acts holds the gate activations of every (step, batch) pair, hs and cs
+	// the states before each step in processing order plus the final ones.
+	acts := make([]float64, seqLen*batch*gates)
+	tanhC := make([]float64, seqLen*batch*hidden)
+	hs := make([]float64, (seqLen+1)*batch*hidden)
+	cs := make([]float64, (seqLen+1)*batch*hidden)
+	copy(hs, h0.data)
+	copy(cs, c0.data)
+	result := make([]float64, (seqLen+2)*batch*hidden)
+	for s := 0; s < seqLen; s++ {
+		t := sequenceTime(s, seqLen, reverse)
+		hPrev := hs[s*batch*hidden : (s+1)*batch*hidden]
+		cPrev := cs[s*batch*hidden : (s+1)*batch*hidden]
+		hNext := hs[(s+1)*batch*hidden : (s+2)*batch*hidden]
+		cNext := cs[(s+1)*batch*hidden : (s+2)*batch*hidden]
+		parallel.For(batch, func(start, end int) {
+			for b := start; b < end; b++ {
+				hRow := hPrev[b*hidden : (b+1)*hidden]
+				if lengths != nil && t >= lengths[b] {
+					copy(hNext[b*hidden:(b+1)*hidden], hRow)
+					copy(cNext[b*hidden:(b+1)*hidden], cPrev[b*hidden:(b+1)*hidden])
+					continue
+				}
+				row := t*batch + b
+				a := acts[row*gates : (row+1)*gates]
+				copy(a, xProj[row*gates:(row+1)*gates])
+				for j := 0; j < gates; j++ {
+					w := weightHH.data[j*hidden : (j+1)*hidden]
+					sum := 0.0
+					for k, hv := range hRow {
+						sum += hv * w[k]
+					}
+					a[j] += sum
+				}
+				for j := 0; j < hidden; j++ {
+					a[j] = sigmoid(a[j])
+					a[hidden+j] = sigmoid(a[hidden+j])
+					a[2*hidden+j] = math.Tanh(a[2*hidden+j])
+					a[3*hidden+j] = sigmoid(a[3*hidden+j])
+					c := a[hidden+j]*cPrev[b*hidden+j] + a[j]*a[2*hidden+j]
+					tc := math.Tanh(c)
+					h := a[3*hidden+j] * tc
+					cNext[b*hidden+j] = c
+					hNext[b*hidden+j] = h
+					tanhC[row*hidden+j] = tc
+					result[row*hidden+j] = h
+				}
+			}
+		})
+	}
+	copy(result[seqLen*batch*hidden:], hs[seqLen*batch*hidden:])
+	copy(result[(seqLen+1)*batch*hidden:], cs[seqLen*batch*hidden:])
+
+	inputs := []*Tensor{x, h0, c0, weightIH, weightHH, bias}
+	combined, err := NewOp(result, []int{seqLen + 2, batch, hidden}, inputs, func(grad *Tensor) []*Tensor {
+		dh := append([]float64(nil), grad.data[seqLen*batch*hidden:(seqLen+1)*batch*hidden]...)
+		dc := append([]float64(nil), grad.data[(seqLen+1)*batch*hidden:]...)
+		dProj := make([]float64, seqLen*batch*gates)
+		dWeightHH := make([]float64, gates*hidden)
+		for s := seqLen - 1; s >= 0; s-- {
+			t := sequenceTime(s, seqLen, reverse)
+			hPrev := hs[s*batch*hidden : (s+1)*batch*hidden]
+			cPrev := cs[s*batch*hidden : (s+1)*batch*hidden]
+			parallel.For(batch, func(start, end int) {
+				for b := start; b < end; b++ {
+					if lengths != nil && t >= lengths[b] {
+						continue
+					}
+					row := t*batch + b
+					a := acts[row*gates : (row+1)*gates]
+					dA := dProj[row*gates : (row+1)*gates]
+					dhRow := dh[b*hidden : (b+1)*hidden]
+					for j := 0; j < hidden; j++ {
+						i, f, g, o := a[j], a[hidden+j], a[2*hidden+j], a[3*hidden+j]
+						tc := tanhC[row*hidden+j]
+						dht := dhRow[j] + grad.data[row*hidden+j]
+						dct := dc[b*hidden+j] + dht*o*(1-tc*tc)
+						dA[j] = dct * g * i * (1 - i)
+						dA[hidden+j] = dct * cPrev[b*hidden+j] * f * (1 - f)
+						dA[2*hidden+j] = dct * i * (1 - g*g)
+						dA[3*hidden+j] = dht * tc * o * (1 - o)
+						dc[b*hidden+j] = dct * f
+					}
+					for k := range dhRow {
+						dhRow[k] = 0
+					}
+					for j, d := range dA {
+						if d == 0 {
+							continue
+						}
+						w := weightHH.data[j*hidden : (j+1)*hidden]
+						for k := range dhRow {
+							dhRow[k] += d * w[k]
+						}
+					}
+				}
+			})
+			recurrentHiddenWeightGrad(dWeightHH, dProj[t*batch*gates:(t+1)*batch*gates], hPrev, batch, hidden, 0, gates)
+		}
+		grads := recurrentInputGrads(x, weightIH, bias, dProj, seqLen*batch, inSize, gates)
+		return []*Tensor{grads[0], MustNew(dh, batch, hidden), MustNew(dc, batch, hidden), grads[1], MustNew(dWeightHH, gates, hidden), grads[2]}
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	parts, err := Split(0, []int{seqLen, 1, 1}, combined)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	hN, err := parts[1].Reshape(batch, hidden)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	cN, err := parts[2].Reshape(batch, hidden)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return parts[0], hN, cN, nil
+}
+
+// GRUSequence is the GRU counterpart of LSTMSequence, with gates in the order
+// update, reset, new (weightIH [3*hidden, in], weightHH [3*hidden, hidden]).
+// The new gate applies its hidden weights to r*h and adds the whole bias
+// outside the reset, so each step takes one hidden matmul for the update and
+// reset gates and one for the new gate.
+func GRUSequence(x, h0, weightIH, weightHH, bias *Tensor, lengths []int, reverse bool) (*Tensor, *Tensor, error) {
+	seqLen, batch, inSize, hidden, err := checkRecurrentSequence(x, h0, weightIH, weightHH, bias, lengths, 3)
+	if err != nil {
+		return nil, nil, err
+	}
+	gates := 3 * hidden
+	xProj := recurrentInputProjection(x, weightIH, bias, seqLen*batch, inSize, gates)
+
+	acts := make([]float64, seqLen*batch*gates)
+	resetHidden := make([]float64, seqLen*batch*hidden)
+	hs := make([]float64, (seqLen+1)*batch*hidden)
+	copy(hs, h0.data)
+	result := make([]float64, (seqLen+1)*batch*hidden)
+	for s := 0; s < seqLen; s++ {
+		t := sequenceTime(s, seqLen, reverse)
+		hPrev := hs[s*batch*hidden : (s+1)*batch*hidden]
+		hNext := hs[(s+1)*batch*hidden : (s+2)*batch*hidden]
+		parallel.For(batch, func(start, end int) {
+			for b := start; b < end; b++ {
+				hRow := hPrev[b*hidden : (b+1)*hidden]
+				if lengths != nil && t >= lengths[b] {
+					copy(hNext[b*hidden:(b+1)*hidden], hRow)
+					continue
+				}
+				row := t*batch + b
+				a := acts[row*gates : (row+1)*gates]
+				rh := resetHidden[row*hidden : (row+1)*hidden]
+				copy(a, xProj[row*gates:(row+1)*gates])
+				for j := 0; j < 2*hidden; j++ {
+					w := weightHH.data[j*hidden : (j+1)*hidden]
+					sum := 0.0
+					for k, hv := range hRow {
+						sum += hv * w[k]
+					}
+					a[j] = sigmoid(a[j] + sum)
+				}
+				for k := range rh {
+					rh[k] = a[hidden+k] * hRow[k]
+				}
+				for j := 0; j < hidden; j++ {
+					w := weightHH.data[(2*hidden+j)*hidden : (2*hidden+j+1)*hidden]
+					sum := 0.0
+					for k, v := range rh {
+						sum += v * w[k]
+					}
+					n := math.Tanh(a[2*hidden+j] + sum)
+					a[2*hidden+j] = n
+					z := a[j]
+					h := (1-z)*n + z*hRow[j]
+					hNext[b*hidden+j] = h
+					result[row*hidden+j] = h
+				}
+			}
+		})
+	}
+	copy(result[seqLen*batch*hidden:], hs[seqLen*batch*hidden:])
+
+	inputs := []*Tensor{x, h0, weightIH, weightHH, bias}
+	combined, err := NewOp(result, []int{seqLen + 1, batch, hidden}, inputs, func(grad *Tensor) []*Tensor {
+		dh := append([]float64(nil), grad.data[seqLen*batch*hidden:]...)
+		dProj := make([]float64, seqLen*batch*gates)
+		dWeightHH := make([]float64, gates*hidden)
+		for s := seqLen - 1; s >= 0; s-- {
+			t := sequenceTime(s, seqLen, reverse)
+			hPrev := hs[s*batch*hidden : (s+1)*batch*hidden]
+			parallel.For(batch, func(start, end int) {
+				dRH := make([]float64, hidden)
+				for b := start; b < end; b++ {
+					if lengths != nil && t >= lengths[b] {
+						continue
+					}
+					row := t*batch + b
+					a := acts[row*gates : (row+1)*gates]
+					dA := dProj[row*gates : (row+1)*gates]
+					hRow := hPrev[b*hidden : (b+1)*hidden]
+					dhRow := dh[b*hidden : (b+1)*hidden]
+					for j := 0; j < hidden; j++ {
+						z, n := a[j], a[2*hidden+j]
+						dht := dhRow[j] + grad.data[row*hidden+j]
+						dA[j] = dht * (hRow[j] - n) * z * (1 - z)
+						dA[2*hidden+j] = dht * (1 - z) * (1 - n*n)
+						dhRow[j] = dht * z
+					}
+					for k := range dRH {
+						dRH[k] = 0
+					}
+					for j := 0; j < hidden; j++ {
+						d := dA[2*hidden+j]
+						if d == 0 {
+							continue
+						}
+						w := weightHH.data[(2*hidden+j)*hidden : (2*hidden+j+1)*hidden]
+						for k := range dRH {
+							dRH[k] += d * w[k]
+						}
+					}
+					for k := 0; k < hidden; k++ {
+						r := a[hidden+k]
+						dA[hidden+k] = dRH[k] * hRow[k] * r * (1 - r)
+						dhRow[k] += dRH[k] * r
+					}
+					for j := 0; j < 2*hidden; j++ {
+						d := dA[j]
+						if d == 0 {
+							continue
+						}
+						w := weightHH.data[j*hidden : (j+1)*hidden]
+						for k := range dhRow {
+							dhRow[k] += d * w[k]
+						}
+					}
+				}
+			})
+			stepGrads := dProj[t*batch*gates : (t+1)*batch*gates]
+			recurrentHiddenWeightGrad(dWeightHH, stepGrads, hPrev, batch, hidden, 0, 2*hidden)
+			recurrentHiddenWeightGrad(dWeightHH, stepGrads, resetHidden[t*batch*hidden:(t+1)*batch*hidden], batch, hidden, 2*hidden, gates)
+		}
+		grads := recurrentInputGrads(x, weightIH, bias, dProj, seqLen*batch, inSize, gates)
+		return []*Tensor{grads[0], MustNew(dh, batch, hidden), grads[1], MustNew(dWeightHH, gates, hidden), grads[2]}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	parts, err := Split(0, []int{seqLen, 1}, combined)
+	if err != nil {
+		return nil, nil, err
+	}
+	hN, err := parts[1].Reshape(batch, hidden)
+	if err != nil {
+		return nil, nil, err
+	}
+	return parts[0], hN, nil
+}
+
+func checkRecurrentSequence(x, h0, weightIH, weightHH, bias *Tensor, lengths []int, numGates int) (int, int, int, int, error) {
+	if x == nil || h0 == nil || weightIH == nil || weightHH == nil {
+		return 0, 0, 0, 0, errors.New("recurrent sequence requires input, state and weights")
+	}
+	if len(x.shape) != 3 {
+		return 0, 0, 0, 0, errors.New("recurrent sequence expects input shape [seq, batch, features]")
+	}
+	seqLen, batch, inSize := x.shape[0], x.shape[1], x.shape[2]
+	if len(h0.shape) != 2 || h0.shape[0] != batch {
+		return 0, 0, 0, 0, errors.New("recurrent sequence hidden state shape mismatch")
+	}
+	hidden := h0.shape[1]
+	gates := numGates * hidden
+	if len(weightIH.shape) != 2 || weightIH.shape[0] != gates || weightIH.shape[1] != inSize {
+		return 0, 0, 0, 0, fmt.Errorf("input weights must be [%d, %d], got %v", gates, inSize, weightIH.shape)
+	}
+	if len(weightHH.shape) != 2 || weightHH.shape[0] != gates || weightHH.shape[1] != hidden {
+		return 0, 0, 0, 0, fmt.Errorf("hidden weights must be [%d, %d], got %v", gates, hidden, weightHH.shape)
+	}
+	if bias != nil && (len(bias.shape) != 1 || bias.shape[0] != gates) {
+		return 0, 0, 0, 0, fmt.Errorf("bias must be [%d], got %v", gates, bias.shape)
+	}
+	if lengths != nil {
+		if len(lengths) != batch {
+			return 0, 0, 0, 0, fmt.Errorf("expected %d lengths, got %d", batch, len(lengths))
+		}
+		for b, l := range lengths {
+			if l < 0 || l > seqLen {
+				return 0, 0, 0, 0, fmt.Errorf("length %d of sequence %d out of range [0, %d]", l, b, seqLen)
+			}
+		}
+	}
+	return seqLen, batch, inSize, hidden, nil
+}
+
+// sequenceTime maps processing step s to its time index.
+func sequenceTime(s, seqLen int, reverse bool) int {
+	if reverse {
+		return seqLen - 1 - s
+	}
+	return s
+}
+
+// recurrentInputProjection computes x weightIHᵀ + bias for all rows at once.
+func recurrentInputProjection(x, weightIH, bias *Tensor, rows, inSize, gates int) []float64 {
+	proj := make([]float64, rows*gates)
+	parallel.For(rows, func(start, end int) {
+		for r := start; r < end; r++ {
+			xRow := x.data[r*inSize : (r+1)*inSize]
+			out := proj[r*gates : (r+1)*gates]
+			for j := range out {
+				w := weightIH.data[j*inSize : (j+1)*inSize]
+				sum := 0.0
+				if bias != nil {
+					sum = bias.data[j]
+				}
+				for k, xv := range xRow {
+					sum += xv * w[k]
+				}
+				out[j] = sum
+			}
+		}
+	})
+	return proj
+}
+
+// recurrentHiddenWeightGrad adds the gradient of gate rows [from, to) of the
+// hidden weights for one step: dW[j] += Σ_b dA[b, j] · h[b].
+func recurrentHiddenWeightGrad(dW, dA, h []float64, batch, hidden, from, to int) {
+	gates := len(dA) / batch
+	parallel.For(to-from, func(start, end int) {
+		for j := from + start; j < from+end; j++ {
+			w := dW[j*hidden : (j+1)*hidden]
+			for b := 0; b < batch; b++ {
+				d := dA[b*gates+j]
+				if d == 0 {
+					continue
+				}
+				hRow := h[b*hidden : (b+1)*hidden]
+				for k := range w {
+					w[k] += d * hRow[k]
+				}
+			}
+		}
+	})
+}
+
+// recurrentInputGrads turns the gradient of the input projection into
+// gradients for x, weightIH and bias (nil when not required).
+func recurrentInputGrads(x, weightIH, bias *Tensor, dProj []float64, rows, inSize, gates int) [3]*Tensor {
+	var grads [3]*Tensor
+	if x.requiresGrad {
+		dx := Zeros(x.shape...)
+		parallel.For(rows, func(start, end int) {
+			for r := start; r < end; r++ {
+				out := dx.data[r*inSize : (r+1)*inSize]
+				for j, d := range dProj[r*gates : (r+1)*gates] {
+					if d == 0 {
+						continue
+					}
+					w := weightIH.data[j*inSize : (j+1)*inSize]
+					for k := range out {
+						out[k] += d * w[k]
+					}
+				}
+			}
+		})
+		grads[0] = dx
+	}
+	if weightIH.requiresGrad {
+		dW := Zeros(weightIH.shape...)
+		parallel.For(gates, func(start, end int) {
+			for j := start; j < end; j++ {
+				out := dW.data[j*inSize : (j+1)*inSize]
+				for r := 0; r < rows; r++ {
+					d := dProj[r*gates+j]
+					if d == 0 {
+						continue
+					}
+					xRow := x.data[r*inSize : (r+1)*inSize]
+					for k := range out {
+						out[k] += d * xRow[k]
+					}
+				}
+			}
+		})
+		grads[1] = dW
+	}
+	if bias != nil && bias.requiresGrad {
+		dB := Zeros(gates)
+		for r := 0; r < rows; r++ {
+			for j := 0; j < gates; j++ {
+				dB.data[j] += dProj[r*gates+j]
+			}
+		}
+		grads[2] = dB
+	}
+	return grads
+}
+
+func sigmoid(v float64) float64 {
+	return 1 / (1 + math.Exp(-v))
+}
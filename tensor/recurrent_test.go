@@ -0,0 +1,110 @@
+package tensor
+
+import (
+	"math"
+	"testing"
+)
+
+func sequenceValues(n int, phase float64) []float64 {
+	vals := make([]float64, n)
+	for i := range vals {
+		vals[i] = math.Sin(float64(i)*0.9+phase) * 0.7
+	}
+	return vals
+}
+
+func TestLSTMSequenceGradients(t *testing.T) {
+	const seqLen, batch, in, hidden = 3, 2, 2, 2
+	shapes := map[string][]int{
+		"x": {seqLen, batch, in}, "h0": {batch, hidden}, "c0": {batch, hidden},
+		"wIH": {4 * hidden, in}, "wHH": {4 * hidden, hidden}, "bias": {4 * hidden},
+	}
+	bases := map[string][]float64{}
+	phase := 0.0
+	for _, name := range []string{"x", "h0", "c0", "wIH", "wHH", "bias"} {
+		n := 1
+		for _, d := range shapes[name] {
+			n *= d
+		}
+		bases[name] = sequenceValues(n, phase)
+		phase += 0.4
+	}
+	for _, reverse := range []bool{false, true} {
+		run := func(name string, in *Tensor) (*Tensor, error) {
+			args := map[string]*Tensor{}
+			for n, vals := range bases {
+				args[n] = MustNew(vals, shapes[n]...)
+			}
+			args[name] = in
+			out, h, c, err := LSTMSequence(args["x"], args["h0"], args["c0"], args["wIH"], args["wHH"], args["bias"], []int{3, 2}, reverse)
+			if err != nil {
+				return nil, err
+			}
+			hN, _ := Unsqueeze(h, 0)
+			cN, _ := Unsqueeze(c, 0)
+			return Concat(0, out, hN, cN)
+		}
+		for name := range shapes {
+			name := name
+			checkInterpolateGrad(t, "lstm "+name, bases[name], shapes[name], func(in *Tensor) (*Tensor, error) {
+				return run(name, in)
+			})
+		}
+	}
+
+	x := MustNew(bases["x"], shapes["x"]...)
+	h0 := MustNew(bases["h0"], shapes["h0"]...)
+	wIH := MustNew(bases["wIH"], shapes["wIH"]...)
+	wHH := MustNew(bases["wHH"], shapes["wHH"]...)
+	out, h, _, err := LSTMSequence(x, h0, h0, wIH, wHH, nil, []int{3, 1}, false)
+	if err != nil {
+		t.Fatalf("LSTMSequence failed: %v", err)
+	}
+	data := out.Data()
+	// the second sequence stops after one step: zero output, state kept
+	if !almostEqualSlices(data[batch*hidden+hidden:2*batch*hidden], []float64{0, 0}, 0) {
+		t.Fatalf("expected zero output past the sequence end, got %v", data)
+	}
+	if !almostEqualSlices(h.Data()[hidden:], data[hidden:2*hidden], 0) {
+		t.Fatalf("final state must be the last valid output")
+	}
+	if _, _, _, err := LSTMSequence(x, h0, h0, Zeros(hidden, in), wHH, nil, nil, false); err == nil {
+		t.Fatalf("expected weight shape error")
+	}
+}
+
+func TestGRUSequenceGradients(t *testing.T) {
+	const seqLen, batch, in, hidden = 3, 2, 2, 2
+	shapes := map[string][]int{
+		"x": {seqLen, batch, in}, "h0": {batch, hidden},
+		"wIH": {3 * hidden, in}, "wHH": {3 * hidden, hidden}, "bias": {3 * hidden},
+	}
+	bases := map[string][]float64{}
+	phase := 0.0
+	for _, name := range []string{"x", "h0", "wIH", "wHH", "bias"} {
+		n := 1
+		for _, d := range shapes[name] {
+			n *= d
+		}
+		bases[name] = sequenceValues(n, phase)
+		phase += 0.4
+	}
+	for _, reverse := range []bool{false, true} {
+		for name := range shapes {
+			name := name
+			checkInterpolateGrad(t, "gru "+name, bases[name], shapes[name], func(in *Tensor) (*Tensor, error) {
+				args := map[string]*Tensor{}
+				for n, vals := range bases {
+					args[n] = MustNew(vals, shapes[n]...)
+				}
+				args[name] = in
+				out, h, err := GRUSequence(args["x"], args["h0"], args["wIH"], args["wHH"], args["bias"], []int{2, 3}, reverse)
+				if err != nil {
+					return nil, err
+				}
+				hN, _ := Unsqueeze(h, 0)
+				return Concat(0, out, hN)
+			})
+		}
+	}
+}
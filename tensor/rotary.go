@@ -0,0 +1,71 @@
+package tensor
+
+import (
+	"errors"
+	"math"
+
+	"github.com/fumitoshi0524/ixeoriNet/internal/parallel"
+)
+
+// ApplyRotary rotates feature pairs (i, i+d/2) of x by position-dependent
+// angles (rotary position embedding). The second-to-last dimension of x is
+// the sequence position, starting at offset, and the last dimension d must
+// be even. Frequencies are base^(-2i/d); base defaults to 10000.
+func ApplyRotary(x *Tensor, offset int, base float64) (*Tensor, error) {
+	rank := len(x.shape)
+	if rank < 2 {
+		return nil, errors.New("rotary embedding expects rank >= 2 input [..., seq, dim]")
+	}
+	seq, dim := x.shape[rank-2], x.shape[rank-1]
+	if dim%2 != 0 {
+		return nil, errors.New("rotary embedding requires an even feature dimension")
+	}
+	if offset < 0 {
+		return nil, errors.New("rotary offset must be non-negative")
+	}
+	if base <= 0 {
+		base = 10000
+	}
+	half := dim / 2
+	cos := make([]float64, seq*half)
+	sin := make([]float64, seq*half)
+	for p := 0; p < seq; p++ {
+		for i := 0; i < half; i++ {
+			angle := float64(p+offset) * math.Pow(base, -2*float64(i)/float64(dim))
+			cos[p*half+i] = math.Cos(angle)
+			sin[p*half+i] = math.Sin(angle)
+		}
+	}
+	// rotate applies the rotation by sign*angle from src into dst.
+	rotate := func(dst, src []float64, sign float64) {
+		rows := len(src) / dim
+		parallel.For(rows, func(start, end int) {
+			for r := start; r < end; r++ {
+				p := r % seq
+				row := src[r*dim : (r+1)*dim]
+				out := dst[r*dim : (r+1)*dim]
+				for i := 0; i < half; i++ {
+					c, s := cos[p*half+i], sign*sin[p*half+i]
+					a, b := row[i], row[i+half]
+					out[i] = a*c - b*s
+					out[i+half] = b*c + a*s
+				}
+			}
+		})
+	}
+	out := Zeros(x.shape...)
+	rotate(out.data, x.data, 1)
+	if x.requiresGrad {
+		out.requiresGrad = true
+		out.parents = []*Tensor{x}
+		out.node = &node{
+			backward: func(grad *Tensor, grads map[*Tensor]*Tensor) {
+				// the transpose of a rotation is the rotation by -angle
+				g := Zeros(x.shape...)
+				rotate(g.data, grad.data, -1)
+				accumulate(grads, x, g)
+			},
+		}
+	}
+	return out, nil
+}
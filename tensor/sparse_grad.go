@@ -0,0 +1,185 @@
+package tensor
+
+import (
+	"errors"
+	"fmt"
+)
+
+// newRowSparse wraps the listed rows of a gradient for a tensor of the given
+// shape. rows must be distinct; values holds one row per entry, in order.
+func newRowSparse(rows []int, values []float64, shape []int) *Tensor {
+	sparseShape := append([]int{len(rows)}, shape[1:]...)
+	return &Tensor{
+		data:    values,
+		shape:   sparseShape,
+		strides: makeStrides(sparseShape),
+		rows:    rows,
+	}
+}
+
+func rowWidth(shape []int) int {
+	width := 1
+	for _, dim := range shape[1:] {
+		width *= dim
+	}
+	return width
+}
+
+// densify expands a row-sparse gradient into a dense tensor of shape.
+func (t *Tensor) densify(shape []int) *Tensor {
+	out := Zeros(shape...)
+	t.scatterInto(out)
+	return out
+}
+
+func (t *Tensor) scatterInto(dst *Tensor) {
+	width := rowWidth(dst.shape)
+	for i, row := range t.rows {
+		dstRow := dst.data[row*width : (row+1)*width]
+		for j, v := range t.data[i*width : (i+1)*width] {
+			dstRow[j] += v
+		}
+	}
+}
+
+// addGrad returns dst + src for two gradients of a tensor shaped shape. Dense
+// gradients are summed in place into dst; two row-sparse gradients merge into
+// a new row-sparse one, and a mix of both becomes dense.
+func addGrad(dst, src *Tensor, shape []int) *Tensor {
+	switch {
+	case dst.rows == nil && src.rows == nil:
+		addInPlace(dst, src)
+		return dst
+	case dst.rows != nil && src.rows != nil:
+		width := rowWidth(shape)
+		rows := append([]int(nil), dst.rows...)
+		values := append([]float64(nil), dst.data...)
+		position := make(map[int]int, len(rows))
+		for i, row := range rows {
+			position[row] = i
+		}
+		for i, row := range src.rows {
+			srcRow := src.data[i*width : (i+1)*width]
+			pos, ok := position[row]
+			if !ok {
+				position[row] = len(rows)
+				rows = append(rows, row)
+				values = append(values, srcRow...)
+				continue
+			}
+			dstRow := values[pos*width : (pos+1)*width]
+			for j, v := range srcRow {
+				dstRow[j] += v
+			}
+		}
+		return newRowSparse(rows, values, shape)
+	}
+	dense := dst
+	if dst.rows != nil {
+		dense = dst.densify(shape)
+	}
+	if src.rows != nil {
+		src.scatterInto(dense)
+	} else {
+		addInPlace(dense, src)
+	}
+	return dense
+}
+
+// SparseGrad returns the accumulated gradient in row-sparse form: the indices
+// along dimension 0 that received a gradient and their values, shaped
+// [len(rows), ...]. Both results are nil when the gradient is dense or
+// absent; Grad always returns the dense equivalent.
+func (t *Tensor) SparseGrad() ([]int, *Tensor) {
+	if t == nil || t.grad == nil || t.grad.rows == nil {
+		return nil, nil
+	}
+	values := t.grad.Clone()
+	values.rows = nil
+	return append([]int(nil), t.grad.rows...), values
+}
+
+// RowValues copies the listed rows (indices along dimension 0) of t into a
+// flat slice, one row after another.
+func (t *Tensor) RowValues(rows []int) ([]float64, error) {
+	if len(t.shape) == 0 {
+		return nil, errors.New("RowValues requires a tensor with rows")
+	}
+	width := rowWidth(t.shape)
+	out := make([]float64, 0, len(rows)*width)
+	for _, row := range rows {
+		if row < 0 || row >= t.shape[0] {
+			return nil, fmt.Errorf("row %d out of range for %d rows", row, t.shape[0])
+		}
+		out = append(out, t.data[row*width:(row+1)*width]...)
+	}
+	return out, nil
+}
+
+// SetRowValues overwrites the listed rows of t with values laid out as
+// RowValues returns them, leaving every other row untouched.
+func (t *Tensor) SetRowValues(rows []int, values []float64) error {
+	if len(t.shape) == 0 {
+		return errors.New("SetRowValues requires a tensor with rows")
+	}
+	width := rowWidth(t.shape)
+	if len(values) != len(rows)*width {
+		return errors.New("SetRowValues expects one full row per index")
+	}
+	for i, row := range rows {
+		if row < 0 || row >= t.shape[0] {
+			return fmt.Errorf("row %d out of range for %d rows", row, t.shape[0])
+		}
+		copy(t.data[row*width:(row+1)*width], values[i*width:(i+1)*width])
+	}
+	return nil
+}
+
+// rowGrad accumulates a gradient for a tensor of the given shape row by row,
+// either densely or as a row-sparse gradient covering only touched rows.
+type rowGrad struct {
+	shape    []int
+	width    int
+	dense    *Tensor
+	rows     []int
+	values   []float64
+	position map[int]int
+}
+
+func newRowGrad(shape []int, sparse bool) *rowGrad {
+	g := &rowGrad{shape: shape, width: rowWidth(shape)}
+	if sparse {
+		g.position = map[int]int{}
+	} else {
+		g.dense = Zeros(shape...)
+	}
+	return g
+}
+
+// row returns the gradient slot of row r for accumulation. The slice is only
+// valid until the next call.
+func (g *rowGrad) row(r int) []float64 {
+	if g.dense != nil {
+		return g.dense.data[r*g.width : (r+1)*g.width]
+	}
+	pos, ok := g.position[r]
+	if !ok {
+		pos = len(g.rows)
+		g.position[r] = pos
+		g.rows = append(g.rows, r)
+		g.values = append(g.values, make([]float64, g.width)...)
+	}
+	return g.values[pos*g.width : (pos+1)*g.width]
+}
+
+// tensor returns the accumulated gradient, or nil when a sparse gradient
+// touched no rows.
+func (g *rowGrad) tensor() *Tensor {
+	if g.dense != nil {
+		return g.dense
+	}
+	if len(g.rows) == 0 {
+		return nil
+	}
+	return newRowSparse(g.rows, g.values, g.shape)
+}
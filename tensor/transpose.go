@@ -39,3 +39,57 @@ func (t *Tensor) MustTranspose() *Tensor {
 	}
 	return tr
 }
+
+// Permute reorders the dimensions of a so that output dimension i is input
+// dimension axes[i].
+func Permute(a *Tensor, axes ...int) (*Tensor, error) {
+	rank := len(a.shape)
+	if len(axes) != rank {
+		return nil, errors.New("permute axes must match tensor rank")
+	}
+	seen := make([]bool, rank)
+	outShape := make([]int, rank)
+	for i, ax := range axes {
+		if ax < 0 || ax >= rank || seen[ax] {
+			return nil, errors.New("permute axes must be a permutation of the dimensions")
+		}
+		seen[ax] = true
+		outShape[i] = a.shape[ax]
+	}
+	// srcStrides[i] is the input stride of output dimension i
+	srcStrides := make([]int, rank)
+	for i, ax := range axes {
+		srcStrides[i] = a.strides[ax]
+	}
+	out := Zeros(outShape...)
+	outStrides := out.strides
+	index := make([]int, len(out.data))
+	parallel.For(len(out.data), func(start, end int) {
+		for flat := start; flat < end; flat++ {
+			rem := flat
+			src := 0
+			for d := 0; d < rank; d++ {
+				src += (rem / outStrides[d]) * srcStrides[d]
+				rem %= outStrides[d]
+			}
+			index[flat] = src
+			out.data[flat] = a.data[src]
+		}
+	})
+	if a.requiresGrad {
+		out.requiresGrad = true
+		out.parents = []*Tensor{a}
+		out.node = &node{
+			backward: func(grad *Tensor, grads map[*Tensor]*Tensor) {
+				g := Zeros(a.shape...)
+				parallel.For(len(grad.data), func(start, end int) {
+					for flat := start; flat < end; flat++ {
+						g.data[index[flat]] = grad.data[flat]
+					}
+				})
+				accumulate(grads, a, g)
+			},
+		}
+	}
+	return out, nil
+}
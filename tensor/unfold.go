@@ -0,0 +1,205 @@
+package tensor
+
+import (
+	"errors"
+
+	"github.com/fumitoshi0524/ixeoriNet/internal/parallel"
+)
+
+// Unfold extracts sliding local blocks from input [batch, channels, spatial...]
+// with 1 to 3 spatial dims, returning [batch, channels*prod(kernel), blocks]
+// where blocks is the number of window positions. Each column holds one
+// flattened patch ordered channel-major, then by kernel position. Nil
+// dilation and stride default to 1 and nil padding to 0; padded positions
+// read as zero.
+func Unfold(input *Tensor, kernel, dilation, padding, stride []int) (*Tensor, error) {
+	if input == nil {
+		return nil, errors.New("Unfold requires input tensor")
+	}
+	spatial := len(kernel)
+	if len(input.shape) != spatial+2 {
+		return nil, errors.New("Unfold expects input shape [batch, channels, spatial...] matching the kernel rank")
+	}
+	batch, channels := input.shape[0], input.shape[1]
+	plan, err := newPatchPlan("Unfold", input.shape[2:], kernel, dilation, padding, stride)
+	if err != nil {
+		return nil, err
+	}
+	rows := channels * plan.kernelSize
+	out := Zeros(batch, rows, plan.blocks)
+	parallel.For(batch*channels, func(start, end int) {
+		for nc := start; nc < end; nc++ {
+			inBase := nc * plan.imageSize
+			outBase := nc * plan.kernelSize * plan.blocks
+			for i, src := range plan.offsets {
+				if src >= 0 {
+					out.data[outBase+i] = input.data[inBase+src]
+				}
+			}
+		}
+	})
+
+	if !input.requiresGrad {
+		return out, nil
+	}
+
+	out.requiresGrad = true
+	out.parents = []*Tensor{input}
+	out.node = &node{
+		backward: func(grad *Tensor, grads map[*Tensor]*Tensor) {
+			gInput := Zeros(input.shape...)
+			plan.scatter(grad.data, gInput.data, batch*channels)
+			accumulate(grads, input, gInput)
+		},
+	}
+
+	return out, nil
+}
+
+// Fold is the adjoint of Unfold: it sums the columns of input
+// [batch, channels*prod(kernel), blocks] back into an image of shape
+// [batch, channels, outputSize...]. Overlapping patches accumulate.
+func Fold(input *Tensor, outputSize, kernel, dilation, padding, stride []int) (*Tensor, error) {
+	if input == nil {
+		return nil, errors.New("Fold requires input tensor")
+	}
+	if len(input.shape) != 3 {
+		return nil, errors.New("Fold expects input shape [batch, channels*kernel, blocks]")
+	}
+	if len(outputSize) != len(kernel) {
+		return nil, errors.New("Fold outputSize length must match kernel rank")
+	}
+	plan, err := newPatchPlan("Fold", outputSize, kernel, dilation, padding, stride)
+	if err != nil {
+		return nil, err
+	}
+	batch, rows := input.shape[0], input.shape[1]
+	if rows%plan.kernelSize != 0 {
+		return nil, errors.New("Fold input channels must be divisible by the kernel size")
+	}
+	if input.shape[2] != plan.blocks {
+		return nil, errors.New("Fold input block count does not match outputSize")
+	}
+	channels := rows / plan.kernelSize
+	out := Zeros(append([]int{batch, channels}, outputSize...)...)
+	plan.scatter(input.data, out.data, batch*channels)
+
+	if !input.requiresGrad {
+		return out, nil
+	}
+
+	out.requiresGrad = true
+	out.parents = []*Tensor{input}
+	out.node = &node{
+		backward: func(grad *Tensor, grads map[*Tensor]*Tensor) {
+			gInput := Zeros(input.shape...)
+			parallel.For(batch*channels, func(start, end int) {
+				for nc := start; nc < end; nc++ {
+					imgBase := nc * plan.imageSize
+					colBase := nc * plan.kernelSize * plan.blocks
+					for i, src := range plan.offsets {
+						if src >= 0 {
+							gInput.data[colBase+i] = grad.data[imgBase+src]
+						}
+					}
+				}
+			})
+			accumulate(grads, input, gInput)
+		},
+	}
+
+	return out, nil
+}
+
+// patchPlan maps every (kernel position, block) pair of one channel to the
+// image offset it reads, or -1 when it falls in the padding.
+type patchPlan struct {
+	offsets    []int
+	kernelSize int
+	blocks     int
+	imageSize  int
+}
+
+func newPatchPlan(name string, image, kernel, dilation, padding, stride []int) (*patchPlan, error) {
+	spatial := len(kernel)
+	if spatial < 1 || spatial > 3 {
+		return nil, errors.New(name + " supports 1 to 3 spatial dims")
+	}
+	dilation = defaultInts(dilation, spatial, 1)
+	padding = defaultInts(padding, spatial, 0)
+	stride = defaultInts(stride, spatial, 1)
+	if len(dilation) != spatial || len(padding) != spatial || len(stride) != spatial {
+		return nil, errors.New(name + " kernel, dilation, padding and stride must have the same length")
+	}
+	outSpatial := make([]int, spatial)
+	kernelSize, blocks, imageSize := 1, 1, 1
+	for d := 0; d < spatial; d++ {
+		if kernel[d] <= 0 {
+			return nil, errors.New("kernel size must be positive")
+		}
+		if dilation[d] <= 0 {
+			return nil, errors.New("dilation must be positive")
+		}
+		if stride[d] <= 0 {
+			return nil, errors.New("stride must be positive")
+		}
+		if padding[d] < 0 {
+			return nil, errors.New("padding must be non-negative")
+		}
+		span := dilation[d]*(kernel[d]-1) + 1
+		outSpatial[d] = (image[d]+2*padding[d]-span)/stride[d] + 1
+		if image[d]+2*padding[d] < span || outSpatial[d] <= 0 {
+			return nil, errors.New("invalid output size")
+		}
+		kernelSize *= kernel[d]
+		blocks *= outSpatial[d]
+		imageSize *= image[d]
+	}
+	kStrides := makeStrides(kernel)
+	bStrides := makeStrides(outSpatial)
+	iStrides := makeStrides(image)
+	offsets := make([]int, kernelSize*blocks)
+	for k := 0; k < kernelSize; k++ {
+		for b := 0; b < blocks; b++ {
+			offset := 0
+			for d := 0; d < spatial; d++ {
+				kd := (k / kStrides[d]) % kernel[d]
+				bd := (b / bStrides[d]) % outSpatial[d]
+				pos := bd*stride[d] - padding[d] + kd*dilation[d]
+				if pos < 0 || pos >= image[d] {
+					offset = -1
+					break
+				}
+				offset += pos * iStrides[d]
+			}
+			offsets[k*blocks+b] = offset
+		}
+	}
+	return &patchPlan{offsets: offsets, kernelSize: kernelSize, blocks: blocks, imageSize: imageSize}, nil
+}
+
+// scatter adds the column values of each plane into its image plane.
+func (p *patchPlan) scatter(cols, image []float64, planes int) {
+	parallel.For(planes, func(start, end int) {
+		for nc := start; nc < end; nc++ {
+			imgBase := nc * p.imageSize
+			colBase := nc * p.kernelSize * p.blocks
+			for i, dst := range p.offsets {
+				if dst >= 0 {
+					image[imgBase+dst] += cols[colBase+i]
+				}
+			}
+		}
+	})
+}
+
+func defaultInts(values []int, n, fill int) []int {
+	if values != nil {
+		return values
+	}
+	out := make([]int, n)
+	for i := range out {
+		out[i] = fill
+	}
+	return out
+}
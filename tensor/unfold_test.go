@@ -0,0 +1,106 @@
+package tensor
+
+import (
+	"math"
+	"testing"
+)
+
+func TestUnfold2DPatches(t *testing.T) {
+	input := MustNew([]float64{
+		1, 2, 3,
+		4, 5, 6,
+		7, 8, 9,
+	}, 1, 1, 3, 3)
+	cols, err := Unfold(input, []int{2, 2}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Unfold failed: %v", err)
+	}
+	if shape := cols.Shape(); shape[0] != 1 || shape[1] != 4 || shape[2] != 4 {
+		t.Fatalf("unexpected Unfold shape %v", shape)
+	}
+	expected := []float64{
+		1, 2, 4, 5,
+		2, 3, 5, 6,
+		4, 5, 7, 8,
+		5, 6, 8, 9,
+	}
+	if !almostEqualSlices(cols.Data(), expected, 1e-12) {
+		t.Fatalf("unexpected Unfold output: %v", cols.Data())
+	}
+
+	// with this padding and dilation every in-bounds tap lands on the centre
+	cols, err = Unfold(input, []int{2, 2}, []int{2, 2}, []int{1, 1}, []int{2, 2})
+	if err != nil {
+		t.Fatalf("dilated Unfold failed: %v", err)
+	}
+	expected = []float64{
+		0, 0, 0, 5,
+		0, 0, 5, 0,
+		0, 5, 0, 0,
+		5, 0, 0, 0,
+	}
+	if !almostEqualSlices(cols.Data(), expected, 1e-12) {
+		t.Fatalf("unexpected dilated Unfold output: %v", cols.Data())
+	}
+
+	// folding overlapping patches counts how often each pixel was read
+	folded, err := Fold(Ones(1, 4, 4), []int{3, 3}, []int{2, 2}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Fold failed: %v", err)
+	}
+	counts := []float64{1, 2, 1, 2, 4, 2, 1, 2, 1}
+	if !almostEqualSlices(folded.Data(), counts, 1e-12) {
+		t.Fatalf("unexpected Fold output: %v", folded.Data())
+	}
+}
+
+func TestUnfoldLowersConv2D(t *testing.T) {
+	inVals := make([]float64, 2*4*5)
+	for i := range inVals {
+		inVals[i] = math.Sin(0.3 * float64(i))
+	}
+	wVals := make([]float64, 3*2*3*2)
+	for i := range wVals {
+		wVals[i] = math.Cos(0.7*float64(i)) * 0.5
+	}
+	ref, err := Conv2D(MustNew(inVals, 1, 2, 4, 5), MustNew(wVals, 3, 2, 3, 2), nil, 1, 2, 1, 0)
+	if err != nil {
+		t.Fatalf("Conv2D failed: %v", err)
+	}
+	cols, err := Unfold(MustNew(inVals, 1, 2, 4, 5), []int{3, 2}, nil, []int{1, 0}, []int{1, 2})
+	if err != nil {
+		t.Fatalf("Unfold failed: %v", err)
+	}
+	shape := cols.Shape()
+	mat, err := cols.Reshape(shape[1], shape[2])
+	if err != nil {
+		t.Fatalf("reshape failed: %v", err)
+	}
+	lowered, err := MatMul(MustNew(wVals, 3, 12), mat)
+	if err != nil {
+		t.Fatalf("MatMul failed: %v", err)
+	}
+	if !almostEqualSlices(lowered.Data(), ref.Data(), 1e-12) {
+		t.Fatalf("lowered conv mismatch:\n got %v\nwant %v", lowered.Data(), ref.Data())
+	}
+}
+
+func TestUnfoldFoldGradients(t *testing.T) {
+	vals := make([]float64, 2*2*3*2)
+	for i := range vals {
+		vals[i] = math.Sin(float64(i)) + 0.1*float64(i)
+	}
+	checkInterpolateGrad(t, "Unfold1D", vals[:12], []int{2, 2, 3}, func(x *Tensor) (*Tensor, error) {
+		return Unfold(x, []int{2}, nil, []int{1}, nil)
+	})
+	checkInterpolateGrad(t, "Unfold3D", vals, []int{1, 2, 2, 3, 2}, func(x *Tensor) (*Tensor, error) {
+		return Unfold(x, []int{2, 2, 1}, []int{1, 2, 1}, []int{0, 1, 0}, []int{1, 1, 2})
+	})
+	checkInterpolateGrad(t, "Fold2D", vals[:16], []int{1, 4, 4}, func(x *Tensor) (*Tensor, error) {
+		return Fold(x, []int{3, 3}, []int{2, 2}, nil, nil, nil)
+	})
+
+	if _, err := Fold(Ones(1, 5, 4), []int{3, 3}, []int{2, 2}, nil, nil, nil); err == nil {
+		t.Fatalf("expected error for channels not divisible by kernel size")
+	}
+}
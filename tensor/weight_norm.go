@@ -0,0 +1,114 @@
+package tensor
+
+import (
+	"errors"
+	"math"
+)
+
+// weightNormEps bounds the row norms of WeightNorm from below, so an all-zero
+// direction row yields a zero weight row instead of 0/0.
+const weightNormEps = 1e-12
+
+// WeightNorm recombines a weight from its direction v, shaped [out, ...], and
+// per-row magnitude g with out elements: w[i] = g[i] · v[i] / ‖v[i]‖, where
+// v[i] is everything indexed by i along dimension 0. Norms below 1e-12 are
+// clamped to 1e-12.
+func WeightNorm(v, g *Tensor) (*Tensor, error) {
+	if v == nil || g == nil {
+		return nil, errors.New("WeightNorm requires direction and magnitude tensors")
+	}
+	if len(v.shape) == 0 || v.shape[0] == 0 {
+		return nil, errors.New("WeightNorm direction must have a non-empty first dimension")
+	}
+	rows := v.shape[0]
+	if g.Numel() != rows {
+		return nil, errors.New("WeightNorm magnitude must have one element per row")
+	}
+	cols := v.Numel() / rows
+	norms := make([]float64, rows)
+	data := make([]float64, len(v.data))
+	for i := 0; i < rows; i++ {
+		row := v.data[i*cols : (i+1)*cols]
+		sq := 0.0
+		for _, x := range row {
+			sq += x * x
+		}
+		norms[i] = math.Max(math.Sqrt(sq), weightNormEps)
+		for j, x := range row {
+			data[i*cols+j] = g.data[i] * x / norms[i]
+		}
+	}
+	vData := append([]float64(nil), v.data...)
+	gData := append([]float64(nil), g.data...)
+	return NewOp(data, v.shape, []*Tensor{v, g}, func(grad *Tensor) []*Tensor {
+		gv := Zeros(v.shape...)
+		gg := Zeros(g.shape...)
+		for i := 0; i < rows; i++ {
+			dot := 0.0
+			for j := 0; j < cols; j++ {
+				dot += grad.data[i*cols+j] * vData[i*cols+j]
+			}
+			n := norms[i]
+			gg.data[i] = dot / n
+			// a clamped norm is constant, so only the direct term remains
+			proj := 0.0
+			if n > weightNormEps {
+				proj = dot / (n * n)
+			}
+			for j := 0; j < cols; j++ {
+				idx := i*cols + j
+				gv.data[idx] = gData[i] / n * (grad.data[idx] - vData[idx]*proj)
+			}
+		}
+		return []*Tensor{gv, gg}
+	})
+}
+
+// SpectralNorm divides weight, viewed as an [out, rest] matrix, by the
+// spectral-norm estimate σ = uᵀ·W·v, where u (out elements) and v (rest
+// elements) are the singular-vector estimates of a power iteration. u and v
+// are treated as constants, so gradients flow through W and σ only.
+func SpectralNorm(weight, u, v *Tensor) (*Tensor, error) {
+	if weight == nil || u == nil || v == nil {
+		return nil, errors.New("SpectralNorm requires weight and singular vectors")
+	}
+	if len(weight.shape) < 2 {
+		return nil, errors.New("SpectralNorm weight must have at least 2 dimensions")
+	}
+	rows := weight.shape[0]
+	cols := weight.Numel() / rows
+	if u.Numel() != rows || v.Numel() != cols {
+		return nil, errors.New("SpectralNorm singular vector size mismatch")
+	}
+	uData := append([]float64(nil), u.data...)
+	vData := append([]float64(nil), v.data...)
+	sigma := 0.0
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			sigma += uData[i] * weight.data[i*cols+j] * vData[j]
+		}
+	}
+	if sigma == 0 {
+		return nil, errors.New("SpectralNorm estimate is zero")
+	}
+	data := make([]float64, len(weight.data))
+	for i, x := range weight.data {
+		data[i] = x / sigma
+	}
+	wData := append([]float64(nil), weight.data...)
+	return NewOp(data, weight.shape, []*Tensor{weight}, func(grad *Tensor) []*Tensor {
+		dot := 0.0
+		for i, g := range grad.data {
+			dot += g * wData[i]
+		}
+		scale := dot / (sigma * sigma)
+		gw := Zeros(weight.shape...)
+		for i := 0; i < rows; i++ {
+			for j := 0; j < cols; j++ {
+				idx := i*cols + j
+				gw.data[idx] = grad.data[idx]/sigma - scale*uData[i]*vData[j]
+			}
+		}
+		return []*Tensor{gw}
+	})
+}
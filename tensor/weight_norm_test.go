@@ -0,0 +1,71 @@
+package tensor
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWeightNormForwardBackward(t *testing.T) {
+	v := MustNew([]float64{3, 4, 0, -2, 0, 0}, 2, 3)
+	out, err := WeightNorm(v, MustNew([]float64{10, 0.5}, 2, 1))
+	if err != nil {
+		t.Fatalf("WeightNorm failed: %v", err)
+	}
+	if !almostEqualSlices(out.Data(), []float64{6, 8, 0, -0.5, 0, 0}, 1e-12) {
+		t.Fatalf("unexpected WeightNorm output %v", out.Data())
+	}
+	base := sequenceValues(12, 0.2)
+	gBase := []float64{0.7, -1.3, 2}
+	checkInterpolateGrad(t, "weightnorm v", base, []int{3, 2, 2}, func(in *Tensor) (*Tensor, error) {
+		return WeightNorm(in, MustNew(gBase, 3))
+	})
+	checkInterpolateGrad(t, "weightnorm g", gBase, []int{3}, func(in *Tensor) (*Tensor, error) {
+		return WeightNorm(MustNew(base, 3, 2, 2), in)
+	})
+	if _, err := WeightNorm(v, MustNew([]float64{1}, 1)); err == nil {
+		t.Fatalf("expected magnitude size error")
+	}
+
+	// an all-zero row stays zero with finite gradients
+	zero := MustNew([]float64{0, 0, 1, 2}, 2, 2)
+	zero.SetRequiresGrad(true)
+	g := MustNew([]float64{0.5, 3}, 2)
+	g.SetRequiresGrad(true)
+	out, err = WeightNorm(zero, g)
+	if err != nil {
+		t.Fatalf("zero-row WeightNorm failed: %v", err)
+	}
+	if !almostEqualSlices(out.Data(), []float64{0, 0, 3 / math.Sqrt(5), 6 / math.Sqrt(5)}, 1e-12) {
+		t.Fatalf("unexpected zero-row output %v", out.Data())
+	}
+	if err := Sum(out).Backward(); err != nil {
+		t.Fatalf("zero-row backward failed: %v", err)
+	}
+	for _, x := range append(zero.Grad().Data(), g.Grad().Data()...) {
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			t.Fatalf("zero-row gradients must be finite: %v %v", zero.Grad().Data(), g.Grad().Data())
+		}
+	}
+}
+
+func TestSpectralNormForwardBackward(t *testing.T) {
+	// diag(3, 1) has singular vectors e1, e1 and spectral norm 3
+	w := MustNew([]float64{3, 0, 0, 1}, 2, 2)
+	e1 := MustNew([]float64{1, 0}, 2)
+	out, err := SpectralNorm(w, e1, e1)
+	if err != nil {
+		t.Fatalf("SpectralNorm failed: %v", err)
+	}
+	if !almostEqualSlices(out.Data(), []float64{1, 0, 0, 1.0 / 3}, 1e-12) {
+		t.Fatalf("unexpected SpectralNorm output %v", out.Data())
+	}
+	base := sequenceValues(6, 0.9)
+	u := MustNew([]float64{0.6, -0.8}, 2)
+	v := MustNew([]float64{1 / math.Sqrt(3), 1 / math.Sqrt(3), -1 / math.Sqrt(3)}, 3)
+	checkInterpolateGrad(t, "spectralnorm weight", base, []int{2, 3}, func(in *Tensor) (*Tensor, error) {
+		return SpectralNorm(in, u, v)
+	})
+	if _, err := SpectralNorm(w, v, e1); err == nil {
+		t.Fatalf("expected singular vector size error")
+	}
+}